@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	pgadapter "github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/postgres"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/config"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/usecases"
+)
+
+// adminUsage は管理コマンドの使い方
+const adminUsage = `usage: professor <command> [flags]
+
+commands:
+  gc-storage   ストレージと files テーブルを突き合わせ、孤立オブジェクトを削除する
+`
+
+// runAdminCommand はサブコマンド付きで起動された場合の管理コマンドを実行し、終了コードを返す。
+func runAdminCommand(cfg *config.Config, args []string) int {
+	switch args[0] {
+	case "gc-storage":
+		return runStorageGC(cfg, args[1:])
+	default:
+		fmt.Fprint(os.Stderr, adminUsage)
+		return 2
+	}
+}
+
+// runStorageGC は孤立オブジェクトの整合性チェックを 1 回実行し、結果を JSON で標準出力に書き出す。
+func runStorageGC(cfg *config.Config, args []string) int {
+	fs := flag.NewFlagSet("gc-storage", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "削除せずに報告のみ行う")
+	grace := fs.Duration("grace", cfg.StorageGCGracePeriod, "孤立オブジェクトを削除するまでの猶予（最終更新からの経過時間）")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := connectDB(cfg.DatabaseURL)
+	if err != nil {
+		slog.Error("failed to connect to database", "error", err)
+		return 1
+	}
+	defer db.Close()
+
+	objectStorage, _, err := newObjectStorage(ctx, cfg)
+	if err != nil {
+		slog.Error("failed to set up object storage", "error", err)
+		return 1
+	}
+
+	gcUC := usecases.NewStorageGCUseCase(pgadapter.NewFileRepo(db), objectStorage)
+	report, err := gcUC.Reconcile(ctx, usecases.ReconcileStorageInput{
+		Now:         time.Now().UTC(),
+		GracePeriod: *grace,
+		DryRun:      *dryRun,
+	})
+	if err != nil {
+		slog.Error("storage reconciliation failed", "error", err)
+		return 1
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		slog.Error("failed to write report", "error", err)
+		return 1
+	}
+	return 0
+}
@@ -4,6 +4,8 @@ package main
 import (
 	"context"
 	"database/sql"
+	"expvar"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
@@ -16,17 +18,24 @@ import (
 	"github.com/labstack/echo/v4"
 	echomw "github.com/labstack/echo/v4/middleware"
 
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/fetcher"
 	grpcadapter "github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/grpc"
 	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/http/handlers"
 	httpmw "github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/http/middleware"
 	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/llm"
 	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/messaging"
 	pgadapter "github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/postgres"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/preview"
 	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/storage"
 	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/config"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
 	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/usecases"
 )
 
+// uploadCleanupInterval は期限切れ直接アップロードを掃除する間隔
+const uploadCleanupInterval = 10 * time.Minute
+
 func main() {
 	// ─── ロガー設定 ───────────────────────────────────────────
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
@@ -36,6 +45,11 @@ func main() {
 
 	// ─── 設定読み込み ─────────────────────────────────────────
 	cfg := config.Load()
+
+	// ─── 管理コマンド（例: professor gc-storage --dry-run） ──
+	if len(os.Args) > 1 {
+		os.Exit(runAdminCommand(cfg, os.Args[1:]))
+	}
 	slog.Info("config loaded", "port", cfg.Port)
 
 	// ─── ルートコンテキスト（アダプタのライフタイム用） ──────
@@ -51,19 +65,20 @@ func main() {
 	defer db.Close()
 	slog.Info("database connected")
 
-	// ─── MinIO アダプター ─────────────────────────────────────
-	objectStorage, err := storage.NewMinioAdapter(
-		cfg.MinioEndpoint,
-		cfg.MinioAccessKey,
-		cfg.MinioSecretKey,
-		cfg.MinioBucket,
-		cfg.MinioUseSSL,
-	)
+	// ─── オブジェクトストレージ ───────────────────────────────
+	objectStorage, localStorageHandler, err := newObjectStorage(rootCtx, cfg)
 	if err != nil {
-		slog.Error("failed to connect to minio", "error", err)
+		slog.Error("failed to set up object storage", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("object storage ready", "backend", cfg.StorageBackend)
+
+	// 再開可能アップロードは各バックエンドのマルチパートアップロードで実装する
+	multipartStorage, ok := objectStorage.(ports.MultipartStorage)
+	if !ok {
+		slog.Error("object storage does not support multipart uploads")
 		os.Exit(1)
 	}
-	slog.Info("minio connected", "bucket", cfg.MinioBucket)
 
 	// ─── Kafka プロデューサー ─────────────────────────────────
 	publisher := messaging.NewKafkaProducer(cfg.KafkaBrokers, cfg.KafkaTopic)
@@ -83,6 +98,22 @@ func main() {
 	}
 	slog.Info("gemini client initialized")
 
+	// ─── 検索結果の並べ替え（Reranker） ───────────────────────
+	reranker, err := newReranker(rootCtx, cfg)
+	if err != nil {
+		slog.Error("failed to create reranker", "error", err, "backend", cfg.RerankBackend)
+		os.Exit(1)
+	}
+	slog.Info("reranker ready", "backend", cfg.RerankBackend)
+
+	// ─── ベクトル検索クエリの拡張 ─────────────────────────────
+	queryExpander, err := newQueryExpander(rootCtx, cfg)
+	if err != nil {
+		slog.Error("failed to create query expander", "error", err, "backend", cfg.QueryExpansionBackend)
+		os.Exit(1)
+	}
+	slog.Info("query expander ready", "backend", cfg.QueryExpansionBackend)
+
 	// ─── Librarian gRPC クライアント ──────────────────────────
 	librarianClient, err := grpcadapter.NewLibrarianClient(cfg.LibrarianAddr)
 	if err != nil {
@@ -91,18 +122,89 @@ func main() {
 	}
 	slog.Info("librarian client connected", "addr", cfg.LibrarianAddr)
 
+	// ─── URL 取り込み用フェッチャー ───────────────────────────
+	documentFetcher := fetcher.NewHTTPFetcher(cfg.URLImportTimeout)
+
+	// ─── ページプレビュー生成 ─────────────────────────────────
+	// pdftoppm が無い環境ではプレビューを生成せずに Ingest を続行する
+	pageRenderer, err := preview.NewPopplerRenderer(cfg.PreviewRendererPath, cfg.PreviewWidth)
+	if err != nil {
+		slog.Warn("page preview disabled", "error", err)
+		pageRenderer = nil
+	}
+
 	// ─── リポジトリ ───────────────────────────────────────────
+	switch cfg.VectorIterativeScan {
+	case pgadapter.IterativeScanOff, pgadapter.IterativeScanStrictOrder, pgadapter.IterativeScanRelaxedOrder:
+	default:
+		slog.Error("unknown VECTOR_ITERATIVE_SCAN", "value", cfg.VectorIterativeScan)
+		os.Exit(1)
+	}
 	subjectRepo := pgadapter.NewSubjectRepo(db)
 	fileRepo := pgadapter.NewFileRepo(db)
 	ingestJobRepo := pgadapter.NewIngestJobRepo(db)
-	chunkRepo := pgadapter.NewChunkRepo(db)
+	chunkRepo := pgadapter.NewChunkRepo(db, pgadapter.VectorSearchOptions{
+		EfSearch:             cfg.VectorEfSearch,
+		IterativeScan:        cfg.VectorIterativeScan,
+		ExactSearchMaxChunks: cfg.VectorExactSearchMaxChunks,
+	})
 	qaSessionRepo := pgadapter.NewQASessionRepo(db)
+	materialUploadRepo := pgadapter.NewMaterialUploadRepo(db)
+	answerCacheRepo := pgadapter.NewAnswerCacheRepo(db)
+
+	// ─── 検索クエリの埋め込みキャッシュ ───────────────────────
+	// チャット・検索のクエリにのみ使う（Ingest のチャンクは同じテキストが繰り返されないため使わない）
+	embeddingStore, err := newEmbeddingCacheStore(db, cfg)
+	if err != nil {
+		slog.Error("failed to create embedding cache store", "error", err, "store", cfg.EmbeddingCacheStore)
+		os.Exit(1)
+	}
+	queryEmbedder := llm.NewEmbeddingCache(llmClient, embeddingStore, cfg.EmbeddingCacheSize)
+	expvar.Publish("embedding_cache", expvar.Func(func() any { return queryEmbedder.Stats() }))
+	slog.Info("embedding cache ready", "size", cfg.EmbeddingCacheSize, "store", cfg.EmbeddingCacheStore)
 
 	// ─── ユースケース ─────────────────────────────────────────
 	subjectUC := usecases.NewSubjectUseCase(subjectRepo)
-	materialUC := usecases.NewMaterialUseCase(fileRepo, ingestJobRepo, objectStorage, publisher, subjectRepo)
-	chatUC := usecases.NewChatUseCase(subjectRepo, qaSessionRepo, chunkRepo, llmClient, librarianClient)
-	ingestUC := usecases.NewIngestUseCase(fileRepo, ingestJobRepo, chunkRepo, objectStorage, llmClient)
+	materialUC := usecases.NewMaterialUseCase(fileRepo, ingestJobRepo, materialUploadRepo, objectStorage, multipartStorage, publisher, subjectRepo, documentFetcher)
+	generationModel, embeddingModel := llm.ModelNames()
+	chatUC := usecases.NewChatUseCase(subjectRepo, qaSessionRepo, chunkRepo, queryEmbedder, librarianClient, reranker, queryExpander, answerCacheRepo, usecases.ChatConfig{
+		Expansion: usecases.EvidenceExpansion{
+			Neighbours:  cfg.EvidenceNeighbourChunks,
+			SamePage:    cfg.EvidenceSamePage,
+			TokenBudget: cfg.EvidenceTokenBudget,
+		},
+		Diversity: usecases.Diversification{
+			Weight:     cfg.SearchDiversityWeight,
+			MaxPerFile: cfg.SearchMaxPerFile,
+		},
+		Search: usecases.SearchExecution{
+			Concurrency:  cfg.SearchConcurrency,
+			RoundTimeout: cfg.SearchRoundTimeout,
+		},
+		Research: usecases.ResearchModes{
+			Quick:    librarianConstraints(cfg.LibrarianQuick),
+			Standard: librarianConstraints(cfg.LibrarianStandard),
+			Deep:     librarianConstraints(cfg.LibrarianDeep),
+		},
+		Rerank: usecases.Reranking{
+			TopK:    cfg.RerankTopK,
+			Timeout: cfg.RerankTimeout,
+		},
+		Cache: usecases.AnswerCaching{
+			MinSimilarity: cfg.AnswerCacheMinSimilarity,
+			TTL:           cfg.AnswerCacheTTL,
+		},
+		Models: domain.TraceModels{
+			Answer:         generationModel,
+			Embedding:      embeddingModel,
+			Rerank:         generationModel,
+			QueryExpansion: generationModel,
+		},
+	})
+	ingestUC := usecases.NewIngestUseCase(fileRepo, ingestJobRepo, chunkRepo, objectStorage, llmClient, pageRenderer)
+	storageGCUC := usecases.NewStorageGCUseCase(fileRepo, objectStorage)
+	chunkUC := usecases.NewChunkUseCase(fileRepo, chunkRepo, llmClient)
+	searchUC := usecases.NewSearchUseCase(subjectRepo, chunkRepo, queryEmbedder)
 
 	// ─── Echo サーバー設定 ────────────────────────────────────
 	e := echo.New()
@@ -119,6 +221,15 @@ func main() {
 	// ─── ルーティング ─────────────────────────────────────────
 	// ヘルスチェック (認証不要)
 	e.GET("/healthz", handlers.Healthz)
+	// メトリクス（埋め込みキャッシュのヒット率など。expvar の JSON。DEBUG_VARS_ENABLED=true の場合のみ）
+	if cfg.DebugVarsEnabled {
+		e.GET("/debug/vars", echo.WrapHandler(expvar.Handler()))
+	}
+
+	// ローカルストレージの署名付き URL（OBJECT_STORAGE_BACKEND=local の場合のみ）
+	if localStorageHandler != nil {
+		e.Any("/storage/*", echo.WrapHandler(http.StripPrefix("/storage", localStorageHandler)))
+	}
 
 	// API v1 グループ
 	v1 := e.Group("/api/v1")
@@ -131,9 +242,21 @@ func main() {
 	materialH := handlers.NewMaterialHandler(materialUC)
 	materialH.Register(v1.Group("/subjects/:subject_id/materials"))
 
+	// チャンク閲覧・手動修正 API (/api/v1/subjects/:subject_id/materials/:fid/chunks)
+	chunkH := handlers.NewChunkHandler(chunkUC)
+	chunkH.Register(v1.Group("/subjects/:subject_id/materials/:fid"))
+
+	// 直接検索 API (/api/v1/subjects/:subject_id/search)
+	searchH := handlers.NewSearchHandler(searchUC)
+	searchH.Register(v1.Group("/subjects/:subject_id/search"))
+
 	// チャット API (/api/v1/subjects/:subject_id/chats)
 	chatH := handlers.NewChatHandler(chatUC)
-	chatH.Register(v1.Group("/subjects/:subject_id/chats"))
+	chatGroup := v1.Group("/subjects/:subject_id/chats")
+	chatH.Register(chatGroup)
+	if cfg.ChatTraceEnabled {
+		chatH.RegisterDebug(chatGroup)
+	}
 
 	// ─── Kafka Ingest Worker goroutine ───────────────────────
 	go func() {
@@ -142,6 +265,41 @@ func main() {
 		}
 	}()
 
+	// ─── 期限切れ直接アップロードの掃除 goroutine ────────────
+	go func() {
+		ticker := time.NewTicker(uploadCleanupInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-rootCtx.Done():
+				return
+			case <-ticker.C:
+				if _, err := materialUC.CleanupExpiredUploads(rootCtx, time.Now().UTC()); err != nil {
+					slog.Error("upload cleanup failed", "error", err)
+				}
+			}
+		}
+	}()
+
+	// ─── 孤立オブジェクトの整合性チェック goroutine ──────────
+	go func() {
+		ticker := time.NewTicker(cfg.StorageGCInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-rootCtx.Done():
+				return
+			case <-ticker.C:
+				if _, err := storageGCUC.Reconcile(rootCtx, usecases.ReconcileStorageInput{
+					Now:         time.Now().UTC(),
+					GracePeriod: cfg.StorageGCGracePeriod,
+				}); err != nil {
+					slog.Error("storage reconciliation failed", "error", err)
+				}
+			}
+		}
+	}()
+
 	// ─── グレースフルシャットダウン ──────────────────────────
 	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
@@ -186,3 +344,113 @@ func connectDB(dsn string) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// librarianConstraints は調べる深さ 1 つ分の設定を Librarian に送る制約に変換する。
+func librarianConstraints(c config.LibrarianConstraints) ports.LibrarianConstraints {
+	return ports.LibrarianConstraints{
+		MaxLoops:   c.MaxLoops,
+		MaxResults: c.MaxResults,
+		Timeout:    c.Timeout,
+	}
+}
+
+// newReranker は設定に応じた Reranker を返す（"none" の場合は nil で、検索結果を並べ替えない）。
+func newReranker(ctx context.Context, cfg *config.Config) (ports.Reranker, error) {
+	switch cfg.RerankBackend {
+	case "none":
+		return nil, nil
+	case "llm":
+		return llm.NewGeminiReranker(ctx, cfg.GeminiAPIKey)
+	default:
+		return nil, fmt.Errorf("unknown RERANK_BACKEND %q", cfg.RerankBackend)
+	}
+}
+
+// newQueryExpander は設定に応じた QueryExpander を返す（"none" の場合は nil で、クエリを拡張しない）。
+func newQueryExpander(ctx context.Context, cfg *config.Config) (ports.QueryExpander, error) {
+	switch cfg.QueryExpansionBackend {
+	case "none":
+		return nil, nil
+	case "llm":
+		return llm.NewGeminiQueryExpander(ctx, cfg.GeminiAPIKey)
+	default:
+		return nil, fmt.Errorf("unknown QUERY_EXPANSION_BACKEND %q", cfg.QueryExpansionBackend)
+	}
+}
+
+// newEmbeddingCacheStore は設定に応じた埋め込みキャッシュの永続ストアを返す（"none" の場合は nil で、メモリにのみ保持する）。
+func newEmbeddingCacheStore(db *sql.DB, cfg *config.Config) (ports.EmbeddingCacheRepository, error) {
+	switch cfg.EmbeddingCacheStore {
+	case "none":
+		return nil, nil
+	case "postgres":
+		return pgadapter.NewEmbeddingCacheRepo(db), nil
+	default:
+		return nil, fmt.Errorf("unknown EMBEDDING_CACHE_STORE %q", cfg.EmbeddingCacheStore)
+	}
+}
+
+// newObjectStorage は設定に応じたストレージバックエンドを組み立て、
+// ストレージパスのスキームで振り分けるルーターを返す。
+// 新規の書き込みは cfg.StorageBackend に行う。MinIO は primary の場合か、
+// MINIO_LEGACY_READ で移行前のファイルの読み出し先として有効にした場合だけ登録する
+// （未登録のバックエンドは整合性チェックの列挙対象にもならない）。
+// local バックエンドの場合は署名付き URL を配信する http.Handler も返す。
+func newObjectStorage(ctx context.Context, cfg *config.Config) (ports.ObjectStorage, http.Handler, error) {
+	backends := make(map[string]ports.ObjectStorage)
+	var localHandler http.Handler
+
+	if cfg.StorageBackend == "minio" || cfg.MinioLegacyRead {
+		minioStorage, err := storage.NewMinioAdapter(
+			cfg.MinioEndpoint,
+			cfg.MinioAccessKey,
+			cfg.MinioSecretKey,
+			cfg.MinioBucket,
+			cfg.MinioUseSSL,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("minio: %w", err)
+		}
+		backends[storage.SchemeMinio] = minioStorage
+	}
+
+	primary := storage.SchemeMinio
+	switch cfg.StorageBackend {
+	case "minio":
+	case "gcs":
+		gcsStorage, err := storage.NewGCSAdapter(ctx, cfg.GCSBucket, cfg.GCSProjectID, cfg.GCSEndpoint)
+		if err != nil {
+			return nil, nil, fmt.Errorf("gcs: %w", err)
+		}
+		backends[storage.SchemeGCS] = gcsStorage
+		primary = storage.SchemeGCS
+	case "local":
+		key := []byte(cfg.LocalStorageSigningKey)
+		localStorage, err := storage.NewLocalAdapter(cfg.LocalStorageRoot, cfg.LocalStorageBaseURL, key)
+		if err != nil {
+			return nil, nil, fmt.Errorf("local: %w", err)
+		}
+		localHandler, err = storage.NewLocalSignedURLHandler(cfg.LocalStorageRoot, key, cfg.LocalStorageMaxUpload)
+		if err != nil {
+			return nil, nil, fmt.Errorf("local: %w", err)
+		}
+		backends[storage.SchemeFile] = localStorage
+		primary = storage.SchemeFile
+	default:
+		return nil, nil, fmt.Errorf("unknown OBJECT_STORAGE_BACKEND %q", cfg.StorageBackend)
+	}
+
+	if cfg.StorageCreateBucket {
+		if b, ok := backends[primary].(storage.BucketEnsurer); ok {
+			if err := b.EnsureBucket(ctx); err != nil {
+				return nil, nil, fmt.Errorf("ensure bucket: %w", err)
+			}
+		}
+	}
+
+	objectStorage, err := storage.NewRouter(primary, backends)
+	if err != nil {
+		return nil, nil, err
+	}
+	return objectStorage, localHandler, nil
+}
@@ -0,0 +1,189 @@
+// Package fetcher は外部 URL からドキュメントを取得するアダプターを提供する。
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+const maxRedirects = 5
+
+// blockedPrefixes は接続を拒否する宛先（SSRF 対策）。
+// netip.Addr の IsPrivate / IsLoopback 等で判定できない範囲を列挙する。
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),  // CGNAT
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF プロトコル割り当て
+	netip.MustParsePrefix("198.18.0.0/15"),  // ベンチマーク
+	netip.MustParsePrefix("240.0.0.0/4"),    // 予約済み（ブロードキャスト含む）
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64（IPv4 内部アドレスへの変換経路）
+	netip.MustParsePrefix("64:ff9b:1::/48"), // ローカル NAT64
+	netip.MustParsePrefix("100::/64"),       // 破棄用
+	netip.MustParsePrefix("2001::/32"),      // Teredo
+	netip.MustParsePrefix("2002::/16"),      // 6to4
+	netip.MustParsePrefix("fec0::/10"),      // 廃止済みサイトローカル
+}
+
+var errBlockedAddress = fmt.Errorf("destination address is not allowed: %w", domain.ErrForbidden)
+
+type httpFetcher struct {
+	client *http.Client
+}
+
+// NewHTTPFetcher は HTTP(S) で教材を取得する DocumentFetcher 実装を返す。
+// 接続先 IP はダイヤル時（DNS 解決後）に検査するため、
+// DNS リバインディングやリダイレクトによる内部ネットワークへの到達も拒否する。
+func NewHTTPFetcher(timeout time.Duration) ports.DocumentFetcher {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil || isBlockedAddr(ap.Addr()) {
+				return errBlockedAddress
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		Proxy:                 nil, // 環境変数のプロキシ経由で検査を迂回させない
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects: %w", maxRedirects, domain.ErrInvalidInput)
+			}
+			return validateURL(req.URL)
+		},
+	}
+	return &httpFetcher{client: client}
+}
+
+// Fetch は rawURL を GET し、maxBytes までの本文を返す。
+func (f *httpFetcher) Fetch(ctx context.Context, rawURL string, maxBytes int64) (*ports.FetchedDocument, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", domain.ErrInvalidInput)
+	}
+	if err := validateURL(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", domain.ErrInvalidInput)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		// ダイヤル拒否・リダイレクト拒否はドメインエラーとして返す
+		if errors.Is(err, domain.ErrForbidden) || errors.Is(err, domain.ErrInvalidInput) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("fetch %s: %w", u.Redacted(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("fetch returned status %d: %w", resp.StatusCode, domain.ErrInvalidInput)
+	}
+	if resp.ContentLength > maxBytes {
+		return nil, fmt.Errorf("document exceeds %d bytes: %w", maxBytes, domain.ErrInvalidInput)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+	if int64(len(body)) > maxBytes {
+		return nil, fmt.Errorf("document exceeds %d bytes: %w", maxBytes, domain.ErrInvalidInput)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if mt, _, err := mime.ParseMediaType(contentType); err != nil || mt == "application/octet-stream" {
+		contentType = http.DetectContentType(body)
+	}
+
+	return &ports.FetchedDocument{
+		URL:         resp.Request.URL.String(),
+		FileName:    fileName(resp),
+		ContentType: contentType,
+		Body:        body,
+	}, nil
+}
+
+// validateURL はスキームとホストを検査する。
+// ホストが IP リテラルの場合はダイヤル前に拒否する。
+func validateURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported url scheme %q: %w", u.Scheme, domain.ErrInvalidInput)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("url host is required: %w", domain.ErrInvalidInput)
+	}
+	if u.User != nil {
+		return fmt.Errorf("url must not contain credentials: %w", domain.ErrInvalidInput)
+	}
+	if port := u.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			return fmt.Errorf("invalid url port: %w", domain.ErrInvalidInput)
+		}
+	}
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil && isBlockedAddr(addr) {
+		return errBlockedAddress
+	}
+	return nil
+}
+
+// isBlockedAddr は内部ネットワーク・特殊用途のアドレスかどうかを返す。
+func isBlockedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return true
+	}
+	for _, p := range blockedPrefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileName は Content-Disposition または最終 URL のパスからファイル名を推定する。
+func fileName(resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		if name := path.Base(params["filename"]); name != "" && name != "." && name != "/" {
+			return name
+		}
+	}
+	if name := path.Base(resp.Request.URL.Path); name != "" && name != "." && name != "/" {
+		return name
+	}
+	return ""
+}
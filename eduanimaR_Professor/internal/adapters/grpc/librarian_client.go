@@ -17,11 +17,8 @@ import (
 	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
 )
 
-const (
-	defaultMaxLoops   = 3
-	defaultMaxResults = 10
-	defaultTimeoutMs  = 30000
-)
+// searchHistoryAction は SearchHistory.action の値（Professor が次のリクエストを送るのは検索の後のみ）
+const searchHistoryAction = "SEARCH"
 
 // librarianClient は ports.LibrarianClient の gRPC 実装。
 type librarianClient struct {
@@ -45,9 +42,9 @@ func NewLibrarianClient(addr string) (ports.LibrarianClient, error) {
 // Think は双方向ストリーミング RPC を使って Librarian に推論を依頼する。
 //
 // フロー:
-//  1. 初回 ThinkRequest（user_query, subject_id）を送信
+//  1. 初回 ThinkRequest（user_query, subject_id, constraints）を送信
 //  2. SearchAction を受信 → onSearchRequest コールバックで検索実行
-//  3. 検索結果を state JSON に詰めて次の ThinkRequest を送信
+//  3. 検索結果を state JSON に、それまでの検索の履歴を search_history に詰めて次の ThinkRequest を送信
 //  4. CompleteAction を受信 → LibrarianThinkResult を返す
 func (c *librarianClient) Think(
 	ctx context.Context,
@@ -55,6 +52,7 @@ func (c *librarianClient) Think(
 	userQuery string,
 	subjectID uuid.UUID,
 	userID uuid.UUID,
+	constraints ports.LibrarianConstraints,
 	onSearchRequest func(req ports.LibrarianSearchRequest) (*ports.LibrarianSearchResponse, error),
 ) (*ports.LibrarianThinkResult, error) {
 
@@ -65,18 +63,18 @@ func (c *librarianClient) Think(
 
 	// 初回リクエスト送信
 	if err := stream.Send(&librarianv1.ThinkRequest{
-		RequestId: requestID,
-		UserQuery: userQuery,
-		SubjectId: subjectID.String(),
-		Constraints: &librarianv1.Constraints{
-			MaxLoops:   defaultMaxLoops,
-			MaxResults: defaultMaxResults,
-			TimeoutMs:  defaultTimeoutMs,
-		},
+		RequestId:   requestID,
+		UserQuery:   userQuery,
+		SubjectId:   subjectID.String(),
+		Constraints: toProtoConstraints(constraints),
 	}); err != nil {
 		return nil, fmt.Errorf("send initial ThinkRequest: %w", err)
 	}
 
+	// 検索の履歴（ステップごとのクエリ・検索の意図・件数）。検索のたびに追加し、次のリクエストで毎回すべて送る
+	var history []*librarianv1.SearchHistory
+	accumulated := 0
+
 	// レスポンスループ
 	for {
 		resp, err := stream.Recv()
@@ -115,10 +113,21 @@ func (c *librarianClient) Think(
 				return nil, fmt.Errorf("serialize search results: %w", err)
 			}
 
-			// 結果を Librarian に送信
+			// 結果は累積のため、前のステップからの増分をこのステップの件数とする
+			history = append(history, &librarianv1.SearchHistory{
+				Step:        int32(len(history) + 1),
+				Action:      searchHistoryAction,
+				QueriesText: action.Search.QueriesText,
+				Rationale:   action.Search.Rationale,
+				ResultCount: int32(len(searchResp.Results) - accumulated),
+			})
+			accumulated = len(searchResp.Results)
+
+			// 結果と検索の履歴を Librarian に送信
 			if err := stream.Send(&librarianv1.ThinkRequest{
-				RequestId: requestID,
-				State:     stateJSON,
+				RequestId:     requestID,
+				State:         stateJSON,
+				SearchHistory: history,
 			}); err != nil {
 				return nil, fmt.Errorf("send search results: %w", err)
 			}
@@ -152,7 +161,7 @@ func (c *librarianClient) Think(
 			)
 			return &ports.LibrarianThinkResult{
 				ErrorType:     action.Error.ErrorType,
-				IsPartial:     action.Error.ErrorType == "LOOP_LIMIT",
+				IsPartial:     isPartialError(action.Error.ErrorType),
 				CoverageNotes: action.Error.Message,
 			}, nil
 		}
@@ -162,6 +171,20 @@ func (c *librarianClient) Think(
 	return &ports.LibrarianThinkResult{}, nil
 }
 
+// isPartialError は検索を打ち切っただけで、それまでの結果で回答できるエラー種別かを返す。
+func isPartialError(errorType string) bool {
+	return errorType == ports.LibrarianErrorLoopLimit || errorType == ports.LibrarianErrorTimeout
+}
+
+// toProtoConstraints は推論の制約を Constraints に変換する（0 の項目は送らず、Librarian の既定値とする）。
+func toProtoConstraints(c ports.LibrarianConstraints) *librarianv1.Constraints {
+	return &librarianv1.Constraints{
+		MaxLoops:   int32(c.MaxLoops),
+		MaxResults: int32(c.MaxResults),
+		TimeoutMs:  int32(c.Timeout.Milliseconds()),
+	}
+}
+
 // serializeSearchResults は検索結果を Librarian が期待する state JSON 文字列に変換する。
 //
 // スキーマ:
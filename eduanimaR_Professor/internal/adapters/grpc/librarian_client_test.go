@@ -0,0 +1,96 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	librarianv1 "github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/gen/proto/librarian/v1"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+// fakeThinkStream は送られた ThinkRequest を記録し、responses を順に返す Think ストリーム
+type fakeThinkStream struct {
+	grpc.ClientStream
+	sent      []*librarianv1.ThinkRequest
+	responses []*librarianv1.ThinkResponse
+}
+
+func (s *fakeThinkStream) Send(req *librarianv1.ThinkRequest) error {
+	s.sent = append(s.sent, req)
+	return nil
+}
+
+func (s *fakeThinkStream) Recv() (*librarianv1.ThinkResponse, error) {
+	resp := s.responses[0]
+	s.responses = s.responses[1:]
+	return resp, nil
+}
+
+func (s *fakeThinkStream) CloseSend() error { return nil }
+
+// fakeLibrarianService は fakeThinkStream を返す LibrarianServiceClient
+type fakeLibrarianService struct {
+	librarianv1.LibrarianServiceClient
+	stream *fakeThinkStream
+}
+
+func (f *fakeLibrarianService) Think(context.Context, ...grpc.CallOption) (grpc.BidiStreamingClient[librarianv1.ThinkRequest, librarianv1.ThinkResponse], error) {
+	return f.stream, nil
+}
+
+func searchAction(rationale string, queries ...string) *librarianv1.ThinkResponse {
+	return &librarianv1.ThinkResponse{Action: &librarianv1.ThinkResponse_Search{
+		Search: &librarianv1.SearchAction{QueriesText: queries, Rationale: rationale},
+	}}
+}
+
+func TestLibrarianClient_Think_SendsConstraintsAndSearchHistory(t *testing.T) {
+	stream := &fakeThinkStream{responses: []*librarianv1.ThinkResponse{
+		searchAction("定義を探す", "定義"),
+		searchAction("例を探す", "例", "具体例"),
+		{Action: &librarianv1.ThinkResponse_Complete{Complete: &librarianv1.CompleteAction{CoverageNotes: "十分"}}},
+	}}
+	client := &librarianClient{client: &fakeLibrarianService{stream: stream}}
+
+	// 検索結果は累積で返す（1 回目 2 件 → 2 回目 5 件）
+	var accumulated []domain.SearchResult
+	added := []int{2, 3}
+	result, err := client.Think(context.Background(), "req-1", "質問", uuid.New(), uuid.New(),
+		ports.LibrarianConstraints{MaxLoops: 6, MaxResults: 20, Timeout: 90 * time.Second},
+		func(ports.LibrarianSearchRequest) (*ports.LibrarianSearchResponse, error) {
+			for i := 0; i < added[0]; i++ {
+				accumulated = append(accumulated, domain.SearchResult{ChunkID: uuid.New()})
+			}
+			added = added[1:]
+			return &ports.LibrarianSearchResponse{Results: accumulated}, nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, "十分", result.CoverageNotes)
+
+	require.Len(t, stream.sent, 3)
+	assert.Equal(t, &librarianv1.Constraints{MaxLoops: 6, MaxResults: 20, TimeoutMs: 90000}, stream.sent[0].Constraints)
+	assert.Empty(t, stream.sent[0].SearchHistory)
+
+	// 検索のたびに、それまでのすべての検索の履歴を送る（件数はそのステップで増えた分）
+	first := stream.sent[1].SearchHistory
+	require.Len(t, first, 1)
+	assert.Equal(t, int32(1), first[0].Step)
+	assert.Equal(t, "SEARCH", first[0].Action)
+	assert.Equal(t, []string{"定義"}, first[0].QueriesText)
+	assert.Equal(t, "定義を探す", first[0].Rationale)
+	assert.Equal(t, int32(2), first[0].ResultCount)
+
+	second := stream.sent[2].SearchHistory
+	require.Len(t, second, 2)
+	assert.Equal(t, first[0], second[0])
+	assert.Equal(t, int32(2), second[1].Step)
+	assert.Equal(t, []string{"例", "具体例"}, second[1].QueriesText)
+	assert.Equal(t, int32(3), second[1].ResultCount)
+}
@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,8 +19,14 @@ import (
 
 // ChatHandler は質問応答セッションの HTTP ハンドラー。
 // POST /api/v1/subjects/:subject_id/chats     → SSE ストリーミング回答（Ask）
-// GET  /api/v1/subjects/:subject_id/chats     → セッション一覧（ListSessions）
+// GET  /api/v1/subjects/:subject_id/chats     → セッション一覧（ListSessions。キーセットページング・質問の検索）
+// GET  /api/v1/subjects/:subject_id/chats/:session_id → セッション詳細（GetSession）
 // POST /api/v1/subjects/:subject_id/chats/:session_id/feedback → フィードバック記録
+// POST /api/v1/subjects/:subject_id/chats/:session_id:cancel  → 回答生成の中断
+// POST /api/v1/subjects/:subject_id/chats/:session_id:regenerate → 回答の再生成（SSE）
+// POST /api/v1/subjects/:subject_id/chats/:session_id:selectVersion → 表示する回答版の選択
+// GET  /api/v1/subjects/:subject_id/chats/:session_id/versions → 回答版の一覧
+// GET  /api/v1/subjects/:subject_id/chats/:session_id/trace → 回答版の推論の記録（デバッグ用。RegisterDebug で登録）
 type ChatHandler struct {
 	uc *usecases.ChatUseCase
 }
@@ -32,7 +40,17 @@ func NewChatHandler(uc *usecases.ChatUseCase) *ChatHandler {
 func (h *ChatHandler) Register(g *echo.Group) {
 	g.POST("", h.Ask)
 	g.GET("", h.ListSessions)
+	g.GET("/:session_id", h.GetSession)
 	g.POST("/:session_id/feedback", h.Feedback)
+	g.GET("/:session_id/versions", h.ListVersions)
+	// カスタムメソッド（{session_id}:cancel など）。Echo のパスパラメータは ":" で区切れないため、ハンドラーで振り分ける
+	g.POST("/:session_id", h.sessionMethod)
+}
+
+// RegisterDebug はデバッグ用のルートを登録する。
+// 推論の記録は内部の検索クエリやモデルの判断を含むため、設定で有効にした場合のみ登録する。
+func (h *ChatHandler) RegisterDebug(g *echo.Group) {
+	g.GET("/:session_id/trace", h.GetTrace)
 }
 
 // ─── Ask (SSE) ────────────────────────────────────────────────────
@@ -40,11 +58,26 @@ func (h *ChatHandler) Register(g *echo.Group) {
 // askRequest は POST /chats のリクエストボディ。
 type askRequest struct {
 	Question string `json:"question"`
+	// ExtraSubjectIDs は関連科目を横断して質問する場合に追加で検索する subject（省略時は subject_id のみ）
+	ExtraSubjectIDs []string `json:"extra_subject_ids,omitempty"`
+	// Diversity は検索結果の多様性の重み（0: 関連度のみ〜1: 多様性のみ。省略時はサーバー設定）
+	Diversity *float64 `json:"diversity,omitempty"`
+	// BypassCache は回答キャッシュを使わずに回答を生成する（Cache-Control: no-cache ヘッダーでも指定できる）
+	BypassCache bool `json:"bypass_cache,omitempty"`
+	// ResearchMode は Librarian に調べさせる深さ（quick / standard / deep。省略時は科目の設定）
+	ResearchMode string `json:"research_mode,omitempty"`
 }
 
 // Ask godoc
 // @Summary     質問応答（SSE ストリーミング）
-// @Description Librarian を使った RAG パイプラインを実行し、SSE で回答をストリーミングする
+// @Description Librarian を使った RAG パイプラインを実行し、SSE で回答をストリーミングする。
+// @Description extra_subject_ids を指定すると、所有するほかの subject の教材も横断して検索する。
+// @Description diversity で似たチャンク・同じ教材に偏らないよう選ぶ度合いを指定できる。
+// @Description research_mode で調べる深さ（quick: 素早く回答 / deep: 時間をかけて調べる）を科目の設定から変えられる。
+// @Description 似た質問への回答がキャッシュにある場合は、その回答と出典を cached: true 付きで返す（bypass_cache で無効化）
+// @Description クライアントが切断した場合・:cancel で中断した場合は、途中までの回答を status: cancelled で保存する
+// @Description Librarian が検索を打ち切った・AI の呼び出しに失敗した場合は caveat イベントで注意を送り、見つかった範囲（または検索結果の抜粋）で回答する。
+// @Description Librarian が質問を扱えない場合は status: 400 の error イベントを送る。
 // @Tags        chats
 // @Accept      json
 // @Produce     text/event-stream
@@ -64,68 +97,113 @@ func (h *ChatHandler) Ask(c echo.Context) error {
 	if err := c.Bind(&req); err != nil || req.Question == "" {
 		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "question is required"})
 	}
+	extraSubjectIDs, err := parseUUIDs(req.ExtraSubjectIDs)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid extra_subject_ids"})
+	}
+	if req.Diversity != nil && (*req.Diversity < 0 || *req.Diversity > 1) {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "diversity must be between 0 and 1"})
+	}
+	mode, err := parseOptionalResearchMode(req.ResearchMode)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid research_mode"})
+	}
 
 	userID := httpmw.GetUserID(c)
 
-	// ─── SSE ヘッダー設定 ───────────────────────────────────────
-	w := c.Response().Writer
-	c.Response().Header().Set("Content-Type", "text/event-stream")
-	c.Response().Header().Set("Cache-Control", "no-cache")
-	c.Response().Header().Set("Connection", "keep-alive")
-	c.Response().Header().Set("X-Accel-Buffering", "no") // nginx バッファリング無効化
-	c.Response().WriteHeader(http.StatusOK)
-
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		return fmt.Errorf("streaming not supported by the response writer")
-	}
-
-	// ─── SSE 書き込みヘルパー ────────────────────────────────────
-	writeEvent := func(eventType domain.SSEEventType, data any) error {
-		payload := map[string]any{
-			"type": string(eventType),
-			"data": data,
-		}
-		b, jsonErr := json.Marshal(payload)
-		if jsonErr != nil {
-			return jsonErr
-		}
-		if _, writeErr := fmt.Fprintf(w, "data: %s\n\n", b); writeErr != nil {
-			return writeErr
-		}
-		flusher.Flush()
-		return nil
+	sse, err := newSSEWriter(c)
+	if err != nil {
+		return err
 	}
+	sse.start()
 
 	// ─── ユースケース呼び出し ────────────────────────────────────
-	_, ucErr := h.uc.Ask(c.Request().Context(), subjectID, userID, req.Question, writeEvent)
+	_, ucErr := h.uc.Ask(c.Request().Context(), subjectID, userID, req.Question, usecases.AskOptions{
+		ExtraSubjectIDs: extraSubjectIDs,
+		Diversity:       req.Diversity,
+		BypassCache:     req.BypassCache || c.Request().Header.Get("Cache-Control") == "no-cache",
+		ResearchMode:    mode,
+	}, sse.write)
 	if ucErr != nil {
 		// SSEEventError は usecase 内で既に送信試行済みだが念のため再送
-		_ = writeEvent(domain.SSEEventError, map[string]any{"message": ucErr.Error()})
+		_ = sse.write(domain.SSEEventError, sseErrorData(ucErr))
 	}
 
 	return nil
 }
 
+// sseWriter は SSE イベントを書き込む。ヘッダーは start または最初のイベントで送る。
+type sseWriter struct {
+	c       echo.Context
+	flusher http.Flusher
+	started bool
+}
+
+func newSSEWriter(c echo.Context) (*sseWriter, error) {
+	flusher, ok := c.Response().Writer.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("streaming not supported by the response writer")
+	}
+	return &sseWriter{c: c, flusher: flusher}, nil
+}
+
+// start は SSE のヘッダーを送る（送信済みの場合は何もしない）。
+func (w *sseWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	h := w.c.Response().Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no") // nginx バッファリング無効化
+	w.c.Response().WriteHeader(http.StatusOK)
+}
+
+// write はイベントを {"type", "data"} の JSON として送る。
+func (w *sseWriter) write(eventType domain.SSEEventType, data any) error {
+	w.start()
+	b, err := json.Marshal(map[string]any{
+		"type": string(eventType),
+		"data": data,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w.c.Response().Writer, "data: %s\n\n", b); err != nil {
+		return err
+	}
+	w.flusher.Flush()
+	return nil
+}
+
 // ─── ListSessions ─────────────────────────────────────────────────
 
 // qaSessionResponse は QASession の JSON 表現。
 type qaSessionResponse struct {
-	ID         string          `json:"id"`
-	Question   string          `json:"question"`
-	Answer     *string         `json:"answer,omitempty"`
-	Sources    []domain.Source `json:"sources,omitempty"`
-	Feedback   *int            `json:"feedback,omitempty"`
-	CreatedAt  string          `json:"created_at"`
-	AnsweredAt *string         `json:"answered_at,omitempty"`
+	ID              string          `json:"id"`
+	ExtraSubjectIDs []string        `json:"extra_subject_ids,omitempty"`
+	Question        string          `json:"question"`
+	Answer          *string         `json:"answer,omitempty"`
+	Sources         []domain.Source `json:"sources,omitempty"`
+	Feedback        *int            `json:"feedback,omitempty"`
+	CreatedAt       string          `json:"created_at"`
+	AnsweredAt      *string         `json:"answered_at,omitempty"`
+	// Status は回答生成の状態（running / completed / cancelled / failed）
+	Status string `json:"status"`
+	// SelectedVersion は answer / sources / feedback / status を返している回答版、LatestVersion は最新の回答版
+	SelectedVersion int `json:"selected_version"`
+	LatestVersion   int `json:"latest_version"`
 }
 
 // listSessionsResponse はセッション一覧レスポンス。
 type listSessionsResponse struct {
 	Sessions []qaSessionResponse `json:"sessions"`
-	Total    int64               `json:"total"`
+	Total    int64               `json:"total"` // q に一致するセッションの件数
 	Limit    int                 `json:"limit"`
-	Offset   int                 `json:"offset"`
+	// NextCursor は次のページを取得する cursor（最後のページの場合は null）
+	NextCursor *string `json:"next_cursor"`
 }
 
 func toQASessionResp(s *domain.QASession) qaSessionResponse {
@@ -136,6 +214,13 @@ func toQASessionResp(s *domain.QASession) qaSessionResponse {
 		Sources:   s.Sources,
 		Feedback:  s.Feedback,
 		CreatedAt: s.CreatedAt.Format(time.RFC3339),
+		Status:    string(s.Status),
+
+		SelectedVersion: s.SelectedVersion,
+		LatestVersion:   s.LatestVersion,
+	}
+	for _, id := range s.ExtraSubjectIDs {
+		r.ExtraSubjectIDs = append(r.ExtraSubjectIDs, id.String())
 	}
 	if s.AnsweredAt != nil {
 		t := s.AnsweredAt.Format(time.RFC3339)
@@ -146,12 +231,16 @@ func toQASessionResp(s *domain.QASession) qaSessionResponse {
 
 // ListSessions godoc
 // @Summary     質問応答セッション一覧
+// @Description 新しい順に返す。次のページは前のレスポンスの next_cursor を cursor に指定して取得する。
 // @Tags        chats
 // @Produce     json
 // @Param       subject_id path  string true  "Subject UUID"
-// @Param       limit      query int    false "件数（デフォルト20）"
-// @Param       offset     query int    false "オフセット（デフォルト0）"
+// @Param       limit      query int    false "件数（デフォルト20・最大100）"
+// @Param       cursor     query string false "前のページの next_cursor"
+// @Param       q          query string false "質問に含まれる文字列"
 // @Success     200 {object} listSessionsResponse
+// @Failure     400 {object} ErrorBody
+// @Failure     404 {object} ErrorBody
 // @Router      /api/v1/subjects/{subject_id}/chats [get]
 func (h *ChatHandler) ListSessions(c echo.Context) error {
 	subjectID, err := uuid.Parse(c.Param("subject_id"))
@@ -159,41 +248,93 @@ func (h *ChatHandler) ListSessions(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject_id"})
 	}
 
-	limit := 20
-	offset := 0
+	filter := domain.QASessionFilter{
+		Query: strings.TrimSpace(c.QueryParam("q")),
+		Limit: 20,
+	}
 	if v := c.QueryParam("limit"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			limit = n
+			filter.Limit = min(n, usecases.MaxSessionPageSize)
 		}
 	}
-	if v := c.QueryParam("offset"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
-			offset = n
+	if v := c.QueryParam("cursor"); v != "" {
+		cursor, err := domain.ParseQASessionCursor(v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid cursor"})
 		}
+		filter.After = &cursor
 	}
 
 	userID := httpmw.GetUserID(c)
 
-	sessions, err := h.uc.ListSessions(c.Request().Context(), subjectID, userID, limit, offset)
+	page, err := h.uc.ListSessions(c.Request().Context(), subjectID, userID, filter)
 	if err != nil {
 		return httpError(c, err)
 	}
 
-	total, err := h.uc.CountSessions(c.Request().Context(), subjectID, userID)
+	total, err := h.uc.CountSessions(c.Request().Context(), subjectID, userID, filter.Query)
 	if err != nil {
 		return httpError(c, err)
 	}
 
-	out := make([]qaSessionResponse, 0, len(sessions))
-	for _, s := range sessions {
+	out := make([]qaSessionResponse, 0, len(page.Sessions))
+	for _, s := range page.Sessions {
 		out = append(out, toQASessionResp(s))
 	}
-
-	return c.JSON(http.StatusOK, listSessionsResponse{
+	resp := listSessionsResponse{
 		Sessions: out,
 		Total:    total,
-		Limit:    limit,
-		Offset:   offset,
+		Limit:    filter.Limit,
+	}
+	if page.Next != nil {
+		next := page.Next.String()
+		resp.NextCursor = &next
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// ─── GetSession ───────────────────────────────────────────────────
+
+// qaSessionDetailResponse はセッション詳細の JSON 表現（出典は Librarian の選定理由 why_relevant を含む）。
+type qaSessionDetailResponse struct {
+	qaSessionResponse
+	SubjectID string `json:"subject_id"`
+	// QueryExpansions はベクトル検索クエリの言い換え・拡張の記録
+	QueryExpansions []domain.QueryExpansion `json:"query_expansions,omitempty"`
+}
+
+// GetSession godoc
+// @Summary     質問応答セッション詳細
+// @Description 選ばれている回答版の回答・出典（エビデンスの選定理由 why_relevant・回答生成に渡した前後文脈を含む）と検索クエリの拡張を返す。
+// @Tags        chats
+// @Produce     json
+// @Param       subject_id path string true "Subject UUID"
+// @Param       session_id path string true "Session UUID"
+// @Success     200 {object} qaSessionDetailResponse
+// @Failure     404 {object} ErrorBody
+// @Router      /api/v1/subjects/{subject_id}/chats/{session_id} [get]
+func (h *ChatHandler) GetSession(c echo.Context) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject_id"})
+	}
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid session_id"})
+	}
+
+	userID := httpmw.GetUserID(c)
+
+	session, err := h.uc.GetSession(c.Request().Context(), subjectID, sessionID, userID)
+	if err != nil {
+		return httpError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, qaSessionDetailResponse{
+		qaSessionResponse: toQASessionResp(session),
+		SubjectID:         session.SubjectID.String(),
+		QueryExpansions:   session.QueryExpansions,
 	})
 }
 
@@ -202,10 +343,13 @@ func (h *ChatHandler) ListSessions(c echo.Context) error {
 // feedbackRequest は POST /chats/:session_id/feedback のリクエストボディ。
 type feedbackRequest struct {
 	Feedback int `json:"feedback"` // 1: good / -1: bad
+	// Version はフィードバックする回答版（省略時は選ばれている版）
+	Version *int `json:"version,omitempty"`
 }
 
 // Feedback godoc
 // @Summary     フィードバック送信
+// @Description フィードバックは回答版ごとに記録する（version を省略した場合は選ばれている版）。
 // @Tags        chats
 // @Accept      json
 // @Produce     json
@@ -213,6 +357,7 @@ type feedbackRequest struct {
 // @Param       session_id path  string          true "Session UUID"
 // @Param       body       body  feedbackRequest true "フィードバック"
 // @Success     200 {object} qaSessionResponse
+// @Failure     404 {object} ErrorBody
 // @Router      /api/v1/subjects/{subject_id}/chats/{session_id}/feedback [post]
 func (h *ChatHandler) Feedback(c echo.Context) error {
 	sessionID, err := uuid.Parse(c.Param("session_id"))
@@ -227,13 +372,321 @@ func (h *ChatHandler) Feedback(c echo.Context) error {
 	if req.Feedback != 1 && req.Feedback != -1 {
 		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "feedback must be 1 (good) or -1 (bad)"})
 	}
+	if req.Version != nil && *req.Version < 1 {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "version must be 1 or greater"})
+	}
+
+	userID := httpmw.GetUserID(c)
+
+	var session *domain.QASession
+	if req.Version != nil {
+		session, err = h.uc.UpdateVersionFeedback(c.Request().Context(), sessionID, userID, *req.Version, req.Feedback)
+	} else {
+		session, err = h.uc.UpdateFeedback(c.Request().Context(), sessionID, userID, req.Feedback)
+	}
+	if err != nil {
+		return httpError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, toQASessionResp(session))
+}
+
+// ─── Custom methods ───────────────────────────────────────────────
+
+// sessionMethod は POST /chats/:session_id のカスタムメソッド（{session_id}:<method>）を振り分ける。
+func (h *ChatHandler) sessionMethod(c echo.Context) error {
+	id, method, _ := strings.Cut(c.Param("session_id"), ":")
+	switch method {
+	case "cancel":
+		return h.Cancel(c, id)
+	case "regenerate":
+		return h.Regenerate(c, id)
+	case "selectVersion":
+		return h.SelectVersion(c, id)
+	default:
+		return c.JSON(http.StatusNotFound, ErrorBody{Error: "not found"})
+	}
+}
+
+// Cancel godoc
+// @Summary     回答生成の中断
+// @Description 回答生成中のセッションの Librarian・LLM のストリームを止め、途中までの回答を status: cancelled で保存する。
+// @Description 回答生成中でない場合は 409 を返す。
+// @Tags        chats
+// @Produce     json
+// @Param       subject_id path string true "Subject UUID"
+// @Param       session_id path string true "Session UUID"
+// @Success     200 {object} qaSessionResponse
+// @Failure     404 {object} ErrorBody
+// @Failure     409 {object} ErrorBody
+// @Router      /api/v1/subjects/{subject_id}/chats/{session_id}:cancel [post]
+func (h *ChatHandler) Cancel(c echo.Context, rawSessionID string) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject_id"})
+	}
+	sessionID, err := uuid.Parse(rawSessionID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid session_id"})
+	}
+
+	userID := httpmw.GetUserID(c)
+
+	session, err := h.uc.Cancel(c.Request().Context(), subjectID, sessionID, userID)
+	if err != nil {
+		return httpError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, toQASessionResp(session))
+}
+
+// regenerateRequest は POST /chats/:session_id:regenerate のリクエストボディ（省略可）。
+type regenerateRequest struct {
+	// Retrieve は検索からやり直す（省略時は選ばれている版の出典から回答だけを生成し直す）
+	Retrieve bool `json:"retrieve,omitempty"`
+	// ResearchMode は検索し直す場合に Librarian に調べさせる深さ（省略時は科目の設定）
+	ResearchMode string `json:"research_mode,omitempty"`
+}
+
+// Regenerate godoc
+// @Summary     回答の再生成（SSE ストリーミング）
+// @Description セッションの回答を生成し直し、同じセッションの新しい回答版として保存する（新しい版が選ばれる）。
+// @Description retrieve: true の場合は Librarian による検索からやり直す。SSE イベントは質問応答と同じ（thinking / done に version を含む）。
+// @Description 回答生成中の場合は 409 を返す。
+// @Tags        chats
+// @Accept      json
+// @Produce     text/event-stream
+// @Param       subject_id path string            true  "Subject UUID"
+// @Param       session_id path string            true  "Session UUID"
+// @Param       body       body regenerateRequest false "再生成の指定"
+// @Success     200
+// @Failure     400 {object} ErrorBody
+// @Failure     404 {object} ErrorBody
+// @Failure     409 {object} ErrorBody
+// @Router      /api/v1/subjects/{subject_id}/chats/{session_id}:regenerate [post]
+func (h *ChatHandler) Regenerate(c echo.Context, rawSessionID string) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject_id"})
+	}
+	sessionID, err := uuid.Parse(rawSessionID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid session_id"})
+	}
+
+	var req regenerateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid request body"})
+	}
+	mode, err := parseOptionalResearchMode(req.ResearchMode)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid research_mode"})
+	}
 
 	userID := httpmw.GetUserID(c)
 
-	session, err := h.uc.UpdateFeedback(c.Request().Context(), sessionID, userID, req.Feedback)
+	// ストリーミング開始前のエラー（セッションが無い・回答生成中）はステータスコードで返す
+	sse, err := newSSEWriter(c)
+	if err != nil {
+		return err
+	}
+	_, ucErr := h.uc.Regenerate(c.Request().Context(), subjectID, sessionID, userID, usecases.RegenerateOptions{
+		Retrieve:     req.Retrieve,
+		ResearchMode: mode,
+	}, sse.write)
+	if ucErr != nil {
+		if !sse.started {
+			return httpError(c, ucErr)
+		}
+		_ = sse.write(domain.SSEEventError, sseErrorData(ucErr))
+	}
+
+	return nil
+}
+
+// selectVersionRequest は POST /chats/:session_id:selectVersion のリクエストボディ。
+type selectVersionRequest struct {
+	Version int `json:"version"`
+}
+
+// SelectVersion godoc
+// @Summary     表示する回答版の選択
+// @Description セッションの answer / sources / feedback / status を指定した回答版のものにする。
+// @Tags        chats
+// @Accept      json
+// @Produce     json
+// @Param       subject_id path string               true "Subject UUID"
+// @Param       session_id path string               true "Session UUID"
+// @Param       body       body selectVersionRequest true "回答版"
+// @Success     200 {object} qaSessionResponse
+// @Failure     404 {object} ErrorBody
+// @Router      /api/v1/subjects/{subject_id}/chats/{session_id}:selectVersion [post]
+func (h *ChatHandler) SelectVersion(c echo.Context, rawSessionID string) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject_id"})
+	}
+	sessionID, err := uuid.Parse(rawSessionID)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid session_id"})
+	}
+
+	var req selectVersionRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid request body"})
+	}
+	if req.Version < 1 {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "version must be 1 or greater"})
+	}
+
+	userID := httpmw.GetUserID(c)
+
+	session, err := h.uc.SelectVersion(c.Request().Context(), subjectID, sessionID, userID, req.Version)
 	if err != nil {
 		return httpError(c, err)
 	}
 
 	return c.JSON(http.StatusOK, toQASessionResp(session))
 }
+
+// ─── Versions ─────────────────────────────────────────────────────
+
+// answerVersionResponse は回答版の JSON 表現。
+type answerVersionResponse struct {
+	Version    int             `json:"version"`
+	Answer     *string         `json:"answer,omitempty"`
+	Sources    []domain.Source `json:"sources,omitempty"`
+	Feedback   *int            `json:"feedback,omitempty"`
+	Status     string          `json:"status"`
+	Retrieved  bool            `json:"retrieved"` // false: 前の版の出典を再利用して生成した
+	CreatedAt  string          `json:"created_at"`
+	AnsweredAt *string         `json:"answered_at,omitempty"`
+}
+
+// listVersionsResponse は回答版一覧レスポンス。
+type listVersionsResponse struct {
+	Versions        []answerVersionResponse `json:"versions"`
+	SelectedVersion int                     `json:"selected_version"`
+}
+
+// ListVersions godoc
+// @Summary     回答版の一覧
+// @Tags        chats
+// @Produce     json
+// @Param       subject_id path string true "Subject UUID"
+// @Param       session_id path string true "Session UUID"
+// @Success     200 {object} listVersionsResponse
+// @Failure     404 {object} ErrorBody
+// @Router      /api/v1/subjects/{subject_id}/chats/{session_id}/versions [get]
+func (h *ChatHandler) ListVersions(c echo.Context) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject_id"})
+	}
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid session_id"})
+	}
+
+	userID := httpmw.GetUserID(c)
+
+	session, versions, err := h.uc.ListVersions(c.Request().Context(), subjectID, sessionID, userID)
+	if err != nil {
+		return httpError(c, err)
+	}
+
+	out := make([]answerVersionResponse, 0, len(versions))
+	for _, v := range versions {
+		r := answerVersionResponse{
+			Version:   v.Version,
+			Answer:    v.Answer,
+			Sources:   v.Sources,
+			Feedback:  v.Feedback,
+			Status:    string(v.Status),
+			Retrieved: v.Retrieved,
+			CreatedAt: v.CreatedAt.Format(time.RFC3339),
+		}
+		if v.AnsweredAt != nil {
+			t := v.AnsweredAt.Format(time.RFC3339)
+			r.AnsweredAt = &t
+		}
+		out = append(out, r)
+	}
+
+	return c.JSON(http.StatusOK, listVersionsResponse{
+		Versions:        out,
+		SelectedVersion: session.SelectedVersion,
+	})
+}
+
+// ─── ReasoningTrace ───────────────────────────────────────────────
+
+// traceResponse は推論の記録（デバッグ用）レスポンス。
+type traceResponse struct {
+	SessionID string `json:"session_id"`
+	Version   int    `json:"version"`
+	// Trace は記録が無い場合（記録を始める前の回答・生成中の回答）は null
+	Trace *domain.ReasoningTrace `json:"trace"`
+}
+
+// GetTrace godoc
+// @Summary     回答版の推論の記録（デバッグ用）
+// @Description 検索ラウンド（クエリ・検索の意図・件数）、選んだエビデンスと選んだ理由、
+// @Description Librarian の終了結果（coverage_notes / is_partial / error_type）、モデル名、所要時間を返す。
+// @Description CHAT_TRACE_ENABLED=true の場合のみ公開する（無効な場合は 404）。
+// @Tags        chats
+// @Produce     json
+// @Param       subject_id path  string true  "Subject UUID"
+// @Param       session_id path  string true  "Session UUID"
+// @Param       version    query int    false "回答版（省略時は選ばれている版）"
+// @Success     200 {object} traceResponse
+// @Failure     400 {object} ErrorBody
+// @Failure     404 {object} ErrorBody
+// @Router      /api/v1/subjects/{subject_id}/chats/{session_id}/trace [get]
+func (h *ChatHandler) GetTrace(c echo.Context) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject_id"})
+	}
+	sessionID, err := uuid.Parse(c.Param("session_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid session_id"})
+	}
+	var version int
+	if v := c.QueryParam("version"); v != "" {
+		version, err = strconv.Atoi(v)
+		if err != nil || version < 1 {
+			return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid version"})
+		}
+	}
+
+	userID := httpmw.GetUserID(c)
+
+	version, trace, err := h.uc.GetTrace(c.Request().Context(), subjectID, sessionID, userID, version)
+	if err != nil {
+		return httpError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, traceResponse{
+		SessionID: sessionID.String(),
+		Version:   version,
+		Trace:     trace,
+	})
+}
+
+// parseOptionalResearchMode は調べる深さを解析する（空の場合は科目の設定を使うため空のまま返す）。
+func parseOptionalResearchMode(v string) (domain.ResearchMode, error) {
+	if v == "" {
+		return "", nil
+	}
+	return domain.ParseResearchMode(v)
+}
+
+// sseErrorData はエラーを SSEEventError のデータに変換する（質問を直す必要があるエラーには status: 400 を付ける）。
+func sseErrorData(err error) map[string]any {
+	data := map[string]any{"message": err.Error()}
+	if errors.Is(err, domain.ErrInvalidInput) {
+		data["status"] = http.StatusBadRequest
+	}
+	return data
+}
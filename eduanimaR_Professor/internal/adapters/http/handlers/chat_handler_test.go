@@ -0,0 +1,51 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/http/handlers"
+	httpmw "github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/http/middleware"
+)
+
+// newChatServer は main.go と同じ構成で ChatHandler のルートを登録した Echo を返す。
+func newChatServer(traceEnabled bool) *echo.Echo {
+	e := echo.New()
+	e.Use(httpmw.DevUser())
+	h := handlers.NewChatHandler(nil)
+	g := e.Group("/api/v1/subjects/:subject_id/chats")
+	h.Register(g)
+	if traceEnabled {
+		h.RegisterDebug(g)
+	}
+	return e
+}
+
+// ─── GetTrace ─────────────────────────────────────────────────────
+
+func TestChatHandler_TraceNotExposedByDefault(t *testing.T) {
+	e := newChatServer(false)
+
+	path := "/api/v1/subjects/" + uuid.NewString() + "/chats/" + uuid.NewString() + "/trace"
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestChatHandler_TraceRegisteredWhenEnabled(t *testing.T) {
+	e := newChatServer(true)
+
+	var found bool
+	for _, r := range e.Routes() {
+		if r.Method == http.MethodGet && r.Path == "/api/v1/subjects/:subject_id/chats/:session_id/trace" {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	httpmw "github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/http/middleware"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/usecases"
+)
+
+// ChunkHandler は教材チャンクの閲覧・手動修正 HTTP ハンドラー。
+// GET    /api/v1/subjects/:subject_id/materials/:fid/chunks                 → チャンク一覧（抽出品質付き）
+// PATCH  /api/v1/subjects/:subject_id/materials/:fid/chunks/:chunk_id       → 内容の修正
+// POST   /api/v1/subjects/:subject_id/materials/:fid/chunks/:chunk_id/split → 分割
+// POST   /api/v1/subjects/:subject_id/materials/:fid/chunks:merge           → 結合
+// DELETE /api/v1/subjects/:subject_id/materials/:fid/chunks/:chunk_id       → 削除
+// GET    /api/v1/subjects/:subject_id/materials/:fid/chunk-edits            → 修正履歴
+type ChunkHandler struct {
+	uc *usecases.ChunkUseCase
+}
+
+// NewChunkHandler は ChunkHandler を生成する。
+func NewChunkHandler(uc *usecases.ChunkUseCase) *ChunkHandler {
+	return &ChunkHandler{uc: uc}
+}
+
+// Register は Echo グループにルートを登録する。
+// ルートプレフィックス: /api/v1/subjects/:subject_id/materials/:fid
+func (h *ChunkHandler) Register(g *echo.Group) {
+	g.GET("/chunks", h.List)
+	g.POST("/chunks\\:merge", h.Merge)
+	g.PATCH("/chunks/:chunk_id", h.Edit)
+	g.POST("/chunks/:chunk_id/split", h.Split)
+	g.DELETE("/chunks/:chunk_id", h.Delete)
+	g.GET("/chunk-edits", h.ListEdits)
+}
+
+// ─── List ─────────────────────────────────────────────────────────
+
+// extractionQualityResponse は抽出品質の JSON 表現。
+type extractionQualityResponse struct {
+	ReadableRatio float64  `json:"readable_ratio"`
+	Suspect       bool     `json:"suspect"`
+	Reasons       []string `json:"reasons"`
+}
+
+// chunkResponse は Chunk の JSON 表現（Embedding は含まない）。
+type chunkResponse struct {
+	ID         string                    `json:"id"`
+	ChunkIndex int                       `json:"chunk_index"`
+	PageNumber *int                      `json:"page_number,omitempty"`
+	Content    string                    `json:"content"`
+	CharCount  int                       `json:"char_count"`
+	Quality    extractionQualityResponse `json:"quality"`
+	// ManuallyEdited は手動修正済み（再処理で明示的な指定が無い限り置き換えない）
+	ManuallyEdited bool    `json:"manually_edited"`
+	UpdatedAt      *string `json:"updated_at,omitempty"`
+}
+
+// listChunksResponse はチャンク一覧レスポンス。
+type listChunksResponse struct {
+	Chunks []chunkResponse `json:"chunks"`
+	Total  int64           `json:"total"`
+	Limit  int             `json:"limit"`
+	Offset int             `json:"offset"`
+}
+
+func toChunkResp(ch *domain.Chunk) chunkResponse {
+	q := domain.AssessExtraction(ch.Content)
+	r := chunkResponse{
+		ID:         ch.ID.String(),
+		ChunkIndex: ch.ChunkIndex,
+		PageNumber: ch.PageNumber,
+		Content:    ch.Content,
+		CharCount:  utf8.RuneCountInString(ch.Content),
+		Quality: extractionQualityResponse{
+			ReadableRatio: q.ReadableRatio,
+			Suspect:       q.Suspect,
+			Reasons:       q.Reasons,
+		},
+		ManuallyEdited: ch.ManuallyEdited,
+	}
+	if ch.UpdatedAt != nil {
+		t := ch.UpdatedAt.Format(time.RFC3339)
+		r.UpdatedAt = &t
+	}
+	return r
+}
+
+// List godoc
+// @Summary     教材チャンク一覧
+// @Description 教材から抽出されたチャンクを chunk_index 順に返す。OCR の取りこぼし・文字化けの確認用に抽出品質の目安を付ける
+// @Tags        materials
+// @Produce     json
+// @Param       subject_id path  string true  "Subject UUID"
+// @Param       fid        path  string true  "Material UUID"
+// @Param       limit      query int    false "件数（デフォルト20・最大100）"
+// @Param       offset     query int    false "オフセット（デフォルト0）"
+// @Success     200 {object} listChunksResponse
+// @Failure     400 {object} ErrorBody
+// @Failure     404 {object} ErrorBody
+// @Router      /api/v1/subjects/{subject_id}/materials/{fid}/chunks [get]
+func (h *ChunkHandler) List(c echo.Context) error {
+	subjectID, fileID, err := materialParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: err.Error()})
+	}
+	limit, offset := chunkPageParams(c)
+	userID := httpmw.GetUserID(c)
+
+	out, err := h.uc.ListByMaterial(c.Request().Context(), subjectID, fileID, userID, limit, offset)
+	if err != nil {
+		return httpError(c, err)
+	}
+
+	chunks := make([]chunkResponse, 0, len(out.Chunks))
+	for _, ch := range out.Chunks {
+		chunks = append(chunks, toChunkResp(ch))
+	}
+	return c.JSON(http.StatusOK, listChunksResponse{
+		Chunks: chunks,
+		Total:  out.Total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// ─── 手動修正 ─────────────────────────────────────────────────────
+
+// editChunkRequest は PATCH /chunks/:chunk_id のリクエストボディ。
+type editChunkRequest struct {
+	Content string `json:"content"`
+}
+
+// splitChunkRequest は POST /chunks/:chunk_id/split のリクエストボディ。
+type splitChunkRequest struct {
+	Parts []string `json:"parts"` // 分割後の各チャンクの内容（2 つ以上）
+}
+
+// mergeChunksRequest は POST /chunks:merge のリクエストボディ。
+type mergeChunksRequest struct {
+	ChunkIDs []string `json:"chunk_ids"`         // 連番が連続するチャンク（2 つ以上）
+	Content  *string  `json:"content,omitempty"` // 省略時は空行区切りで連結する
+}
+
+// splitChunkResponse は分割後のチャンク一覧。
+type splitChunkResponse struct {
+	Chunks []chunkResponse `json:"chunks"`
+}
+
+// Edit godoc
+// @Summary     チャンク内容の修正
+// @Description OCR の崩れ（数式・表など）を修正する。Embedding は修正後の内容で再生成する
+// @Tags        materials
+// @Accept      json
+// @Produce     json
+// @Param       subject_id path string           true "Subject UUID"
+// @Param       fid        path string           true "Material UUID"
+// @Param       chunk_id   path string           true "Chunk UUID"
+// @Param       body       body editChunkRequest true "修正後の内容"
+// @Success     200 {object} chunkResponse
+// @Failure     400 {object} ErrorBody
+// @Failure     404 {object} ErrorBody
+// @Failure     409 {object} ErrorBody
+// @Router      /api/v1/subjects/{subject_id}/materials/{fid}/chunks/{chunk_id} [patch]
+func (h *ChunkHandler) Edit(c echo.Context) error {
+	subjectID, fileID, err := materialParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: err.Error()})
+	}
+	chunkID, err := uuid.Parse(c.Param("chunk_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid chunk id"})
+	}
+	var req editChunkRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid request body"})
+	}
+	chunk, err := h.uc.Edit(c.Request().Context(), usecases.EditChunkInput{
+		SubjectID: subjectID,
+		FileID:    fileID,
+		ChunkID:   chunkID,
+		UserID:    httpmw.GetUserID(c),
+		Content:   req.Content,
+	})
+	if err != nil {
+		return httpError(c, err)
+	}
+	return c.JSON(http.StatusOK, toChunkResp(chunk))
+}
+
+// Split godoc
+// @Summary     チャンクの分割
+// @Description 1 つのチャンクを複数に分割する。後続のチャンクの chunk_index はずらす
+// @Tags        materials
+// @Accept      json
+// @Produce     json
+// @Param       subject_id path string            true "Subject UUID"
+// @Param       fid        path string            true "Material UUID"
+// @Param       chunk_id   path string            true "Chunk UUID"
+// @Param       body       body splitChunkRequest true "分割後の内容"
+// @Success     200 {object} splitChunkResponse
+// @Failure     400 {object} ErrorBody
+// @Failure     404 {object} ErrorBody
+// @Failure     409 {object} ErrorBody
+// @Router      /api/v1/subjects/{subject_id}/materials/{fid}/chunks/{chunk_id}/split [post]
+func (h *ChunkHandler) Split(c echo.Context) error {
+	subjectID, fileID, err := materialParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: err.Error()})
+	}
+	chunkID, err := uuid.Parse(c.Param("chunk_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid chunk id"})
+	}
+	var req splitChunkRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid request body"})
+	}
+	chunks, err := h.uc.Split(c.Request().Context(), usecases.SplitChunkInput{
+		SubjectID: subjectID,
+		FileID:    fileID,
+		ChunkID:   chunkID,
+		UserID:    httpmw.GetUserID(c),
+		Parts:     req.Parts,
+	})
+	if err != nil {
+		return httpError(c, err)
+	}
+	out := make([]chunkResponse, 0, len(chunks))
+	for _, ch := range chunks {
+		out = append(out, toChunkResp(ch))
+	}
+	return c.JSON(http.StatusOK, splitChunkResponse{Chunks: out})
+}
+
+// Merge godoc
+// @Summary     チャンクの結合
+// @Description 連番が連続する複数のチャンクを 1 つに結合する。後続のチャンクの chunk_index は詰める
+// @Tags        materials
+// @Accept      json
+// @Produce     json
+// @Param       subject_id path string             true "Subject UUID"
+// @Param       fid        path string             true "Material UUID"
+// @Param       body       body mergeChunksRequest true "結合するチャンク"
+// @Success     200 {object} chunkResponse
+// @Failure     400 {object} ErrorBody
+// @Failure     404 {object} ErrorBody
+// @Failure     409 {object} ErrorBody
+// @Router      /api/v1/subjects/{subject_id}/materials/{fid}/chunks:merge [post]
+func (h *ChunkHandler) Merge(c echo.Context) error {
+	subjectID, fileID, err := materialParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: err.Error()})
+	}
+	var req mergeChunksRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid request body"})
+	}
+	ids := make([]uuid.UUID, 0, len(req.ChunkIDs))
+	for _, v := range req.ChunkIDs {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid chunk id"})
+		}
+		ids = append(ids, id)
+	}
+	chunk, err := h.uc.Merge(c.Request().Context(), usecases.MergeChunksInput{
+		SubjectID: subjectID,
+		FileID:    fileID,
+		UserID:    httpmw.GetUserID(c),
+		ChunkIDs:  ids,
+		Content:   req.Content,
+	})
+	if err != nil {
+		return httpError(c, err)
+	}
+	return c.JSON(http.StatusOK, toChunkResp(chunk))
+}
+
+// Delete godoc
+// @Summary     チャンクの削除
+// @Description 後続のチャンクの chunk_index は詰める
+// @Tags        materials
+// @Param       subject_id path string true "Subject UUID"
+// @Param       fid        path string true "Material UUID"
+// @Param       chunk_id   path string true "Chunk UUID"
+// @Success     204
+// @Failure     404 {object} ErrorBody
+// @Failure     409 {object} ErrorBody
+// @Router      /api/v1/subjects/{subject_id}/materials/{fid}/chunks/{chunk_id} [delete]
+func (h *ChunkHandler) Delete(c echo.Context) error {
+	subjectID, fileID, err := materialParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: err.Error()})
+	}
+	chunkID, err := uuid.Parse(c.Param("chunk_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid chunk id"})
+	}
+	if err := h.uc.Delete(c.Request().Context(), subjectID, fileID, chunkID, httpmw.GetUserID(c)); err != nil {
+		return httpError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ─── ListEdits ────────────────────────────────────────────────────
+
+// chunkEditResponse は ChunkEdit の JSON 表現。
+type chunkEditResponse struct {
+	ID        string                 `json:"id"`
+	UserID    string                 `json:"user_id"`
+	Operation string                 `json:"operation"`
+	Before    []domain.ChunkSnapshot `json:"before"`
+	After     []domain.ChunkSnapshot `json:"after"`
+	CreatedAt string                 `json:"created_at"`
+}
+
+// listChunkEditsResponse は修正履歴一覧レスポンス。
+type listChunkEditsResponse struct {
+	Edits  []chunkEditResponse `json:"edits"`
+	Total  int64               `json:"total"`
+	Limit  int                 `json:"limit"`
+	Offset int                 `json:"offset"`
+}
+
+// ListEdits godoc
+// @Summary     チャンク修正履歴
+// @Description 誰がいつどのチャンクをどう修正したかを新しい順に返す
+// @Tags        materials
+// @Produce     json
+// @Param       subject_id path  string true  "Subject UUID"
+// @Param       fid        path  string true  "Material UUID"
+// @Param       limit      query int    false "件数（デフォルト20・最大100）"
+// @Param       offset     query int    false "オフセット（デフォルト0）"
+// @Success     200 {object} listChunkEditsResponse
+// @Failure     404 {object} ErrorBody
+// @Router      /api/v1/subjects/{subject_id}/materials/{fid}/chunk-edits [get]
+func (h *ChunkHandler) ListEdits(c echo.Context) error {
+	subjectID, fileID, err := materialParams(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: err.Error()})
+	}
+	limit, offset := chunkPageParams(c)
+
+	out, err := h.uc.ListEdits(c.Request().Context(), subjectID, fileID, httpmw.GetUserID(c), limit, offset)
+	if err != nil {
+		return httpError(c, err)
+	}
+
+	edits := make([]chunkEditResponse, 0, len(out.Edits))
+	for _, e := range out.Edits {
+		edits = append(edits, chunkEditResponse{
+			ID:        e.ID.String(),
+			UserID:    e.UserID.String(),
+			Operation: string(e.Operation),
+			Before:    e.Before,
+			After:     e.After,
+			CreatedAt: e.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return c.JSON(http.StatusOK, listChunkEditsResponse{
+		Edits:  edits,
+		Total:  out.Total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// ─── パラメータ ───────────────────────────────────────────────────
+
+// materialParams はパスの subject_id と fid を解析する。
+func materialParams(c echo.Context) (subjectID, fileID uuid.UUID, err error) {
+	subjectID, err = uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, errors.New("invalid subject id")
+	}
+	fileID, err = uuid.Parse(c.Param("fid"))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, errors.New("invalid file id")
+	}
+	return subjectID, fileID, nil
+}
+
+// chunkPageParams は limit / offset クエリを解析する（limit はデフォルト 20・最大 MaxChunkPageSize）。
+func chunkPageParams(c echo.Context) (limit, offset int) {
+	limit = 20
+	if v := c.QueryParam("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = min(n, usecases.MaxChunkPageSize)
+		}
+	}
+	if v := c.QueryParam("offset"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			offset = n
+		}
+	}
+	return limit, offset
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,6 +10,7 @@ import (
 
 	httpmw "github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/http/middleware"
 	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
 	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/usecases"
 )
 
@@ -27,9 +29,25 @@ func NewMaterialHandler(uc *usecases.MaterialUseCase) *MaterialHandler {
 func (h *MaterialHandler) Register(g *echo.Group) {
 	g.GET("", h.List)
 	g.POST("", h.Upload)
+	g.POST("\\:import-zip", h.ImportZip)
+	g.POST("\\:import-url", h.ImportURL)
+	g.POST("/:fid/refetch", h.Refetch)
+	g.POST("/:fid/reprocess", h.Reprocess)
+	g.GET("/:fid/download", h.Download)
+	g.GET("/:fid/pages/:page/preview", h.PagePreview)
 	g.DELETE("/:fid", h.Delete)
+	g.POST("/uploads", h.RequestUpload)
+	g.POST("/uploads/:upload_id/complete", h.CompleteUpload)
+	g.POST("/resumable-uploads", h.CreateResumableUpload)
+	g.GET("/resumable-uploads/:upload_id", h.GetResumableUpload)
+	g.PATCH("/resumable-uploads/:upload_id", h.AppendUploadChunk)
+	g.DELETE("/resumable-uploads/:upload_id", h.AbortResumableUpload)
+	g.POST("/resumable-uploads/:upload_id/complete", h.CompleteUpload)
 }
 
+// uploadOffsetHeader は再開可能アップロードの受信済みオフセットを示すヘッダー
+const uploadOffsetHeader = "Upload-Offset"
+
 // ─── レスポンス型 ──────────────────────────────────────────
 
 type materialResponse struct {
@@ -42,6 +60,9 @@ type materialResponse struct {
 	ErrorMsg    *string `json:"error_message,omitempty"`
 	UploadedAt  string  `json:"uploaded_at"`
 	ProcessedAt *string `json:"processed_at,omitempty"`
+	FolderPath  *string `json:"folder_path,omitempty"`
+	SourceURL   *string `json:"source_url,omitempty"`
+	PageCount   int     `json:"page_count,omitempty"` // プレビュー生成済みのページ数
 }
 
 func toMaterialResp(f *domain.File) materialResponse {
@@ -54,6 +75,9 @@ func toMaterialResp(f *domain.File) materialResponse {
 		Status:     string(f.Status),
 		ErrorMsg:   f.ErrorMessage,
 		UploadedAt: f.UploadedAt.Format(time.RFC3339),
+		FolderPath: f.FolderPath,
+		SourceURL:  f.SourceURL,
+		PageCount:  f.PreviewPageCount,
 	}
 	if f.ProcessedAt != nil {
 		s := f.ProcessedAt.Format(time.RFC3339)
@@ -62,6 +86,95 @@ func toMaterialResp(f *domain.File) materialResponse {
 	return r
 }
 
+type uploadRequest struct {
+	FileName  string `json:"file_name"`
+	MimeType  string `json:"mime_type"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+type uploadResponse struct {
+	UploadID   string            `json:"upload_id"`
+	MaterialID string            `json:"material_id"`
+	UploadURL  string            `json:"upload_url"`
+	Method     string            `json:"method"`
+	Headers    map[string]string `json:"headers"`
+	ExpiresAt  string            `json:"expires_at"`
+}
+
+type resumableUploadResponse struct {
+	UploadID    string `json:"upload_id"`
+	MaterialID  string `json:"material_id"`
+	Offset      int64  `json:"offset"`
+	SizeBytes   int64  `json:"size_bytes"`
+	MinPartSize int64  `json:"min_part_size"`
+	ExpiresAt   string `json:"expires_at"`
+}
+
+func toResumableUploadResp(u *domain.MaterialUpload) resumableUploadResponse {
+	return resumableUploadResponse{
+		UploadID:    u.ID.String(),
+		MaterialID:  u.FileID.String(),
+		Offset:      u.UploadedBytes,
+		SizeBytes:   u.SizeBytes,
+		MinPartSize: ports.MinMultipartPartSize,
+		ExpiresAt:   u.ExpiresAt.Format(time.RFC3339),
+	}
+}
+
+type importURLRequest struct {
+	URL string `json:"url"`
+}
+
+type reprocessRequest struct {
+	// 手動修正されたチャンクも OCR 結果で置き換える（省略時は手動修正を残す）
+	OverwriteManualEdits bool `json:"overwrite_manual_edits"`
+}
+
+type refetchResponse struct {
+	Changed  bool             `json:"changed"` // true: 内容が変わり再処理を投入した
+	Material materialResponse `json:"material"`
+}
+
+type downloadResponse struct {
+	URL        string `json:"url"`
+	ExpiresAt  string `json:"expires_at"`
+	FileName   string `json:"file_name"`
+	MimeType   string `json:"mime_type"`
+	PageNumber *int   `json:"page_number,omitempty"`
+	PreviewURL string `json:"preview_url,omitempty"`
+}
+
+type zipEntryResponse struct {
+	Path     string            `json:"path"`
+	Status   string            `json:"status"` // imported / unsupported / too_large / invalid_name / failed
+	Reason   string            `json:"reason,omitempty"`
+	Material *materialResponse `json:"material,omitempty"`
+}
+
+type zipImportResponse struct {
+	Imported int                `json:"imported"`
+	Skipped  int                `json:"skipped"`
+	Entries  []zipEntryResponse `json:"entries"`
+}
+
+func toZipImportResp(out *usecases.ImportZipOutput) zipImportResponse {
+	resp := zipImportResponse{Entries: make([]zipEntryResponse, 0, len(out.Entries))}
+	for _, e := range out.Entries {
+		r := zipEntryResponse{Path: e.Path, Status: string(e.Status), Reason: e.Reason}
+		if e.File != nil {
+			m := toMaterialResp(e.File)
+			r.Material = &m
+		}
+		if e.Status == usecases.ZipEntryImported {
+			resp.Imported++
+		} else {
+			resp.Skipped++
+		}
+		resp.Entries = append(resp.Entries, r)
+	}
+	return resp
+}
+
 // ─── ハンドラー ────────────────────────────────────────────
 
 // List godoc
@@ -135,6 +248,226 @@ func (h *MaterialHandler) Upload(c echo.Context) error {
 	return c.JSON(http.StatusCreated, toMaterialResp(file))
 }
 
+// ImportZip godoc
+// @Summary 教材の ZIP 一括取り込み
+// @Description ZIP を展開し、対応形式（PDF / 画像）のエントリごとに教材登録と OCR ジョブ投入を行う。
+// @Description ZIP 内のフォルダパスは folder_path として保持する。非対応・サイズ超過のエントリは entries に理由付きで返す
+// @Tags materials
+// @Accept multipart/form-data
+// @Produce json
+// @Param subject_id path string true "Subject ID"
+// @Param file formData file true "ZIP archive"
+// @Success 201 {object} zipImportResponse
+// @Failure 400 {object} ErrorBody
+// @Router /api/v1/subjects/{subject_id}/materials:import-zip [post]
+func (h *MaterialHandler) ImportZip(c echo.Context) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject id"})
+	}
+
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "file is required"})
+	}
+
+	src, err := fh.Open()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorBody{Error: "failed to open file"})
+	}
+	defer src.Close()
+
+	userID := httpmw.GetUserID(c)
+	out, err := h.uc.ImportZip(c.Request().Context(), usecases.ImportZipInput{
+		SubjectID: subjectID,
+		UserID:    userID,
+		Reader:    src,
+		Size:      fh.Size,
+	})
+	if err != nil {
+		return httpError(c, err)
+	}
+	return c.JSON(http.StatusCreated, toZipImportResp(out))
+}
+
+// ImportURL godoc
+// @Summary URL から教材を取り込み
+// @Description 公開 URL のドキュメント（PDF / 画像）を取得して教材登録と OCR ジョブ投入を行う。
+// @Description 内部ネットワーク宛ての URL は拒否する
+// @Tags materials
+// @Accept json
+// @Produce json
+// @Param subject_id path string true "Subject ID"
+// @Param body body importURLRequest true "取り込む URL"
+// @Success 201 {object} materialResponse
+// @Failure 400 {object} ErrorBody
+// @Failure 403 {object} ErrorBody
+// @Router /api/v1/subjects/{subject_id}/materials:import-url [post]
+func (h *MaterialHandler) ImportURL(c echo.Context) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject id"})
+	}
+	var req importURLRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid request body"})
+	}
+	userID := httpmw.GetUserID(c)
+	file, err := h.uc.ImportURL(c.Request().Context(), usecases.ImportURLInput{
+		SubjectID: subjectID,
+		UserID:    userID,
+		URL:       req.URL,
+	})
+	if err != nil {
+		return httpError(c, err)
+	}
+	return c.JSON(http.StatusCreated, toMaterialResp(file))
+}
+
+// Refetch godoc
+// @Summary URL 取り込み教材の再取得
+// @Description 取得元 URL を再取得し、内容が変わっていれば OCR/Embedding を再実行する
+// @Tags materials
+// @Produce json
+// @Param subject_id path string true "Subject ID"
+// @Param fid path string true "Material ID"
+// @Success 200 {object} refetchResponse
+// @Failure 400 {object} ErrorBody
+// @Failure 409 {object} ErrorBody
+// @Router /api/v1/subjects/{subject_id}/materials/{fid}/refetch [post]
+func (h *MaterialHandler) Refetch(c echo.Context) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject id"})
+	}
+	fileID, err := uuid.Parse(c.Param("fid"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid file id"})
+	}
+	userID := httpmw.GetUserID(c)
+	out, err := h.uc.RefetchURL(c.Request().Context(), subjectID, fileID, userID)
+	if err != nil {
+		return httpError(c, err)
+	}
+	return c.JSON(http.StatusOK, refetchResponse{
+		Changed:  out.Changed,
+		Material: toMaterialResp(out.File),
+	})
+}
+
+// Reprocess godoc
+// @Summary 教材の再処理
+// @Description OCR/Embedding を再実行する。手動修正されたチャンクは overwrite_manual_edits を指定した場合のみ置き換える
+// @Tags materials
+// @Accept json
+// @Produce json
+// @Param subject_id path string true "Subject ID"
+// @Param fid path string true "Material ID"
+// @Param body body reprocessRequest false "再処理オプション"
+// @Success 202 {object} materialResponse
+// @Failure 400 {object} ErrorBody
+// @Failure 404 {object} ErrorBody
+// @Failure 409 {object} ErrorBody
+// @Router /api/v1/subjects/{subject_id}/materials/{fid}/reprocess [post]
+func (h *MaterialHandler) Reprocess(c echo.Context) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject id"})
+	}
+	fileID, err := uuid.Parse(c.Param("fid"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid file id"})
+	}
+	var req reprocessRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid request body"})
+	}
+	userID := httpmw.GetUserID(c)
+	file, err := h.uc.Reprocess(c.Request().Context(), subjectID, fileID, userID, req.OverwriteManualEdits)
+	if err != nil {
+		return httpError(c, err)
+	}
+	return c.JSON(http.StatusAccepted, toMaterialResp(file))
+}
+
+// Download godoc
+// @Summary 教材の一時ダウンロード URL 発行
+// @Description 出典の教材を開くための presigned GET URL を返す。page を指定すると PDF の該当ページを開くアンカーを付ける
+// @Tags materials
+// @Produce json
+// @Param subject_id path string true "Subject ID"
+// @Param fid path string true "Material ID"
+// @Param page query int false "ページ番号（1 始まり）"
+// @Success 200 {object} downloadResponse
+// @Failure 400 {object} ErrorBody
+// @Failure 404 {object} ErrorBody
+// @Router /api/v1/subjects/{subject_id}/materials/{fid}/download [get]
+func (h *MaterialHandler) Download(c echo.Context) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject id"})
+	}
+	fileID, err := uuid.Parse(c.Param("fid"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid file id"})
+	}
+	var page *int
+	if v := c.QueryParam("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid page"})
+		}
+		page = &n
+	}
+	userID := httpmw.GetUserID(c)
+	out, err := h.uc.GetDownloadURL(c.Request().Context(), subjectID, fileID, userID, page)
+	if err != nil {
+		return httpError(c, err)
+	}
+	return c.JSON(http.StatusOK, downloadResponse{
+		URL:        out.URL,
+		ExpiresAt:  out.ExpiresAt.Format(time.RFC3339),
+		FileName:   out.File.Name,
+		MimeType:   out.File.MimeType,
+		PageNumber: out.PageNumber,
+		PreviewURL: out.PreviewURL,
+	})
+}
+
+// PagePreview godoc
+// @Summary 教材ページのプレビュー画像
+// @Description ページのサムネイル画像の presigned GET URL へリダイレクトする（img 要素から直接参照できる）
+// @Tags materials
+// @Param subject_id path string true "Subject ID"
+// @Param fid path string true "Material ID"
+// @Param page path int true "ページ番号（1 始まり）"
+// @Success 302
+// @Failure 400 {object} ErrorBody
+// @Failure 404 {object} ErrorBody
+// @Router /api/v1/subjects/{subject_id}/materials/{fid}/pages/{page}/preview [get]
+func (h *MaterialHandler) PagePreview(c echo.Context) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject id"})
+	}
+	fileID, err := uuid.Parse(c.Param("fid"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid file id"})
+	}
+	page, err := strconv.Atoi(c.Param("page"))
+	if err != nil || page < 1 {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid page"})
+	}
+	userID := httpmw.GetUserID(c)
+	u, err := h.uc.GetPagePreviewURL(c.Request().Context(), subjectID, fileID, userID, page)
+	if err != nil {
+		return httpError(c, err)
+	}
+	// 署名付き URL は期限があるためキャッシュさせない
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.Redirect(http.StatusFound, u)
+}
+
 // Delete godoc
 // @Summary 教材削除
 // @Tags materials
@@ -153,3 +486,200 @@ func (h *MaterialHandler) Delete(c echo.Context) error {
 	}
 	return c.NoContent(http.StatusNoContent)
 }
+
+// RequestUpload godoc
+// @Summary 直接アップロード開始（presigned PUT URL 発行）
+// @Description クライアントは upload_url に headers を付けて PUT した後、complete を呼び出す
+// @Tags materials
+// @Accept json
+// @Produce json
+// @Param subject_id path string true "Subject ID"
+// @Param body body uploadRequest true "アップロードするファイルの情報"
+// @Success 201 {object} uploadResponse
+// @Router /api/v1/subjects/{subject_id}/materials/uploads [post]
+func (h *MaterialHandler) RequestUpload(c echo.Context) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject id"})
+	}
+	var req uploadRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid request body"})
+	}
+	userID := httpmw.GetUserID(c)
+	out, err := h.uc.RequestUpload(c.Request().Context(), usecases.RequestUploadInput{
+		SubjectID: subjectID,
+		UserID:    userID,
+		FileName:  req.FileName,
+		MimeType:  req.MimeType,
+		Size:      req.SizeBytes,
+	})
+	if err != nil {
+		return httpError(c, err)
+	}
+	return c.JSON(http.StatusCreated, uploadResponse{
+		UploadID:   out.Upload.ID.String(),
+		MaterialID: out.Upload.FileID.String(),
+		UploadURL:  out.UploadURL,
+		Method:     http.MethodPut,
+		Headers:    map[string]string{"Content-Type": out.Upload.MimeType},
+		ExpiresAt:  out.Upload.ExpiresAt.Format(time.RFC3339),
+	})
+}
+
+// CompleteUpload godoc
+// @Summary 直接アップロード完了
+// @Description バケット上のオブジェクトのサイズ・種別を検証し、教材登録と OCR ジョブ投入を行う
+// @Tags materials
+// @Produce json
+// @Param subject_id path string true "Subject ID"
+// @Param upload_id path string true "Upload ID"
+// @Success 201 {object} materialResponse
+// @Failure 400 {object} ErrorBody
+// @Failure 409 {object} ErrorBody
+// @Router /api/v1/subjects/{subject_id}/materials/uploads/{upload_id}/complete [post]
+func (h *MaterialHandler) CompleteUpload(c echo.Context) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject id"})
+	}
+	uploadID, err := uuid.Parse(c.Param("upload_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid upload id"})
+	}
+	userID := httpmw.GetUserID(c)
+	file, err := h.uc.CompleteUpload(c.Request().Context(), subjectID, uploadID, userID)
+	if err != nil {
+		return httpError(c, err)
+	}
+	return c.JSON(http.StatusCreated, toMaterialResp(file))
+}
+
+// CreateResumableUpload godoc
+// @Summary 再開可能アップロード開始
+// @Description 大容量教材をバイト範囲に分けて PATCH で送信する。中断時は GET で受信済みオフセットを確認して再開する
+// @Tags materials
+// @Accept json
+// @Produce json
+// @Param subject_id path string true "Subject ID"
+// @Param body body uploadRequest true "アップロードするファイルの情報"
+// @Success 201 {object} resumableUploadResponse
+// @Router /api/v1/subjects/{subject_id}/materials/resumable-uploads [post]
+func (h *MaterialHandler) CreateResumableUpload(c echo.Context) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject id"})
+	}
+	var req uploadRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid request body"})
+	}
+	userID := httpmw.GetUserID(c)
+	upload, err := h.uc.CreateResumableUpload(c.Request().Context(), usecases.RequestUploadInput{
+		SubjectID: subjectID,
+		UserID:    userID,
+		FileName:  req.FileName,
+		MimeType:  req.MimeType,
+		Size:      req.SizeBytes,
+	})
+	if err != nil {
+		return httpError(c, err)
+	}
+	c.Response().Header().Set(uploadOffsetHeader, strconv.FormatInt(upload.UploadedBytes, 10))
+	return c.JSON(http.StatusCreated, toResumableUploadResp(upload))
+}
+
+// GetResumableUpload godoc
+// @Summary 再開可能アップロードの受信済みオフセット取得
+// @Tags materials
+// @Produce json
+// @Param subject_id path string true "Subject ID"
+// @Param upload_id path string true "Upload ID"
+// @Success 200 {object} resumableUploadResponse
+// @Router /api/v1/subjects/{subject_id}/materials/resumable-uploads/{upload_id} [get]
+func (h *MaterialHandler) GetResumableUpload(c echo.Context) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject id"})
+	}
+	uploadID, err := uuid.Parse(c.Param("upload_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid upload id"})
+	}
+	userID := httpmw.GetUserID(c)
+	upload, err := h.uc.GetUpload(c.Request().Context(), subjectID, uploadID, userID)
+	if err != nil {
+		return httpError(c, err)
+	}
+	c.Response().Header().Set(uploadOffsetHeader, strconv.FormatInt(upload.UploadedBytes, 10))
+	return c.JSON(http.StatusOK, toResumableUploadResp(upload))
+}
+
+// AppendUploadChunk godoc
+// @Summary 再開可能アップロードへのバイト範囲送信
+// @Description Upload-Offset ヘッダーに受信済みオフセットを指定し、続きのバイト列を送信する。
+// @Description 最終パート以外は min_part_size 以上である必要がある
+// @Tags materials
+// @Accept application/octet-stream
+// @Param subject_id path string true "Subject ID"
+// @Param upload_id path string true "Upload ID"
+// @Param Upload-Offset header int true "送信するバイト範囲の先頭オフセット"
+// @Success 204
+// @Failure 400 {object} ErrorBody
+// @Failure 409 {object} ErrorBody
+// @Router /api/v1/subjects/{subject_id}/materials/resumable-uploads/{upload_id} [patch]
+func (h *MaterialHandler) AppendUploadChunk(c echo.Context) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject id"})
+	}
+	uploadID, err := uuid.Parse(c.Param("upload_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid upload id"})
+	}
+	offset, err := strconv.ParseInt(c.Request().Header.Get(uploadOffsetHeader), 10, 64)
+	if err != nil || offset < 0 {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid Upload-Offset header"})
+	}
+	size := c.Request().ContentLength
+	if size <= 0 {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "Content-Length is required"})
+	}
+	userID := httpmw.GetUserID(c)
+	upload, err := h.uc.AppendUploadChunk(c.Request().Context(), usecases.AppendUploadChunkInput{
+		SubjectID: subjectID,
+		UploadID:  uploadID,
+		UserID:    userID,
+		Offset:    offset,
+		Size:      size,
+		Reader:    c.Request().Body,
+	})
+	if err != nil {
+		return httpError(c, err)
+	}
+	c.Response().Header().Set(uploadOffsetHeader, strconv.FormatInt(upload.UploadedBytes, 10))
+	return c.NoContent(http.StatusNoContent)
+}
+
+// AbortResumableUpload godoc
+// @Summary 再開可能アップロードの中止
+// @Tags materials
+// @Param subject_id path string true "Subject ID"
+// @Param upload_id path string true "Upload ID"
+// @Success 204
+// @Router /api/v1/subjects/{subject_id}/materials/resumable-uploads/{upload_id} [delete]
+func (h *MaterialHandler) AbortResumableUpload(c echo.Context) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject id"})
+	}
+	uploadID, err := uuid.Parse(c.Param("upload_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid upload id"})
+	}
+	userID := httpmw.GetUserID(c)
+	if err := h.uc.AbortUpload(c.Request().Context(), subjectID, uploadID, userID); err != nil {
+		return httpError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
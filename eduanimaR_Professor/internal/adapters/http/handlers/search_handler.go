@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	httpmw "github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/http/middleware"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/usecases"
+)
+
+// SearchHandler は教材の直接検索 HTTP ハンドラー（LLM を使わない）。
+// GET /api/v1/subjects/:subject_id/search?q= → ハイブリッド検索（extra_subject_id で関連科目も横断）
+type SearchHandler struct {
+	uc *usecases.SearchUseCase
+}
+
+// NewSearchHandler は SearchHandler を生成する。
+func NewSearchHandler(uc *usecases.SearchUseCase) *SearchHandler {
+	return &SearchHandler{uc: uc}
+}
+
+// Register は Echo グループにルートを登録する。
+// ルートプレフィックス: /api/v1/subjects/:subject_id/search
+func (h *SearchHandler) Register(g *echo.Group) {
+	g.GET("", h.Search)
+}
+
+// highlightResponse はハイライト付き抜粋の断片。
+type highlightResponse struct {
+	Text  string `json:"text"`
+	Match bool   `json:"match"`
+}
+
+// searchHitResponse は検索結果 1 件の JSON 表現。
+type searchHitResponse struct {
+	SubjectID   string              `json:"subject_id"`
+	SubjectName string              `json:"subject_name"`
+	ChunkID     string              `json:"chunk_id"`
+	FileID      string              `json:"file_id"`
+	FileName    string              `json:"file_name"`
+	PageNumber  *int                `json:"page_number,omitempty"`
+	ChunkIndex  int                 `json:"chunk_index"`
+	Score       float64             `json:"score"`
+	Highlights  []highlightResponse `json:"highlights"`
+	PreviewURL  string              `json:"preview_url,omitempty"`
+	// 検索方式ごとの順位（1 始まり。ヒットしない場合は省略）と関連度
+	TextRank    *int     `json:"text_rank,omitempty"`
+	TextScore   *float64 `json:"text_score,omitempty"`
+	VectorRank  *int     `json:"vector_rank,omitempty"`
+	VectorScore *float64 `json:"vector_score,omitempty"`
+}
+
+// searchResponse は検索レスポンス。
+type searchResponse struct {
+	Query   string              `json:"query"`
+	Results []searchHitResponse `json:"results"`
+	// VectorSearch はベクトル検索を実行できたか（false の場合は全文検索のみの結果）
+	VectorSearch bool `json:"vector_search"`
+}
+
+func toSearchHitResp(hit usecases.SearchHit) searchHitResponse {
+	r := hit.Result
+	resp := searchHitResponse{
+		SubjectID:   r.SubjectID.String(),
+		SubjectName: r.SubjectName,
+		ChunkID:     r.ChunkID.String(),
+		FileID:      r.FileID.String(),
+		FileName:    r.FileName,
+		PageNumber:  r.PageNumber,
+		ChunkIndex:  r.ChunkIndex,
+		Score:       hit.Score,
+		Highlights:  make([]highlightResponse, 0, len(hit.Highlights)),
+		PreviewURL:  hit.PreviewURL,
+	}
+	for _, s := range hit.Highlights {
+		resp.Highlights = append(resp.Highlights, highlightResponse{Text: s.Text, Match: s.Match})
+	}
+	if hit.TextRank > 0 {
+		rank, score := hit.TextRank, hit.TextScore
+		resp.TextRank, resp.TextScore = &rank, &score
+	}
+	if hit.VectorRank > 0 {
+		rank, score := hit.VectorRank, hit.VectorScore
+		resp.VectorRank, resp.VectorScore = &rank, &score
+	}
+	return resp
+}
+
+// Search godoc
+// @Summary     教材の直接検索
+// @Description 全文検索とベクトル検索を統合（RRF）して、一致したチャンクをハイライト・教材名・ページ付きで返す。LLM による回答生成は行わない
+// @Tags        search
+// @Produce     json
+// @Param       subject_id       path  string   true  "Subject UUID"
+// @Param       q                query string   true  "検索クエリ"
+// @Param       extra_subject_id query []string false "横断検索する subject UUID（複数指定可・最大9）" collectionFormat(multi)
+// @Param       file_id          query []string false "対象の教材 UUID（複数指定可）" collectionFormat(multi)
+// @Param       page_from        query int      false "ページ番号の下限（含む）"
+// @Param       page_to          query int      false "ページ番号の上限（含む）"
+// @Param       limit            query int      false "件数（デフォルト20・最大50）"
+// @Success     200 {object} searchResponse
+// @Failure     400 {object} ErrorBody
+// @Failure     404 {object} ErrorBody
+// @Router      /api/v1/subjects/{subject_id}/search [get]
+func (h *SearchHandler) Search(c echo.Context) error {
+	subjectID, err := uuid.Parse(c.Param("subject_id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject id"})
+	}
+	extraSubjectIDs, err := parseUUIDs(c.QueryParams()["extra_subject_id"])
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid extra_subject_id"})
+	}
+	var filter domain.SearchFilter
+	if filter.FileIDs, err = parseUUIDs(c.QueryParams()["file_id"]); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid file_id"})
+	}
+	if filter.PageFrom, err = optionalIntParam(c, "page_from"); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid page_from"})
+	}
+	if filter.PageTo, err = optionalIntParam(c, "page_to"); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid page_to"})
+	}
+	limit, err := optionalIntParam(c, "limit")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid limit"})
+	}
+
+	in := usecases.SearchInput{
+		SubjectID:       subjectID,
+		ExtraSubjectIDs: extraSubjectIDs,
+		UserID:          httpmw.GetUserID(c),
+		Query:           c.QueryParam("q"),
+		Filter:          filter,
+	}
+	if limit != nil {
+		in.Limit = *limit
+	}
+	out, err := h.uc.Search(c.Request().Context(), in)
+	if err != nil {
+		return httpError(c, err)
+	}
+
+	results := make([]searchHitResponse, 0, len(out.Hits))
+	for _, hit := range out.Hits {
+		results = append(results, toSearchHitResp(hit))
+	}
+	return c.JSON(http.StatusOK, searchResponse{
+		Query:        in.Query,
+		Results:      results,
+		VectorSearch: out.VectorSearch,
+	})
+}
+
+// parseUUIDs は UUID 文字列の一覧を解析する（空の場合は nil）。
+func parseUUIDs(values []string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for _, v := range values {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// optionalIntParam は整数のクエリパラメータを解析する（未指定の場合は nil）。
+func optionalIntParam(c echo.Context, name string) (*int, error) {
+	v := c.QueryParam(name)
+	if v == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
@@ -37,17 +37,20 @@ type subjectResponse struct {
 	ID          string  `json:"id"`
 	Name        string  `json:"name"`
 	LMSCourseID *string `json:"lms_course_id,omitempty"`
-	CreatedAt   string  `json:"created_at"`
-	UpdatedAt   string  `json:"updated_at"`
+	// ResearchMode は質問で Librarian に調べさせる深さ（quick / standard / deep）
+	ResearchMode string `json:"research_mode"`
+	CreatedAt    string `json:"created_at"`
+	UpdatedAt    string `json:"updated_at"`
 }
 
 func toSubjectResp(s *domain.Subject) subjectResponse {
 	return subjectResponse{
-		ID:          s.ID.String(),
-		Name:        s.Name,
-		LMSCourseID: s.LMSCourseID,
-		CreatedAt:   s.CreatedAt.Format(time.RFC3339),
-		UpdatedAt:   s.UpdatedAt.Format(time.RFC3339),
+		ID:           s.ID.String(),
+		Name:         s.Name,
+		LMSCourseID:  s.LMSCourseID,
+		ResearchMode: string(s.ResearchMode),
+		CreatedAt:    s.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:    s.UpdatedAt.Format(time.RFC3339),
 	}
 }
 
@@ -102,16 +105,18 @@ func (h *SubjectHandler) Get(c echo.Context) error {
 // @Router /api/v1/subjects [post]
 func (h *SubjectHandler) Create(c echo.Context) error {
 	var req struct {
-		Name        string  `json:"name"`
-		LMSCourseID *string `json:"lms_course_id"`
+		Name         string  `json:"name"`
+		LMSCourseID  *string `json:"lms_course_id"`
+		ResearchMode string  `json:"research_mode"` // 省略時は standard
 	}
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid request body"})
 	}
 	userID := httpmw.GetUserID(c)
 	s, err := h.uc.Create(c.Request().Context(), userID, usecases.CreateSubjectInput{
-		Name:        req.Name,
-		LMSCourseID: req.LMSCourseID,
+		Name:         req.Name,
+		LMSCourseID:  req.LMSCourseID,
+		ResearchMode: domain.ResearchMode(req.ResearchMode),
 	})
 	if err != nil {
 		return httpError(c, err)
@@ -120,7 +125,7 @@ func (h *SubjectHandler) Create(c echo.Context) error {
 }
 
 // Update godoc
-// @Summary 科目名更新
+// @Summary 科目名・調べる深さの更新
 // @Tags subjects
 // @Accept json
 // @Produce json
@@ -133,16 +138,28 @@ func (h *SubjectHandler) Update(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid subject id"})
 	}
 	var req struct {
-		Name string `json:"name"`
+		Name         string  `json:"name"`
+		ResearchMode *string `json:"research_mode"` // 省略時は変更しない
 	}
 	if err := c.Bind(&req); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid request body"})
 	}
+	var mode domain.ResearchMode
+	if req.ResearchMode != nil {
+		if mode, err = domain.ParseResearchMode(*req.ResearchMode); err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorBody{Error: "invalid research_mode"})
+		}
+	}
 	userID := httpmw.GetUserID(c)
 	s, err := h.uc.UpdateName(c.Request().Context(), id, userID, req.Name)
 	if err != nil {
 		return httpError(c, err)
 	}
+	if mode != "" {
+		if s, err = h.uc.UpdateResearchMode(c.Request().Context(), id, userID, mode); err != nil {
+			return httpError(c, err)
+		}
+	}
 	return c.JSON(http.StatusOK, toSubjectResp(s))
 }
 
@@ -0,0 +1,199 @@
+package llm
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+// EmbeddingCache は ports.LLMClient の GenerateEmbedding にキャッシュを付けるデコレーター。
+// メモリ上の LRU → 永続ストア（任意）→ 埋め込み API の順に引き、
+// 同じテキストの同時リクエストは 1 回の呼び出しにまとめる。
+// GenerateEmbedding 以外のメソッドはそのまま next に委譲する。
+type EmbeddingCache struct {
+	ports.LLMClient
+	store ports.EmbeddingCacheRepository // nil の場合は永続化しない
+	lru   *embeddingLRU
+	group singleflight.Group
+
+	requests    atomic.Int64
+	memoryHits  atomic.Int64
+	storeHits   atomic.Int64
+	coalesced   atomic.Int64
+	misses      atomic.Int64
+	storeErrors atomic.Int64
+}
+
+// EmbeddingCacheStats は EmbeddingCache の累計の統計（メトリクスとして公開する）
+type EmbeddingCacheStats struct {
+	Requests    int64   `json:"requests"`
+	MemoryHits  int64   `json:"memory_hits"`
+	StoreHits   int64   `json:"store_hits"`
+	Coalesced   int64   `json:"coalesced"`    // 実行中の同じリクエストの結果を共有した件数
+	Misses      int64   `json:"misses"`       // 埋め込み API を呼んだ件数
+	StoreErrors int64   `json:"store_errors"` // 永続ストアの読み書きの失敗件数
+	HitRate     float64 `json:"hit_rate"`     // 埋め込み API を呼ばずに済んだ割合
+}
+
+// NewEmbeddingCache は next の GenerateEmbedding にキャッシュを付けた EmbeddingCache を返す。
+// size は LRU に保持する埋め込みの件数（0 の場合はメモリに保持しない）。
+func NewEmbeddingCache(next ports.LLMClient, store ports.EmbeddingCacheRepository, size int) *EmbeddingCache {
+	return &EmbeddingCache{
+		LLMClient: next,
+		store:     store,
+		lru:       newEmbeddingLRU(size),
+	}
+}
+
+// embeddingCacheKey は埋め込みモデル名とテキストからキャッシュのキーを作る。
+func embeddingCacheKey(text string) string {
+	sum := sha256.Sum256([]byte(embeddingModel + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateEmbedding はキャッシュ済みの埋め込みを返し、無い場合は next で生成してキャッシュする。
+// 同時リクエストの呼び出しは先頭のリクエストのキャンセルに影響されないよう ctx から切り離して実行し、
+// 各リクエストは自分の ctx のキャンセルで待機をやめる。
+func (c *EmbeddingCache) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	c.requests.Add(1)
+	key := embeddingCacheKey(text)
+	if emb, ok := c.lru.get(key); ok {
+		c.memoryHits.Add(1)
+		return slices.Clone(emb), nil
+	}
+
+	var executed atomic.Bool
+	ch := c.group.DoChan(key, func() (any, error) {
+		executed.Store(true)
+		callCtx, cancel := detachContext(ctx)
+		defer cancel()
+		return c.load(callCtx, key, text)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if !executed.Load() {
+			c.coalesced.Add(1)
+		}
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return slices.Clone(res.Val.([]float32)), nil
+	}
+}
+
+// load は永続ストア → 埋め込み API の順に埋め込みを取得し、LRU（と永続ストア）に保存する。
+func (c *EmbeddingCache) load(ctx context.Context, key, text string) ([]float32, error) {
+	if c.store != nil {
+		emb, err := c.store.Get(ctx, key)
+		switch {
+		case err == nil:
+			c.storeHits.Add(1)
+			c.lru.add(key, emb)
+			return emb, nil
+		case !errors.Is(err, domain.ErrNotFound):
+			c.storeErrors.Add(1)
+			slog.Warn("get cached embedding failed", "error", err)
+		}
+	}
+
+	c.misses.Add(1)
+	emb, err := c.LLMClient.GenerateEmbedding(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	c.lru.add(key, emb)
+	if c.store != nil {
+		if err := c.store.Put(ctx, key, embeddingModel, emb); err != nil {
+			c.storeErrors.Add(1)
+			slog.Warn("put cached embedding failed", "error", err)
+		}
+	}
+	return emb, nil
+}
+
+// Stats は累計の統計を返す。
+func (c *EmbeddingCache) Stats() EmbeddingCacheStats {
+	s := EmbeddingCacheStats{
+		Requests:    c.requests.Load(),
+		MemoryHits:  c.memoryHits.Load(),
+		StoreHits:   c.storeHits.Load(),
+		Coalesced:   c.coalesced.Load(),
+		Misses:      c.misses.Load(),
+		StoreErrors: c.storeErrors.Load(),
+	}
+	if s.Requests > 0 {
+		s.HitRate = float64(s.MemoryHits+s.StoreHits+s.Coalesced) / float64(s.Requests)
+	}
+	return s
+}
+
+// detachContext は ctx のキャンセルを引き継がず、期限だけを引き継いだコンテキストを返す。
+func detachContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(detached, deadline)
+	}
+	return context.WithCancel(detached)
+}
+
+// ─── LRU ─────────────────────────────────────────────────────────
+
+// embeddingLRU は件数上限付きの LRU キャッシュ（size が 0 の場合は何も保持しない）
+type embeddingLRU struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // 先頭が最近使ったエントリ
+	items map[string]*list.Element
+}
+
+type embeddingLRUEntry struct {
+	key string
+	emb []float32
+}
+
+func newEmbeddingLRU(size int) *embeddingLRU {
+	return &embeddingLRU{size: size, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (l *embeddingLRU) get(key string) ([]float32, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	l.order.MoveToFront(el)
+	return el.Value.(*embeddingLRUEntry).emb, true
+}
+
+func (l *embeddingLRU) add(key string, emb []float32) {
+	if l.size <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if el, ok := l.items[key]; ok {
+		el.Value.(*embeddingLRUEntry).emb = emb
+		l.order.MoveToFront(el)
+		return
+	}
+	l.items[key] = l.order.PushFront(&embeddingLRUEntry{key: key, emb: emb})
+	for l.order.Len() > l.size {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.items, oldest.Value.(*embeddingLRUEntry).key)
+	}
+}
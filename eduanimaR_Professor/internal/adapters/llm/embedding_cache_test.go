@@ -0,0 +1,169 @@
+package llm_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/llm"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/testhelper"
+)
+
+// blockingEmbedder は release が閉じられるまで GenerateEmbedding を待たせる LLMClient
+type blockingEmbedder struct {
+	ports.LLMClient
+	calls   atomic.Int64
+	release chan struct{}
+}
+
+func (b *blockingEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	b.calls.Add(1)
+	select {
+	case <-b.release:
+		return []float32{float32(len(text)), 1}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestEmbeddingCache_MemoryHit(t *testing.T) {
+	ctx := context.Background()
+	next := &testhelper.MockLLMClient{}
+	next.On("GenerateEmbedding", mock.Anything, "定義").Return([]float32{1, 2}, nil).Once()
+
+	cache := llm.NewEmbeddingCache(next, nil, 10)
+	for range 3 {
+		emb, err := cache.GenerateEmbedding(ctx, "定義")
+		require.NoError(t, err)
+		assert.Equal(t, []float32{1, 2}, emb)
+	}
+
+	next.AssertExpectations(t)
+	stats := cache.Stats()
+	assert.Equal(t, int64(3), stats.Requests)
+	assert.Equal(t, int64(2), stats.MemoryHits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.InDelta(t, 2.0/3.0, stats.HitRate, 1e-9)
+}
+
+func TestEmbeddingCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	next := &testhelper.MockLLMClient{}
+	next.On("GenerateEmbedding", mock.Anything, "a").Return([]float32{1}, nil).Twice()
+	next.On("GenerateEmbedding", mock.Anything, "b").Return([]float32{2}, nil).Once()
+
+	cache := llm.NewEmbeddingCache(next, nil, 1)
+	for _, q := range []string{"a", "b", "a"} {
+		_, err := cache.GenerateEmbedding(ctx, q)
+		require.NoError(t, err)
+	}
+
+	next.AssertExpectations(t)
+	assert.Equal(t, int64(3), cache.Stats().Misses)
+}
+
+func TestEmbeddingCache_StoreHit(t *testing.T) {
+	ctx := context.Background()
+	next := &testhelper.MockLLMClient{}
+	store := &testhelper.MockEmbeddingCacheRepository{}
+	store.On("Get", mock.Anything, mock.AnythingOfType("string")).Return([]float32{3, 4}, nil).Once()
+
+	cache := llm.NewEmbeddingCache(next, store, 10)
+	emb, err := cache.GenerateEmbedding(ctx, "定義")
+	require.NoError(t, err)
+	assert.Equal(t, []float32{3, 4}, emb)
+	// 2 回目はメモリから返す
+	_, err = cache.GenerateEmbedding(ctx, "定義")
+	require.NoError(t, err)
+
+	next.AssertNotCalled(t, "GenerateEmbedding", mock.Anything, mock.Anything)
+	store.AssertExpectations(t)
+	stats := cache.Stats()
+	assert.Equal(t, int64(1), stats.StoreHits)
+	assert.Equal(t, int64(1), stats.MemoryHits)
+	assert.Equal(t, int64(0), stats.Misses)
+}
+
+func TestEmbeddingCache_StoreMissSavesEmbedding(t *testing.T) {
+	ctx := context.Background()
+	next := &testhelper.MockLLMClient{}
+	next.On("GenerateEmbedding", mock.Anything, "定義").Return([]float32{1, 2}, nil).Once()
+	store := &testhelper.MockEmbeddingCacheRepository{}
+	var key string
+	store.On("Get", mock.Anything, mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) { key = args.String(1) }).
+		Return(nil, domain.ErrNotFound).Once()
+	store.On("Put", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("string"), []float32{1, 2}).Return(nil).Once()
+
+	cache := llm.NewEmbeddingCache(next, store, 10)
+	emb, err := cache.GenerateEmbedding(ctx, "定義")
+	require.NoError(t, err)
+	assert.Equal(t, []float32{1, 2}, emb)
+
+	next.AssertExpectations(t)
+	store.AssertExpectations(t)
+	store.AssertCalled(t, "Put", mock.Anything, key, mock.Anything, mock.Anything)
+	assert.Equal(t, int64(0), cache.Stats().StoreErrors)
+}
+
+func TestEmbeddingCache_CoalescesConcurrentRequests(t *testing.T) {
+	ctx := context.Background()
+	next := &blockingEmbedder{release: make(chan struct{})}
+	cache := llm.NewEmbeddingCache(next, nil, 10)
+
+	const callers = 5
+	var wg sync.WaitGroup
+	results := make([][]float32, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			emb, err := cache.GenerateEmbedding(ctx, "定義")
+			assert.NoError(t, err)
+			results[i] = emb
+		}()
+	}
+	require.Eventually(t, func() bool { return cache.Stats().Requests == callers }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond) // 全員が実行中の呼び出しを待つまで待つ
+	close(next.release)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), next.calls.Load())
+	for _, emb := range results {
+		assert.Equal(t, []float32{6, 1}, emb) // len("定義") はバイト数
+	}
+	stats := cache.Stats()
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, int64(callers-1), stats.Coalesced)
+}
+
+func TestEmbeddingCache_CanceledWaiterDoesNotCancelSharedCall(t *testing.T) {
+	next := &blockingEmbedder{release: make(chan struct{})}
+	cache := llm.NewEmbeddingCache(next, nil, 10)
+
+	// 先頭のリクエストがキャンセルされても、埋め込みの生成は続けてキャッシュする
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := cache.GenerateEmbedding(ctx, "定義")
+		done <- err
+	}()
+	require.Eventually(t, func() bool { return next.calls.Load() == 1 }, time.Second, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	close(next.release)
+	require.Eventually(t, func() bool {
+		emb, err := cache.GenerateEmbedding(context.Background(), "定義")
+		return err == nil && len(emb) == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int64(1), next.calls.Load())
+}
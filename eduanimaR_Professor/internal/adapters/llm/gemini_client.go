@@ -20,6 +20,12 @@ const (
 	generationModel = "gemini-2.0-flash-lite"
 )
 
+// ModelNames は回答生成・埋め込みに使うモデル名を返す（質問セッションの推論の記録用）。
+// Reranker・QueryExpander も生成モデルを使う。
+func ModelNames() (generation, embedding string) {
+	return generationModel, embeddingModel
+}
+
 // geminiClient は ports.LLMClient の Gemini API 実装。
 type geminiClient struct {
 	client *genai.Client
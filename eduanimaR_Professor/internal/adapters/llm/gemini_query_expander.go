@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+// geminiQueryExpander は ports.QueryExpander の Gemini API 実装。
+type geminiQueryExpander struct {
+	client *genai.Client
+}
+
+// NewGeminiQueryExpander は Gemini API を使う ports.QueryExpander を返す。
+// ctx はクライアントのライフタイム用コンテキスト（通常は main の ctx）。
+func NewGeminiQueryExpander(ctx context.Context, apiKey string) (ports.QueryExpander, error) {
+	c, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	if err != nil {
+		return nil, fmt.Errorf("gemini: new client: %w", err)
+	}
+	return &geminiQueryExpander{client: c}, nil
+}
+
+// queryExpansionResponse は書き換え・拡張結果の JSON 表現
+type queryExpansionResponse struct {
+	Paraphrases        []string `json:"paraphrases"`
+	HypotheticalAnswer string   `json:"hypothetical_answer"`
+	Translations       []string `json:"translations"`
+}
+
+// ExpandQuery は query の言い換え・仮想的な回答文（HyDE）・日本語/英語の訳を生成する。
+func (g *geminiQueryExpander) ExpandQuery(ctx context.Context, query string) (*domain.QueryExpansion, error) {
+	model := g.client.GenerativeModel(generationModel)
+	model.ResponseMIMEType = "application/json"
+	model.ResponseSchema = &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"paraphrases":         {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+			"hypothetical_answer": {Type: genai.TypeString},
+			"translations":        {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+		},
+		Required: []string{"paraphrases", "hypothetical_answer", "translations"},
+	}
+
+	resp, err := model.GenerateContent(ctx, genai.Text(buildQueryExpansionPrompt(query)))
+	if err != nil {
+		return nil, fmt.Errorf("gemini: expand query: %w", err)
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return nil, fmt.Errorf("gemini: expand query: empty response")
+	}
+
+	var sb strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if t, ok := part.(genai.Text); ok {
+			sb.WriteString(string(t))
+		}
+	}
+	var out queryExpansionResponse
+	if err := json.Unmarshal([]byte(sb.String()), &out); err != nil {
+		return nil, fmt.Errorf("gemini: expand query: decode: %w", err)
+	}
+
+	return &domain.QueryExpansion{
+		Query:              query,
+		Paraphrases:        trimNonEmpty(out.Paraphrases),
+		HypotheticalAnswer: strings.TrimSpace(out.HypotheticalAnswer),
+		Translations:       trimNonEmpty(out.Translations),
+	}, nil
+}
+
+// buildQueryExpansionPrompt は query から書き換え・拡張用のプロンプトを構築する。
+func buildQueryExpansionPrompt(query string) string {
+	var sb strings.Builder
+
+	sb.WriteString("You help a search engine find passages in university course materials (lecture slides, textbooks, exercises).\n")
+	sb.WriteString("Rewrite the search query below so that semantically similar passages can be found.\n\n")
+	sb.WriteString("## Search Query\n\n")
+	sb.WriteString(query)
+	sb.WriteString("\n\n## Instructions\n")
+	sb.WriteString("- paraphrases: up to 3 alternative phrasings, spelling out abbreviations and symbols (e.g. \"R²\" → \"coefficient of determination\")\n")
+	sb.WriteString("- hypothetical_answer: a short passage (2-4 sentences) as it might appear in a textbook that answers or defines the query\n")
+	sb.WriteString("- translations: the query in Japanese and in English (omit the language the query is already written in)\n")
+	sb.WriteString("- Do NOT add facts unrelated to the query\n")
+
+	return sb.String()
+}
+
+// trimNonEmpty は前後の空白を除き、空の要素を取り除く。
+func trimNonEmpty(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
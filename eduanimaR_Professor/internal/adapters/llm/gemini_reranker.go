@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+// rerankMaxScore は LLM に付けさせる関連度の最大値（0〜rerankMaxScore の整数）
+const rerankMaxScore = 10
+
+// geminiReranker は ports.Reranker の Gemini API 実装。
+// 質問とチャンクの組をまとめて 1 回のプロンプトで採点させる。
+type geminiReranker struct {
+	client *genai.Client
+}
+
+// NewGeminiReranker は Gemini API を使う ports.Reranker を返す。
+// ctx はクライアントのライフタイム用コンテキスト（通常は main の ctx）。
+func NewGeminiReranker(ctx context.Context, apiKey string) (ports.Reranker, error) {
+	c, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	if err != nil {
+		return nil, fmt.Errorf("gemini: new client: %w", err)
+	}
+	return &geminiReranker{client: c}, nil
+}
+
+// rerankResponse は採点結果の JSON 表現
+type rerankResponse struct {
+	Scores []float64 `json:"scores"`
+}
+
+// Rerank は documents の各要素と query の関連度を 0〜1 で返す。
+func (g *geminiReranker) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	if len(documents) == 0 {
+		return nil, nil
+	}
+	model := g.client.GenerativeModel(generationModel)
+	model.ResponseMIMEType = "application/json"
+	model.ResponseSchema = &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"scores": {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeNumber}},
+		},
+		Required: []string{"scores"},
+	}
+	var temperature float32
+	model.Temperature = &temperature
+
+	resp, err := model.GenerateContent(ctx, genai.Text(buildRerankPrompt(query, documents)))
+	if err != nil {
+		return nil, fmt.Errorf("gemini: rerank: %w", err)
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return nil, fmt.Errorf("gemini: rerank: empty response")
+	}
+
+	var sb strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if t, ok := part.(genai.Text); ok {
+			sb.WriteString(string(t))
+		}
+	}
+	var out rerankResponse
+	if err := json.Unmarshal([]byte(sb.String()), &out); err != nil {
+		return nil, fmt.Errorf("gemini: rerank: decode scores: %w", err)
+	}
+	if len(out.Scores) != len(documents) {
+		return nil, fmt.Errorf("gemini: rerank: got %d scores for %d documents", len(out.Scores), len(documents))
+	}
+
+	scores := make([]float64, len(out.Scores))
+	for i, s := range out.Scores {
+		scores[i] = min(max(s/rerankMaxScore, 0), 1)
+	}
+	return scores, nil
+}
+
+// buildRerankPrompt は query と documents から採点用のプロンプトを構築する。
+func buildRerankPrompt(query string, documents []string) string {
+	var sb strings.Builder
+
+	sb.WriteString("You are a relevance judge for course materials. ")
+	sb.WriteString("Rate how useful each passage is for answering the student's question.\n\n")
+	sb.WriteString("## Student Question\n\n")
+	sb.WriteString(query)
+	sb.WriteString("\n\n## Passages\n\n")
+
+	for i, doc := range documents {
+		fmt.Fprintf(&sb, "### Passage %d\n%s\n\n", i+1, doc)
+	}
+
+	sb.WriteString("## Instructions\n")
+	fmt.Fprintf(&sb, "- Give each passage an integer score from 0 (irrelevant) to %d (directly answers the question)\n", rerankMaxScore)
+	sb.WriteString("- Judge each passage on its own content, not on its position\n")
+	fmt.Fprintf(&sb, "- Return JSON {\"scores\": [...]} with exactly %d scores in passage order\n", len(documents))
+
+	return sb.String()
+}
@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	pgvector "github.com/pgvector/pgvector-go"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/postgres/sqlcgen"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+type answerCacheRepo struct {
+	db *sql.DB
+	q  *sqlcgen.Queries
+}
+
+// NewAnswerCacheRepo は ports.AnswerCacheRepository の postgres 実装を返す。
+func NewAnswerCacheRepo(db *sql.DB) ports.AnswerCacheRepository {
+	return &answerCacheRepo{db: db, q: sqlcgen.New(db)}
+}
+
+// MaterialVersion はチャンク数と最終更新日時（マイクロ秒）を教材のバージョンとして返す。
+func (r *answerCacheRepo) MaterialVersion(ctx context.Context, subjectIDs []uuid.UUID) (string, error) {
+	row, err := r.q.GetMaterialVersion(ctx, subjectIDs)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d", row.ChunkCount, row.LastChangedAt.UnixMicro()), nil
+}
+
+func (r *answerCacheRepo) FindSimilar(ctx context.Context, key domain.AnswerCacheKey, embedding pgvector.Vector, createdAfter time.Time) (*domain.CachedAnswer, error) {
+	row, err := r.q.FindSimilarCachedAnswer(ctx, sqlcgen.FindSimilarCachedAnswerParams{
+		QuestionEmbedding: embedding,
+		SubjectID:         key.SubjectID,
+		ExtraSubjectIds:   extraSubjectIDs(key.ExtraSubjectIDs),
+		MaterialVersion:   key.MaterialVersion,
+		CreatedAfter:      createdAfter,
+	})
+	if err != nil {
+		return nil, mapDBError(err)
+	}
+	var sources []domain.Source
+	if err := json.Unmarshal(row.Sources, &sources); err != nil {
+		return nil, fmt.Errorf("unmarshal cached sources: %w", err)
+	}
+	entry := &domain.CachedAnswer{
+		ID:         row.CacheID,
+		Key:        domain.AnswerCacheKey{SubjectID: row.SubjectID, ExtraSubjectIDs: row.ExtraSubjectIds, MaterialVersion: row.MaterialVersion},
+		Question:   row.Question,
+		Answer:     row.Answer,
+		Sources:    sources,
+		HitCount:   int(row.HitCount),
+		CreatedAt:  row.CreatedAt,
+		Similarity: row.Similarity,
+	}
+	if row.SessionID.Valid {
+		entry.SessionID = &row.SessionID.UUID
+	}
+	return entry, nil
+}
+
+func (r *answerCacheRepo) Save(ctx context.Context, entry *domain.CachedAnswer, createdAfter time.Time) error {
+	sources, err := json.Marshal(entry.Sources)
+	if err != nil {
+		return fmt.Errorf("marshal sources: %w", err)
+	}
+	extra := extraSubjectIDs(entry.Key.ExtraSubjectIDs)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Commit 済みの場合は何もしない
+	q := r.q.WithTx(tx)
+
+	if err := q.DeleteStaleCachedAnswers(ctx, sqlcgen.DeleteStaleCachedAnswersParams{
+		SubjectID:       entry.Key.SubjectID,
+		ExtraSubjectIds: extra,
+		MaterialVersion: entry.Key.MaterialVersion,
+		CreatedAfter:    createdAfter,
+	}); err != nil {
+		return fmt.Errorf("delete stale cached answers: %w", err)
+	}
+	params := sqlcgen.InsertCachedAnswerParams{
+		CacheID:           entry.ID,
+		SubjectID:         entry.Key.SubjectID,
+		ExtraSubjectIds:   extra,
+		MaterialVersion:   entry.Key.MaterialVersion,
+		Question:          entry.Question,
+		QuestionEmbedding: entry.QuestionEmbedding,
+		Answer:            entry.Answer,
+		Sources:           sources,
+	}
+	if entry.SessionID != nil {
+		params.SessionID = uuid.NullUUID{UUID: *entry.SessionID, Valid: true}
+	}
+	if err := q.InsertCachedAnswer(ctx, params); err != nil {
+		return fmt.Errorf("insert cached answer: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (r *answerCacheRepo) RecordHit(ctx context.Context, id uuid.UUID) error {
+	return r.q.RecordCachedAnswerHit(ctx, id)
+}
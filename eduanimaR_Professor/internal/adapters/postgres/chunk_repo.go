@@ -3,6 +3,10 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
 
 	"github.com/google/uuid"
 	pgvector "github.com/pgvector/pgvector-go"
@@ -13,12 +17,33 @@ import (
 )
 
 type chunkRepo struct {
-	q *sqlcgen.Queries
+	db     *sql.DB
+	q      *sqlcgen.Queries
+	vector VectorSearchOptions
+}
+
+// pgvector の hnsw.iterative_scan の値
+const (
+	IterativeScanOff          = "off"
+	IterativeScanStrictOrder  = "strict_order"
+	IterativeScanRelaxedOrder = "relaxed_order"
+)
+
+// VectorSearchOptions は HNSW インデックスによるベクトル検索の設定。
+// HNSW はインデックスの走査後に subject_id で絞り込むため、大きなテーブルの中でチャンクの少ない subject は
+// limit 件に満たない・一致度の低い結果になりやすい。反復スキャンと小さな subject の厳密検索でこれを補う。
+type VectorSearchOptions struct {
+	EfSearch int // hnsw.ef_search（0 は pgvector のデフォルト。limit より小さい場合は limit を使う）
+	// IterativeScan は絞り込みで件数が足りない場合にインデックスの走査を続ける方式（空は設定しない）
+	IterativeScan string
+	// ExactSearchMaxChunks は検索対象の subject のチャンク数がこれ以下の場合に
+	// HNSW インデックスを使わず全件の距離で並べる（0 は常に HNSW を使う）
+	ExactSearchMaxChunks int
 }
 
 // NewChunkRepo は ports.ChunkRepository の postgres 実装を返す。
-func NewChunkRepo(db *sql.DB) ports.ChunkRepository {
-	return &chunkRepo{q: sqlcgen.New(db)}
+func NewChunkRepo(db *sql.DB, vector VectorSearchOptions) ports.ChunkRepository {
+	return &chunkRepo{db: db, q: sqlcgen.New(db), vector: vector}
 }
 
 func (r *chunkRepo) ListByFileID(ctx context.Context, fileID uuid.UUID) ([]*domain.Chunk, error) {
@@ -33,20 +58,145 @@ func (r *chunkRepo) ListByFileID(ctx context.Context, fileID uuid.UUID) ([]*doma
 	return result, nil
 }
 
+// ListPageByFileID はチャンク閲覧用に chunk_index 順でページング取得する（Embedding は含まない）。
+func (r *chunkRepo) ListPageByFileID(ctx context.Context, fileID uuid.UUID, limit, offset int) ([]*domain.Chunk, error) {
+	rows, err := r.q.ListChunkPageByFileID(ctx, sqlcgen.ListChunkPageByFileIDParams{
+		FileID: fileID,
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*domain.Chunk, len(rows))
+	for i, row := range rows {
+		c := &domain.Chunk{
+			ID:             row.ChunkID,
+			FileID:         row.FileID,
+			SubjectID:      row.SubjectID,
+			ChunkIndex:     int(row.ChunkIndex),
+			Content:        row.Content,
+			CreatedAt:      row.CreatedAt,
+			ManuallyEdited: row.ManuallyEdited,
+		}
+		if row.PageNumber.Valid {
+			v := int(row.PageNumber.Int32)
+			c.PageNumber = &v
+		}
+		if row.UpdatedAt.Valid {
+			c.UpdatedAt = &row.UpdatedAt.Time
+		}
+		result[i] = c
+	}
+	return result, nil
+}
+
+// ListNeighbours はエビデンスの前後文脈として、chunk_index が indexFrom〜indexTo の範囲、
+// または page が一致するチャンクを chunk_index 順に返す（Embedding は含まない）。
+func (r *chunkRepo) ListNeighbours(ctx context.Context, fileID uuid.UUID, indexFrom, indexTo int, page *int) ([]*domain.Chunk, error) {
+	rows, err := r.q.ListNeighbourChunks(ctx, sqlcgen.ListNeighbourChunksParams{
+		FileID:     fileID,
+		IndexFrom:  int32(indexFrom),
+		IndexTo:    int32(indexTo),
+		PageNumber: toNullInt32(page),
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*domain.Chunk, len(rows))
+	for i, row := range rows {
+		c := &domain.Chunk{
+			ID:             row.ChunkID,
+			FileID:         row.FileID,
+			SubjectID:      row.SubjectID,
+			ChunkIndex:     int(row.ChunkIndex),
+			Content:        row.Content,
+			CreatedAt:      row.CreatedAt,
+			ManuallyEdited: row.ManuallyEdited,
+		}
+		if row.PageNumber.Valid {
+			v := int(row.PageNumber.Int32)
+			c.PageNumber = &v
+		}
+		if row.UpdatedAt.Valid {
+			c.UpdatedAt = &row.UpdatedAt.Time
+		}
+		result[i] = c
+	}
+	return result, nil
+}
+
+// GetEmbeddings は指定チャンクの Embedding を chunk_id ごとに返す（存在しないチャンクは含まない）。
+func (r *chunkRepo) GetEmbeddings(ctx context.Context, chunkIDs []uuid.UUID) (map[uuid.UUID]pgvector.Vector, error) {
+	result := make(map[uuid.UUID]pgvector.Vector, len(chunkIDs))
+	if len(chunkIDs) == 0 {
+		return result, nil
+	}
+	rows, err := r.q.ListChunkEmbeddings(ctx, chunkIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		result[row.ChunkID] = row.Embedding
+	}
+	return result, nil
+}
+
+func (r *chunkRepo) CountByFileID(ctx context.Context, fileID uuid.UUID) (int64, error) {
+	return r.q.CountChunksByFileID(ctx, fileID)
+}
+
+func (r *chunkRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Chunk, error) {
+	row, err := r.q.GetChunkByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return sqlcChunkToDomainChunk(row), nil
+}
+
 func (r *chunkRepo) BatchCreate(ctx context.Context, chunks []*domain.Chunk) error {
+	return insertChunks(ctx, r.q, chunks)
+}
+
+// ReplaceByFileID は教材の既存チャンクの削除と chunks の保存を 1 トランザクションで行う。
+// 途中で失敗した場合は既存チャンクがそのまま残る。
+func (r *chunkRepo) ReplaceByFileID(ctx context.Context, fileID uuid.UUID, chunks []*domain.Chunk) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Commit 済みの場合は何もしない
+	q := r.q.WithTx(tx)
+
+	if err := q.DeleteChunksByFileID(ctx, fileID); err != nil {
+		return fmt.Errorf("delete chunks: %w", err)
+	}
+	if err := insertChunks(ctx, q, chunks); err != nil {
+		return fmt.Errorf("insert chunks: %w", err)
+	}
+	return tx.Commit()
+}
+
+// insertChunks はチャンクを 1 件ずつ保存する（q がトランザクションの場合はその中で保存する）。
+func insertChunks(ctx context.Context, q *sqlcgen.Queries, chunks []*domain.Chunk) error {
 	for _, c := range chunks {
-		var pageNum sql.NullInt32
-		if c.PageNumber != nil {
-			pageNum = sql.NullInt32{Int32: int32(*c.PageNumber), Valid: true}
+		var updatedAt sql.NullTime
+		if c.UpdatedAt != nil {
+			updatedAt = sql.NullTime{Time: *c.UpdatedAt, Valid: true}
 		}
-		_, err := r.q.InsertChunk(ctx, sqlcgen.InsertChunkParams{
-			ChunkID:    c.ID,
-			FileID:     c.FileID,
-			SubjectID:  c.SubjectID,
-			PageNumber: pageNum,
-			ChunkIndex: int32(c.ChunkIndex),
-			Content:    c.Content,
-			Embedding:  c.Embedding,
+		_, err := q.InsertChunk(ctx, sqlcgen.InsertChunkParams{
+			ChunkID:        c.ID,
+			FileID:         c.FileID,
+			SubjectID:      c.SubjectID,
+			PageNumber:     toNullInt32(c.PageNumber),
+			ChunkIndex:     int32(c.ChunkIndex),
+			Content:        c.Content,
+			Embedding:      c.Embedding,
+			ManuallyEdited: c.ManuallyEdited,
+			UpdatedAt:      updatedAt,
 		})
 		if err != nil {
 			return err
@@ -56,16 +206,39 @@ func (r *chunkRepo) BatchCreate(ctx context.Context, chunks []*domain.Chunk) err
 }
 
 // SearchByVector は pgvector HNSW コサイン類似度検索を実行する。
-// Note: sqlcgen の SearchChunksByVectorParams.Column1 は `$1::vector` に対応する。
-func (r *chunkRepo) SearchByVector(ctx context.Context, subjectID uuid.UUID, embedding pgvector.Vector, limit int) ([]*domain.SearchResult, error) {
-	rows, err := r.q.SearchChunksByVector(ctx, sqlcgen.SearchChunksByVectorParams{
-		Column1:   embedding,
-		SubjectID: subjectID,
-		Limit:     int32(limit),
+func (r *chunkRepo) SearchByVector(ctx context.Context, subjectIDs []uuid.UUID, embedding pgvector.Vector, limit int, filter domain.SearchFilter) ([]*domain.SearchResult, error) {
+	// 検索パラメータはトランザクション内に限って設定する（コネクションプールの他のクエリに影響させない）
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // Commit 済みの場合は何もしない
+	q := r.q.WithTx(tx)
+
+	exact, err := r.useExactVectorSearch(ctx, q, subjectIDs)
+	if err != nil {
+		return nil, fmt.Errorf("count chunks: %w", err)
+	}
+	for name, value := range r.vectorSearchConfig(exact, limit) {
+		if err := q.SetLocalSearchConfig(ctx, sqlcgen.SetLocalSearchConfigParams{Name: name, Value: value}); err != nil {
+			return nil, fmt.Errorf("set %s: %w", name, err)
+		}
+	}
+
+	rows, err := q.SearchChunksByVector(ctx, sqlcgen.SearchChunksByVectorParams{
+		QueryEmbedding: embedding,
+		SubjectIds:     subjectIDs,
+		FileIds:        searchFileIDs(filter),
+		PageFrom:       toNullInt32(filter.PageFrom),
+		PageTo:         toNullInt32(filter.PageTo),
+		MaxResults:     int32(limit),
 	})
 	if err != nil {
 		return nil, err
 	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	result := make([]*domain.SearchResult, len(rows))
 	for i, row := range rows {
 		result[i] = sqlcVectorRowToSearchResult(row)
@@ -73,12 +246,47 @@ func (r *chunkRepo) SearchByVector(ctx context.Context, subjectID uuid.UUID, emb
 	return result, nil
 }
 
+// useExactVectorSearch は検索対象の subject のチャンク数が少なく、厳密検索する場合に true を返す。
+func (r *chunkRepo) useExactVectorSearch(ctx context.Context, q *sqlcgen.Queries, subjectIDs []uuid.UUID) (bool, error) {
+	if r.vector.ExactSearchMaxChunks <= 0 {
+		return false, nil
+	}
+	n, err := q.CountChunksBySubjectIDs(ctx, sqlcgen.CountChunksBySubjectIDsParams{
+		SubjectIds: subjectIDs,
+		MaxCount:   int32(r.vector.ExactSearchMaxChunks),
+	})
+	if err != nil {
+		return false, err
+	}
+	return n <= int64(r.vector.ExactSearchMaxChunks), nil
+}
+
+// vectorSearchConfig はベクトル検索のトランザクションに設定するパラメータを返す。
+// 厳密検索ではインデックススキャンを無効にし、subject_id で絞り込んだチャンクを距離で並べる。
+func (r *chunkRepo) vectorSearchConfig(exact bool, limit int) map[string]string {
+	if exact {
+		return map[string]string{"enable_indexscan": "off"}
+	}
+	cfg := make(map[string]string)
+	if r.vector.EfSearch > 0 {
+		// ef_search より多くは返らないため、limit 以上にする
+		cfg["hnsw.ef_search"] = strconv.Itoa(max(r.vector.EfSearch, limit))
+	}
+	if r.vector.IterativeScan != "" {
+		cfg["hnsw.iterative_scan"] = r.vector.IterativeScan
+	}
+	return cfg
+}
+
 // SearchByText は PostgreSQL 全文検索（plainto_tsquery）を実行する。
-func (r *chunkRepo) SearchByText(ctx context.Context, subjectID uuid.UUID, query string, limit int) ([]*domain.SearchResult, error) {
+func (r *chunkRepo) SearchByText(ctx context.Context, subjectIDs []uuid.UUID, query string, limit int, filter domain.SearchFilter) ([]*domain.SearchResult, error) {
 	rows, err := r.q.SearchChunksByText(ctx, sqlcgen.SearchChunksByTextParams{
-		PlaintoTsquery: query,
-		SubjectID:      subjectID,
-		Limit:          int32(limit),
+		QueryText:  query,
+		SubjectIds: subjectIDs,
+		FileIds:    searchFileIDs(filter),
+		PageFrom:   toNullInt32(filter.PageFrom),
+		PageTo:     toNullInt32(filter.PageTo),
+		MaxResults: int32(limit),
 	})
 	if err != nil {
 		return nil, err
@@ -94,33 +302,177 @@ func (r *chunkRepo) DeleteByFileID(ctx context.Context, fileID uuid.UUID) error
 	return r.q.DeleteChunksByFileID(ctx, fileID)
 }
 
+// ApplyEdit は手動修正を 1 トランザクションで適用する。
+// 教材のチャンクを行ロックして edit.Before と一致することを確かめてから、
+// 削除 → chunk_index のシフト → 作成/更新 の順に実行し、最後に修正履歴を記録する。
+func (r *chunkRepo) ApplyEdit(ctx context.Context, edit *domain.ChunkEdit, changes ports.ChunkChanges) error {
+	before, err := json.Marshal(edit.Before)
+	if err != nil {
+		return fmt.Errorf("marshal before chunks: %w", err)
+	}
+	after, err := json.Marshal(edit.After)
+	if err != nil {
+		return fmt.Errorf("marshal after chunks: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Commit 済みの場合は何もしない
+	q := r.q.WithTx(tx)
+
+	// 修正前のチャンクはトランザクションの外で読んでいるため、並行する修正で変わっていないかを確かめる
+	locked, err := q.LockChunksByFileID(ctx, edit.FileID)
+	if err != nil {
+		return fmt.Errorf("lock chunks: %w", err)
+	}
+	if err := checkEditBefore(locked, edit.Before); err != nil {
+		return err
+	}
+
+	for _, id := range changes.Delete {
+		if err := q.DeleteChunkByID(ctx, id); err != nil {
+			return fmt.Errorf("delete chunk %s: %w", id, err)
+		}
+	}
+	if changes.ShiftBy != 0 {
+		if err := q.ShiftChunkIndexes(ctx, sqlcgen.ShiftChunkIndexesParams{
+			FileID:    edit.FileID,
+			FromIndex: int32(changes.ShiftFrom),
+			Delta:     int32(changes.ShiftBy),
+		}); err != nil {
+			return fmt.Errorf("shift chunk indexes: %w", err)
+		}
+	}
+	for _, c := range changes.Save {
+		if err := q.UpsertEditedChunk(ctx, sqlcgen.UpsertEditedChunkParams{
+			ChunkID:    c.ID,
+			FileID:     c.FileID,
+			SubjectID:  c.SubjectID,
+			PageNumber: toNullInt32(c.PageNumber),
+			ChunkIndex: int32(c.ChunkIndex),
+			Content:    c.Content,
+			Embedding:  c.Embedding,
+		}); err != nil {
+			return fmt.Errorf("save chunk %s: %w", c.ID, err)
+		}
+	}
+	if err := q.InsertChunkEdit(ctx, sqlcgen.InsertChunkEditParams{
+		EditID:       edit.ID,
+		FileID:       edit.FileID,
+		UserID:       edit.UserID,
+		Operation:    string(edit.Operation),
+		BeforeChunks: before,
+		AfterChunks:  after,
+		CreatedAt:    edit.CreatedAt,
+	}); err != nil {
+		return fmt.Errorf("insert chunk edit: %w", err)
+	}
+	return tx.Commit()
+}
+
+// checkEditBefore はロックしたチャンクが修正前のスナップショットと一致するかを検証する。
+// 削除済み・連番や内容が変わったチャンクがある場合は domain.ErrConflict を返す。
+func checkEditBefore(locked []sqlcgen.LockChunksByFileIDRow, before []domain.ChunkSnapshot) error {
+	current := make(map[uuid.UUID]sqlcgen.LockChunksByFileIDRow, len(locked))
+	for _, row := range locked {
+		current[row.ChunkID] = row
+	}
+	for _, b := range before {
+		row, ok := current[b.ChunkID]
+		if !ok || int(row.ChunkIndex) != b.ChunkIndex || row.Content != b.Content {
+			return fmt.Errorf("chunk %s was modified by another edit: %w", b.ChunkID, domain.ErrConflict)
+		}
+	}
+	return nil
+}
+
+func (r *chunkRepo) ListEdits(ctx context.Context, fileID uuid.UUID, limit, offset int) ([]*domain.ChunkEdit, error) {
+	rows, err := r.q.ListChunkEditsByFileID(ctx, sqlcgen.ListChunkEditsByFileIDParams{
+		FileID: fileID,
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*domain.ChunkEdit, len(rows))
+	for i, row := range rows {
+		e := &domain.ChunkEdit{
+			ID:        row.EditID,
+			FileID:    row.FileID,
+			UserID:    row.UserID,
+			Operation: domain.ChunkEditOperation(row.Operation),
+			CreatedAt: row.CreatedAt,
+		}
+		if err := json.Unmarshal(row.BeforeChunks, &e.Before); err != nil {
+			return nil, fmt.Errorf("unmarshal before chunks: %w", err)
+		}
+		if err := json.Unmarshal(row.AfterChunks, &e.After); err != nil {
+			return nil, fmt.Errorf("unmarshal after chunks: %w", err)
+		}
+		result[i] = e
+	}
+	return result, nil
+}
+
+func (r *chunkRepo) CountEdits(ctx context.Context, fileID uuid.UUID) (int64, error) {
+	return r.q.CountChunkEditsByFileID(ctx, fileID)
+}
+
 // ─── 変換ヘルパー ─────────────────────────────────────────────────
 
 func sqlcChunkToDomainChunk(row sqlcgen.Chunk) *domain.Chunk {
 	c := &domain.Chunk{
-		ID:         row.ChunkID,
-		FileID:     row.FileID,
-		SubjectID:  row.SubjectID,
-		ChunkIndex: int(row.ChunkIndex),
-		Content:    row.Content,
-		Embedding:  row.Embedding,
-		CreatedAt:  row.CreatedAt,
+		ID:             row.ChunkID,
+		FileID:         row.FileID,
+		SubjectID:      row.SubjectID,
+		ChunkIndex:     int(row.ChunkIndex),
+		Content:        row.Content,
+		Embedding:      row.Embedding,
+		CreatedAt:      row.CreatedAt,
+		ManuallyEdited: row.ManuallyEdited,
 	}
 	if row.PageNumber.Valid {
 		v := int(row.PageNumber.Int32)
 		c.PageNumber = &v
 	}
+	if row.UpdatedAt.Valid {
+		c.UpdatedAt = &row.UpdatedAt.Time
+	}
 	return c
 }
 
+// searchFileIDs は教材の絞り込み条件を返す。
+// NULL を渡すと cardinality(NULL) で全件が除外されるため、未指定の場合も空配列にする。
+func searchFileIDs(filter domain.SearchFilter) []uuid.UUID {
+	if filter.FileIDs == nil {
+		return []uuid.UUID{}
+	}
+	return filter.FileIDs
+}
+
+func toNullInt32(v *int) sql.NullInt32 {
+	if v == nil {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: int32(*v), Valid: true}
+}
+
 func sqlcVectorRowToSearchResult(row sqlcgen.SearchChunksByVectorRow) *domain.SearchResult {
 	sr := &domain.SearchResult{
-		ChunkID:    row.ChunkID,
-		FileID:     row.FileID,
-		SubjectID:  row.SubjectID,
-		ChunkIndex: int(row.ChunkIndex),
-		Content:    row.Content,
-		CreatedAt:  row.CreatedAt,
+		ChunkID:          row.ChunkID,
+		FileID:           row.FileID,
+		SubjectID:        row.SubjectID,
+		ChunkIndex:       int(row.ChunkIndex),
+		Content:          row.Content,
+		FileName:         row.FileName,
+		CreatedAt:        row.CreatedAt,
+		MimeType:         row.MimeType,
+		PreviewPageCount: int(row.PreviewPageCount),
+		SubjectName:      row.SubjectName,
+		Score:            row.Score,
 	}
 	if row.PageNumber.Valid {
 		v := int(row.PageNumber.Int32)
@@ -131,12 +483,17 @@ func sqlcVectorRowToSearchResult(row sqlcgen.SearchChunksByVectorRow) *domain.Se
 
 func sqlcTextRowToSearchResult(row sqlcgen.SearchChunksByTextRow) *domain.SearchResult {
 	sr := &domain.SearchResult{
-		ChunkID:    row.ChunkID,
-		FileID:     row.FileID,
-		SubjectID:  row.SubjectID,
-		ChunkIndex: int(row.ChunkIndex),
-		Content:    row.Content,
-		CreatedAt:  row.CreatedAt,
+		ChunkID:          row.ChunkID,
+		FileID:           row.FileID,
+		SubjectID:        row.SubjectID,
+		ChunkIndex:       int(row.ChunkIndex),
+		Content:          row.Content,
+		FileName:         row.FileName,
+		CreatedAt:        row.CreatedAt,
+		MimeType:         row.MimeType,
+		PreviewPageCount: int(row.PreviewPageCount),
+		SubjectName:      row.SubjectName,
+		Score:            row.Score,
 	}
 	if row.PageNumber.Valid {
 		v := int(row.PageNumber.Int32)
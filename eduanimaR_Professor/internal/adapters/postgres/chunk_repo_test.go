@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/postgres/sqlcgen"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+)
+
+// ─── checkEditBefore ──────────────────────────────────────────────
+
+func TestCheckEditBefore(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	locked := []sqlcgen.LockChunksByFileIDRow{
+		{ChunkID: a, ChunkIndex: 3, Content: "フーリエ変換"},
+		{ChunkID: b, ChunkIndex: 4, Content: "ラプラス変換"},
+	}
+
+	tests := []struct {
+		name     string
+		before   []domain.ChunkSnapshot
+		conflict bool
+	}{
+		{"unchanged", []domain.ChunkSnapshot{
+			{ChunkID: a, ChunkIndex: 3, Content: "フーリエ変換"},
+			{ChunkID: b, ChunkIndex: 4, Content: "ラプラス変換"},
+		}, false},
+		{"content changed", []domain.ChunkSnapshot{{ChunkID: a, ChunkIndex: 3, Content: "フーリエ級数"}}, true},
+		{"index shifted", []domain.ChunkSnapshot{{ChunkID: b, ChunkIndex: 5, Content: "ラプラス変換"}}, true},
+		{"deleted", []domain.ChunkSnapshot{{ChunkID: uuid.New(), ChunkIndex: 3, Content: "フーリエ変換"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkEditBefore(locked, tt.before)
+			if !tt.conflict {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, domain.ErrConflict)
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	pgvector "github.com/pgvector/pgvector-go"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/postgres/sqlcgen"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+type embeddingCacheRepo struct {
+	q *sqlcgen.Queries
+}
+
+// NewEmbeddingCacheRepo は ports.EmbeddingCacheRepository の postgres 実装を返す。
+func NewEmbeddingCacheRepo(db *sql.DB) ports.EmbeddingCacheRepository {
+	return &embeddingCacheRepo{q: sqlcgen.New(db)}
+}
+
+func (r *embeddingCacheRepo) Get(ctx context.Context, key string) ([]float32, error) {
+	v, err := r.q.GetCachedEmbedding(ctx, key)
+	if err != nil {
+		return nil, mapDBError(err)
+	}
+	return v.Slice(), nil
+}
+
+func (r *embeddingCacheRepo) Put(ctx context.Context, key, model string, embedding []float32) error {
+	return r.q.UpsertCachedEmbedding(ctx, sqlcgen.UpsertCachedEmbeddingParams{
+		CacheKey:  key,
+		Model:     model,
+		Embedding: pgvector.NewVector(embedding),
+	})
+}
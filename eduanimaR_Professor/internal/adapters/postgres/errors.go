@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"errors"
 
+	"github.com/jackc/pgx/v5/pgconn"
+
 	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
 )
 
@@ -16,3 +18,9 @@ func mapDBError(err error) error {
 	}
 	return err
 }
+
+// isUniqueViolation は一意制約違反（SQLSTATE 23505）かどうかを返す。
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -59,18 +59,36 @@ func (r *fileRepo) ListBySubjectID(ctx context.Context, subjectID uuid.UUID) ([]
 	return out, nil
 }
 
+func (r *fileRepo) ListAll(ctx context.Context) ([]*domain.File, error) {
+	rows, err := r.q.ListAllFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*domain.File, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, toFileDomain(row))
+	}
+	return out, nil
+}
+
 func (r *fileRepo) Create(ctx context.Context, f *domain.File) error {
 	created, err := r.q.CreateFile(ctx, sqlcgen.CreateFileParams{
-		FileID:      f.ID,
-		SubjectID:   f.SubjectID,
-		UserID:      f.UserID,
-		Name:        f.Name,
-		StoragePath: f.StoragePath,
-		MimeType:    f.MimeType,
-		SizeBytes:   f.SizeBytes,
-		Status:      sqlcgen.FileStatus(f.Status),
+		FileID:        f.ID,
+		SubjectID:     f.SubjectID,
+		UserID:        f.UserID,
+		Name:          f.Name,
+		StoragePath:   f.StoragePath,
+		MimeType:      f.MimeType,
+		SizeBytes:     f.SizeBytes,
+		Status:        sqlcgen.FileStatus(f.Status),
+		FolderPath:    nullString(f.FolderPath),
+		SourceUrl:     nullString(f.SourceURL),
+		ContentSha256: nullString(f.ContentHash),
 	})
 	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrConflict
+		}
 		return err
 	}
 	f.UploadedAt = created.UploadedAt
@@ -96,6 +114,39 @@ func (r *fileRepo) UpdateStatus(ctx context.Context, id uuid.UUID, status domain
 	return toFileDomain(row), nil
 }
 
+func (r *fileRepo) UpdateContent(ctx context.Context, id uuid.UUID, name, storagePath, mimeType string, size int64, contentHash string) (*domain.File, error) {
+	row, err := r.q.UpdateFileContent(ctx, sqlcgen.UpdateFileContentParams{
+		FileID:        id,
+		Name:          name,
+		StoragePath:   storagePath,
+		MimeType:      mimeType,
+		SizeBytes:     size,
+		ContentSha256: sql.NullString{String: contentHash, Valid: true},
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return toFileDomain(row), nil
+}
+
+func (r *fileRepo) UpdatePreviews(ctx context.Context, id uuid.UUID, previewPath *string, pageCount int) (*domain.File, error) {
+	row, err := r.q.UpdateFilePreviews(ctx, sqlcgen.UpdateFilePreviewsParams{
+		FileID:           id,
+		PreviewPath:      nullString(previewPath),
+		PreviewPageCount: int32(pageCount),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return toFileDomain(row), nil
+}
+
 func (r *fileRepo) Delete(ctx context.Context, id, userID uuid.UUID) error {
 	return r.q.DeleteFile(ctx, sqlcgen.DeleteFileParams{
 		FileID: id,
@@ -105,15 +156,16 @@ func (r *fileRepo) Delete(ctx context.Context, id, userID uuid.UUID) error {
 
 func toFileDomain(row sqlcgen.File) *domain.File {
 	f := &domain.File{
-		ID:          row.FileID,
-		SubjectID:   row.SubjectID,
-		UserID:      row.UserID,
-		Name:        row.Name,
-		StoragePath: row.StoragePath,
-		MimeType:    row.MimeType,
-		SizeBytes:   row.SizeBytes,
-		Status:      domain.FileStatus(row.Status),
-		UploadedAt:  row.UploadedAt,
+		ID:               row.FileID,
+		SubjectID:        row.SubjectID,
+		UserID:           row.UserID,
+		Name:             row.Name,
+		StoragePath:      row.StoragePath,
+		MimeType:         row.MimeType,
+		SizeBytes:        row.SizeBytes,
+		Status:           domain.FileStatus(row.Status),
+		UploadedAt:       row.UploadedAt,
+		PreviewPageCount: int(row.PreviewPageCount),
 	}
 	if row.ErrorMessage.Valid {
 		f.ErrorMessage = &row.ErrorMessage.String
@@ -122,6 +174,18 @@ func toFileDomain(row sqlcgen.File) *domain.File {
 		t := row.ProcessedAt.Time
 		f.ProcessedAt = &t
 	}
+	if row.FolderPath.Valid {
+		f.FolderPath = &row.FolderPath.String
+	}
+	if row.SourceUrl.Valid {
+		f.SourceURL = &row.SourceUrl.String
+	}
+	if row.ContentSha256.Valid {
+		f.ContentHash = &row.ContentSha256.String
+	}
+	if row.PreviewPath.Valid {
+		f.PreviewPath = &row.PreviewPath.String
+	}
 	_ = time.Time{} // suppress unused import if needed
 	return f
 }
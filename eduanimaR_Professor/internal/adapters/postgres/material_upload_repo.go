@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/postgres/sqlcgen"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+type materialUploadRepo struct {
+	q *sqlcgen.Queries
+}
+
+// NewMaterialUploadRepo は MaterialUploadRepository 実装を返す。
+func NewMaterialUploadRepo(db *sql.DB) ports.MaterialUploadRepository {
+	return &materialUploadRepo{q: sqlcgen.New(db)}
+}
+
+func (r *materialUploadRepo) Create(ctx context.Context, u *domain.MaterialUpload) error {
+	created, err := r.q.CreateMaterialUpload(ctx, sqlcgen.CreateMaterialUploadParams{
+		UploadID:          u.ID,
+		FileID:            u.FileID,
+		SubjectID:         u.SubjectID,
+		UserID:            u.UserID,
+		FileName:          u.FileName,
+		StorageKey:        u.StorageKey,
+		MimeType:          u.MimeType,
+		SizeBytes:         u.SizeBytes,
+		ExpiresAt:         u.ExpiresAt,
+		MultipartUploadID: nullString(u.MultipartUploadID),
+	})
+	if err != nil {
+		return err
+	}
+	u.CreatedAt = created.CreatedAt
+	return nil
+}
+
+func (r *materialUploadRepo) GetByIDAndUserID(ctx context.Context, id, userID uuid.UUID) (*domain.MaterialUpload, error) {
+	row, err := r.q.GetMaterialUploadByIDAndUserID(ctx, sqlcgen.GetMaterialUploadByIDAndUserIDParams{
+		UploadID: id,
+		UserID:   userID,
+	})
+	if err != nil {
+		return nil, mapDBError(err)
+	}
+	return toMaterialUploadDomain(row), nil
+}
+
+// MarkCompleted は未完了の予約を完了にする。
+// 該当行が無い（完了済み）場合は domain.ErrConflict を返す。
+func (r *materialUploadRepo) MarkCompleted(ctx context.Context, id uuid.UUID) (*domain.MaterialUpload, error) {
+	row, err := r.q.MarkMaterialUploadCompleted(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrConflict
+		}
+		return nil, err
+	}
+	return toMaterialUploadDomain(row), nil
+}
+
+func (r *materialUploadRepo) ListExpired(ctx context.Context, before time.Time, limit int) ([]*domain.MaterialUpload, error) {
+	rows, err := r.q.ListExpiredMaterialUploads(ctx, sqlcgen.ListExpiredMaterialUploadsParams{
+		ExpiresAt: before,
+		Limit:     int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*domain.MaterialUpload, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, toMaterialUploadDomain(row))
+	}
+	return out, nil
+}
+
+func (r *materialUploadRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.q.DeleteMaterialUpload(ctx, id)
+}
+
+// AddPart はオフセットが一致する場合のみパートを記録する。
+// 該当行が無い（オフセット不一致 or 完了済み）場合は domain.ErrConflict を返す。
+func (r *materialUploadRepo) AddPart(ctx context.Context, id uuid.UUID, expectedOffset int64, part domain.MaterialUploadPart, expiresAt time.Time) error {
+	_, err := r.q.AddMaterialUploadPart(ctx, sqlcgen.AddMaterialUploadPartParams{
+		SizeBytes:      part.SizeBytes,
+		ExpiresAt:      expiresAt,
+		UploadID:       id,
+		ExpectedOffset: expectedOffset,
+		PartNumber:     int32(part.PartNumber),
+		Etag:           part.ETag,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *materialUploadRepo) ListParts(ctx context.Context, id uuid.UUID) ([]domain.MaterialUploadPart, error) {
+	rows, err := r.q.ListMaterialUploadParts(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]domain.MaterialUploadPart, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, domain.MaterialUploadPart{
+			PartNumber: int(row.PartNumber),
+			ETag:       row.Etag,
+			SizeBytes:  row.SizeBytes,
+		})
+	}
+	return out, nil
+}
+
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func toMaterialUploadDomain(row sqlcgen.MaterialUpload) *domain.MaterialUpload {
+	u := &domain.MaterialUpload{
+		ID:            row.UploadID,
+		FileID:        row.FileID,
+		SubjectID:     row.SubjectID,
+		UserID:        row.UserID,
+		FileName:      row.FileName,
+		StorageKey:    row.StorageKey,
+		MimeType:      row.MimeType,
+		SizeBytes:     row.SizeBytes,
+		ExpiresAt:     row.ExpiresAt,
+		CreatedAt:     row.CreatedAt,
+		UploadedBytes: row.UploadedBytes,
+	}
+	if row.CompletedAt.Valid {
+		t := row.CompletedAt.Time
+		u.CompletedAt = &t
+	}
+	if row.MultipartUploadID.Valid {
+		u.MultipartUploadID = &row.MultipartUploadID.String
+	}
+	return u
+}
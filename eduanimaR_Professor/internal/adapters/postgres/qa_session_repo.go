@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/sqlc-dev/pqtype"
@@ -13,23 +15,48 @@ import (
 	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
 )
 
+// qaSessionRepo は回答を qa_answer_versions に版ごとに保存し、
+// 選ばれている版の値を qa_sessions に反映する（一覧・取得は qa_sessions のみを読む）。
 type qaSessionRepo struct {
-	q *sqlcgen.Queries
+	db *sql.DB
+	q  *sqlcgen.Queries
 }
 
 // NewQASessionRepo は ports.QASessionRepository の postgres 実装を返す。
 func NewQASessionRepo(db *sql.DB) ports.QASessionRepository {
-	return &qaSessionRepo{q: sqlcgen.New(db)}
+	return &qaSessionRepo{db: db, q: sqlcgen.New(db)}
 }
 
 func (r *qaSessionRepo) Create(ctx context.Context, session *domain.QASession) error {
-	_, err := r.q.CreateQASession(ctx, sqlcgen.CreateQASessionParams{
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Commit 済みの場合は何もしない
+	q := r.q.WithTx(tx)
+
+	if _, err := q.CreateQASession(ctx, sqlcgen.CreateQASessionParams{
+		SessionID:       session.ID,
+		UserID:          session.UserID,
+		SubjectID:       session.SubjectID,
+		Question:        session.Question,
+		ExtraSubjectIds: extraSubjectIDs(session.ExtraSubjectIDs),
+	}); err != nil {
+		return err
+	}
+	v, err := q.CreateQAAnswerVersion(ctx, sqlcgen.CreateQAAnswerVersionParams{
 		SessionID: session.ID,
-		UserID:    session.UserID,
-		SubjectID: session.SubjectID,
-		Question:  session.Question,
+		Retrieved: true,
 	})
-	return err
+	if err != nil {
+		return fmt.Errorf("create answer version: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	session.SelectedVersion = int(v.Version)
+	session.LatestVersion = int(v.Version)
+	return nil
 }
 
 func (r *qaSessionRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.QASession, error) {
@@ -51,13 +78,18 @@ func (r *qaSessionRepo) GetByIDAndUserID(ctx context.Context, id, userID uuid.UU
 	return sqlcQASessionToDomain(row)
 }
 
-func (r *qaSessionRepo) ListBySubjectID(ctx context.Context, subjectID, userID uuid.UUID, limit, offset int) ([]*domain.QASession, error) {
-	rows, err := r.q.ListQASessionsBySubjectID(ctx, sqlcgen.ListQASessionsBySubjectIDParams{
-		SubjectID: subjectID,
-		UserID:    userID,
-		Limit:     int32(limit),
-		Offset:    int32(offset),
-	})
+func (r *qaSessionRepo) ListBySubjectID(ctx context.Context, subjectID, userID uuid.UUID, filter domain.QASessionFilter) ([]*domain.QASession, error) {
+	params := sqlcgen.ListQASessionsBySubjectIDParams{
+		SubjectID:       subjectID,
+		UserID:          userID,
+		QuestionPattern: containsPattern(filter.Query),
+		MaxResults:      int32(filter.Limit),
+	}
+	if filter.After != nil {
+		params.CursorCreatedAt = sql.NullTime{Time: filter.After.CreatedAt, Valid: true}
+		params.CursorSessionID = uuid.NullUUID{UUID: filter.After.ID, Valid: true}
+	}
+	rows, err := r.q.ListQASessionsBySubjectID(ctx, params)
 	if err != nil {
 		return nil, err
 	}
@@ -72,38 +104,203 @@ func (r *qaSessionRepo) ListBySubjectID(ctx context.Context, subjectID, userID u
 	return result, nil
 }
 
-func (r *qaSessionRepo) CountBySubjectID(ctx context.Context, subjectID, userID uuid.UUID) (int64, error) {
+func (r *qaSessionRepo) CountBySubjectID(ctx context.Context, subjectID, userID uuid.UUID, query string) (int64, error) {
 	return r.q.CountQASessionsBySubjectID(ctx, sqlcgen.CountQASessionsBySubjectIDParams{
-		SubjectID: subjectID,
-		UserID:    userID,
+		SubjectID:       subjectID,
+		UserID:          userID,
+		QuestionPattern: containsPattern(query),
 	})
 }
 
-func (r *qaSessionRepo) UpdateAnswer(ctx context.Context, id uuid.UUID, answer string, sources []domain.Source) (*domain.QASession, error) {
+func (r *qaSessionRepo) UpdateAnswer(ctx context.Context, id uuid.UUID, answer string, sources []domain.Source, trace *domain.ReasoningTrace) (*domain.QASession, error) {
+	return r.finishLatestVersion(ctx, id, domain.QASessionStatusCompleted, answer, sources, trace)
+}
+
+func (r *qaSessionRepo) MarkInterrupted(ctx context.Context, id uuid.UUID, status domain.QASessionStatus, partialAnswer string, sources []domain.Source, trace *domain.ReasoningTrace) (*domain.QASession, error) {
+	return r.finishLatestVersion(ctx, id, status, partialAnswer, sources, trace)
+}
+
+// finishLatestVersion は最新の回答版に回答・推論の記録と終了時の状態を保存し、セッションに反映する（回答が空の場合は NULL）。
+func (r *qaSessionRepo) finishLatestVersion(ctx context.Context, id uuid.UUID, status domain.QASessionStatus, answer string, sources []domain.Source, trace *domain.ReasoningTrace) (*domain.QASession, error) {
 	sourcesJSON, err := sourcesToNullRawMessage(sources)
 	if err != nil {
 		return nil, err
 	}
-	row, err := r.q.UpdateQASessionAnswer(ctx, sqlcgen.UpdateQASessionAnswerParams{
+	traceJSON, err := traceToNullRawMessage(trace)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // Commit 済みの場合は何もしない
+	q := r.q.WithTx(tx)
+
+	if err := q.FinishLatestQAAnswerVersion(ctx, sqlcgen.FinishLatestQAAnswerVersionParams{
 		SessionID: id,
-		Answer:    sql.NullString{String: answer, Valid: true},
+		Answer:    sql.NullString{String: answer, Valid: answer != "" || status == domain.QASessionStatusCompleted},
 		Sources:   sourcesJSON,
-	})
+		Status:    sqlcgen.QaSessionStatus(status),
+		Trace:     traceJSON,
+	}); err != nil {
+		return nil, fmt.Errorf("finish answer version: %w", err)
+	}
+	return r.syncAndCommit(ctx, tx, q, id)
+}
+
+func (r *qaSessionRepo) UpdateQueryExpansions(ctx context.Context, id uuid.UUID, expansions []domain.QueryExpansion) error {
+	b, err := json.Marshal(expansions)
 	if err != nil {
-		return nil, mapDBError(err)
+		return err
 	}
-	return sqlcQASessionToDomain(row)
+	return r.q.UpdateQASessionQueryExpansions(ctx, sqlcgen.UpdateQASessionQueryExpansionsParams{
+		SessionID:       id,
+		QueryExpansions: pqtype.NullRawMessage{RawMessage: b, Valid: len(expansions) > 0},
+	})
 }
 
 func (r *qaSessionRepo) UpdateFeedback(ctx context.Context, id, userID uuid.UUID, feedback int) (*domain.QASession, error) {
-	row, err := r.q.UpdateQASessionFeedback(ctx, sqlcgen.UpdateQASessionFeedbackParams{
+	session, err := r.GetByIDAndUserID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	return r.UpdateVersionFeedback(ctx, id, userID, session.SelectedVersion, feedback)
+}
+
+func (r *qaSessionRepo) UpdateVersionFeedback(ctx context.Context, id, userID uuid.UUID, version, feedback int) (*domain.QASession, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // Commit 済みの場合は何もしない
+	q := r.q.WithTx(tx)
+
+	n, err := q.UpdateQAAnswerVersionFeedback(ctx, sqlcgen.UpdateQAAnswerVersionFeedbackParams{
 		SessionID: id,
-		UserID:    userID,
+		Version:   int32(version),
 		Feedback:  sql.NullInt16{Int16: int16(feedback), Valid: true},
+		UserID:    userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("answer version %d of qa session %s: %w", version, id, domain.ErrNotFound)
+	}
+	return r.syncAndCommit(ctx, tx, q, id)
+}
+
+func (r *qaSessionRepo) AddVersion(ctx context.Context, id, userID uuid.UUID, retrieved bool) (*domain.QASession, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // Commit 済みの場合は何もしない
+	q := r.q.WithTx(tx)
+
+	v, err := q.CreateQAAnswerVersion(ctx, sqlcgen.CreateQAAnswerVersionParams{
+		SessionID: id,
+		Retrieved: retrieved,
 	})
 	if err != nil {
 		return nil, mapDBError(err)
 	}
+	n, err := q.SelectQASessionVersion(ctx, sqlcgen.SelectQASessionVersionParams{
+		SessionID:       id,
+		SelectedVersion: v.Version,
+		UserID:          userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("qa session %s: %w", id, domain.ErrNotFound)
+	}
+	return r.syncAndCommit(ctx, tx, q, id)
+}
+
+func (r *qaSessionRepo) SelectVersion(ctx context.Context, id, userID uuid.UUID, version int) (*domain.QASession, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback() // Commit 済みの場合は何もしない
+	q := r.q.WithTx(tx)
+
+	n, err := q.SelectQASessionVersion(ctx, sqlcgen.SelectQASessionVersionParams{
+		SessionID:       id,
+		SelectedVersion: int32(version),
+		UserID:          userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("answer version %d of qa session %s: %w", version, id, domain.ErrNotFound)
+	}
+	return r.syncAndCommit(ctx, tx, q, id)
+}
+
+func (r *qaSessionRepo) ListVersions(ctx context.Context, id uuid.UUID) ([]*domain.AnswerVersion, error) {
+	rows, err := r.q.ListQAAnswerVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*domain.AnswerVersion, 0, len(rows))
+	for _, row := range rows {
+		v := &domain.AnswerVersion{
+			SessionID: row.SessionID,
+			Version:   int(row.Version),
+			Status:    domain.QASessionStatus(row.Status),
+			Retrieved: row.Retrieved,
+			CreatedAt: row.CreatedAt,
+		}
+		if row.Answer.Valid {
+			v.Answer = &row.Answer.String
+		}
+		if row.Feedback.Valid {
+			f := int(row.Feedback.Int16)
+			v.Feedback = &f
+		}
+		if row.AnsweredAt.Valid {
+			v.AnsweredAt = &row.AnsweredAt.Time
+		}
+		if v.Sources, err = unmarshalSources(row.Sources, row.SubjectID); err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+	return result, nil
+}
+
+func (r *qaSessionRepo) GetTrace(ctx context.Context, id uuid.UUID, version int) (*domain.ReasoningTrace, error) {
+	row, err := r.q.GetQAAnswerVersionTrace(ctx, sqlcgen.GetQAAnswerVersionTraceParams{
+		SessionID: id,
+		Version:   int32(version),
+	})
+	if err != nil {
+		return nil, mapDBError(err)
+	}
+	if !row.Trace.Valid {
+		return nil, nil
+	}
+	var trace domain.ReasoningTrace
+	if err := json.Unmarshal(row.Trace.RawMessage, &trace); err != nil {
+		return nil, err
+	}
+	return &trace, nil
+}
+
+// syncAndCommit は選ばれている回答版の値をセッションに反映してコミットし、反映後のセッションを返す。
+func (r *qaSessionRepo) syncAndCommit(ctx context.Context, tx *sql.Tx, q *sqlcgen.Queries, id uuid.UUID) (*domain.QASession, error) {
+	row, err := q.SyncQASessionWithSelectedVersion(ctx, id)
+	if err != nil {
+		return nil, mapDBError(err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
 	return sqlcQASessionToDomain(row)
 }
 
@@ -116,6 +313,13 @@ func sqlcQASessionToDomain(row sqlcgen.QaSession) (*domain.QASession, error) {
 		SubjectID: row.SubjectID,
 		Question:  row.Question,
 		CreatedAt: row.CreatedAt,
+		Status:    domain.QASessionStatus(row.Status),
+
+		SelectedVersion: int(row.SelectedVersion),
+		LatestVersion:   int(row.LatestVersion),
+	}
+	if len(row.ExtraSubjectIds) > 0 {
+		s.ExtraSubjectIDs = row.ExtraSubjectIds
 	}
 	if row.Answer.Valid {
 		s.Answer = &row.Answer.String
@@ -127,16 +331,57 @@ func sqlcQASessionToDomain(row sqlcgen.QaSession) (*domain.QASession, error) {
 	if row.AnsweredAt.Valid {
 		s.AnsweredAt = &row.AnsweredAt.Time
 	}
-	if row.Sources.Valid {
-		var srcs []domain.Source
-		if err := json.Unmarshal(row.Sources.RawMessage, &srcs); err != nil {
+	srcs, err := unmarshalSources(row.Sources, row.SubjectID)
+	if err != nil {
+		return nil, err
+	}
+	s.Sources = srcs
+	if row.QueryExpansions.Valid {
+		if err := json.Unmarshal(row.QueryExpansions.RawMessage, &s.QueryExpansions); err != nil {
 			return nil, err
 		}
-		s.Sources = srcs
 	}
 	return s, nil
 }
 
+// unmarshalSources は JSONB の出典を返す（NULL の場合は nil）。
+// 横断質問の導入前に保存された出典は subject を持たないため、セッションの subject とみなす。
+func unmarshalSources(raw pqtype.NullRawMessage, subjectID uuid.UUID) ([]domain.Source, error) {
+	if !raw.Valid {
+		return nil, nil
+	}
+	var srcs []domain.Source
+	if err := json.Unmarshal(raw.RawMessage, &srcs); err != nil {
+		return nil, err
+	}
+	for i := range srcs {
+		if srcs[i].SubjectID == uuid.Nil {
+			srcs[i].SubjectID = subjectID
+		}
+	}
+	return srcs, nil
+}
+
+// likeEscaper は ILIKE のワイルドカードとエスケープ文字をエスケープする（既定のエスケープ文字は \）
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// containsPattern は query を部分一致で検索する ILIKE パターンを返す（query が空の場合は空）。
+func containsPattern(query string) string {
+	if query == "" {
+		return ""
+	}
+	return "%" + likeEscaper.Replace(query) + "%"
+}
+
+// extraSubjectIDs は追加の検索対象 subject を返す。
+// NOT NULL 列に NULL を渡さないよう、未指定の場合も空配列にする。
+func extraSubjectIDs(ids []uuid.UUID) []uuid.UUID {
+	if ids == nil {
+		return []uuid.UUID{}
+	}
+	return ids
+}
+
 func sourcesToNullRawMessage(sources []domain.Source) (pqtype.NullRawMessage, error) {
 	if len(sources) == 0 {
 		return pqtype.NullRawMessage{Valid: false}, nil
@@ -147,3 +392,14 @@ func sourcesToNullRawMessage(sources []domain.Source) (pqtype.NullRawMessage, er
 	}
 	return pqtype.NullRawMessage{RawMessage: b, Valid: true}, nil
 }
+
+func traceToNullRawMessage(trace *domain.ReasoningTrace) (pqtype.NullRawMessage, error) {
+	if trace == nil {
+		return pqtype.NullRawMessage{Valid: false}, nil
+	}
+	b, err := json.Marshal(trace)
+	if err != nil {
+		return pqtype.NullRawMessage{}, err
+	}
+	return pqtype.NullRawMessage{RawMessage: b, Valid: true}, nil
+}
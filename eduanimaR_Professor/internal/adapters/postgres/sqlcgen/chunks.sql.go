@@ -10,10 +10,58 @@ import (
 	"database/sql"
 	"time"
 
-	uuid "github.com/google/uuid"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 	pgvector "github.com/pgvector/pgvector-go"
 )
 
+const countChunksByFileID = `-- name: CountChunksByFileID :one
+SELECT COUNT(*)
+FROM chunks
+WHERE file_id = $1
+`
+
+func (q *Queries) CountChunksByFileID(ctx context.Context, fileID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChunksByFileID, fileID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countChunksBySubjectIDs = `-- name: CountChunksBySubjectIDs :one
+SELECT COUNT(*)
+FROM (
+    SELECT 1
+    FROM chunks
+    WHERE subject_id = ANY($1::uuid[])
+    LIMIT $2::int + 1
+) AS scoped
+`
+
+type CountChunksBySubjectIDsParams struct {
+	SubjectIds []uuid.UUID `json:"subject_ids"`
+	MaxCount   int32       `json:"max_count"`
+}
+
+// 検索対象の subject のチャンク数（max_count + 1 件で数えるのを打ち切る）
+// ベクトル検索で HNSW インデックスを使わず厳密検索するかの判定に使う
+func (q *Queries) CountChunksBySubjectIDs(ctx context.Context, arg CountChunksBySubjectIDsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChunksBySubjectIDs, pq.Array(arg.SubjectIds), arg.MaxCount)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const deleteChunkByID = `-- name: DeleteChunkByID :exec
+DELETE FROM chunks
+WHERE chunk_id = $1
+`
+
+func (q *Queries) DeleteChunkByID(ctx context.Context, chunkID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteChunkByID, chunkID)
+	return err
+}
+
 const deleteChunksByFileID = `-- name: DeleteChunksByFileID :exec
 DELETE FROM chunks
 WHERE file_id = $1
@@ -24,6 +72,30 @@ func (q *Queries) DeleteChunksByFileID(ctx context.Context, fileID uuid.UUID) er
 	return err
 }
 
+const getChunkByID = `-- name: GetChunkByID :one
+SELECT chunk_id, file_id, subject_id, page_number, chunk_index, content, embedding, created_at, manually_edited, updated_at
+FROM chunks
+WHERE chunk_id = $1
+`
+
+func (q *Queries) GetChunkByID(ctx context.Context, chunkID uuid.UUID) (Chunk, error) {
+	row := q.db.QueryRowContext(ctx, getChunkByID, chunkID)
+	var i Chunk
+	err := row.Scan(
+		&i.ChunkID,
+		&i.FileID,
+		&i.SubjectID,
+		&i.PageNumber,
+		&i.ChunkIndex,
+		&i.Content,
+		&i.Embedding,
+		&i.CreatedAt,
+		&i.ManuallyEdited,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const insertChunk = `-- name: InsertChunk :one
 INSERT INTO chunks (
     chunk_id,
@@ -32,20 +104,24 @@ INSERT INTO chunks (
     page_number,
     chunk_index,
     content,
-    embedding
+    embedding,
+    manually_edited,
+    updated_at
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING chunk_id, file_id, subject_id, page_number, chunk_index, content, embedding, created_at
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING chunk_id, file_id, subject_id, page_number, chunk_index, content, embedding, created_at, manually_edited, updated_at
 `
 
 type InsertChunkParams struct {
-	ChunkID    uuid.UUID       `json:"chunk_id"`
-	FileID     uuid.UUID       `json:"file_id"`
-	SubjectID  uuid.UUID       `json:"subject_id"`
-	PageNumber sql.NullInt32   `json:"page_number"`
-	ChunkIndex int32           `json:"chunk_index"`
-	Content    string          `json:"content"`
-	Embedding  pgvector.Vector `json:"embedding"`
+	ChunkID        uuid.UUID       `json:"chunk_id"`
+	FileID         uuid.UUID       `json:"file_id"`
+	SubjectID      uuid.UUID       `json:"subject_id"`
+	PageNumber     sql.NullInt32   `json:"page_number"`
+	ChunkIndex     int32           `json:"chunk_index"`
+	Content        string          `json:"content"`
+	Embedding      pgvector.Vector `json:"embedding"`
+	ManuallyEdited bool            `json:"manually_edited"`
+	UpdatedAt      sql.NullTime    `json:"updated_at"`
 }
 
 func (q *Queries) InsertChunk(ctx context.Context, arg InsertChunkParams) (Chunk, error) {
@@ -57,6 +133,8 @@ func (q *Queries) InsertChunk(ctx context.Context, arg InsertChunkParams) (Chunk
 		arg.ChunkIndex,
 		arg.Content,
 		arg.Embedding,
+		arg.ManuallyEdited,
+		arg.UpdatedAt,
 	)
 	var i Chunk
 	err := row.Scan(
@@ -68,13 +146,123 @@ func (q *Queries) InsertChunk(ctx context.Context, arg InsertChunkParams) (Chunk
 		&i.Content,
 		&i.Embedding,
 		&i.CreatedAt,
+		&i.ManuallyEdited,
+		&i.UpdatedAt,
 	)
 	return i, err
 }
 
+const listChunkEmbeddings = `-- name: ListChunkEmbeddings :many
+SELECT
+    chunk_id,
+    embedding
+FROM chunks
+WHERE chunk_id = ANY($1::uuid[])
+`
+
+type ListChunkEmbeddingsRow struct {
+	ChunkID   uuid.UUID       `json:"chunk_id"`
+	Embedding pgvector.Vector `json:"embedding"`
+}
+
+// 検索候補の embedding の取得（MMR による多様性を考慮した並べ替えに使う）
+func (q *Queries) ListChunkEmbeddings(ctx context.Context, chunkIds []uuid.UUID) ([]ListChunkEmbeddingsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChunkEmbeddings, pq.Array(chunkIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChunkEmbeddingsRow
+	for rows.Next() {
+		var i ListChunkEmbeddingsRow
+		if err := rows.Scan(&i.ChunkID, &i.Embedding); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listChunkPageByFileID = `-- name: ListChunkPageByFileID :many
+SELECT
+    chunk_id,
+    file_id,
+    subject_id,
+    page_number,
+    chunk_index,
+    content,
+    created_at,
+    manually_edited,
+    updated_at
+FROM chunks
+WHERE file_id = $1
+ORDER BY chunk_index
+LIMIT  $2
+OFFSET $3
+`
+
+type ListChunkPageByFileIDParams struct {
+	FileID uuid.UUID `json:"file_id"`
+	Limit  int32     `json:"limit"`
+	Offset int32     `json:"offset"`
+}
+
+type ListChunkPageByFileIDRow struct {
+	ChunkID        uuid.UUID     `json:"chunk_id"`
+	FileID         uuid.UUID     `json:"file_id"`
+	SubjectID      uuid.UUID     `json:"subject_id"`
+	PageNumber     sql.NullInt32 `json:"page_number"`
+	ChunkIndex     int32         `json:"chunk_index"`
+	Content        string        `json:"content"`
+	CreatedAt      time.Time     `json:"created_at"`
+	ManuallyEdited bool          `json:"manually_edited"`
+	UpdatedAt      sql.NullTime  `json:"updated_at"`
+}
+
+// チャンク閲覧用のページング取得（embedding は返さない）
+// $1: file_id, $2: limit, $3: offset
+func (q *Queries) ListChunkPageByFileID(ctx context.Context, arg ListChunkPageByFileIDParams) ([]ListChunkPageByFileIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, listChunkPageByFileID, arg.FileID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListChunkPageByFileIDRow
+	for rows.Next() {
+		var i ListChunkPageByFileIDRow
+		if err := rows.Scan(
+			&i.ChunkID,
+			&i.FileID,
+			&i.SubjectID,
+			&i.PageNumber,
+			&i.ChunkIndex,
+			&i.Content,
+			&i.CreatedAt,
+			&i.ManuallyEdited,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listChunksByFileID = `-- name: ListChunksByFileID :many
 
-SELECT chunk_id, file_id, subject_id, page_number, chunk_index, content, embedding, created_at
+SELECT chunk_id, file_id, subject_id, page_number, chunk_index, content, embedding, created_at, manually_edited, updated_at
 FROM chunks
 WHERE file_id = $1
 ORDER BY chunk_index
@@ -99,6 +287,8 @@ func (q *Queries) ListChunksByFileID(ctx context.Context, fileID uuid.UUID) ([]C
 			&i.Content,
 			&i.Embedding,
 			&i.CreatedAt,
+			&i.ManuallyEdited,
+			&i.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -113,7 +303,7 @@ func (q *Queries) ListChunksByFileID(ctx context.Context, fileID uuid.UUID) ([]C
 	return items, nil
 }
 
-const searchChunksByText = `-- name: SearchChunksByText :many
+const listNeighbourChunks = `-- name: ListNeighbourChunks :many
 SELECT
     chunk_id,
     file_id,
@@ -121,34 +311,179 @@ SELECT
     page_number,
     chunk_index,
     content,
-    created_at
+    created_at,
+    manually_edited,
+    updated_at
 FROM chunks
-WHERE subject_id = $2
-  AND to_tsvector('simple', content) @@ plainto_tsquery('simple', $1)
-ORDER BY ts_rank(to_tsvector('simple', content), plainto_tsquery('simple', $1)) DESC
-LIMIT $3
+WHERE file_id = $1
+  AND (
+        chunk_index BETWEEN $2::int AND $3::int
+     OR ($4::int IS NOT NULL AND page_number = $4::int)
+  )
+ORDER BY chunk_index
+`
+
+type ListNeighbourChunksParams struct {
+	FileID     uuid.UUID     `json:"file_id"`
+	IndexFrom  int32         `json:"index_from"`
+	IndexTo    int32         `json:"index_to"`
+	PageNumber sql.NullInt32 `json:"page_number"`
+}
+
+type ListNeighbourChunksRow struct {
+	ChunkID        uuid.UUID     `json:"chunk_id"`
+	FileID         uuid.UUID     `json:"file_id"`
+	SubjectID      uuid.UUID     `json:"subject_id"`
+	PageNumber     sql.NullInt32 `json:"page_number"`
+	ChunkIndex     int32         `json:"chunk_index"`
+	Content        string        `json:"content"`
+	CreatedAt      time.Time     `json:"created_at"`
+	ManuallyEdited bool          `json:"manually_edited"`
+	UpdatedAt      sql.NullTime  `json:"updated_at"`
+}
+
+// エビデンスの前後文脈の取得（embedding は返さない）
+// chunk_index が index_from〜index_to の範囲、または page_number が一致するチャンク（page_number が NULL の場合は範囲のみ）
+func (q *Queries) ListNeighbourChunks(ctx context.Context, arg ListNeighbourChunksParams) ([]ListNeighbourChunksRow, error) {
+	rows, err := q.db.QueryContext(ctx, listNeighbourChunks,
+		arg.FileID,
+		arg.IndexFrom,
+		arg.IndexTo,
+		arg.PageNumber,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListNeighbourChunksRow
+	for rows.Next() {
+		var i ListNeighbourChunksRow
+		if err := rows.Scan(
+			&i.ChunkID,
+			&i.FileID,
+			&i.SubjectID,
+			&i.PageNumber,
+			&i.ChunkIndex,
+			&i.Content,
+			&i.CreatedAt,
+			&i.ManuallyEdited,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockChunksByFileID = `-- name: LockChunksByFileID :many
+SELECT chunk_id, chunk_index, content
+FROM chunks
+WHERE file_id = $1
+ORDER BY chunk_index
+FOR UPDATE
+`
+
+type LockChunksByFileIDRow struct {
+	ChunkID    uuid.UUID `json:"chunk_id"`
+	ChunkIndex int32     `json:"chunk_index"`
+	Content    string    `json:"content"`
+}
+
+// 手動修正のトランザクション内でファイルの全チャンクを行ロックし、修正前の状態を読み直す
+// 同じ教材への修正はここで直列化される
+func (q *Queries) LockChunksByFileID(ctx context.Context, fileID uuid.UUID) ([]LockChunksByFileIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, lockChunksByFileID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LockChunksByFileIDRow
+	for rows.Next() {
+		var i LockChunksByFileIDRow
+		if err := rows.Scan(&i.ChunkID, &i.ChunkIndex, &i.Content); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchChunksByText = `-- name: SearchChunksByText :many
+SELECT
+    c.chunk_id,
+    c.file_id,
+    c.subject_id,
+    c.page_number,
+    c.chunk_index,
+    c.content,
+    c.created_at,
+    f.name AS file_name,
+    f.mime_type,
+    f.preview_page_count,
+    s.name AS subject_name,
+    ts_rank(to_tsvector('simple', c.content), plainto_tsquery('simple', $1))::float8 AS score
+FROM chunks c
+JOIN files f ON f.file_id = c.file_id
+JOIN subjects s ON s.subject_id = c.subject_id
+WHERE c.subject_id = ANY($2::uuid[])
+  AND to_tsvector('simple', c.content) @@ plainto_tsquery('simple', $1)
+  AND (cardinality($3::uuid[]) = 0 OR c.file_id = ANY($3::uuid[]))
+  AND ($4::int IS NULL OR c.page_number >= $4::int)
+  AND ($5::int IS NULL OR c.page_number <= $5::int)
+ORDER BY score DESC, c.chunk_id
+LIMIT $6
 `
 
 type SearchChunksByTextParams struct {
-	PlaintoTsquery string    `json:"plainto_tsquery"`
-	SubjectID      uuid.UUID `json:"subject_id"`
-	Limit          int32     `json:"limit"`
+	QueryText  string        `json:"query_text"`
+	SubjectIds []uuid.UUID   `json:"subject_ids"`
+	FileIds    []uuid.UUID   `json:"file_ids"`
+	PageFrom   sql.NullInt32 `json:"page_from"`
+	PageTo     sql.NullInt32 `json:"page_to"`
+	MaxResults int32         `json:"max_results"`
 }
 
 type SearchChunksByTextRow struct {
-	ChunkID    uuid.UUID     `json:"chunk_id"`
-	FileID     uuid.UUID     `json:"file_id"`
-	SubjectID  uuid.UUID     `json:"subject_id"`
-	PageNumber sql.NullInt32 `json:"page_number"`
-	ChunkIndex int32         `json:"chunk_index"`
-	Content    string        `json:"content"`
-	CreatedAt  time.Time     `json:"created_at"`
+	ChunkID          uuid.UUID     `json:"chunk_id"`
+	FileID           uuid.UUID     `json:"file_id"`
+	SubjectID        uuid.UUID     `json:"subject_id"`
+	PageNumber       sql.NullInt32 `json:"page_number"`
+	ChunkIndex       int32         `json:"chunk_index"`
+	Content          string        `json:"content"`
+	CreatedAt        time.Time     `json:"created_at"`
+	FileName         string        `json:"file_name"`
+	MimeType         string        `json:"mime_type"`
+	PreviewPageCount int32         `json:"preview_page_count"`
+	SubjectName      string        `json:"subject_name"`
+	Score            float64       `json:"score"`
 }
 
 // 全文検索（simple 辞書 / plainto_tsquery）
-// $1: query_text, $2: subject_id, $3: limit
+// subject_ids: 所有権確認済みの subject（物理制約。複数 subject を横断する場合も必ず指定する）
+// file_ids が空の場合は全教材、page_from / page_to が NULL の場合はページで絞り込まない
+// score: ts_rank
 func (q *Queries) SearchChunksByText(ctx context.Context, arg SearchChunksByTextParams) ([]SearchChunksByTextRow, error) {
-	rows, err := q.db.QueryContext(ctx, searchChunksByText, arg.PlaintoTsquery, arg.SubjectID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, searchChunksByText,
+		arg.QueryText,
+		pq.Array(arg.SubjectIds),
+		pq.Array(arg.FileIds),
+		arg.PageFrom,
+		arg.PageTo,
+		arg.MaxResults,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -164,6 +499,11 @@ func (q *Queries) SearchChunksByText(ctx context.Context, arg SearchChunksByText
 			&i.ChunkIndex,
 			&i.Content,
 			&i.CreatedAt,
+			&i.FileName,
+			&i.MimeType,
+			&i.PreviewPageCount,
+			&i.SubjectName,
+			&i.Score,
 		); err != nil {
 			return nil, err
 		}
@@ -180,39 +520,66 @@ func (q *Queries) SearchChunksByText(ctx context.Context, arg SearchChunksByText
 
 const searchChunksByVector = `-- name: SearchChunksByVector :many
 SELECT
-    chunk_id,
-    file_id,
-    subject_id,
-    page_number,
-    chunk_index,
-    content,
-    created_at
-FROM chunks
-WHERE subject_id = $2
-ORDER BY embedding <=> $1::vector
-LIMIT $3
+    c.chunk_id,
+    c.file_id,
+    c.subject_id,
+    c.page_number,
+    c.chunk_index,
+    c.content,
+    c.created_at,
+    f.name AS file_name,
+    f.mime_type,
+    f.preview_page_count,
+    s.name AS subject_name,
+    (1 - (c.embedding <=> $1::vector))::float8 AS score
+FROM chunks c
+JOIN files f ON f.file_id = c.file_id
+JOIN subjects s ON s.subject_id = c.subject_id
+WHERE c.subject_id = ANY($2::uuid[])
+  AND (cardinality($3::uuid[]) = 0 OR c.file_id = ANY($3::uuid[]))
+  AND ($4::int IS NULL OR c.page_number >= $4::int)
+  AND ($5::int IS NULL OR c.page_number <= $5::int)
+ORDER BY c.embedding <=> $1::vector
+LIMIT $6
 `
 
 type SearchChunksByVectorParams struct {
-	Column1   pgvector.Vector `json:"column_1"`
-	SubjectID uuid.UUID       `json:"subject_id"`
-	Limit     int32           `json:"limit"`
+	QueryEmbedding pgvector.Vector `json:"query_embedding"`
+	SubjectIds     []uuid.UUID     `json:"subject_ids"`
+	FileIds        []uuid.UUID     `json:"file_ids"`
+	PageFrom       sql.NullInt32   `json:"page_from"`
+	PageTo         sql.NullInt32   `json:"page_to"`
+	MaxResults     int32           `json:"max_results"`
 }
 
 type SearchChunksByVectorRow struct {
-	ChunkID    uuid.UUID     `json:"chunk_id"`
-	FileID     uuid.UUID     `json:"file_id"`
-	SubjectID  uuid.UUID     `json:"subject_id"`
-	PageNumber sql.NullInt32 `json:"page_number"`
-	ChunkIndex int32         `json:"chunk_index"`
-	Content    string        `json:"content"`
-	CreatedAt  time.Time     `json:"created_at"`
+	ChunkID          uuid.UUID     `json:"chunk_id"`
+	FileID           uuid.UUID     `json:"file_id"`
+	SubjectID        uuid.UUID     `json:"subject_id"`
+	PageNumber       sql.NullInt32 `json:"page_number"`
+	ChunkIndex       int32         `json:"chunk_index"`
+	Content          string        `json:"content"`
+	CreatedAt        time.Time     `json:"created_at"`
+	FileName         string        `json:"file_name"`
+	MimeType         string        `json:"mime_type"`
+	PreviewPageCount int32         `json:"preview_page_count"`
+	SubjectName      string        `json:"subject_name"`
+	Score            float64       `json:"score"`
 }
 
-// コサイン類似度でのベクトル検索（HNSW インデックス使用）
-// $1: query_embedding (vector), $2: subject_id, $3: limit
+// コサイン類似度でのベクトル検索（HNSW インデックス使用。ef_search・iterative_scan・厳密検索への切り替えは SetLocalSearchConfig で行う）
+// subject_ids: 所有権確認済みの subject（物理制約。複数 subject を横断する場合も必ず指定する）
+// file_ids が空の場合は全教材、page_from / page_to が NULL の場合はページで絞り込まない
+// score: コサイン類似度（1 - コサイン距離）
 func (q *Queries) SearchChunksByVector(ctx context.Context, arg SearchChunksByVectorParams) ([]SearchChunksByVectorRow, error) {
-	rows, err := q.db.QueryContext(ctx, searchChunksByVector, arg.Column1, arg.SubjectID, arg.Limit)
+	rows, err := q.db.QueryContext(ctx, searchChunksByVector,
+		arg.QueryEmbedding,
+		pq.Array(arg.SubjectIds),
+		pq.Array(arg.FileIds),
+		arg.PageFrom,
+		arg.PageTo,
+		arg.MaxResults,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -228,6 +595,11 @@ func (q *Queries) SearchChunksByVector(ctx context.Context, arg SearchChunksByVe
 			&i.ChunkIndex,
 			&i.Content,
 			&i.CreatedAt,
+			&i.FileName,
+			&i.MimeType,
+			&i.PreviewPageCount,
+			&i.SubjectName,
+			&i.Score,
 		); err != nil {
 			return nil, err
 		}
@@ -241,3 +613,84 @@ func (q *Queries) SearchChunksByVector(ctx context.Context, arg SearchChunksByVe
 	}
 	return items, nil
 }
+
+const setLocalSearchConfig = `-- name: SetLocalSearchConfig :exec
+SELECT set_config($1::text, $2::text, true)
+`
+
+type SetLocalSearchConfigParams struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// 検索用のパラメータ（hnsw.ef_search など）をトランザクション内に限って設定する
+func (q *Queries) SetLocalSearchConfig(ctx context.Context, arg SetLocalSearchConfigParams) error {
+	_, err := q.db.ExecContext(ctx, setLocalSearchConfig, arg.Name, arg.Value)
+	return err
+}
+
+const shiftChunkIndexes = `-- name: ShiftChunkIndexes :exec
+UPDATE chunks
+SET chunk_index = chunk_index + $1::int
+WHERE file_id = $2
+  AND chunk_index >= $3::int
+`
+
+type ShiftChunkIndexesParams struct {
+	Delta     int32     `json:"delta"`
+	FileID    uuid.UUID `json:"file_id"`
+	FromIndex int32     `json:"from_index"`
+}
+
+// 分割・結合・削除の後にファイル内連番を詰める／空ける
+// file_id 内で from_index 以上の chunk_index に delta を加算する
+func (q *Queries) ShiftChunkIndexes(ctx context.Context, arg ShiftChunkIndexesParams) error {
+	_, err := q.db.ExecContext(ctx, shiftChunkIndexes, arg.Delta, arg.FileID, arg.FromIndex)
+	return err
+}
+
+const upsertEditedChunk = `-- name: UpsertEditedChunk :exec
+INSERT INTO chunks (
+    chunk_id,
+    file_id,
+    subject_id,
+    page_number,
+    chunk_index,
+    content,
+    embedding,
+    manually_edited,
+    updated_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, TRUE, NOW())
+ON CONFLICT (chunk_id) DO UPDATE
+SET page_number     = EXCLUDED.page_number,
+    chunk_index     = EXCLUDED.chunk_index,
+    content         = EXCLUDED.content,
+    embedding       = EXCLUDED.embedding,
+    manually_edited = TRUE,
+    updated_at      = NOW()
+`
+
+type UpsertEditedChunkParams struct {
+	ChunkID    uuid.UUID       `json:"chunk_id"`
+	FileID     uuid.UUID       `json:"file_id"`
+	SubjectID  uuid.UUID       `json:"subject_id"`
+	PageNumber sql.NullInt32   `json:"page_number"`
+	ChunkIndex int32           `json:"chunk_index"`
+	Content    string          `json:"content"`
+	Embedding  pgvector.Vector `json:"embedding"`
+}
+
+// 手動修正したチャンクを作成または更新する（manually_edited = TRUE）
+func (q *Queries) UpsertEditedChunk(ctx context.Context, arg UpsertEditedChunkParams) error {
+	_, err := q.db.ExecContext(ctx, upsertEditedChunk,
+		arg.ChunkID,
+		arg.FileID,
+		arg.SubjectID,
+		arg.PageNumber,
+		arg.ChunkIndex,
+		arg.Content,
+		arg.Embedding,
+	)
+	return err
+}
@@ -9,7 +9,7 @@ import (
 	"context"
 	"database/sql"
 
-	uuid "github.com/google/uuid"
+	"github.com/google/uuid"
 )
 
 const createFile = `-- name: CreateFile :one
@@ -21,21 +21,27 @@ INSERT INTO files (
     storage_path,
     mime_type,
     size_bytes,
-    status
+    status,
+    folder_path,
+    source_url,
+    content_sha256
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING file_id, subject_id, user_id, name, storage_path, mime_type, size_bytes, status, error_message, uploaded_at, processed_at
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+RETURNING file_id, subject_id, user_id, name, storage_path, mime_type, size_bytes, status, error_message, uploaded_at, processed_at, folder_path, source_url, content_sha256, preview_path, preview_page_count
 `
 
 type CreateFileParams struct {
-	FileID      uuid.UUID  `json:"file_id"`
-	SubjectID   uuid.UUID  `json:"subject_id"`
-	UserID      uuid.UUID  `json:"user_id"`
-	Name        string     `json:"name"`
-	StoragePath string     `json:"storage_path"`
-	MimeType    string     `json:"mime_type"`
-	SizeBytes   int64      `json:"size_bytes"`
-	Status      FileStatus `json:"status"`
+	FileID        uuid.UUID      `json:"file_id"`
+	SubjectID     uuid.UUID      `json:"subject_id"`
+	UserID        uuid.UUID      `json:"user_id"`
+	Name          string         `json:"name"`
+	StoragePath   string         `json:"storage_path"`
+	MimeType      string         `json:"mime_type"`
+	SizeBytes     int64          `json:"size_bytes"`
+	Status        FileStatus     `json:"status"`
+	FolderPath    sql.NullString `json:"folder_path"`
+	SourceUrl     sql.NullString `json:"source_url"`
+	ContentSha256 sql.NullString `json:"content_sha256"`
 }
 
 func (q *Queries) CreateFile(ctx context.Context, arg CreateFileParams) (File, error) {
@@ -48,6 +54,9 @@ func (q *Queries) CreateFile(ctx context.Context, arg CreateFileParams) (File, e
 		arg.MimeType,
 		arg.SizeBytes,
 		arg.Status,
+		arg.FolderPath,
+		arg.SourceUrl,
+		arg.ContentSha256,
 	)
 	var i File
 	err := row.Scan(
@@ -62,6 +71,11 @@ func (q *Queries) CreateFile(ctx context.Context, arg CreateFileParams) (File, e
 		&i.ErrorMessage,
 		&i.UploadedAt,
 		&i.ProcessedAt,
+		&i.FolderPath,
+		&i.SourceUrl,
+		&i.ContentSha256,
+		&i.PreviewPath,
+		&i.PreviewPageCount,
 	)
 	return i, err
 }
@@ -84,7 +98,7 @@ func (q *Queries) DeleteFile(ctx context.Context, arg DeleteFileParams) error {
 
 const getFileByID = `-- name: GetFileByID :one
 
-SELECT file_id, subject_id, user_id, name, storage_path, mime_type, size_bytes, status, error_message, uploaded_at, processed_at
+SELECT file_id, subject_id, user_id, name, storage_path, mime_type, size_bytes, status, error_message, uploaded_at, processed_at, folder_path, source_url, content_sha256, preview_path, preview_page_count
 FROM files
 WHERE file_id = $1
 `
@@ -105,12 +119,17 @@ func (q *Queries) GetFileByID(ctx context.Context, fileID uuid.UUID) (File, erro
 		&i.ErrorMessage,
 		&i.UploadedAt,
 		&i.ProcessedAt,
+		&i.FolderPath,
+		&i.SourceUrl,
+		&i.ContentSha256,
+		&i.PreviewPath,
+		&i.PreviewPageCount,
 	)
 	return i, err
 }
 
 const getFileByIDAndUserID = `-- name: GetFileByIDAndUserID :one
-SELECT file_id, subject_id, user_id, name, storage_path, mime_type, size_bytes, status, error_message, uploaded_at, processed_at
+SELECT file_id, subject_id, user_id, name, storage_path, mime_type, size_bytes, status, error_message, uploaded_at, processed_at, folder_path, source_url, content_sha256, preview_path, preview_page_count
 FROM files
 WHERE file_id = $1
   AND user_id = $2
@@ -136,12 +155,64 @@ func (q *Queries) GetFileByIDAndUserID(ctx context.Context, arg GetFileByIDAndUs
 		&i.ErrorMessage,
 		&i.UploadedAt,
 		&i.ProcessedAt,
+		&i.FolderPath,
+		&i.SourceUrl,
+		&i.ContentSha256,
+		&i.PreviewPath,
+		&i.PreviewPageCount,
 	)
 	return i, err
 }
 
+const listAllFiles = `-- name: ListAllFiles :many
+SELECT file_id, subject_id, user_id, name, storage_path, mime_type, size_bytes, status, error_message, uploaded_at, processed_at, folder_path, source_url, content_sha256, preview_path, preview_page_count
+FROM files
+ORDER BY file_id
+`
+
+// ストレージ整合性チェック（孤立オブジェクト検出）用の全件取得
+func (q *Queries) ListAllFiles(ctx context.Context) ([]File, error) {
+	rows, err := q.db.QueryContext(ctx, listAllFiles)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []File
+	for rows.Next() {
+		var i File
+		if err := rows.Scan(
+			&i.FileID,
+			&i.SubjectID,
+			&i.UserID,
+			&i.Name,
+			&i.StoragePath,
+			&i.MimeType,
+			&i.SizeBytes,
+			&i.Status,
+			&i.ErrorMessage,
+			&i.UploadedAt,
+			&i.ProcessedAt,
+			&i.FolderPath,
+			&i.SourceUrl,
+			&i.ContentSha256,
+			&i.PreviewPath,
+			&i.PreviewPageCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listFilesBySubjectID = `-- name: ListFilesBySubjectID :many
-SELECT file_id, subject_id, user_id, name, storage_path, mime_type, size_bytes, status, error_message, uploaded_at, processed_at
+SELECT file_id, subject_id, user_id, name, storage_path, mime_type, size_bytes, status, error_message, uploaded_at, processed_at, folder_path, source_url, content_sha256, preview_path, preview_page_count
 FROM files
 WHERE subject_id = $1
 ORDER BY uploaded_at DESC
@@ -168,6 +239,11 @@ func (q *Queries) ListFilesBySubjectID(ctx context.Context, subjectID uuid.UUID)
 			&i.ErrorMessage,
 			&i.UploadedAt,
 			&i.ProcessedAt,
+			&i.FolderPath,
+			&i.SourceUrl,
+			&i.ContentSha256,
+			&i.PreviewPath,
+			&i.PreviewPageCount,
 		); err != nil {
 			return nil, err
 		}
@@ -182,6 +258,102 @@ func (q *Queries) ListFilesBySubjectID(ctx context.Context, subjectID uuid.UUID)
 	return items, nil
 }
 
+const updateFileContent = `-- name: UpdateFileContent :one
+UPDATE files
+SET
+    name           = $2,
+    storage_path   = $3,
+    mime_type      = $4,
+    size_bytes     = $5,
+    content_sha256 = $6,
+    status         = 'pending',
+    error_message  = NULL,
+    processed_at   = NULL
+WHERE file_id = $1
+RETURNING file_id, subject_id, user_id, name, storage_path, mime_type, size_bytes, status, error_message, uploaded_at, processed_at, folder_path, source_url, content_sha256, preview_path, preview_page_count
+`
+
+type UpdateFileContentParams struct {
+	FileID        uuid.UUID      `json:"file_id"`
+	Name          string         `json:"name"`
+	StoragePath   string         `json:"storage_path"`
+	MimeType      string         `json:"mime_type"`
+	SizeBytes     int64          `json:"size_bytes"`
+	ContentSha256 sql.NullString `json:"content_sha256"`
+}
+
+// 再取得で内容が変わった教材を再処理待ちに戻す（種別が変わった場合は教材名も更新する）
+func (q *Queries) UpdateFileContent(ctx context.Context, arg UpdateFileContentParams) (File, error) {
+	row := q.db.QueryRowContext(ctx, updateFileContent,
+		arg.FileID,
+		arg.Name,
+		arg.StoragePath,
+		arg.MimeType,
+		arg.SizeBytes,
+		arg.ContentSha256,
+	)
+	var i File
+	err := row.Scan(
+		&i.FileID,
+		&i.SubjectID,
+		&i.UserID,
+		&i.Name,
+		&i.StoragePath,
+		&i.MimeType,
+		&i.SizeBytes,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.UploadedAt,
+		&i.ProcessedAt,
+		&i.FolderPath,
+		&i.SourceUrl,
+		&i.ContentSha256,
+		&i.PreviewPath,
+		&i.PreviewPageCount,
+	)
+	return i, err
+}
+
+const updateFilePreviews = `-- name: UpdateFilePreviews :one
+UPDATE files
+SET
+    preview_path       = $2,
+    preview_page_count = $3
+WHERE file_id = $1
+RETURNING file_id, subject_id, user_id, name, storage_path, mime_type, size_bytes, status, error_message, uploaded_at, processed_at, folder_path, source_url, content_sha256, preview_path, preview_page_count
+`
+
+type UpdateFilePreviewsParams struct {
+	FileID           uuid.UUID      `json:"file_id"`
+	PreviewPath      sql.NullString `json:"preview_path"`
+	PreviewPageCount int32          `json:"preview_page_count"`
+}
+
+// Ingest 時に生成したページプレビューの保存先とページ数を記録する
+func (q *Queries) UpdateFilePreviews(ctx context.Context, arg UpdateFilePreviewsParams) (File, error) {
+	row := q.db.QueryRowContext(ctx, updateFilePreviews, arg.FileID, arg.PreviewPath, arg.PreviewPageCount)
+	var i File
+	err := row.Scan(
+		&i.FileID,
+		&i.SubjectID,
+		&i.UserID,
+		&i.Name,
+		&i.StoragePath,
+		&i.MimeType,
+		&i.SizeBytes,
+		&i.Status,
+		&i.ErrorMessage,
+		&i.UploadedAt,
+		&i.ProcessedAt,
+		&i.FolderPath,
+		&i.SourceUrl,
+		&i.ContentSha256,
+		&i.PreviewPath,
+		&i.PreviewPageCount,
+	)
+	return i, err
+}
+
 const updateFileStatus = `-- name: UpdateFileStatus :one
 UPDATE files
 SET
@@ -192,7 +364,7 @@ SET
                         ELSE processed_at
                     END
 WHERE file_id = $1
-RETURNING file_id, subject_id, user_id, name, storage_path, mime_type, size_bytes, status, error_message, uploaded_at, processed_at
+RETURNING file_id, subject_id, user_id, name, storage_path, mime_type, size_bytes, status, error_message, uploaded_at, processed_at, folder_path, source_url, content_sha256, preview_path, preview_page_count
 `
 
 type UpdateFileStatusParams struct {
@@ -216,6 +388,11 @@ func (q *Queries) UpdateFileStatus(ctx context.Context, arg UpdateFileStatusPara
 		&i.ErrorMessage,
 		&i.UploadedAt,
 		&i.ProcessedAt,
+		&i.FolderPath,
+		&i.SourceUrl,
+		&i.ContentSha256,
+		&i.PreviewPath,
+		&i.PreviewPageCount,
 	)
 	return i, err
 }
@@ -10,7 +10,7 @@ import (
 	"fmt"
 	"time"
 
-	uuid "github.com/google/uuid"
+	"github.com/google/uuid"
 	pgvector "github.com/pgvector/pgvector-go"
 	"github.com/sqlc-dev/pqtype"
 )
@@ -207,29 +207,132 @@ func AllJobStatusValues() []JobStatus {
 	}
 }
 
+type QaSessionStatus string
+
+const (
+	QaSessionStatusRunning   QaSessionStatus = "running"
+	QaSessionStatusCompleted QaSessionStatus = "completed"
+	QaSessionStatusCancelled QaSessionStatus = "cancelled"
+	QaSessionStatusFailed    QaSessionStatus = "failed"
+)
+
+func (e *QaSessionStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = QaSessionStatus(s)
+	case string:
+		*e = QaSessionStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for QaSessionStatus: %T", src)
+	}
+	return nil
+}
+
+type NullQaSessionStatus struct {
+	QaSessionStatus QaSessionStatus `json:"qa_session_status"`
+	Valid           bool            `json:"valid"` // Valid is true if QaSessionStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullQaSessionStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.QaSessionStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.QaSessionStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullQaSessionStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.QaSessionStatus), nil
+}
+
+func (e QaSessionStatus) Valid() bool {
+	switch e {
+	case QaSessionStatusRunning,
+		QaSessionStatusCompleted,
+		QaSessionStatusCancelled,
+		QaSessionStatusFailed:
+		return true
+	}
+	return false
+}
+
+func AllQaSessionStatusValues() []QaSessionStatus {
+	return []QaSessionStatus{
+		QaSessionStatusRunning,
+		QaSessionStatusCompleted,
+		QaSessionStatusCancelled,
+		QaSessionStatusFailed,
+	}
+}
+
+type AnswerCache struct {
+	CacheID           uuid.UUID       `json:"cache_id"`
+	SubjectID         uuid.UUID       `json:"subject_id"`
+	ExtraSubjectIds   []uuid.UUID     `json:"extra_subject_ids"`
+	MaterialVersion   string          `json:"material_version"`
+	Question          string          `json:"question"`
+	QuestionEmbedding pgvector.Vector `json:"question_embedding"`
+	Answer            string          `json:"answer"`
+	Sources           []byte          `json:"sources"`
+	SessionID         uuid.NullUUID   `json:"session_id"`
+	HitCount          int32           `json:"hit_count"`
+	CreatedAt         time.Time       `json:"created_at"`
+	LastHitAt         sql.NullTime    `json:"last_hit_at"`
+}
+
 type Chunk struct {
-	ChunkID    uuid.UUID       `json:"chunk_id"`
-	FileID     uuid.UUID       `json:"file_id"`
-	SubjectID  uuid.UUID       `json:"subject_id"`
-	PageNumber sql.NullInt32   `json:"page_number"`
-	ChunkIndex int32           `json:"chunk_index"`
-	Content    string          `json:"content"`
-	Embedding  pgvector.Vector `json:"embedding"`
-	CreatedAt  time.Time       `json:"created_at"`
+	ChunkID        uuid.UUID       `json:"chunk_id"`
+	FileID         uuid.UUID       `json:"file_id"`
+	SubjectID      uuid.UUID       `json:"subject_id"`
+	PageNumber     sql.NullInt32   `json:"page_number"`
+	ChunkIndex     int32           `json:"chunk_index"`
+	Content        string          `json:"content"`
+	Embedding      pgvector.Vector `json:"embedding"`
+	CreatedAt      time.Time       `json:"created_at"`
+	ManuallyEdited bool            `json:"manually_edited"`
+	UpdatedAt      sql.NullTime    `json:"updated_at"`
+}
+
+type ChunkEdit struct {
+	EditID       uuid.UUID `json:"edit_id"`
+	FileID       uuid.UUID `json:"file_id"`
+	UserID       uuid.UUID `json:"user_id"`
+	Operation    string    `json:"operation"`
+	BeforeChunks []byte    `json:"before_chunks"`
+	AfterChunks  []byte    `json:"after_chunks"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type EmbeddingCache struct {
+	CacheKey  string          `json:"cache_key"`
+	Model     string          `json:"model"`
+	Embedding pgvector.Vector `json:"embedding"`
+	CreatedAt time.Time       `json:"created_at"`
 }
 
 type File struct {
-	FileID       uuid.UUID      `json:"file_id"`
-	SubjectID    uuid.UUID      `json:"subject_id"`
-	UserID       uuid.UUID      `json:"user_id"`
-	Name         string         `json:"name"`
-	StoragePath  string         `json:"storage_path"`
-	MimeType     string         `json:"mime_type"`
-	SizeBytes    int64          `json:"size_bytes"`
-	Status       FileStatus     `json:"status"`
-	ErrorMessage sql.NullString `json:"error_message"`
-	UploadedAt   time.Time      `json:"uploaded_at"`
-	ProcessedAt  sql.NullTime   `json:"processed_at"`
+	FileID           uuid.UUID      `json:"file_id"`
+	SubjectID        uuid.UUID      `json:"subject_id"`
+	UserID           uuid.UUID      `json:"user_id"`
+	Name             string         `json:"name"`
+	StoragePath      string         `json:"storage_path"`
+	MimeType         string         `json:"mime_type"`
+	SizeBytes        int64          `json:"size_bytes"`
+	Status           FileStatus     `json:"status"`
+	ErrorMessage     sql.NullString `json:"error_message"`
+	UploadedAt       time.Time      `json:"uploaded_at"`
+	ProcessedAt      sql.NullTime   `json:"processed_at"`
+	FolderPath       sql.NullString `json:"folder_path"`
+	SourceUrl        sql.NullString `json:"source_url"`
+	ContentSha256    sql.NullString `json:"content_sha256"`
+	PreviewPath      sql.NullString `json:"preview_path"`
+	PreviewPageCount int32          `json:"preview_page_count"`
 }
 
 type IngestJob struct {
@@ -244,25 +347,68 @@ type IngestJob struct {
 	CompletedAt  sql.NullTime   `json:"completed_at"`
 }
 
-type QaSession struct {
+type MaterialUpload struct {
+	UploadID          uuid.UUID      `json:"upload_id"`
+	FileID            uuid.UUID      `json:"file_id"`
+	SubjectID         uuid.UUID      `json:"subject_id"`
+	UserID            uuid.UUID      `json:"user_id"`
+	FileName          string         `json:"file_name"`
+	StorageKey        string         `json:"storage_key"`
+	MimeType          string         `json:"mime_type"`
+	SizeBytes         int64          `json:"size_bytes"`
+	ExpiresAt         time.Time      `json:"expires_at"`
+	CompletedAt       sql.NullTime   `json:"completed_at"`
+	CreatedAt         time.Time      `json:"created_at"`
+	MultipartUploadID sql.NullString `json:"multipart_upload_id"`
+	UploadedBytes     int64          `json:"uploaded_bytes"`
+}
+
+type MaterialUploadPart struct {
+	UploadID   uuid.UUID `json:"upload_id"`
+	PartNumber int32     `json:"part_number"`
+	Etag       string    `json:"etag"`
+	SizeBytes  int64     `json:"size_bytes"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type QaAnswerVersion struct {
 	SessionID  uuid.UUID             `json:"session_id"`
-	UserID     uuid.UUID             `json:"user_id"`
-	SubjectID  uuid.UUID             `json:"subject_id"`
-	Question   string                `json:"question"`
+	Version    int32                 `json:"version"`
 	Answer     sql.NullString        `json:"answer"`
 	Sources    pqtype.NullRawMessage `json:"sources"`
 	Feedback   sql.NullInt16         `json:"feedback"`
+	Status     QaSessionStatus       `json:"status"`
+	Retrieved  bool                  `json:"retrieved"`
 	CreatedAt  time.Time             `json:"created_at"`
 	AnsweredAt sql.NullTime          `json:"answered_at"`
+	Trace      pqtype.NullRawMessage `json:"trace"`
+}
+
+type QaSession struct {
+	SessionID       uuid.UUID             `json:"session_id"`
+	UserID          uuid.UUID             `json:"user_id"`
+	SubjectID       uuid.UUID             `json:"subject_id"`
+	Question        string                `json:"question"`
+	Answer          sql.NullString        `json:"answer"`
+	Sources         pqtype.NullRawMessage `json:"sources"`
+	Feedback        sql.NullInt16         `json:"feedback"`
+	CreatedAt       time.Time             `json:"created_at"`
+	AnsweredAt      sql.NullTime          `json:"answered_at"`
+	ExtraSubjectIds []uuid.UUID           `json:"extra_subject_ids"`
+	QueryExpansions pqtype.NullRawMessage `json:"query_expansions"`
+	Status          QaSessionStatus       `json:"status"`
+	SelectedVersion int32                 `json:"selected_version"`
+	LatestVersion   int32                 `json:"latest_version"`
 }
 
 type Subject struct {
-	SubjectID   uuid.UUID      `json:"subject_id"`
-	UserID      uuid.UUID      `json:"user_id"`
-	Name        string         `json:"name"`
-	LmsCourseID sql.NullString `json:"lms_course_id"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
+	SubjectID    uuid.UUID      `json:"subject_id"`
+	UserID       uuid.UUID      `json:"user_id"`
+	Name         string         `json:"name"`
+	LmsCourseID  sql.NullString `json:"lms_course_id"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	ResearchMode string         `json:"research_mode"`
 }
 
 type User struct {
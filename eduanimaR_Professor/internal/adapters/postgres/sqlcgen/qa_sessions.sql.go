@@ -9,7 +9,8 @@ import (
 	"context"
 	"database/sql"
 
-	uuid "github.com/google/uuid"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/sqlc-dev/pqtype"
 )
 
@@ -18,15 +19,17 @@ SELECT COUNT(*)
 FROM qa_sessions
 WHERE subject_id = $1
   AND user_id    = $2
+  AND ($3::text = '' OR question ILIKE $3::text)
 `
 
 type CountQASessionsBySubjectIDParams struct {
-	SubjectID uuid.UUID `json:"subject_id"`
-	UserID    uuid.UUID `json:"user_id"`
+	SubjectID       uuid.UUID `json:"subject_id"`
+	UserID          uuid.UUID `json:"user_id"`
+	QuestionPattern string    `json:"question_pattern"`
 }
 
 func (q *Queries) CountQASessionsBySubjectID(ctx context.Context, arg CountQASessionsBySubjectIDParams) (int64, error) {
-	row := q.db.QueryRowContext(ctx, countQASessionsBySubjectID, arg.SubjectID, arg.UserID)
+	row := q.db.QueryRowContext(ctx, countQASessionsBySubjectID, arg.SubjectID, arg.UserID, arg.QuestionPattern)
 	var count int64
 	err := row.Scan(&count)
 	return count, err
@@ -34,16 +37,17 @@ func (q *Queries) CountQASessionsBySubjectID(ctx context.Context, arg CountQASes
 
 const createQASession = `-- name: CreateQASession :one
 
-INSERT INTO qa_sessions (session_id, user_id, subject_id, question)
-VALUES ($1, $2, $3, $4)
-RETURNING session_id, user_id, subject_id, question, answer, sources, feedback, created_at, answered_at
+INSERT INTO qa_sessions (session_id, user_id, subject_id, question, extra_subject_ids)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING session_id, user_id, subject_id, question, answer, sources, feedback, created_at, answered_at, extra_subject_ids, query_expansions, status, selected_version, latest_version
 `
 
 type CreateQASessionParams struct {
-	SessionID uuid.UUID `json:"session_id"`
-	UserID    uuid.UUID `json:"user_id"`
-	SubjectID uuid.UUID `json:"subject_id"`
-	Question  string    `json:"question"`
+	SessionID       uuid.UUID   `json:"session_id"`
+	UserID          uuid.UUID   `json:"user_id"`
+	SubjectID       uuid.UUID   `json:"subject_id"`
+	Question        string      `json:"question"`
+	ExtraSubjectIds []uuid.UUID `json:"extra_subject_ids"`
 }
 
 // sql/queries/qa_sessions.sql
@@ -53,6 +57,7 @@ func (q *Queries) CreateQASession(ctx context.Context, arg CreateQASessionParams
 		arg.UserID,
 		arg.SubjectID,
 		arg.Question,
+		pq.Array(arg.ExtraSubjectIds),
 	)
 	var i QaSession
 	err := row.Scan(
@@ -65,12 +70,17 @@ func (q *Queries) CreateQASession(ctx context.Context, arg CreateQASessionParams
 		&i.Feedback,
 		&i.CreatedAt,
 		&i.AnsweredAt,
+		pq.Array(&i.ExtraSubjectIds),
+		&i.QueryExpansions,
+		&i.Status,
+		&i.SelectedVersion,
+		&i.LatestVersion,
 	)
 	return i, err
 }
 
 const getQASessionByID = `-- name: GetQASessionByID :one
-SELECT session_id, user_id, subject_id, question, answer, sources, feedback, created_at, answered_at
+SELECT session_id, user_id, subject_id, question, answer, sources, feedback, created_at, answered_at, extra_subject_ids, query_expansions, status, selected_version, latest_version
 FROM qa_sessions
 WHERE session_id = $1
 `
@@ -88,12 +98,17 @@ func (q *Queries) GetQASessionByID(ctx context.Context, sessionID uuid.UUID) (Qa
 		&i.Feedback,
 		&i.CreatedAt,
 		&i.AnsweredAt,
+		pq.Array(&i.ExtraSubjectIds),
+		&i.QueryExpansions,
+		&i.Status,
+		&i.SelectedVersion,
+		&i.LatestVersion,
 	)
 	return i, err
 }
 
 const getQASessionByIDAndUserID = `-- name: GetQASessionByIDAndUserID :one
-SELECT session_id, user_id, subject_id, question, answer, sources, feedback, created_at, answered_at
+SELECT session_id, user_id, subject_id, question, answer, sources, feedback, created_at, answered_at, extra_subject_ids, query_expansions, status, selected_version, latest_version
 FROM qa_sessions
 WHERE session_id = $1
   AND user_id    = $2
@@ -117,6 +132,11 @@ func (q *Queries) GetQASessionByIDAndUserID(ctx context.Context, arg GetQASessio
 		&i.Feedback,
 		&i.CreatedAt,
 		&i.AnsweredAt,
+		pq.Array(&i.ExtraSubjectIds),
+		&i.QueryExpansions,
+		&i.Status,
+		&i.SelectedVersion,
+		&i.LatestVersion,
 	)
 	return i, err
 }
@@ -125,28 +145,37 @@ const listQASessionsBySubjectID = `-- name: ListQASessionsBySubjectID :many
 SELECT
     session_id, user_id, subject_id,
     question, answer, sources, feedback,
-    created_at, answered_at
+    created_at, answered_at, extra_subject_ids, query_expansions, status,
+    selected_version, latest_version
 FROM qa_sessions
 WHERE subject_id = $1
   AND user_id    = $2
-ORDER BY created_at DESC
-LIMIT  $3
-OFFSET $4
+  AND ($3::timestamptz IS NULL
+       OR (created_at, session_id) < ($3::timestamptz, $4::uuid))
+  AND ($5::text = '' OR question ILIKE $5::text)
+ORDER BY created_at DESC, session_id DESC
+LIMIT $6
 `
 
 type ListQASessionsBySubjectIDParams struct {
-	SubjectID uuid.UUID `json:"subject_id"`
-	UserID    uuid.UUID `json:"user_id"`
-	Limit     int32     `json:"limit"`
-	Offset    int32     `json:"offset"`
+	SubjectID       uuid.UUID     `json:"subject_id"`
+	UserID          uuid.UUID     `json:"user_id"`
+	CursorCreatedAt sql.NullTime  `json:"cursor_created_at"`
+	CursorSessionID uuid.NullUUID `json:"cursor_session_id"`
+	QuestionPattern string        `json:"question_pattern"`
+	MaxResults      int32         `json:"max_results"`
 }
 
+// 新しい順のキーセットページング（cursor_created_at / cursor_session_id が NULL の場合は最新から）
+// question_pattern: 質問の ILIKE パターン（空の場合は絞り込まない）
 func (q *Queries) ListQASessionsBySubjectID(ctx context.Context, arg ListQASessionsBySubjectIDParams) ([]QaSession, error) {
 	rows, err := q.db.QueryContext(ctx, listQASessionsBySubjectID,
 		arg.SubjectID,
 		arg.UserID,
-		arg.Limit,
-		arg.Offset,
+		arg.CursorCreatedAt,
+		arg.CursorSessionID,
+		arg.QuestionPattern,
+		arg.MaxResults,
 	)
 	if err != nil {
 		return nil, err
@@ -165,6 +194,11 @@ func (q *Queries) ListQASessionsBySubjectID(ctx context.Context, arg ListQASessi
 			&i.Feedback,
 			&i.CreatedAt,
 			&i.AnsweredAt,
+			pq.Array(&i.ExtraSubjectIds),
+			&i.QueryExpansions,
+			&i.Status,
+			&i.SelectedVersion,
+			&i.LatestVersion,
 		); err != nil {
 			return nil, err
 		}
@@ -179,24 +213,47 @@ func (q *Queries) ListQASessionsBySubjectID(ctx context.Context, arg ListQASessi
 	return items, nil
 }
 
-const updateQASessionAnswer = `-- name: UpdateQASessionAnswer :one
+const selectQASessionVersion = `-- name: SelectQASessionVersion :execrows
 UPDATE qa_sessions
-SET
-    answer      = $2,
-    sources     = $3,
-    answered_at = NOW()
+SET selected_version = $2
 WHERE session_id = $1
-RETURNING session_id, user_id, subject_id, question, answer, sources, feedback, created_at, answered_at
+  AND user_id    = $3
+  AND $2 BETWEEN 1 AND latest_version
 `
 
-type UpdateQASessionAnswerParams struct {
-	SessionID uuid.UUID             `json:"session_id"`
-	Answer    sql.NullString        `json:"answer"`
-	Sources   pqtype.NullRawMessage `json:"sources"`
+type SelectQASessionVersionParams struct {
+	SessionID       uuid.UUID `json:"session_id"`
+	SelectedVersion int32     `json:"selected_version"`
+	UserID          uuid.UUID `json:"user_id"`
+}
+
+// 表示する回答版を選ぶ（存在しない版の場合は 0 行）
+func (q *Queries) SelectQASessionVersion(ctx context.Context, arg SelectQASessionVersionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, selectQASessionVersion, arg.SessionID, arg.SelectedVersion, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
-func (q *Queries) UpdateQASessionAnswer(ctx context.Context, arg UpdateQASessionAnswerParams) (QaSession, error) {
-	row := q.db.QueryRowContext(ctx, updateQASessionAnswer, arg.SessionID, arg.Answer, arg.Sources)
+const syncQASessionWithSelectedVersion = `-- name: SyncQASessionWithSelectedVersion :one
+UPDATE qa_sessions s
+SET
+    answer      = v.answer,
+    sources     = v.sources,
+    feedback    = v.feedback,
+    status      = v.status,
+    answered_at = v.answered_at
+FROM qa_answer_versions v
+WHERE s.session_id = $1
+  AND v.session_id = s.session_id
+  AND v.version    = s.selected_version
+RETURNING s.session_id, s.user_id, s.subject_id, s.question, s.answer, s.sources, s.feedback, s.created_at, s.answered_at, s.extra_subject_ids, s.query_expansions, s.status, s.selected_version, s.latest_version
+`
+
+// 選ばれている回答版の回答・出典・フィードバック・状態をセッションに反映する
+func (q *Queries) SyncQASessionWithSelectedVersion(ctx context.Context, sessionID uuid.UUID) (QaSession, error) {
+	row := q.db.QueryRowContext(ctx, syncQASessionWithSelectedVersion, sessionID)
 	var i QaSession
 	err := row.Scan(
 		&i.SessionID,
@@ -208,37 +265,27 @@ func (q *Queries) UpdateQASessionAnswer(ctx context.Context, arg UpdateQASession
 		&i.Feedback,
 		&i.CreatedAt,
 		&i.AnsweredAt,
+		pq.Array(&i.ExtraSubjectIds),
+		&i.QueryExpansions,
+		&i.Status,
+		&i.SelectedVersion,
+		&i.LatestVersion,
 	)
 	return i, err
 }
 
-const updateQASessionFeedback = `-- name: UpdateQASessionFeedback :one
+const updateQASessionQueryExpansions = `-- name: UpdateQASessionQueryExpansions :exec
 UPDATE qa_sessions
-SET feedback = $2
+SET query_expansions = $2
 WHERE session_id = $1
-  AND user_id    = $3
-RETURNING session_id, user_id, subject_id, question, answer, sources, feedback, created_at, answered_at
 `
 
-type UpdateQASessionFeedbackParams struct {
-	SessionID uuid.UUID     `json:"session_id"`
-	Feedback  sql.NullInt16 `json:"feedback"`
-	UserID    uuid.UUID     `json:"user_id"`
+type UpdateQASessionQueryExpansionsParams struct {
+	SessionID       uuid.UUID             `json:"session_id"`
+	QueryExpansions pqtype.NullRawMessage `json:"query_expansions"`
 }
 
-func (q *Queries) UpdateQASessionFeedback(ctx context.Context, arg UpdateQASessionFeedbackParams) (QaSession, error) {
-	row := q.db.QueryRowContext(ctx, updateQASessionFeedback, arg.SessionID, arg.Feedback, arg.UserID)
-	var i QaSession
-	err := row.Scan(
-		&i.SessionID,
-		&i.UserID,
-		&i.SubjectID,
-		&i.Question,
-		&i.Answer,
-		&i.Sources,
-		&i.Feedback,
-		&i.CreatedAt,
-		&i.AnsweredAt,
-	)
-	return i, err
+func (q *Queries) UpdateQASessionQueryExpansions(ctx context.Context, arg UpdateQASessionQueryExpansionsParams) error {
+	_, err := q.db.ExecContext(ctx, updateQASessionQueryExpansions, arg.SessionID, arg.QueryExpansions)
+	return err
 }
@@ -9,20 +9,21 @@ import (
 	"context"
 	"database/sql"
 
-	uuid "github.com/google/uuid"
+	"github.com/google/uuid"
 )
 
 const createSubject = `-- name: CreateSubject :one
-INSERT INTO subjects (subject_id, user_id, name, lms_course_id)
-VALUES ($1, $2, $3, $4)
-RETURNING subject_id, user_id, name, lms_course_id, created_at, updated_at
+INSERT INTO subjects (subject_id, user_id, name, lms_course_id, research_mode)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING subject_id, user_id, name, lms_course_id, created_at, updated_at, research_mode
 `
 
 type CreateSubjectParams struct {
-	SubjectID   uuid.UUID      `json:"subject_id"`
-	UserID      uuid.UUID      `json:"user_id"`
-	Name        string         `json:"name"`
-	LmsCourseID sql.NullString `json:"lms_course_id"`
+	SubjectID    uuid.UUID      `json:"subject_id"`
+	UserID       uuid.UUID      `json:"user_id"`
+	Name         string         `json:"name"`
+	LmsCourseID  sql.NullString `json:"lms_course_id"`
+	ResearchMode string         `json:"research_mode"`
 }
 
 func (q *Queries) CreateSubject(ctx context.Context, arg CreateSubjectParams) (Subject, error) {
@@ -31,6 +32,7 @@ func (q *Queries) CreateSubject(ctx context.Context, arg CreateSubjectParams) (S
 		arg.UserID,
 		arg.Name,
 		arg.LmsCourseID,
+		arg.ResearchMode,
 	)
 	var i Subject
 	err := row.Scan(
@@ -40,6 +42,7 @@ func (q *Queries) CreateSubject(ctx context.Context, arg CreateSubjectParams) (S
 		&i.LmsCourseID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ResearchMode,
 	)
 	return i, err
 }
@@ -61,7 +64,7 @@ func (q *Queries) DeleteSubject(ctx context.Context, arg DeleteSubjectParams) er
 }
 
 const getSubjectByID = `-- name: GetSubjectByID :one
-SELECT subject_id, user_id, name, lms_course_id, created_at, updated_at
+SELECT subject_id, user_id, name, lms_course_id, created_at, updated_at, research_mode
 FROM subjects
 WHERE subject_id = $1
 `
@@ -76,12 +79,13 @@ func (q *Queries) GetSubjectByID(ctx context.Context, subjectID uuid.UUID) (Subj
 		&i.LmsCourseID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ResearchMode,
 	)
 	return i, err
 }
 
 const getSubjectByIDAndUserID = `-- name: GetSubjectByIDAndUserID :one
-SELECT subject_id, user_id, name, lms_course_id, created_at, updated_at
+SELECT subject_id, user_id, name, lms_course_id, created_at, updated_at, research_mode
 FROM subjects
 WHERE subject_id = $1
   AND user_id    = $2
@@ -102,13 +106,14 @@ func (q *Queries) GetSubjectByIDAndUserID(ctx context.Context, arg GetSubjectByI
 		&i.LmsCourseID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ResearchMode,
 	)
 	return i, err
 }
 
 const listSubjectsByUserID = `-- name: ListSubjectsByUserID :many
 
-SELECT subject_id, user_id, name, lms_course_id, created_at, updated_at
+SELECT subject_id, user_id, name, lms_course_id, created_at, updated_at, research_mode
 FROM subjects
 WHERE user_id = $1
 ORDER BY created_at DESC
@@ -131,6 +136,7 @@ func (q *Queries) ListSubjectsByUserID(ctx context.Context, userID uuid.UUID) ([
 			&i.LmsCourseID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ResearchMode,
 		); err != nil {
 			return nil, err
 		}
@@ -151,7 +157,7 @@ SET name       = $2,
     updated_at = NOW()
 WHERE subject_id = $1
   AND user_id    = $3
-RETURNING subject_id, user_id, name, lms_course_id, created_at, updated_at
+RETURNING subject_id, user_id, name, lms_course_id, created_at, updated_at, research_mode
 `
 
 type UpdateSubjectNameParams struct {
@@ -170,6 +176,37 @@ func (q *Queries) UpdateSubjectName(ctx context.Context, arg UpdateSubjectNamePa
 		&i.LmsCourseID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ResearchMode,
+	)
+	return i, err
+}
+
+const updateSubjectResearchMode = `-- name: UpdateSubjectResearchMode :one
+UPDATE subjects
+SET research_mode = $2,
+    updated_at    = NOW()
+WHERE subject_id = $1
+  AND user_id    = $3
+RETURNING subject_id, user_id, name, lms_course_id, created_at, updated_at, research_mode
+`
+
+type UpdateSubjectResearchModeParams struct {
+	SubjectID    uuid.UUID `json:"subject_id"`
+	ResearchMode string    `json:"research_mode"`
+	UserID       uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) UpdateSubjectResearchMode(ctx context.Context, arg UpdateSubjectResearchModeParams) (Subject, error) {
+	row := q.db.QueryRowContext(ctx, updateSubjectResearchMode, arg.SubjectID, arg.ResearchMode, arg.UserID)
+	var i Subject
+	err := row.Scan(
+		&i.SubjectID,
+		&i.UserID,
+		&i.Name,
+		&i.LmsCourseID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ResearchMode,
 	)
 	return i, err
 }
@@ -9,7 +9,7 @@ import (
 	"context"
 	"database/sql"
 
-	uuid "github.com/google/uuid"
+	"github.com/google/uuid"
 )
 
 const createUser = `-- name: CreateUser :one
@@ -65,10 +65,11 @@ func (r *subjectRepo) Create(ctx context.Context, s *domain.Subject) error {
 		ns = sql.NullString{String: *s.LMSCourseID, Valid: true}
 	}
 	created, err := r.q.CreateSubject(ctx, sqlcgen.CreateSubjectParams{
-		SubjectID:   s.ID,
-		UserID:      s.UserID,
-		Name:        s.Name,
-		LmsCourseID: ns,
+		SubjectID:    s.ID,
+		UserID:       s.UserID,
+		Name:         s.Name,
+		LmsCourseID:  ns,
+		ResearchMode: string(s.ResearchMode),
 	})
 	if err != nil {
 		return err
@@ -93,6 +94,21 @@ func (r *subjectRepo) UpdateName(ctx context.Context, id, userID uuid.UUID, name
 	return toSubjectDomain(row), nil
 }
 
+func (r *subjectRepo) UpdateResearchMode(ctx context.Context, id, userID uuid.UUID, mode domain.ResearchMode) (*domain.Subject, error) {
+	row, err := r.q.UpdateSubjectResearchMode(ctx, sqlcgen.UpdateSubjectResearchModeParams{
+		SubjectID:    id,
+		UserID:       userID,
+		ResearchMode: string(mode),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return toSubjectDomain(row), nil
+}
+
 func (r *subjectRepo) Delete(ctx context.Context, id, userID uuid.UUID) error {
 	return r.q.DeleteSubject(ctx, sqlcgen.DeleteSubjectParams{
 		SubjectID: id,
@@ -107,6 +123,8 @@ func toSubjectDomain(row sqlcgen.Subject) *domain.Subject {
 		Name:      row.Name,
 		CreatedAt: row.CreatedAt,
 		UpdatedAt: row.UpdatedAt,
+
+		ResearchMode: domain.ResearchMode(row.ResearchMode),
 	}
 	if row.LmsCourseID.Valid {
 		s.LMSCourseID = &row.LmsCourseID.String
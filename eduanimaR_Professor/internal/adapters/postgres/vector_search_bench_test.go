@@ -0,0 +1,160 @@
+//go:build integration
+
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	pgvector "github.com/pgvector/pgvector-go"
+
+	pgadapter "github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/postgres"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+)
+
+// BenchmarkSearchByVectorRecall はチャンクの少ない subject に対するベクトル検索の recall@k を計測する。
+// 大きな subject（BENCH_LARGE_CHUNKS 件）と小さな subject（BENCH_SMALL_CHUNKS 件）を同じテーブルに作り、
+// 小さな subject での検索結果を厳密検索の結果と比べる。
+//
+//	DATABASE_URL=... go test -tags=integration -run '^$' -bench SearchByVectorRecall ./internal/adapters/postgres/
+func BenchmarkSearchByVectorRecall(b *testing.B) {
+	const k = 10
+	db := openBenchDB(b)
+	ctx := context.Background()
+	largeN := benchEnvInt("BENCH_LARGE_CHUNKS", 20000)
+	smallN := benchEnvInt("BENCH_SMALL_CHUNKS", 200)
+
+	userID := seedBenchUser(ctx, b, db)
+	seedBenchSubject(ctx, b, db, userID, largeN)
+	small := seedBenchSubject(ctx, b, db, userID, smallN)
+
+	queries := make([]pgvector.Vector, 20)
+	for i := range queries {
+		queries[i] = randomVector()
+	}
+	exact := pgadapter.NewChunkRepo(db, pgadapter.VectorSearchOptions{ExactSearchMaxChunks: largeN + smallN})
+	truth := make([]map[uuid.UUID]struct{}, len(queries))
+	for i, q := range queries {
+		results, err := exact.SearchByVector(ctx, []uuid.UUID{small}, q, k, domain.SearchFilter{})
+		if err != nil {
+			b.Fatalf("exact search: %v", err)
+		}
+		truth[i] = make(map[uuid.UUID]struct{}, len(results))
+		for _, r := range results {
+			truth[i][r.ChunkID] = struct{}{}
+		}
+	}
+
+	cases := []struct {
+		name string
+		opts pgadapter.VectorSearchOptions
+	}{
+		{"hnsw_default", pgadapter.VectorSearchOptions{}},
+		{"hnsw_ef100", pgadapter.VectorSearchOptions{EfSearch: 100}},
+		{"hnsw_ef100_strict_order", pgadapter.VectorSearchOptions{EfSearch: 100, IterativeScan: pgadapter.IterativeScanStrictOrder}},
+		{"hnsw_ef100_relaxed_order", pgadapter.VectorSearchOptions{EfSearch: 100, IterativeScan: pgadapter.IterativeScanRelaxedOrder}},
+		{"exact_small_subjects", pgadapter.VectorSearchOptions{EfSearch: 100, ExactSearchMaxChunks: smallN}},
+	}
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			repo := pgadapter.NewChunkRepo(db, tc.opts)
+			hits, total := 0, 0
+			for i := 0; b.Loop(); i++ {
+				qi := i % len(queries)
+				results, err := repo.SearchByVector(ctx, []uuid.UUID{small}, queries[qi], k, domain.SearchFilter{})
+				if err != nil {
+					b.Fatalf("search: %v", err)
+				}
+				for _, r := range results {
+					if _, ok := truth[qi][r.ChunkID]; ok {
+						hits++
+					}
+				}
+				total += len(truth[qi])
+			}
+			b.ReportMetric(float64(hits)/float64(max(total, 1)), fmt.Sprintf("recall@%d", k))
+		})
+	}
+}
+
+func openBenchDB(b *testing.B) *sql.DB {
+	b.Helper()
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		b.Skip("DATABASE_URL is not set")
+	}
+	cfg, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		b.Fatalf("parse DATABASE_URL: %v", err)
+	}
+	db := stdlib.OpenDB(*cfg)
+	b.Cleanup(func() { db.Close() })
+	if err := db.Ping(); err != nil {
+		b.Fatalf("ping: %v", err)
+	}
+	return db
+}
+
+// seedBenchUser は計測用のユーザーを作成する（終了時に subject・教材・チャンクごと削除する）。
+func seedBenchUser(ctx context.Context, b *testing.B, db *sql.DB) uuid.UUID {
+	b.Helper()
+	userID := uuid.New()
+	if _, err := db.ExecContext(ctx, `INSERT INTO users (user_id, email) VALUES ($1, $2)`,
+		userID, "bench-"+userID.String()+"@example.com"); err != nil {
+		b.Fatalf("insert user: %v", err)
+	}
+	b.Cleanup(func() {
+		if _, err := db.ExecContext(context.Background(), `DELETE FROM users WHERE user_id = $1`, userID); err != nil {
+			b.Logf("cleanup user: %v", err)
+		}
+	})
+	return userID
+}
+
+// seedBenchSubject は n 件のランダムなベクトルを持つ subject を作成する。
+func seedBenchSubject(ctx context.Context, b *testing.B, db *sql.DB, userID uuid.UUID, n int) uuid.UUID {
+	b.Helper()
+	subjectID, fileID := uuid.New(), uuid.New()
+	if _, err := db.ExecContext(ctx, `INSERT INTO subjects (subject_id, user_id, name) VALUES ($1, $2, $3)`,
+		subjectID, userID, "bench"); err != nil {
+		b.Fatalf("insert subject: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO files (file_id, subject_id, user_id, name, storage_path, mime_type, size_bytes, status)
+		VALUES ($1, $2, $3, 'bench.pdf', 'minio://bench/bench.pdf', 'application/pdf', 0, 'ready')`,
+		fileID, subjectID, userID); err != nil {
+		b.Fatalf("insert file: %v", err)
+	}
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO chunks (file_id, subject_id, chunk_index, content, embedding)
+		SELECT $1, $2, g, 'bench',
+		       (SELECT array_agg(random() - 0.5 + g * 0)::vector FROM generate_series(1, 768))
+		FROM generate_series(0, $3::int - 1) AS g`,
+		fileID, subjectID, n); err != nil {
+		b.Fatalf("insert chunks: %v", err)
+	}
+	return subjectID
+}
+
+func randomVector() pgvector.Vector {
+	v := make([]float32, 768)
+	for i := range v {
+		v[i] = rand.Float32() - 0.5
+	}
+	return pgvector.NewVector(v)
+}
+
+func benchEnvInt(key string, defaultVal int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n > 0 {
+		return n
+	}
+	return defaultVal
+}
@@ -0,0 +1,94 @@
+// Package preview は教材のページプレビュー画像を生成するアダプターを提供する。
+package preview
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+// pageFilePattern は pdftoppm の出力ファイル名（"{prefix}-{page}.png"）にマッチする。
+// ページ番号の桁数は総ページ数によって変わる（例: page-1.png / page-01.png）。
+var pageFilePattern = regexp.MustCompile(`^page-(\d+)\.png$`)
+
+type popplerRenderer struct {
+	binPath string // pdftoppm の実行ファイルパス
+	width   int    // サムネイルの横幅（px）
+}
+
+// NewPopplerRenderer は poppler-utils の pdftoppm を使って PDF の各ページを PNG に変換する
+// PageRenderer 実装を返す。binPath が空の場合は PATH から pdftoppm を探す。
+func NewPopplerRenderer(binPath string, width int) (ports.PageRenderer, error) {
+	if binPath == "" {
+		binPath = "pdftoppm"
+	}
+	resolved, err := exec.LookPath(binPath)
+	if err != nil {
+		return nil, fmt.Errorf("pdftoppm not found: %w", err)
+	}
+	return &popplerRenderer{binPath: resolved, width: width}, nil
+}
+
+// RenderPages は PDF の先頭 maxPages ページを横幅 width の PNG に変換する。
+// PDF 以外は (nil, nil) を返す（画像教材は原本をそのままプレビューに使う）。
+func (r *popplerRenderer) RenderPages(ctx context.Context, content []byte, mimeType string, maxPages int) ([]ports.PageImage, error) {
+	if mimeType != "application/pdf" {
+		return nil, nil
+	}
+
+	dir, err := os.MkdirTemp("", "eduanima-preview-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "source.pdf")
+	if err := os.WriteFile(src, content, 0o600); err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		"-png",
+		"-scale-to-x", strconv.Itoa(r.width),
+		"-scale-to-y", "-1", // アスペクト比を維持
+	}
+	if maxPages > 0 {
+		args = append(args, "-l", strconv.Itoa(maxPages))
+	}
+	args = append(args, src, filepath.Join(dir, "page"))
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.binPath, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("pdftoppm: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	pages := make([]ports.PageImage, 0, len(entries))
+	for _, e := range entries {
+		m := pageFilePattern.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(m[1])
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, ports.PageImage{PageNumber: n, Data: data})
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].PageNumber < pages[j].PageNumber })
+	return pages, nil
+}
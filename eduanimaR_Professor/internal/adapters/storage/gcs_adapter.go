@@ -0,0 +1,307 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	gcs "cloud.google.com/go/storage"
+	"github.com/google/uuid"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+const (
+	// gcsMultipartPrefix はマルチパートアップロードの一時パートを置くプレフィックス。
+	// 教材キー（{userID}/...）と衝突しないようバケット直下の別階層に置く。
+	gcsMultipartPrefix = ".multipart/"
+	// gcsMaxComposeSources は 1 回の compose で結合できる最大オブジェクト数（GCS の制約）
+	gcsMaxComposeSources = 32
+)
+
+type gcsAdapter struct {
+	client    *gcs.Client
+	bucket    string
+	projectID string // EnsureBucket でバケットを作成する場合のみ使用
+}
+
+// NewGCSAdapter は Google Cloud Storage を使った ObjectStorage 実装を返す。
+// 返却値は ports.MultipartStorage と BucketEnsurer も実装する。
+// endpoint を指定した場合は認証なしでその URL に接続する（fake-gcs-server 等のローカル検証用）。
+// 未指定の場合は Application Default Credentials を使う。
+func NewGCSAdapter(ctx context.Context, bucket, projectID, endpoint string) (ports.ObjectStorage, error) {
+	var opts []option.ClientOption
+	if endpoint != "" {
+		opts = append(opts,
+			option.WithEndpoint(endpoint),
+			option.WithoutAuthentication(),
+			gcs.WithJSONReads(), // XML API を持たないエミュレーターでも読めるようにする
+		)
+	}
+	client, err := gcs.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create gcs client: %w", err)
+	}
+	return &gcsAdapter{client: client, bucket: bucket, projectID: projectID}, nil
+}
+
+// EnsureBucket はバケットが存在しない場合に作成する。
+func (a *gcsAdapter) EnsureBucket(ctx context.Context) error {
+	_, err := a.client.Bucket(a.bucket).Attrs(ctx)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gcs.ErrBucketNotExist) {
+		return err
+	}
+	return a.client.Bucket(a.bucket).Create(ctx, a.projectID, nil)
+}
+
+// resolve はオブジェクトキーまたは "gs://bucket/key" をオブジェクトハンドルに変換する。
+func (a *gcsAdapter) resolve(location string) (*gcs.ObjectHandle, error) {
+	bucket, key, ok := splitBucketPath(location, SchemeGCS, a.bucket)
+	if !ok {
+		return nil, fmt.Errorf("not a gcs storage path %q: %w", location, domain.ErrInvalidInput)
+	}
+	return a.client.Bucket(bucket).Object(key), nil
+}
+
+// StoragePath は "gs://{bucket}/{key}" を返す。
+func (a *gcsAdapter) StoragePath(key string) string {
+	return formatPath(SchemeGCS, a.bucket, key)
+}
+
+// Upload はファイルを GCS にアップロードし、ストレージパスを返す。
+func (a *gcsAdapter) Upload(ctx context.Context, key string, reader io.Reader, _ int64, contentType string) (string, error) {
+	obj, err := a.resolve(key)
+	if err != nil {
+		return "", err
+	}
+	if err := a.write(ctx, obj, reader, contentType); err != nil {
+		return "", err
+	}
+	return formatPath(SchemeGCS, obj.BucketName(), obj.ObjectName()), nil
+}
+
+// Download は GCS からファイルをダウンロードする。
+func (a *gcsAdapter) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	obj, err := a.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	r, err := obj.NewReader(ctx)
+	if err != nil {
+		if errors.Is(err, gcs.ErrObjectNotExist) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// Delete は GCS からファイルを削除する。存在しない場合もエラーにしない。
+func (a *gcsAdapter) Delete(ctx context.Context, key string) error {
+	obj, err := a.resolve(key)
+	if err != nil {
+		return err
+	}
+	if err := obj.Delete(ctx); err != nil && !errors.Is(err, gcs.ErrObjectNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Stat は GCS 上のオブジェクトのメタデータを返す。
+// オブジェクトが存在しない場合は domain.ErrNotFound を返す。
+func (a *gcsAdapter) Stat(ctx context.Context, key string) (*ports.ObjectInfo, error) {
+	obj, err := a.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		if errors.Is(err, gcs.ErrObjectNotExist) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &ports.ObjectInfo{
+		Key:         attrs.Name,
+		Size:        attrs.Size,
+		ContentType: attrs.ContentType,
+	}, nil
+}
+
+// GetPresignedURL は V4 署名付き一時 GET URL を生成する。
+// 署名にはサービスアカウントの認証情報（または IAM signBlob 権限）が必要。
+func (a *gcsAdapter) GetPresignedURL(_ context.Context, key string, expiry time.Duration) (string, error) {
+	return a.signedURL(key, http.MethodGet, expiry)
+}
+
+// GetPresignedPutURL は V4 署名付き一時 PUT URL を生成する。
+func (a *gcsAdapter) GetPresignedPutURL(_ context.Context, key string, expiry time.Duration) (string, error) {
+	return a.signedURL(key, http.MethodPut, expiry)
+}
+
+func (a *gcsAdapter) signedURL(location, method string, expiry time.Duration) (string, error) {
+	obj, err := a.resolve(location)
+	if err != nil {
+		return "", err
+	}
+	return a.client.Bucket(obj.BucketName()).SignedURL(obj.ObjectName(), &gcs.SignedURLOptions{
+		Scheme:  gcs.SigningSchemeV4,
+		Method:  method,
+		Expires: time.Now().Add(expiry),
+	})
+}
+
+// ListObjects はバケット内のキーが prefix で始まるオブジェクトを列挙する。
+// マルチパートアップロードの一時オブジェクト（gcsMultipartPrefix 配下）は除く。
+func (a *gcsAdapter) ListObjects(ctx context.Context, prefix string, fn func(ports.ObjectInfo) error) error {
+	it := a.client.Bucket(a.bucket).Objects(ctx, &gcs.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(attrs.Name, gcsMultipartPrefix) {
+			continue
+		}
+		if err := fn(ports.ObjectInfo{
+			Key:          attrs.Name,
+			Size:         attrs.Size,
+			ContentType:  attrs.ContentType,
+			StoragePath:  formatPath(SchemeGCS, a.bucket, attrs.Name),
+			LastModified: attrs.Updated,
+		}); err != nil {
+			return err
+		}
+	}
+}
+
+func (a *gcsAdapter) write(ctx context.Context, obj *gcs.ObjectHandle, reader io.Reader, contentType string) error {
+	w := obj.NewWriter(ctx)
+	w.ContentType = contentType
+	if _, err := io.Copy(w, reader); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// ─── マルチパートアップロード（ports.MultipartStorage） ───────────
+// GCS には S3 互換のマルチパート API がないため、パートを一時オブジェクトとして保存し、
+// 完了時に compose で結合する。
+
+// CreateMultipartUpload はアップロード ID を発行し、Content-Type をメタ情報として記録する。
+func (a *gcsAdapter) CreateMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	uploadID := uuid.NewString()
+	meta := a.client.Bucket(a.bucket).Object(gcsMultipartPrefix + uploadID + "/meta")
+	w := meta.NewWriter(ctx)
+	w.ContentType = contentType
+	w.Metadata = map[string]string{"key": key}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("create multipart meta: %w", err)
+	}
+	return uploadID, nil
+}
+
+// UploadPart は 1 パートを一時オブジェクトとして保存し、ETag を返す。
+func (a *gcsAdapter) UploadPart(ctx context.Context, _, uploadID string, partNumber int, reader io.Reader, _ int64) (string, error) {
+	part := a.client.Bucket(a.bucket).Object(gcsPartName(uploadID, partNumber))
+	w := part.NewWriter(ctx)
+	if _, err := io.Copy(w, reader); err != nil {
+		w.Close()
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return w.Attrs().Etag, nil
+}
+
+// CompleteMultipartUpload はパートを compose で結合してオブジェクトを確定し、一時オブジェクトを削除する。
+// パート数が compose の上限を超える場合は中間オブジェクトを経由して段階的に結合する。
+func (a *gcsAdapter) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []ports.MultipartPart) error {
+	if len(parts) == 0 {
+		return fmt.Errorf("no parts to complete: %w", domain.ErrInvalidInput)
+	}
+	bkt := a.client.Bucket(a.bucket)
+	metaAttrs, err := bkt.Object(gcsMultipartPrefix + uploadID + "/meta").Attrs(ctx)
+	if err != nil {
+		return fmt.Errorf("read multipart meta: %w", err)
+	}
+
+	// UploadPart が返した ETag と一致するパートだけを結合する。
+	// 検証した世代を compose の入力に指定し、検証後の上書きも取り込まない。
+	srcs := make([]*gcs.ObjectHandle, len(parts))
+	for i, p := range parts {
+		part := bkt.Object(gcsPartName(uploadID, p.PartNumber))
+		attrs, err := part.Attrs(ctx)
+		if err != nil {
+			if errors.Is(err, gcs.ErrObjectNotExist) {
+				return fmt.Errorf("part %d not found: %w", p.PartNumber, domain.ErrInvalidInput)
+			}
+			return fmt.Errorf("read part %d: %w", p.PartNumber, err)
+		}
+		if attrs.Etag != p.ETag {
+			return fmt.Errorf("part %d etag mismatch: %w", p.PartNumber, domain.ErrInvalidInput)
+		}
+		srcs[i] = part.Generation(attrs.Generation)
+	}
+	for round := 0; len(srcs) > gcsMaxComposeSources; round++ {
+		var next []*gcs.ObjectHandle
+		for i := 0; i < len(srcs); i += gcsMaxComposeSources {
+			end := min(i+gcsMaxComposeSources, len(srcs))
+			dst := bkt.Object(fmt.Sprintf("%s%s/compose-%d-%d", gcsMultipartPrefix, uploadID, round, i))
+			if _, err := dst.ComposerFrom(srcs[i:end]...).Run(ctx); err != nil {
+				return fmt.Errorf("compose intermediate: %w", err)
+			}
+			next = append(next, dst)
+		}
+		srcs = next
+	}
+
+	composer := bkt.Object(key).ComposerFrom(srcs...)
+	composer.ContentType = metaAttrs.ContentType
+	if _, err := composer.Run(ctx); err != nil {
+		return fmt.Errorf("compose object: %w", err)
+	}
+	return a.deleteMultipartObjects(ctx, uploadID)
+}
+
+// AbortMultipartUpload は一時オブジェクトを削除する。
+func (a *gcsAdapter) AbortMultipartUpload(ctx context.Context, _, uploadID string) error {
+	return a.deleteMultipartObjects(ctx, uploadID)
+}
+
+func (a *gcsAdapter) deleteMultipartObjects(ctx context.Context, uploadID string) error {
+	bkt := a.client.Bucket(a.bucket)
+	it := bkt.Objects(ctx, &gcs.Query{Prefix: gcsMultipartPrefix + uploadID + "/"})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := bkt.Object(attrs.Name).Delete(ctx); err != nil && !errors.Is(err, gcs.ErrObjectNotExist) {
+			return err
+		}
+	}
+}
+
+func gcsPartName(uploadID string, partNumber int) string {
+	return fmt.Sprintf("%s%s/part-%05d", gcsMultipartPrefix, uploadID, partNumber)
+}
@@ -0,0 +1,520 @@
+package storage_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/storage"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+const gcsTestBucket = "materials"
+
+// ─── GCS JSON API のフェイク ──────────────────────────────────────
+// アダプターが使う JSON API（アップロード・取得・削除・一覧・compose）だけをメモリ上で実装する。
+
+type fakeGCSObject struct {
+	data        []byte
+	contentType string
+	metadata    map[string]string
+	generation  int64
+	updated     time.Time
+}
+
+type fakeGCS struct {
+	mu             sync.Mutex
+	objects        map[string]*fakeGCSObject // "{bucket}/{name}" → オブジェクト
+	generation     int64
+	composeSources []int // compose 1 回ごとの入力オブジェクト数
+}
+
+// newFakeGCS はフェイクサーバーに接続した GCS アダプターを返す。
+func newFakeGCS(t *testing.T) (*fakeGCS, ports.ObjectStorage) {
+	t.Helper()
+	f := &fakeGCS{objects: make(map[string]*fakeGCSObject)}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	s, err := storage.NewGCSAdapter(context.Background(), gcsTestBucket, "", srv.URL+"/storage/v1/")
+	require.NoError(t, err)
+	return f, s
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p := r.URL.EscapedPath()
+	switch {
+	case strings.HasPrefix(p, "/upload/storage/v1/b/"):
+		bucket, _, _ := strings.Cut(strings.TrimPrefix(p, "/upload/storage/v1/b/"), "/")
+		f.upload(w, r, bucket)
+	case strings.HasPrefix(p, "/storage/v1/b/"):
+		segs := strings.Split(strings.TrimPrefix(p, "/storage/v1/b/"), "/")
+		if len(segs) < 2 || segs[1] != "o" {
+			writeGCSError(w, http.StatusNotFound)
+			return
+		}
+		if len(segs) == 2 {
+			f.list(w, r, segs[0])
+			return
+		}
+		name, err := url.PathUnescape(segs[2])
+		if err != nil {
+			writeGCSError(w, http.StatusBadRequest)
+			return
+		}
+		switch {
+		case len(segs) == 4 && segs[3] == "compose" && r.Method == http.MethodPost:
+			f.compose(w, r, segs[0], name)
+		case len(segs) == 3 && r.Method == http.MethodGet:
+			f.get(w, r, segs[0], name)
+		case len(segs) == 3 && r.Method == http.MethodDelete:
+			if _, ok := f.objects[segs[0]+"/"+name]; !ok {
+				writeGCSError(w, http.StatusNotFound)
+				return
+			}
+			delete(f.objects, segs[0]+"/"+name)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeGCSError(w, http.StatusNotFound)
+		}
+	default:
+		writeGCSError(w, http.StatusNotFound)
+	}
+}
+
+func (f *fakeGCS) put(bucket, name string, data []byte, contentType string, metadata map[string]string) *fakeGCSObject {
+	f.generation++
+	obj := &fakeGCSObject{
+		data:        data,
+		contentType: contentType,
+		metadata:    metadata,
+		generation:  f.generation,
+		updated:     time.Now().UTC(),
+	}
+	f.objects[bucket+"/"+name] = obj
+	return obj
+}
+
+func (f *fakeGCS) upload(w http.ResponseWriter, r *http.Request, bucket string) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || r.URL.Query().Get("uploadType") != "multipart" {
+		writeGCSError(w, http.StatusBadRequest)
+		return
+	}
+	mr := multipart.NewReader(r.Body, params["boundary"])
+	metaPart, err := mr.NextPart()
+	if err != nil {
+		writeGCSError(w, http.StatusBadRequest)
+		return
+	}
+	var meta struct {
+		Name        string            `json:"name"`
+		ContentType string            `json:"contentType"`
+		Metadata    map[string]string `json:"metadata"`
+	}
+	if err := json.NewDecoder(metaPart).Decode(&meta); err != nil {
+		writeGCSError(w, http.StatusBadRequest)
+		return
+	}
+	mediaPart, err := mr.NextPart()
+	if err != nil {
+		writeGCSError(w, http.StatusBadRequest)
+		return
+	}
+	data, err := io.ReadAll(mediaPart)
+	if err != nil {
+		writeGCSError(w, http.StatusBadRequest)
+		return
+	}
+	contentType := meta.ContentType
+	if contentType == "" {
+		contentType = mediaPart.Header.Get("Content-Type")
+	}
+	obj := f.put(bucket, meta.Name, data, contentType, meta.Metadata)
+	writeGCSJSON(w, objectResource(bucket, meta.Name, obj))
+}
+
+func (f *fakeGCS) get(w http.ResponseWriter, r *http.Request, bucket, name string) {
+	obj, ok := f.objects[bucket+"/"+name]
+	if !ok {
+		writeGCSError(w, http.StatusNotFound)
+		return
+	}
+	if r.URL.Query().Get("alt") == "media" {
+		w.Header().Set("Content-Type", obj.contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+		_, _ = w.Write(obj.data)
+		return
+	}
+	writeGCSJSON(w, objectResource(bucket, name, obj))
+}
+
+func (f *fakeGCS) list(w http.ResponseWriter, r *http.Request, bucket string) {
+	prefix := r.URL.Query().Get("prefix")
+	var names []string
+	for k := range f.objects {
+		b, name, _ := strings.Cut(k, "/")
+		if b == bucket && strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	items := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		items = append(items, objectResource(bucket, name, f.objects[bucket+"/"+name]))
+	}
+	writeGCSJSON(w, map[string]any{"kind": "storage#objects", "items": items})
+}
+
+func (f *fakeGCS) compose(w http.ResponseWriter, r *http.Request, bucket, name string) {
+	var req struct {
+		SourceObjects []struct {
+			Name       string `json:"name"`
+			Generation int64  `json:"generation,string"`
+		} `json:"sourceObjects"`
+		Destination struct {
+			ContentType string `json:"contentType"`
+		} `json:"destination"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeGCSError(w, http.StatusBadRequest)
+		return
+	}
+	// GCS と同じく 1 回の compose の入力は 32 個まで
+	if len(req.SourceObjects) == 0 || len(req.SourceObjects) > 32 {
+		writeGCSError(w, http.StatusBadRequest)
+		return
+	}
+	var data []byte
+	for _, src := range req.SourceObjects {
+		obj, ok := f.objects[bucket+"/"+src.Name]
+		if !ok || (src.Generation != 0 && src.Generation != obj.generation) {
+			writeGCSError(w, http.StatusNotFound)
+			return
+		}
+		data = append(data, obj.data...)
+	}
+	f.composeSources = append(f.composeSources, len(req.SourceObjects))
+	obj := f.put(bucket, name, data, req.Destination.ContentType, nil)
+	writeGCSJSON(w, objectResource(bucket, name, obj))
+}
+
+// names はバケット内のオブジェクト名を返す。
+func (f *fakeGCS) names() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var names []string
+	for k := range f.objects {
+		_, name, _ := strings.Cut(k, "/")
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func objectResource(bucket, name string, obj *fakeGCSObject) map[string]any {
+	return map[string]any{
+		"kind":        "storage#object",
+		"bucket":      bucket,
+		"name":        name,
+		"size":        strconv.Itoa(len(obj.data)),
+		"contentType": obj.contentType,
+		"metadata":    obj.metadata,
+		"generation":  strconv.FormatInt(obj.generation, 10),
+		"etag":        fmt.Sprintf("etag-%d", obj.generation),
+		"updated":     obj.updated.Format(time.RFC3339Nano),
+	}
+}
+
+func writeGCSJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeGCSError(w http.ResponseWriter, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"code": code, "message": http.StatusText(code)},
+	})
+}
+
+// ─── 基本操作 ─────────────────────────────────────────────────────
+
+func TestGCSAdapter_UploadDownloadStatDelete(t *testing.T) {
+	ctx := context.Background()
+	_, s := newFakeGCS(t)
+	const key = "user/subject/file/handout.pdf"
+
+	storagePath, err := s.Upload(ctx, key, strings.NewReader("%PDF-1.7 handout"), 16, "application/pdf")
+	require.NoError(t, err)
+	assert.Equal(t, "gs://materials/"+key, storagePath)
+	assert.Equal(t, storagePath, s.StoragePath(key))
+
+	// キーとストレージパスのどちらでも読める
+	for _, location := range []string{key, storagePath} {
+		rc, err := s.Download(ctx, location)
+		require.NoError(t, err)
+		got, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+		assert.Equal(t, "%PDF-1.7 handout", string(got))
+
+		info, err := s.Stat(ctx, location)
+		require.NoError(t, err)
+		assert.Equal(t, key, info.Key)
+		assert.Equal(t, int64(16), info.Size)
+		assert.Equal(t, "application/pdf", info.ContentType)
+	}
+
+	require.NoError(t, s.Delete(ctx, storagePath))
+	_, err = s.Stat(ctx, storagePath)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = s.Download(ctx, storagePath)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	// 存在しないオブジェクトの削除はエラーにしない
+	assert.NoError(t, s.Delete(ctx, storagePath))
+}
+
+func TestGCSAdapter_RejectsOtherSchemes(t *testing.T) {
+	ctx := context.Background()
+	_, s := newFakeGCS(t)
+
+	for _, location := range []string{"minio://materials/a/b.pdf", "file:///tmp/a.pdf", "gs://materials/"} {
+		t.Run(location, func(t *testing.T) {
+			_, err := s.Download(ctx, location)
+			assert.ErrorIs(t, err, domain.ErrInvalidInput)
+			_, err = s.Stat(ctx, location)
+			assert.ErrorIs(t, err, domain.ErrInvalidInput)
+			assert.ErrorIs(t, s.Delete(ctx, location), domain.ErrInvalidInput)
+		})
+	}
+}
+
+func TestGCSAdapter_ListObjects(t *testing.T) {
+	ctx := context.Background()
+	_, s := newFakeGCS(t)
+	mp := s.(ports.MultipartStorage)
+
+	for _, key := range []string{"user/a/1/x.pdf", "user/a/2/y.pdf", "user/b/3/z.pdf"} {
+		_, err := s.Upload(ctx, key, strings.NewReader("data"), 4, "application/pdf")
+		require.NoError(t, err)
+	}
+	// マルチパートの一時オブジェクトは列挙しない
+	uploadID, err := mp.CreateMultipartUpload(ctx, "user/a/4/w.pdf", "application/pdf")
+	require.NoError(t, err)
+	_, err = mp.UploadPart(ctx, "user/a/4/w.pdf", uploadID, 1, strings.NewReader("part"), 4)
+	require.NoError(t, err)
+
+	var got []ports.ObjectInfo
+	require.NoError(t, s.ListObjects(ctx, "user/a/", func(obj ports.ObjectInfo) error {
+		got = append(got, obj)
+		return nil
+	}))
+
+	require.Len(t, got, 2)
+	assert.Equal(t, "user/a/1/x.pdf", got[0].Key)
+	assert.Equal(t, "gs://materials/user/a/1/x.pdf", got[0].StoragePath)
+	assert.Equal(t, int64(4), got[0].Size)
+	assert.False(t, got[0].LastModified.IsZero())
+	assert.Equal(t, "user/a/2/y.pdf", got[1].Key)
+
+	var all int
+	require.NoError(t, s.ListObjects(ctx, "", func(ports.ObjectInfo) error {
+		all++
+		return nil
+	}))
+	assert.Equal(t, 3, all)
+}
+
+// ─── 署名付き URL ─────────────────────────────────────────────────
+
+// newSigningGCS はサービスアカウント鍵で署名する GCS アダプターを返す（署名はローカルで行い通信しない）。
+func newSigningGCS(t *testing.T) ports.ObjectStorage {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	creds, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"project_id":     "test-project",
+		"private_key_id": "test-key",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"client_email":   "signer@test-project.iam.gserviceaccount.com",
+		"client_id":      "1",
+		"token_uri":      "https://oauth2.googleapis.com/token",
+	})
+	require.NoError(t, err)
+	credsPath := filepath.Join(t.TempDir(), "credentials.json")
+	require.NoError(t, os.WriteFile(credsPath, creds, 0o600))
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credsPath)
+	t.Setenv("STORAGE_EMULATOR_HOST", "")
+
+	s, err := storage.NewGCSAdapter(context.Background(), gcsTestBucket, "", "")
+	require.NoError(t, err)
+	return s
+}
+
+func TestGCSAdapter_PresignedURLs(t *testing.T) {
+	ctx := context.Background()
+	s := newSigningGCS(t)
+	const key = "user/subject/file/handout.pdf"
+
+	getURL, err := s.GetPresignedURL(ctx, "gs://materials/"+key, 15*time.Minute)
+	require.NoError(t, err)
+	putURL, err := s.GetPresignedPutURL(ctx, key, 15*time.Minute)
+	require.NoError(t, err)
+
+	for _, raw := range []string{getURL, putURL} {
+		u, err := url.Parse(raw)
+		require.NoError(t, err)
+		assert.Equal(t, "storage.googleapis.com", u.Host)
+		assert.Equal(t, "/materials/"+key, u.Path)
+		q := u.Query()
+		assert.Equal(t, "GOOG4-RSA-SHA256", q.Get("X-Goog-Algorithm"))
+		assert.True(t, strings.HasPrefix(q.Get("X-Goog-Credential"), "signer@test-project.iam.gserviceaccount.com/"))
+		expires, err := strconv.Atoi(q.Get("X-Goog-Expires"))
+		require.NoError(t, err)
+		assert.InDelta(t, 900, expires, 5)
+		assert.NotEmpty(t, q.Get("X-Goog-Signature"))
+	}
+	// メソッドは署名に含まれるため GET と PUT で署名が異なる
+	assert.NotEqual(t, getURL, putURL)
+
+	_, err = s.GetPresignedURL(ctx, "minio://materials/"+key, time.Minute)
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+}
+
+// ─── マルチパートアップロード ─────────────────────────────────────
+
+// uploadGCSParts は n 個のパートを送信し、結合後に期待する内容と送信済みパートを返す。
+func uploadGCSParts(t *testing.T, mp ports.MultipartStorage, key, uploadID string, n int) (string, []ports.MultipartPart) {
+	t.Helper()
+	ctx := context.Background()
+	var want strings.Builder
+	parts := make([]ports.MultipartPart, 0, n)
+	for i := 1; i <= n; i++ {
+		body := fmt.Sprintf("[part %03d]", i)
+		etag, err := mp.UploadPart(ctx, key, uploadID, i, strings.NewReader(body), int64(len(body)))
+		require.NoError(t, err)
+		require.NotEmpty(t, etag)
+		want.WriteString(body)
+		parts = append(parts, ports.MultipartPart{PartNumber: i, ETag: etag})
+	}
+	return want.String(), parts
+}
+
+func TestGCSAdapter_MultipartUpload(t *testing.T) {
+	ctx := context.Background()
+	fake, s := newFakeGCS(t)
+	mp := s.(ports.MultipartStorage)
+	const key = "user/subject/file/slides.pdf"
+
+	uploadID, err := mp.CreateMultipartUpload(ctx, key, "application/pdf")
+	require.NoError(t, err)
+	want, parts := uploadGCSParts(t, mp, key, uploadID, 3)
+
+	require.NoError(t, mp.CompleteMultipartUpload(ctx, key, uploadID, parts))
+
+	rc, err := s.Download(ctx, key)
+	require.NoError(t, err)
+	got, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, want, string(got))
+	info, err := s.Stat(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", info.ContentType)
+
+	assert.Equal(t, []int{3}, fake.composeSources)
+	// 一時オブジェクトは残さない
+	assert.Equal(t, []string{key}, fake.names())
+}
+
+func TestGCSAdapter_MultipartUpload_ComposesInRounds(t *testing.T) {
+	ctx := context.Background()
+	fake, s := newFakeGCS(t)
+	mp := s.(ports.MultipartStorage)
+	const key = "user/subject/file/lecture.pdf"
+
+	// 32 個ずつの中間 compose が 3 回、最終 compose が 1 回
+	uploadID, err := mp.CreateMultipartUpload(ctx, key, "application/pdf")
+	require.NoError(t, err)
+	want, parts := uploadGCSParts(t, mp, key, uploadID, 70)
+
+	require.NoError(t, mp.CompleteMultipartUpload(ctx, key, uploadID, parts))
+
+	rc, err := s.Download(ctx, key)
+	require.NoError(t, err)
+	got, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	assert.Equal(t, want, string(got))
+
+	assert.Equal(t, []int{32, 32, 6, 3}, fake.composeSources)
+	assert.Equal(t, []string{key}, fake.names())
+}
+
+func TestGCSAdapter_MultipartUpload_RejectsETagMismatch(t *testing.T) {
+	ctx := context.Background()
+	fake, s := newFakeGCS(t)
+	mp := s.(ports.MultipartStorage)
+	const key = "user/subject/file/slides.pdf"
+
+	uploadID, err := mp.CreateMultipartUpload(ctx, key, "application/pdf")
+	require.NoError(t, err)
+	_, parts := uploadGCSParts(t, mp, key, uploadID, 2)
+	// ETag の取得後にパート 2 が別の内容で上書きされた
+	_, err = mp.UploadPart(ctx, key, uploadID, 2, strings.NewReader("[tampered]"), 10)
+	require.NoError(t, err)
+
+	err = mp.CompleteMultipartUpload(ctx, key, uploadID, parts)
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	// 送信されていないパートも拒否する
+	err = mp.CompleteMultipartUpload(ctx, key, uploadID, append(parts, ports.MultipartPart{PartNumber: 3, ETag: "etag-x"}))
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	_, err = s.Stat(ctx, key)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.Empty(t, fake.composeSources)
+}
+
+func TestGCSAdapter_AbortMultipartUpload(t *testing.T) {
+	ctx := context.Background()
+	fake, s := newFakeGCS(t)
+	mp := s.(ports.MultipartStorage)
+	const key = "user/subject/file/slides.pdf"
+
+	uploadID, err := mp.CreateMultipartUpload(ctx, key, "application/pdf")
+	require.NoError(t, err)
+	uploadGCSParts(t, mp, key, uploadID, 2)
+
+	require.NoError(t, mp.AbortMultipartUpload(ctx, key, uploadID))
+	assert.Empty(t, fake.names())
+}
@@ -0,0 +1,396 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+// localMultipartDir はマルチパートアップロードの一時パートを置くディレクトリ（root 直下）
+const localMultipartDir = ".multipart"
+
+type localAdapter struct {
+	root           string // 絶対パス
+	baseURL        string // 署名付き URL のベース（NewLocalSignedURLHandler の公開先）
+	signingKey     []byte
+	maxUploadBytes int64 // 署名付き PUT で受け付ける最大サイズ（NewLocalSignedURLHandler のみ使用）
+}
+
+// NewLocalAdapter はローカルファイルシステムを使った ObjectStorage 実装を返す。
+// 開発環境・単一ノード構成向け。返却値は ports.MultipartStorage も実装する。
+// 署名付き URL は baseURL 配下を指し、NewLocalSignedURLHandler で配信する。
+func NewLocalAdapter(root, baseURL string, signingKey []byte) (ports.ObjectStorage, error) {
+	return newLocalAdapter(root, baseURL, signingKey)
+}
+
+func newLocalAdapter(root, baseURL string, signingKey []byte) (*localAdapter, error) {
+	if len(signingKey) == 0 {
+		return nil, errors.New("local storage signing key is required")
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("resolve local storage root: %w", err)
+	}
+	return &localAdapter{
+		root:       abs,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		signingKey: signingKey,
+	}, nil
+}
+
+// resolve はオブジェクトキーまたは "file:///path" を root 配下の絶対パスに変換する。
+// 正規化されていないパス（".." や "." セグメント、連続した "/" を含む）と
+// root の外を指すパスは domain.ErrInvalidInput を返す。
+// 正規化で ".." を畳み込むと、別の科目のプレフィックス配下を指すキーを受け付けてしまうため。
+func (a *localAdapter) resolve(location string) (string, error) {
+	scheme, rest := splitScheme(location)
+	if !isCleanPath(rest) {
+		return "", fmt.Errorf("path %q is not clean: %w", location, domain.ErrInvalidInput)
+	}
+	var p string
+	switch scheme {
+	case "":
+		p = filepath.Join(a.root, filepath.FromSlash(rest))
+	case SchemeFile:
+		p = filepath.Clean(filepath.FromSlash(rest))
+	default:
+		return "", fmt.Errorf("not a file storage path %q: %w", location, domain.ErrInvalidInput)
+	}
+	rel, err := filepath.Rel(a.root, p)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q is outside storage root: %w", location, domain.ErrInvalidInput)
+	}
+	return p, nil
+}
+
+// isCleanPath は p が空でなく、path.Clean で変化せず、".." セグメントを含まない場合に true を返す。
+func isCleanPath(p string) bool {
+	if p == "" || path.Clean(p) != p {
+		return false
+	}
+	for _, seg := range strings.Split(p, "/") {
+		if seg == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// StoragePath は "file:///{root}/{key}" を返す。
+func (a *localAdapter) StoragePath(key string) string {
+	p, err := a.resolve(key)
+	if err != nil {
+		return ""
+	}
+	return SchemeFile + "://" + filepath.ToSlash(p)
+}
+
+// Upload はファイルを一時ファイルに書き込んでから rename し、ストレージパスを返す。
+func (a *localAdapter) Upload(_ context.Context, key string, reader io.Reader, _ int64, _ string) (string, error) {
+	p, err := a.resolve(key)
+	if err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(p, reader); err != nil {
+		return "", err
+	}
+	return SchemeFile + "://" + filepath.ToSlash(p), nil
+}
+
+// Download はファイルを開く。
+func (a *localAdapter) Download(_ context.Context, key string) (io.ReadCloser, error) {
+	p, err := a.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+// Delete はファイルを削除する。存在しない場合もエラーにしない。
+func (a *localAdapter) Delete(_ context.Context, key string) error {
+	p, err := a.resolve(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Stat はファイルのメタデータを返す。
+// ファイルシステムは Content-Type を保持しないため、先頭バイトから推定する。
+func (a *localAdapter) Stat(_ context.Context, key string) (*ports.ObjectInfo, error) {
+	p, err := a.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	rel, _ := filepath.Rel(a.root, p)
+	return &ports.ObjectInfo{
+		Key:         filepath.ToSlash(rel),
+		Size:        fi.Size(),
+		ContentType: http.DetectContentType(head[:n]),
+	}, nil
+}
+
+// ListObjects は root 配下のキーが prefix で始まるファイルを列挙する。
+// マルチパートの一時ディレクトリと書き込み途中の一時ファイルは除く。
+func (a *localAdapter) ListObjects(ctx context.Context, prefix string, fn func(ports.ObjectInfo) error) error {
+	err := filepath.WalkDir(a.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(a.root, p)
+		key := filepath.ToSlash(rel)
+		if d.IsDir() {
+			if key == localMultipartDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".tmp-") || !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(ports.ObjectInfo{
+			Key:          key,
+			Size:         fi.Size(),
+			StoragePath:  SchemeFile + "://" + filepath.ToSlash(p),
+			LastModified: fi.ModTime(),
+		})
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil // root 未作成（まだ何も保存されていない）
+	}
+	return err
+}
+
+// GetPresignedURL は HMAC 署名付きの一時 GET URL を生成する。
+func (a *localAdapter) GetPresignedURL(_ context.Context, key string, expiry time.Duration) (string, error) {
+	return a.signedURL(key, http.MethodGet, expiry)
+}
+
+// GetPresignedPutURL は HMAC 署名付きの一時 PUT URL を生成する。
+func (a *localAdapter) GetPresignedPutURL(_ context.Context, key string, expiry time.Duration) (string, error) {
+	return a.signedURL(key, http.MethodPut, expiry)
+}
+
+func (a *localAdapter) signedURL(location, method string, expiry time.Duration) (string, error) {
+	p, err := a.resolve(location)
+	if err != nil {
+		return "", err
+	}
+	rel, _ := filepath.Rel(a.root, p)
+	key := filepath.ToSlash(rel)
+	expires := time.Now().Add(expiry).Unix()
+	q := url.Values{}
+	q.Set("expires", strconv.FormatInt(expires, 10))
+	q.Set("signature", a.sign(method, key, expires))
+	return a.baseURL + "/" + (&url.URL{Path: key}).EscapedPath() + "?" + q.Encode(), nil
+}
+
+func (a *localAdapter) sign(method, key string, expires int64) string {
+	mac := hmac.New(sha256.New, a.signingKey)
+	fmt.Fprintf(mac, "%s\n%s\n%d", method, key, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify は署名と有効期限を検証する。
+func (a *localAdapter) verify(method, key, expiresParam, signature string, now time.Time) bool {
+	expires, err := strconv.ParseInt(expiresParam, 10, 64)
+	if err != nil || now.Unix() > expires {
+		return false
+	}
+	want := a.sign(method, key, expires)
+	return hmac.Equal([]byte(want), []byte(signature))
+}
+
+// ─── マルチパートアップロード（ports.MultipartStorage） ───────────
+
+// CreateMultipartUpload はパート保存用の一時ディレクトリを作成する。
+func (a *localAdapter) CreateMultipartUpload(_ context.Context, _, _ string) (string, error) {
+	uploadID := uuid.NewString()
+	if err := os.MkdirAll(a.multipartDir(uploadID), 0o750); err != nil {
+		return "", err
+	}
+	return uploadID, nil
+}
+
+// UploadPart はパートを一時ファイルに保存し、内容の SHA-256 を ETag として返す。
+func (a *localAdapter) UploadPart(_ context.Context, _, uploadID string, partNumber int, reader io.Reader, _ int64) (string, error) {
+	h := sha256.New()
+	p := filepath.Join(a.multipartDir(uploadID), fmt.Sprintf("part-%05d", partNumber))
+	if err := writeFileAtomic(p, io.TeeReader(reader, h)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// CompleteMultipartUpload はパートを番号順に連結してファイルを確定し、一時ディレクトリを削除する。
+func (a *localAdapter) CompleteMultipartUpload(_ context.Context, key, uploadID string, parts []ports.MultipartPart) error {
+	dst, err := a.resolve(key)
+	if err != nil {
+		return err
+	}
+	sorted := append([]ports.MultipartPart(nil), parts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].PartNumber < sorted[j].PartNumber })
+
+	readers := make([]io.Reader, 0, len(sorted))
+	for _, part := range sorted {
+		f, err := os.Open(filepath.Join(a.multipartDir(uploadID), fmt.Sprintf("part-%05d", part.PartNumber)))
+		if err != nil {
+			return fmt.Errorf("open part %d: %w", part.PartNumber, err)
+		}
+		defer f.Close()
+		readers = append(readers, f)
+	}
+	if err := writeFileAtomic(dst, io.MultiReader(readers...)); err != nil {
+		return err
+	}
+	return os.RemoveAll(a.multipartDir(uploadID))
+}
+
+// AbortMultipartUpload は一時ディレクトリを削除する。
+func (a *localAdapter) AbortMultipartUpload(_ context.Context, _, uploadID string) error {
+	return os.RemoveAll(a.multipartDir(uploadID))
+}
+
+func (a *localAdapter) multipartDir(uploadID string) string {
+	return filepath.Join(a.root, localMultipartDir, filepath.Base(uploadID))
+}
+
+// writeFileAtomic は同じディレクトリの一時ファイルに書き込んでから rename する。
+func writeFileAtomic(p string, reader io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // rename 成功後は存在しないため無視される
+	if _, err := io.Copy(tmp, reader); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+// ─── 署名付き URL の配信 ───────────────────────────────────────────
+
+// NewLocalSignedURLHandler はローカルストレージの署名付き URL（GET / PUT）を処理する
+// http.Handler を返す。NewLocalAdapter の baseURL のパスにマウントし、
+// リクエストパスはマウント先のプレフィックスを除いたオブジェクトキーである必要がある。
+// PUT の本文が maxUploadBytes を超える場合は書き込まずに 413 を返す。
+func NewLocalSignedURLHandler(root string, signingKey []byte, maxUploadBytes int64) (http.Handler, error) {
+	if maxUploadBytes <= 0 {
+		return nil, errors.New("local storage max upload size must be positive")
+	}
+	a, err := newLocalAdapter(root, "", signingKey)
+	if err != nil {
+		return nil, err
+	}
+	a.maxUploadBytes = maxUploadBytes
+	return http.HandlerFunc(a.serveSignedURL), nil
+}
+
+func (a *localAdapter) serveSignedURL(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	q := r.URL.Query()
+	if !a.verify(r.Method, key, q.Get("expires"), q.Get("signature"), time.Now()) {
+		http.Error(w, "invalid or expired signature", http.StatusForbidden)
+		return
+	}
+	p, err := a.resolve(key)
+	if err != nil {
+		http.Error(w, "invalid key", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		f, err := os.Open(p)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		http.ServeContent(w, r, fi.Name(), fi.ModTime(), f)
+	case http.MethodPut:
+		if r.ContentLength > a.maxUploadBytes {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		// Content-Length を持たない（chunked の）本文も上限で打ち切る
+		body := http.MaxBytesReader(w, r.Body, a.maxUploadBytes)
+		if err := writeFileAtomic(p, body); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "write failed", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,287 @@
+package storage_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/storage"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+// newLocalStorage は一時ディレクトリを root とするローカルストレージを返す。
+func newLocalStorage(t *testing.T) (string, ports.ObjectStorage) {
+	t.Helper()
+	root := t.TempDir()
+	s, err := storage.NewLocalAdapter(root, "http://localhost/files", []byte("test-key"))
+	require.NoError(t, err)
+	return root, s
+}
+
+// ─── キーの正規化チェック ─────────────────────────────────────────
+
+func TestLocalAdapter_RejectsTraversalKeys(t *testing.T) {
+	ctx := context.Background()
+	root, s := newLocalStorage(t)
+
+	// 別の科目のプレフィックス配下にあるオブジェクト
+	victim := filepath.Join(root, "subjects", "other", "secret.pdf")
+	require.NoError(t, os.MkdirAll(filepath.Dir(victim), 0o755))
+	require.NoError(t, os.WriteFile(victim, []byte("secret"), 0o644))
+
+	keys := []string{
+		"subjects/mine/../other/secret.pdf",
+		"subjects/mine/../../subjects/other/secret.pdf",
+		"../outside.pdf",
+		"subjects/mine/./file.pdf",
+		"subjects//mine/file.pdf",
+		"subjects/mine/",
+		"file://" + filepath.ToSlash(root) + "/subjects/mine/../other/secret.pdf",
+	}
+	for _, key := range keys {
+		t.Run(key, func(t *testing.T) {
+			_, err := s.Download(ctx, key)
+			assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+			_, err = s.Upload(ctx, key, strings.NewReader("overwrite"), 9, "application/pdf")
+			assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+			_, err = s.GetPresignedURL(ctx, key, time.Minute)
+			assert.ErrorIs(t, err, domain.ErrInvalidInput)
+		})
+	}
+
+	got, err := os.ReadFile(victim)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(got))
+}
+
+func TestLocalAdapter_AcceptsCleanKeys(t *testing.T) {
+	ctx := context.Background()
+	_, s := newLocalStorage(t)
+
+	_, err := s.Upload(ctx, "subjects/mine/file.pdf", strings.NewReader("content"), 7, "application/pdf")
+	require.NoError(t, err)
+
+	rc, err := s.Download(ctx, "subjects/mine/file.pdf")
+	require.NoError(t, err)
+	defer rc.Close()
+	got, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(got))
+}
+
+// ─── 署名付き URL ─────────────────────────────────────────────────
+
+// newSignedURLServer は署名付き URL を配信するサーバーと、その URL を発行するローカルストレージを返す。
+func newSignedURLServer(t *testing.T, maxUploadBytes int64) (string, ports.ObjectStorage) {
+	t.Helper()
+	root := t.TempDir()
+	key := []byte("test-key")
+	h, err := storage.NewLocalSignedURLHandler(root, key, maxUploadBytes)
+	require.NoError(t, err)
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	s, err := storage.NewLocalAdapter(root, srv.URL, key)
+	require.NoError(t, err)
+	return root, s
+}
+
+func doRequest(t *testing.T, method, rawURL string, body io.Reader) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, rawURL, body)
+	require.NoError(t, err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	got, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	return res.StatusCode, string(got)
+}
+
+func TestLocalSignedURL_PutThenGet(t *testing.T) {
+	ctx := context.Background()
+	_, s := newSignedURLServer(t, 1<<20)
+	const key = "user/subject/file/handout.pdf"
+
+	putURL, err := s.GetPresignedPutURL(ctx, key, time.Minute)
+	require.NoError(t, err)
+	code, _ := doRequest(t, http.MethodPut, putURL, strings.NewReader("%PDF-1.7 handout"))
+	require.Equal(t, http.StatusOK, code)
+
+	getURL, err := s.GetPresignedURL(ctx, s.StoragePath(key), time.Minute)
+	require.NoError(t, err)
+	code, body := doRequest(t, http.MethodGet, getURL, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "%PDF-1.7 handout", body)
+}
+
+func TestLocalSignedURL_RejectsExpired(t *testing.T) {
+	ctx := context.Background()
+	root, s := newSignedURLServer(t, 1<<20)
+	const key = "user/subject/file/handout.pdf"
+
+	putURL, err := s.GetPresignedPutURL(ctx, key, -time.Minute)
+	require.NoError(t, err)
+	code, _ := doRequest(t, http.MethodPut, putURL, strings.NewReader("content"))
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.NoFileExists(t, filepath.Join(root, filepath.FromSlash(key)))
+
+	_, err = s.Upload(ctx, key, strings.NewReader("content"), 7, "application/pdf")
+	require.NoError(t, err)
+	getURL, err := s.GetPresignedURL(ctx, key, -time.Minute)
+	require.NoError(t, err)
+	code, _ = doRequest(t, http.MethodGet, getURL, nil)
+	assert.Equal(t, http.StatusForbidden, code)
+}
+
+func TestLocalSignedURL_RejectsTampering(t *testing.T) {
+	ctx := context.Background()
+	root, s := newSignedURLServer(t, 1<<20)
+	const key = "user/subject/file/handout.pdf"
+	_, err := s.Upload(ctx, "user/subject/other/secret.pdf", strings.NewReader("secret"), 6, "application/pdf")
+	require.NoError(t, err)
+
+	putURL, err := s.GetPresignedPutURL(ctx, key, time.Minute)
+	require.NoError(t, err)
+	getURL, err := s.GetPresignedURL(ctx, key, time.Minute)
+	require.NoError(t, err)
+	u, err := url.Parse(putURL)
+	require.NoError(t, err)
+	q := u.Query()
+
+	// 有効期限の延長
+	extended := *u
+	eq := u.Query()
+	eq.Set("expires", strconv.FormatInt(time.Now().Add(24*time.Hour).Unix(), 10))
+	extended.RawQuery = eq.Encode()
+	// 署名の改変
+	forged := *u
+	fq := u.Query()
+	fq.Set("signature", strings.Repeat("0", len(q.Get("signature"))))
+	forged.RawQuery = fq.Encode()
+	// 別のキーへの付け替え
+	moved := *u
+	moved.Path = "/user/subject/other/secret.pdf"
+	moved.RawPath = ""
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+	}{
+		{"extended expiry", http.MethodPut, extended.String()},
+		{"forged signature", http.MethodPut, forged.String()},
+		{"other key", http.MethodPut, moved.String()},
+		{"get url used for put", http.MethodPut, getURL},
+		{"put url used for get", http.MethodGet, putURL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := doRequest(t, tt.method, tt.url, strings.NewReader("overwrite"))
+			assert.Equal(t, http.StatusForbidden, code)
+		})
+	}
+
+	assert.NoFileExists(t, filepath.Join(root, filepath.FromSlash(key)))
+	got, err := os.ReadFile(filepath.Join(root, "user", "subject", "other", "secret.pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(got))
+}
+
+func TestLocalSignedURL_RejectsOversizedPut(t *testing.T) {
+	ctx := context.Background()
+	root, s := newSignedURLServer(t, 16)
+	const key = "user/subject/file/handout.pdf"
+
+	putURL, err := s.GetPresignedPutURL(ctx, key, time.Minute)
+	require.NoError(t, err)
+
+	// Content-Length で判定できる本文と、chunked で送られる本文
+	bodies := map[string]io.Reader{
+		"content length": strings.NewReader(strings.Repeat("x", 17)),
+		"chunked":        io.MultiReader(strings.NewReader(strings.Repeat("x", 10)), strings.NewReader(strings.Repeat("x", 10))),
+	}
+	for name, body := range bodies {
+		t.Run(name, func(t *testing.T) {
+			code, _ := doRequest(t, http.MethodPut, putURL, body)
+			assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+			assert.NoFileExists(t, filepath.Join(root, filepath.FromSlash(key)))
+		})
+	}
+
+	// 上限ちょうどは受け付ける
+	code, _ := doRequest(t, http.MethodPut, putURL, strings.NewReader(strings.Repeat("x", 16)))
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestNewLocalSignedURLHandler_RequiresMaxUploadBytes(t *testing.T) {
+	_, err := storage.NewLocalSignedURLHandler(t.TempDir(), []byte("test-key"), 0)
+	assert.Error(t, err)
+}
+
+// ─── マルチパートアップロード ─────────────────────────────────────
+
+func TestLocalAdapter_MultipartUpload(t *testing.T) {
+	ctx := context.Background()
+	root, s := newLocalStorage(t)
+	mp := s.(ports.MultipartStorage)
+	const key = "user/subject/file/slides.pdf"
+
+	uploadID, err := mp.CreateMultipartUpload(ctx, key, "application/pdf")
+	require.NoError(t, err)
+	// パートは番号順でなくても送信できる
+	var parts []ports.MultipartPart
+	for _, n := range []int{2, 1, 3} {
+		etag, err := mp.UploadPart(ctx, key, uploadID, n, strings.NewReader("["+strconv.Itoa(n)+"]"), 3)
+		require.NoError(t, err)
+		parts = append(parts, ports.MultipartPart{PartNumber: n, ETag: etag})
+	}
+
+	// 一時パートは列挙しない
+	var listed int
+	require.NoError(t, s.ListObjects(ctx, "", func(ports.ObjectInfo) error {
+		listed++
+		return nil
+	}))
+	assert.Zero(t, listed)
+
+	require.NoError(t, mp.CompleteMultipartUpload(ctx, key, uploadID, parts))
+
+	rc, err := s.Download(ctx, key)
+	require.NoError(t, err)
+	defer rc.Close()
+	got, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	assert.Equal(t, "[1][2][3]", string(got))
+	assert.NoDirExists(t, filepath.Join(root, ".multipart", uploadID))
+}
+
+func TestLocalAdapter_AbortMultipartUpload(t *testing.T) {
+	ctx := context.Background()
+	root, s := newLocalStorage(t)
+	mp := s.(ports.MultipartStorage)
+	const key = "user/subject/file/slides.pdf"
+
+	uploadID, err := mp.CreateMultipartUpload(ctx, key, "application/pdf")
+	require.NoError(t, err)
+	_, err = mp.UploadPart(ctx, key, uploadID, 1, strings.NewReader("part"), 4)
+	require.NoError(t, err)
+
+	require.NoError(t, mp.AbortMultipartUpload(ctx, key, uploadID))
+	assert.NoDirExists(t, filepath.Join(root, ".multipart", uploadID))
+	_, err = s.Stat(ctx, key)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
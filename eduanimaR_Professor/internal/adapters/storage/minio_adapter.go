@@ -3,22 +3,27 @@ package storage
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"net/http"
 	"time"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
 	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
 )
 
 type minioAdapter struct {
 	client *minio.Client
+	core   *minio.Core // マルチパートアップロード等の低レベル API 用
 	bucket string
 }
 
 // NewMinioAdapter は MinIO を使った ObjectStorage 実装を返す。
-// Phase 1 用。Phase 2 では GCS に差し替える。
+// 返却値は ports.MultipartStorage と BucketEnsurer も実装する。
+// 接続やバケット作成は行わない（必要なら EnsureBucket を明示的に呼ぶ）。
 func NewMinioAdapter(endpoint, accessKey, secretKey, bucket string, useSSL bool) (ports.ObjectStorage, error) {
 	client, err := minio.New(endpoint, &minio.Options{
 		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
@@ -27,52 +32,177 @@ func NewMinioAdapter(endpoint, accessKey, secretKey, bucket string, useSSL bool)
 	if err != nil {
 		return nil, err
 	}
+	return &minioAdapter{client: client, core: &minio.Core{Client: client}, bucket: bucket}, nil
+}
 
-	// バケットが存在しない場合は作成
-	ctx := context.Background()
-	exists, err := client.BucketExists(ctx, bucket)
+// EnsureBucket はバケットが存在しない場合に作成する。
+func (a *minioAdapter) EnsureBucket(ctx context.Context) error {
+	exists, err := a.client.BucketExists(ctx, a.bucket)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if !exists {
-		if err := client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{}); err != nil {
-			return nil, err
-		}
+	if exists {
+		return nil
+	}
+	return a.client.MakeBucket(ctx, a.bucket, minio.MakeBucketOptions{})
+}
+
+// resolve はオブジェクトキーまたは "minio://bucket/key" をバケットとキーに分解する。
+func (a *minioAdapter) resolve(location string) (bucket, key string, err error) {
+	bucket, key, ok := splitBucketPath(location, SchemeMinio, a.bucket)
+	if !ok {
+		return "", "", fmt.Errorf("not a minio storage path %q: %w", location, domain.ErrInvalidInput)
 	}
+	return bucket, key, nil
+}
 
-	return &minioAdapter{client: client, bucket: bucket}, nil
+// StoragePath は "minio://{bucket}/{key}" を返す。
+func (a *minioAdapter) StoragePath(key string) string {
+	return formatPath(SchemeMinio, a.bucket, key)
 }
 
-// Upload はファイルを MinIO にアップロードし、ストレージキーを返す。
+// Upload はファイルを MinIO にアップロードし、ストレージパスを返す。
 func (a *minioAdapter) Upload(ctx context.Context, key string, reader io.Reader, size int64, contentType string) (string, error) {
-	_, err := a.client.PutObject(ctx, a.bucket, key, reader, size, minio.PutObjectOptions{
+	bucket, key, err := a.resolve(key)
+	if err != nil {
+		return "", err
+	}
+	_, err = a.client.PutObject(ctx, bucket, key, reader, size, minio.PutObjectOptions{
 		ContentType: contentType,
 	})
 	if err != nil {
 		return "", err
 	}
-	return key, nil
+	return formatPath(SchemeMinio, bucket, key), nil
 }
 
 // Download は MinIO からファイルをダウンロードする。
 func (a *minioAdapter) Download(ctx context.Context, key string) (io.ReadCloser, error) {
-	obj, err := a.client.GetObject(ctx, a.bucket, key, minio.GetObjectOptions{})
+	bucket, key, err := a.resolve(key)
 	if err != nil {
 		return nil, err
 	}
+	obj, err := a.client.GetObject(ctx, bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// GetObject は遅延取得のため、存在確認を兼ねて Stat する
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+		if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
 	return obj, nil
 }
 
 // Delete は MinIO からファイルを削除する。
 func (a *minioAdapter) Delete(ctx context.Context, key string) error {
-	return a.client.RemoveObject(ctx, a.bucket, key, minio.RemoveObjectOptions{})
+	bucket, key, err := a.resolve(key)
+	if err != nil {
+		return err
+	}
+	return a.client.RemoveObject(ctx, bucket, key, minio.RemoveObjectOptions{})
+}
+
+// Stat は MinIO 上のオブジェクトのメタデータを返す。
+// オブジェクトが存在しない場合は domain.ErrNotFound を返す。
+func (a *minioAdapter) Stat(ctx context.Context, key string) (*ports.ObjectInfo, error) {
+	bucket, key, err := a.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	info, err := a.client.StatObject(ctx, bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &ports.ObjectInfo{
+		Key:         info.Key,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+	}, nil
 }
 
 // GetPresignedURL は MinIO の署名付き一時 URL を生成する。
 func (a *minioAdapter) GetPresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
-	u, err := a.client.PresignedGetObject(ctx, a.bucket, key, expiry, nil)
+	bucket, key, err := a.resolve(key)
+	if err != nil {
+		return "", err
+	}
+	u, err := a.client.PresignedGetObject(ctx, bucket, key, expiry, nil)
 	if err != nil {
 		return "", err
 	}
 	return u.String(), nil
 }
+
+// GetPresignedPutURL は MinIO の署名付き一時 PUT URL を生成する。
+func (a *minioAdapter) GetPresignedPutURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	bucket, key, err := a.resolve(key)
+	if err != nil {
+		return "", err
+	}
+	u, err := a.client.PresignedPutObject(ctx, bucket, key, expiry)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+// ─── マルチパートアップロード（ports.MultipartStorage） ───────────
+
+// CreateMultipartUpload は S3 マルチパートアップロードを開始する。
+func (a *minioAdapter) CreateMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	return a.core.NewMultipartUpload(ctx, a.bucket, key, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+}
+
+// UploadPart は 1 パートを送信し、ETag を返す。
+func (a *minioAdapter) UploadPart(ctx context.Context, key, uploadID string, partNumber int, reader io.Reader, size int64) (string, error) {
+	part, err := a.core.PutObjectPart(ctx, a.bucket, key, uploadID, partNumber, reader, size, minio.PutObjectPartOptions{})
+	if err != nil {
+		return "", err
+	}
+	return part.ETag, nil
+}
+
+// CompleteMultipartUpload はパートを結合してオブジェクトを確定する。
+func (a *minioAdapter) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []ports.MultipartPart) error {
+	completeParts := make([]minio.CompletePart, len(parts))
+	for i, p := range parts {
+		completeParts[i] = minio.CompletePart{PartNumber: p.PartNumber, ETag: p.ETag}
+	}
+	_, err := a.core.CompleteMultipartUpload(ctx, a.bucket, key, uploadID, completeParts, minio.PutObjectOptions{})
+	return err
+}
+
+// AbortMultipartUpload は未完了のマルチパートアップロードを破棄する。
+func (a *minioAdapter) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	return a.core.AbortMultipartUpload(ctx, a.bucket, key, uploadID)
+}
+
+// ListObjects はバケット内のキーが prefix で始まるオブジェクトを列挙する。
+func (a *minioAdapter) ListObjects(ctx context.Context, prefix string, fn func(ports.ObjectInfo) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // fn のエラーで中断した場合に列挙 goroutine を止める
+	for obj := range a.client.ListObjects(ctx, a.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return obj.Err
+		}
+		if err := fn(ports.ObjectInfo{
+			Key:          obj.Key,
+			Size:         obj.Size,
+			ContentType:  obj.ContentType,
+			StoragePath:  formatPath(SchemeMinio, a.bucket, obj.Key),
+			LastModified: obj.LastModified,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
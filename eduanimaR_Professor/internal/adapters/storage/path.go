@@ -0,0 +1,48 @@
+package storage
+
+import "strings"
+
+// ストレージパスのスキーム。domain.File.StoragePath は必ずスキーム付きで保存する。
+//
+//	minio://{bucket}/{key}
+//	gs://{bucket}/{key}
+//	file:///{absolute path}
+const (
+	SchemeMinio = "minio"
+	SchemeGCS   = "gs"
+	SchemeFile  = "file"
+)
+
+// formatPath はスキーム付きストレージパスを組み立てる。
+func formatPath(scheme, bucket, key string) string {
+	return scheme + "://" + bucket + "/" + key
+}
+
+// splitScheme はストレージパスからスキームと残りを取り出す。
+// スキームを持たない値（オブジェクトキー、またはスキーム導入前に保存されたパス）は scheme="" を返す。
+func splitScheme(location string) (scheme, rest string) {
+	i := strings.Index(location, "://")
+	if i <= 0 {
+		return "", location
+	}
+	return location[:i], location[i+3:]
+}
+
+// splitBucketPath は "{scheme}://{bucket}/{key}" を bucket と key に分解する。
+// スキームが一致しない場合は ok=false を返す。
+// スキームを持たない値は defaultBucket 内のキーとして扱う。
+func splitBucketPath(location, wantScheme, defaultBucket string) (bucket, key string, ok bool) {
+	scheme, rest := splitScheme(location)
+	switch scheme {
+	case "":
+		return defaultBucket, rest, true
+	case wantScheme:
+		bucket, key, found := strings.Cut(rest, "/")
+		if !found || bucket == "" || key == "" {
+			return "", "", false
+		}
+		return bucket, key, true
+	default:
+		return "", "", false
+	}
+}
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+// BucketEnsurer はバケットの存在を保証できるストレージ（MinIO / GCS）
+type BucketEnsurer interface {
+	EnsureBucket(ctx context.Context) error
+}
+
+type router struct {
+	primary  ports.ObjectStorage
+	legacy   ports.ObjectStorage            // スキームを持たないパスの読み出し・削除先
+	backends map[string]ports.ObjectStorage // スキーム → バックエンド
+}
+
+// multipartRouter は primary が ports.MultipartStorage を実装する場合の router
+type multipartRouter struct {
+	*router
+	ports.MultipartStorage
+}
+
+// NewRouter はストレージパスのスキームでバックエンドを振り分ける ObjectStorage を返す。
+// 新規の書き込み（Upload・署名付き PUT・マルチパート）は primary のバックエンドに行い、
+// スキーム付きパスの読み出し・削除はスキームに対応するバックエンドに振り分ける。
+// スキームを持たないパスはスキーム導入前に MinIO が返したキーのため、
+// 読み出し・削除は MinIO が登録されていれば MinIO で処理する（未登録の場合は primary）。
+// primary が ports.MultipartStorage を実装する場合、返却値も実装する。
+func NewRouter(primary string, backends map[string]ports.ObjectStorage) (ports.ObjectStorage, error) {
+	p, ok := backends[primary]
+	if !ok {
+		return nil, fmt.Errorf("primary storage backend %q is not configured", primary)
+	}
+	legacy, ok := backends[SchemeMinio]
+	if !ok {
+		legacy = p
+	}
+	r := &router{primary: p, legacy: legacy, backends: backends}
+	if mp, ok := p.(ports.MultipartStorage); ok {
+		return &multipartRouter{router: r, MultipartStorage: mp}, nil
+	}
+	return r, nil
+}
+
+// backend は読み出し・削除するストレージパスのスキームに対応するバックエンドを返す。
+func (r *router) backend(location string) (ports.ObjectStorage, error) {
+	scheme, _ := splitScheme(location)
+	if scheme == "" {
+		return r.legacy, nil
+	}
+	return r.schemeBackend(scheme)
+}
+
+// writeBackend は書き込み先のバックエンドを返す（スキームを持たないキーは primary に書き込む）。
+func (r *router) writeBackend(location string) (ports.ObjectStorage, error) {
+	scheme, _ := splitScheme(location)
+	if scheme == "" {
+		return r.primary, nil
+	}
+	return r.schemeBackend(scheme)
+}
+
+func (r *router) schemeBackend(scheme string) (ports.ObjectStorage, error) {
+	b, ok := r.backends[scheme]
+	if !ok {
+		return nil, fmt.Errorf("no storage backend for scheme %q: %w", scheme, domain.ErrInvalidInput)
+	}
+	return b, nil
+}
+
+func (r *router) Upload(ctx context.Context, key string, reader io.Reader, size int64, contentType string) (string, error) {
+	b, err := r.writeBackend(key)
+	if err != nil {
+		return "", err
+	}
+	return b.Upload(ctx, key, reader, size, contentType)
+}
+
+func (r *router) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	b, err := r.backend(key)
+	if err != nil {
+		return nil, err
+	}
+	return b.Download(ctx, key)
+}
+
+func (r *router) Delete(ctx context.Context, key string) error {
+	b, err := r.backend(key)
+	if err != nil {
+		return err
+	}
+	return b.Delete(ctx, key)
+}
+
+func (r *router) Stat(ctx context.Context, key string) (*ports.ObjectInfo, error) {
+	b, err := r.backend(key)
+	if err != nil {
+		return nil, err
+	}
+	return b.Stat(ctx, key)
+}
+
+func (r *router) StoragePath(key string) string {
+	return r.primary.StoragePath(key)
+}
+
+func (r *router) GetPresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	b, err := r.backend(key)
+	if err != nil {
+		return "", err
+	}
+	return b.GetPresignedURL(ctx, key, expiry)
+}
+
+func (r *router) GetPresignedPutURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	b, err := r.writeBackend(key)
+	if err != nil {
+		return "", err
+	}
+	return b.GetPresignedPutURL(ctx, key, expiry)
+}
+
+// ListObjects は登録済みの全バックエンドを列挙する（スキーム名順）。
+// バックエンドを切り替えた後も旧バックエンドに残るオブジェクトを対象にできる。
+func (r *router) ListObjects(ctx context.Context, prefix string, fn func(ports.ObjectInfo) error) error {
+	schemes := make([]string, 0, len(r.backends))
+	for scheme := range r.backends {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	for _, scheme := range schemes {
+		if err := r.backends[scheme].ListObjects(ctx, prefix, fn); err != nil {
+			return fmt.Errorf("list %s objects: %w", scheme, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package storage_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/adapters/storage"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/testhelper"
+)
+
+// ─── スキームを持たないパス（スキーム導入前の MinIO キー） ──────────────
+
+func TestRouter_LegacyKeyReadsFromMinio(t *testing.T) {
+	ctx := context.Background()
+	const legacyKey = "user/subject/file/handout.pdf"
+
+	for _, primary := range []string{storage.SchemeGCS, storage.SchemeFile} {
+		t.Run(primary, func(t *testing.T) {
+			minio := new(testhelper.MockObjectStorage)
+			other := new(testhelper.MockObjectStorage)
+			r, err := storage.NewRouter(primary, map[string]ports.ObjectStorage{
+				storage.SchemeMinio: minio,
+				primary:             other,
+			})
+			require.NoError(t, err)
+
+			minio.On("Stat", ctx, legacyKey).Return(&ports.ObjectInfo{Key: legacyKey, Size: 7}, nil)
+			minio.On("Download", ctx, legacyKey).Return(io.NopCloser(strings.NewReader("content")), nil)
+			minio.On("Delete", ctx, legacyKey).Return(nil)
+
+			info, err := r.Stat(ctx, legacyKey)
+			require.NoError(t, err)
+			assert.Equal(t, int64(7), info.Size)
+			rc, err := r.Download(ctx, legacyKey)
+			require.NoError(t, err)
+			_ = rc.Close()
+			require.NoError(t, r.Delete(ctx, legacyKey))
+
+			minio.AssertExpectations(t)
+			other.AssertNotCalled(t, "Stat", mock.Anything, mock.Anything)
+			other.AssertNotCalled(t, "Download", mock.Anything, mock.Anything)
+			other.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestRouter_NewKeyWritesToPrimary(t *testing.T) {
+	ctx := context.Background()
+	const key = "user/subject/file/handout.pdf"
+
+	minio := new(testhelper.MockObjectStorage)
+	gcs := new(testhelper.MockObjectStorage)
+	r, err := storage.NewRouter(storage.SchemeGCS, map[string]ports.ObjectStorage{
+		storage.SchemeMinio: minio,
+		storage.SchemeGCS:   gcs,
+	})
+	require.NoError(t, err)
+
+	body := strings.NewReader("content")
+	gcs.On("Upload", ctx, key, body, int64(7), "application/pdf").Return("gs://materials/"+key, nil)
+	gcs.On("StoragePath", key).Return("gs://materials/" + key)
+	gcs.On("Stat", ctx, "gs://materials/"+key).Return(&ports.ObjectInfo{Key: key, Size: 7}, nil)
+
+	storagePath, err := r.Upload(ctx, key, body, 7, "application/pdf")
+	require.NoError(t, err)
+	assert.Equal(t, "gs://materials/"+key, storagePath)
+	assert.Equal(t, storagePath, r.StoragePath(key))
+	_, err = r.Stat(ctx, r.StoragePath(key))
+	require.NoError(t, err)
+
+	gcs.AssertExpectations(t)
+	minio.AssertNotCalled(t, "Upload", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	minio.AssertNotCalled(t, "Stat", mock.Anything, mock.Anything)
+}
+
+func TestRouter_LegacyKeyWithoutMinioUsesPrimary(t *testing.T) {
+	ctx := context.Background()
+	const key = "user/subject/file/handout.pdf"
+
+	gcs := new(testhelper.MockObjectStorage)
+	r, err := storage.NewRouter(storage.SchemeGCS, map[string]ports.ObjectStorage{
+		storage.SchemeGCS: gcs,
+	})
+	require.NoError(t, err)
+
+	gcs.On("Delete", ctx, key).Return(nil)
+
+	require.NoError(t, r.Delete(ctx, key))
+	gcs.AssertExpectations(t)
+}
+
+// ─── ListObjects ──────────────────────────────────────────────────
+
+func TestRouter_ListObjectsWithoutMinio(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	local, err := storage.NewLocalAdapter(root, "http://localhost/storage", []byte("test-key"))
+	require.NoError(t, err)
+	// MinIO を登録しないローカルのみの構成
+	r, err := storage.NewRouter(storage.SchemeFile, map[string]ports.ObjectStorage{
+		storage.SchemeFile: local,
+	})
+	require.NoError(t, err)
+
+	const key = "user/subject/file/handout.pdf"
+	storagePath, err := r.Upload(ctx, key, strings.NewReader("content"), 7, "application/pdf")
+	require.NoError(t, err)
+
+	var got []ports.ObjectInfo
+	require.NoError(t, r.ListObjects(ctx, "", func(obj ports.ObjectInfo) error {
+		got = append(got, obj)
+		return nil
+	}))
+
+	require.Len(t, got, 1)
+	assert.Equal(t, key, got[0].Key)
+	assert.Equal(t, storagePath, got[0].StoragePath)
+}
+
+func TestRouter_ListObjectsVisitsEveryRegisteredBackend(t *testing.T) {
+	ctx := context.Background()
+	minio := new(testhelper.MockObjectStorage)
+	gcs := new(testhelper.MockObjectStorage)
+	r, err := storage.NewRouter(storage.SchemeGCS, map[string]ports.ObjectStorage{
+		storage.SchemeMinio: minio,
+		storage.SchemeGCS:   gcs,
+	})
+	require.NoError(t, err)
+
+	gcs.On("ListObjects", ctx, "", mock.Anything).Return([]ports.ObjectInfo{{StoragePath: "gs://materials/a"}}, nil)
+	minio.On("ListObjects", ctx, "", mock.Anything).Return([]ports.ObjectInfo{{StoragePath: "minio://eduanima/b"}}, nil)
+
+	var got []string
+	require.NoError(t, r.ListObjects(ctx, "", func(obj ports.ObjectInfo) error {
+		got = append(got, obj.StoragePath)
+		return nil
+	}))
+
+	assert.Equal(t, []string{"gs://materials/a", "minio://eduanima/b"}, got)
+}
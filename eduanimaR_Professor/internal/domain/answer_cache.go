@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	pgvector "github.com/pgvector/pgvector-go"
+)
+
+// AnswerCacheKey は回答キャッシュの検索キー（質問の埋め込みの類似度は別に比べる）
+type AnswerCacheKey struct {
+	SubjectID       uuid.UUID
+	ExtraSubjectIDs []uuid.UUID // ソート済み（指定順によらず同じ検索対象を同じキーにする。無い場合は nil）
+	// MaterialVersion は検索対象の教材のバージョン。教材が変わるとキーが変わり、古いエントリは一致しなくなる
+	MaterialVersion string
+}
+
+// NewAnswerCacheKey は検索対象の subject と教材のバージョンから AnswerCacheKey を作る。
+func NewAnswerCacheKey(subjectID uuid.UUID, extraSubjectIDs []uuid.UUID, materialVersion string) AnswerCacheKey {
+	var extra []uuid.UUID
+	if len(extraSubjectIDs) > 0 {
+		extra = slices.Clone(extraSubjectIDs)
+	}
+	slices.SortFunc(extra, func(a, b uuid.UUID) int { return slices.Compare(a[:], b[:]) })
+	return AnswerCacheKey{SubjectID: subjectID, ExtraSubjectIDs: extra, MaterialVersion: materialVersion}
+}
+
+// CachedAnswer は回答キャッシュのエントリ（生成済みの回答と出典）
+type CachedAnswer struct {
+	ID                uuid.UUID
+	Key               AnswerCacheKey
+	Question          string
+	QuestionEmbedding pgvector.Vector
+	Answer            string
+	Sources           []Source
+	SessionID         *uuid.UUID // 回答を生成した質問セッション
+	HitCount          int
+	CreatedAt         time.Time
+	// Similarity は検索した質問とのコサイン類似度（FindSimilar の結果のみ）
+	Similarity float64
+}
@@ -2,6 +2,8 @@ package domain
 
 import (
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	pgvector "github.com/pgvector/pgvector-go"
@@ -17,6 +19,9 @@ type Chunk struct {
 	Content    string          // OCR/抽出テキスト
 	Embedding  pgvector.Vector // Gemini Embedding（768次元）
 	CreatedAt  time.Time
+	// ManuallyEdited は手動修正されたチャンク（再処理で明示的な指定が無い限り置き換えない）
+	ManuallyEdited bool
+	UpdatedAt      *time.Time // 最後に手動修正した日時
 }
 
 // SearchResult は検索クエリに対するチャンク検索結果
@@ -30,4 +35,82 @@ type SearchResult struct {
 	Content    string
 	FileName   string // JOIN で取得（files.name）
 	CreatedAt  time.Time
+	// JOIN で取得（files.mime_type / files.preview_page_count）。出典のプレビューリンク生成に使う
+	MimeType         string
+	PreviewPageCount int
+	SubjectName      string // JOIN で取得（subjects.name）。複数 subject を横断した場合の出典表示に使う
+	// Score は検索方式ごとの関連度（ベクトル検索: コサイン類似度 / 全文検索: ts_rank）
+	Score float64
+}
+
+// SearchFilter は検索対象の絞り込み条件（ゼロ値は絞り込みなし）
+type SearchFilter struct {
+	FileIDs  []uuid.UUID // 対象の教材（空の場合は subject 内のすべて）
+	PageFrom *int        // ページ番号の下限（含む）。指定時はページ番号の無いチャンクを除く
+	PageTo   *int        // ページ番号の上限（含む）。指定時はページ番号の無いチャンクを除く
+}
+
+// 抽出品質の判定理由
+const (
+	ExtractionTooShort          = "too_short"          // 文字数が少なすぎる（画像のみのページ等）
+	ExtractionInvalidCharacters = "invalid_characters" // 置換文字・制御文字を含む（文字化け）
+	ExtractionLowReadableRatio  = "low_readable_ratio" // 文字・数字・句読点以外の割合が高い
+	ExtractionRepeatedRun       = "repeated_run"       // 同じ文字の長い連続（罫線・OCR のノイズ）
+)
+
+const (
+	extractionMinChars        = 20
+	extractionMinReadableRate = 0.85
+	extractionMaxRunLength    = 16
+)
+
+// ExtractionQuality は OCR/抽出テキストの品質の目安（ヒューリスティック）
+type ExtractionQuality struct {
+	CharCount     int      // 文字数（rune 数）
+	ReadableRatio float64  // 文字・数字・空白・句読点・数式記号の割合（0〜1）
+	Suspect       bool     // 抽出に問題がある可能性が高い
+	Reasons       []string // Suspect の理由（Extraction* 定数）
+}
+
+// AssessExtraction はチャンク本文から抽出品質を推定する。
+// OCR の信頼度は保存していないため、文字種の分布から文字化け・取りこぼしを検出する。
+func AssessExtraction(content string) ExtractionQuality {
+	q := ExtractionQuality{Reasons: []string{}}
+	var readable, invalid, run, maxRun int
+	var prev rune
+	for _, r := range content {
+		q.CharCount++
+		switch {
+		case r == utf8.RuneError || (unicode.IsControl(r) && !unicode.IsSpace(r)) || unicode.Is(unicode.Co, r):
+			invalid++
+		case unicode.IsLetter(r), unicode.IsDigit(r), unicode.IsSpace(r), unicode.IsPunct(r),
+			unicode.IsMark(r), unicode.Is(unicode.Sm, r):
+			readable++
+		}
+		if r == prev && !unicode.IsSpace(r) {
+			run++
+		} else {
+			run = 1
+		}
+		maxRun = max(maxRun, run)
+		prev = r
+	}
+
+	if q.CharCount > 0 {
+		q.ReadableRatio = float64(readable) / float64(q.CharCount)
+	}
+	if q.CharCount < extractionMinChars {
+		q.Reasons = append(q.Reasons, ExtractionTooShort)
+	}
+	if invalid > 0 {
+		q.Reasons = append(q.Reasons, ExtractionInvalidCharacters)
+	}
+	if q.CharCount > 0 && q.ReadableRatio < extractionMinReadableRate {
+		q.Reasons = append(q.Reasons, ExtractionLowReadableRatio)
+	}
+	if maxRun >= extractionMaxRunLength {
+		q.Reasons = append(q.Reasons, ExtractionRepeatedRun)
+	}
+	q.Suspect = len(q.Reasons) > 0
+	return q
 }
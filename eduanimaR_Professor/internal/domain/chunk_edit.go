@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChunkEditOperation はチャンクの手動修正の種類
+type ChunkEditOperation string
+
+const (
+	ChunkEditOperationEdit   ChunkEditOperation = "edit"   // 内容の修正
+	ChunkEditOperationSplit  ChunkEditOperation = "split"  // 1 チャンクを複数に分割
+	ChunkEditOperationMerge  ChunkEditOperation = "merge"  // 連続する複数チャンクを 1 つに結合
+	ChunkEditOperationDelete ChunkEditOperation = "delete" // チャンクの削除
+)
+
+// ChunkSnapshot は修正履歴に残すチャンクの内容
+type ChunkSnapshot struct {
+	ChunkID    uuid.UUID `json:"chunk_id"`
+	ChunkIndex int       `json:"chunk_index"`
+	PageNumber *int      `json:"page_number,omitempty"`
+	Content    string    `json:"content"`
+}
+
+// NewChunkSnapshot は Chunk の現在の内容からスナップショットを作る。
+func NewChunkSnapshot(c *Chunk) ChunkSnapshot {
+	return ChunkSnapshot{
+		ChunkID:    c.ID,
+		ChunkIndex: c.ChunkIndex,
+		PageNumber: c.PageNumber,
+		Content:    c.Content,
+	}
+}
+
+// ChunkEdit はチャンクの手動修正の履歴（1 操作 = 1 レコード）
+type ChunkEdit struct {
+	ID        uuid.UUID
+	FileID    uuid.UUID
+	UserID    uuid.UUID // 修正したユーザー
+	Operation ChunkEditOperation
+	Before    []ChunkSnapshot // 変更前のチャンク
+	After     []ChunkSnapshot // 変更後のチャンク（削除の場合は空）
+	CreatedAt time.Time
+}
@@ -1,6 +1,8 @@
 package domain
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -30,4 +32,38 @@ type File struct {
 	ErrorMessage *string // status=failed 時のエラー詳細
 	UploadedAt   time.Time
 	ProcessedAt  *time.Time // status=ready になった時刻
+	FolderPath   *string    // ZIP 一括取り込み時の ZIP 内フォルダパス（単体アップロードは nil）
+	SourceURL    *string    // URL から取り込んだ教材の取得元（アップロードされた教材は nil）
+	ContentHash  *string    // 取得内容の SHA-256（16 進）。再取得時の変更検知に使う
+	// PreviewPath はページプレビュー画像を置くディレクトリのストレージパス（未生成は nil）
+	PreviewPath      *string
+	PreviewPageCount int // 生成済みプレビューのページ数（未生成は 0）
+}
+
+// IsInProgress は OCR/Embedding の処理待ちまたは処理中かどうかを返す
+func (f *File) IsInProgress() bool {
+	return f.Status == FileStatusPending || f.Status == FileStatusProcessing
+}
+
+// IsImage は画像教材（1 枚のスライド）かどうかを返す
+func (f *File) IsImage() bool {
+	return strings.HasPrefix(f.MimeType, "image/")
+}
+
+// HasPagePreview は指定ページ（1 始まり）のプレビュー画像が生成済みかどうかを返す
+func (f *File) HasPagePreview(page int) bool {
+	return f.PreviewPath != nil && page >= 1 && page <= f.PreviewPageCount
+}
+
+// PagePreviewPath は指定ページのプレビュー画像のストレージパスを返す
+func (f *File) PagePreviewPath(page int) string {
+	if f.PreviewPath == nil {
+		return ""
+	}
+	return *f.PreviewPath + "/" + PagePreviewName(page)
+}
+
+// PagePreviewName はプレビューディレクトリ内のページ画像のオブジェクト名を返す
+func PagePreviewName(page int) string {
+	return fmt.Sprintf("page-%04d.png", page)
 }
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaterialUpload は直接アップロードの予約エンティティ。
+// presigned URL 方式ではクライアントがバケットへ直接 PUT し、
+// 再開可能方式では Professor 経由でバイト範囲を逐次受け取る（S3 マルチパート）。
+// いずれも complete を呼んだ時点で初めて File / IngestJob が作成される。
+type MaterialUpload struct {
+	ID          uuid.UUID
+	FileID      uuid.UUID // 完了時に作成する File の ID（ストレージキーに含まれる）
+	SubjectID   uuid.UUID
+	UserID      uuid.UUID
+	FileName    string
+	StorageKey  string // {userID}/{subjectID}/{fileID}/{fileName}
+	MimeType    string // クライアント申告値
+	SizeBytes   int64  // クライアント申告値
+	ExpiresAt   time.Time
+	CompletedAt *time.Time
+	CreatedAt   time.Time
+
+	// 再開可能アップロードのみ使用
+	MultipartUploadID *string // ストレージ側のマルチパートアップロード ID（nil: presigned PUT 方式）
+	UploadedBytes     int64   // 受信済みバイト数（= 次に受け付けるオフセット）
+}
+
+// MaterialUploadPart は再開可能アップロードで受信済みのパート
+type MaterialUploadPart struct {
+	PartNumber int // 1 始まり
+	ETag       string
+	SizeBytes  int64
+}
+
+// IsCompleted は complete 済みかどうかを返す
+func (u *MaterialUpload) IsCompleted() bool {
+	return u.CompletedAt != nil
+}
+
+// IsExpired は予約の有効期限を過ぎているかどうかを返す
+func (u *MaterialUpload) IsExpired(now time.Time) bool {
+	return now.After(u.ExpiresAt)
+}
+
+// IsResumable は再開可能（マルチパート）方式かどうかを返す
+func (u *MaterialUpload) IsResumable() bool {
+	return u.MultipartUploadID != nil
+}
@@ -1,6 +1,9 @@
 package domain
 
 import (
+	"encoding/base64"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,24 +11,166 @@ import (
 
 // QASession は質問応答セッションエンティティ
 type QASession struct {
-	ID         uuid.UUID
-	UserID     uuid.UUID
-	SubjectID  uuid.UUID
-	Question   string
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	SubjectID uuid.UUID
+	// ExtraSubjectIDs は SubjectID に加えて検索対象にした subject（横断質問でない場合は空）
+	ExtraSubjectIDs []uuid.UUID
+	Question        string
+	// Answer / Sources / Feedback / AnsweredAt / Status は SelectedVersion の回答版の値
 	Answer     *string  // SSE ストリーミング完了後に保存
 	Sources    []Source // JSONB として永続化
 	Feedback   *int     // -1: bad, 1: good, nil: 未評価
 	CreatedAt  time.Time
 	AnsweredAt *time.Time
+	// QueryExpansions はベクトル検索クエリの書き換え・拡張の記録（監査用。拡張しなかった場合は空）
+	QueryExpansions []QueryExpansion
+	Status          QASessionStatus
+	// SelectedVersion はクライアントに表示する回答版（1 始まり。再生成すると新しい版が選ばれる）
+	SelectedVersion int
+	// LatestVersion は最新の回答版の番号（回答版の数）
+	LatestVersion int
+}
+
+// AnswerVersion は質問セッションの回答の版（最初の回答が 1、再生成するたびに増える）。
+// フィードバックは版ごとに記録する。
+type AnswerVersion struct {
+	SessionID  uuid.UUID
+	Version    int
+	Answer     *string
+	Sources    []Source
+	Feedback   *int // -1: bad, 1: good, nil: 未評価
+	Status     QASessionStatus
+	Retrieved  bool // 検索し直して生成した版（false の場合は前の版の出典を再利用した）
+	CreatedAt  time.Time
+	AnsweredAt *time.Time
+}
+
+// QASessionStatus は質問セッションの回答生成の状態
+type QASessionStatus string
+
+const (
+	QASessionStatusRunning   QASessionStatus = "running"   // 回答生成中
+	QASessionStatusCompleted QASessionStatus = "completed" // 回答済み
+	QASessionStatusCancelled QASessionStatus = "cancelled" // クライアントの切断・中断（途中までの回答を保存）
+	QASessionStatusFailed    QASessionStatus = "failed"    // エラーで終了（途中までの回答を保存）
+)
+
+// QASessionFilter は質問セッション一覧（会話履歴）の条件
+type QASessionFilter struct {
+	Query string           // 質問に含まれる文字列（空の場合は絞り込まない）
+	After *QASessionCursor // この位置より古いセッションを返す（nil の場合は最新から）
+	Limit int
+}
+
+// QASessionCursor は会話履歴のキーセットページングの位置（新しい順の一覧の最後のセッション）
+type QASessionCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// CursorAfter はセッション s の次から一覧を続ける位置を返す
+func CursorAfter(s *QASession) QASessionCursor {
+	return QASessionCursor{CreatedAt: s.CreatedAt, ID: s.ID}
+}
+
+// String はクライアントに渡す不透明な文字列を返す（ParseQASessionCursor で戻せる）
+func (c QASessionCursor) String() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "_" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseQASessionCursor は QASessionCursor.String の文字列を位置に戻す（不正な場合は ErrInvalidInput）
+func ParseQASessionCursor(s string) (QASessionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return QASessionCursor{}, fmt.Errorf("cursor: %w", ErrInvalidInput)
+	}
+	ts, id, ok := strings.Cut(string(raw), "_")
+	if !ok {
+		return QASessionCursor{}, fmt.Errorf("cursor: %w", ErrInvalidInput)
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return QASessionCursor{}, fmt.Errorf("cursor: %w", ErrInvalidInput)
+	}
+	sessionID, err := uuid.Parse(id)
+	if err != nil {
+		return QASessionCursor{}, fmt.Errorf("cursor: %w", ErrInvalidInput)
+	}
+	return QASessionCursor{CreatedAt: createdAt, ID: sessionID}, nil
+}
+
+// ScopeSubjectIDs は検索対象の subject（SubjectID と ExtraSubjectIDs）を返す
+func (s *QASession) ScopeSubjectIDs() []uuid.UUID {
+	return append([]uuid.UUID{s.SubjectID}, s.ExtraSubjectIDs...)
 }
 
 // Source は回答の参照元チャンク情報
 type Source struct {
-	FileID     uuid.UUID `json:"file_id"`
-	ChunkID    uuid.UUID `json:"chunk_id"`
-	FileName   string    `json:"file_name"`
-	PageNumber *int      `json:"page_number,omitempty"`
-	Excerpt    string    `json:"excerpt"` // 抜粋テキスト（最大 300 文字程度）
+	// SubjectID / SubjectName は出典の subject（複数 subject を横断した質問でどの科目の教材か示す）
+	SubjectID   uuid.UUID `json:"subject_id"`
+	SubjectName string    `json:"subject_name,omitempty"`
+	FileID      uuid.UUID `json:"file_id"`
+	ChunkID     uuid.UUID `json:"chunk_id"`
+	FileName    string    `json:"file_name"`
+	PageNumber  *int      `json:"page_number,omitempty"`
+	Excerpt     string    `json:"excerpt"` // 抜粋テキスト（最大 300 文字程度）
+	// PreviewURL は出典ページのプレビュー画像 API のパス（プレビューが無い場合は空）。
+	// 署名付き URL は期限切れになるため、永続化するのは API パスとし、
+	// API がアクセス時に署名付き URL へリダイレクトする。
+	PreviewURL string `json:"preview_url,omitempty"`
+	// Context は回答生成に渡した前後文脈の範囲（隣接チャンクで補わなかった場合は nil）
+	Context *EvidenceContext `json:"context,omitempty"`
+	// WhyRelevant は Librarian がエビデンスに選んだ理由（フォールバックで選んだ場合などは空）
+	WhyRelevant string `json:"why_relevant,omitempty"`
+}
+
+// QueryExpansion はベクトル検索クエリ 1 件の書き換え・拡張結果。
+// 「R²」「決定係数」のような短いクエリは埋め込みの一致が弱いため、
+// 変形もすべて埋め込んで検索し、結果を統合する。
+type QueryExpansion struct {
+	Query              string   `json:"query"`                         // Librarian が指定した元のクエリ
+	Paraphrases        []string `json:"paraphrases,omitempty"`         // 言い換え
+	HypotheticalAnswer string   `json:"hypothetical_answer,omitempty"` // 仮想的な回答文（HyDE）
+	Translations       []string `json:"translations,omitempty"`        // 日本語・英語の訳
+}
+
+// Variants は検索に使う変形を返す（元のクエリ・空文字列・重複を除く）
+func (e QueryExpansion) Variants() []string {
+	seen := map[string]struct{}{e.Query: {}}
+	var variants []string
+	add := func(v string) {
+		if _, ok := seen[v]; ok || v == "" {
+			return
+		}
+		seen[v] = struct{}{}
+		variants = append(variants, v)
+	}
+	for _, v := range e.Paraphrases {
+		add(v)
+	}
+	add(e.HypotheticalAnswer)
+	for _, v := range e.Translations {
+		add(v)
+	}
+	return variants
+}
+
+// EvidenceContext はエビデンスを同じ教材の隣接チャンクで補った範囲。
+// 定義・数式がチャンク境界をまたぐ場合に、前後のチャンクをまとめて回答生成に渡す。
+type EvidenceContext struct {
+	ChunkIDs       []uuid.UUID `json:"chunk_ids"` // chunk_index 順（エビデンス自身を含む）
+	ChunkIndexFrom int         `json:"chunk_index_from"`
+	ChunkIndexTo   int         `json:"chunk_index_to"`
+	PageFrom       *int        `json:"page_from,omitempty"`
+	PageTo         *int        `json:"page_to,omitempty"`
+}
+
+// PagePreviewAPIPath は教材ページのプレビュー画像 API のパスを返す
+// （MaterialHandler の GET /materials/:fid/pages/:page/preview に対応）
+func PagePreviewAPIPath(subjectID, fileID uuid.UUID, page int) string {
+	return fmt.Sprintf("/api/v1/subjects/%s/materials/%s/pages/%d/preview", subjectID, fileID, page)
 }
 
 // SSEEvent は SSE ストリーミングで送信するイベント型
@@ -35,6 +180,7 @@ const (
 	SSEEventThinking  SSEEventType = "thinking"  // Librarian が推論中
 	SSEEventSearching SSEEventType = "searching" // 検索クエリ実行中
 	SSEEventEvidence  SSEEventType = "evidence"  // エビデンスチャンク発見
+	SSEEventCaveat    SSEEventType = "caveat"    // 回答の注意（検索の打ち切り・検索結果のみの回答）
 	SSEEventAnswer    SSEEventType = "answer"    // 回答テキスト（チャンク送信）
 	SSEEventDone      SSEEventType = "done"      // ストリーミング完了
 	SSEEventError     SSEEventType = "error"     // エラー発生
@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReasoningTrace は回答版 1 件の推論の記録。
+// 質の悪い回答を後から調べるためのデバッグ用で、Librarian の検索ラウンド・選んだエビデンスと
+// 選んだ理由・Librarian の終了結果・使ったモデル・所要時間を回答版ごとに保存する。
+type ReasoningTrace struct {
+	// Retrieved が false の場合は前の回答版の出典を再利用した（Librarian を呼ばないため Rounds は空）
+	Retrieved bool `json:"retrieved"`
+	// ResearchMode・Constraints は Librarian に調べさせた深さと、そのとき送った制約（Retrieved の場合のみ）
+	ResearchMode ResearchMode      `json:"research_mode,omitempty"`
+	Constraints  *TraceConstraints `json:"constraints,omitempty"`
+	Rounds       []SearchRound     `json:"rounds,omitempty"`
+	// Evidences は回答生成に渡したエビデンス（Librarian が選んだもの、またはフォールバックで選んだもの）
+	Evidences []TraceEvidence `json:"evidences,omitempty"`
+	// Cache は回答キャッシュの回答を再利用した場合の記録（生成した場合は nil）
+	Cache *TraceCacheHit `json:"cache,omitempty"`
+
+	// Librarian の終了結果（LibrarianThinkResult）
+	CoverageNotes string `json:"coverage_notes,omitempty"`
+	IsPartial     bool   `json:"is_partial,omitempty"`
+	ErrorType     string `json:"error_type,omitempty"`
+
+	// Error は回答生成を終えたエラー（中断・正常終了の場合は空）
+	Error string `json:"error,omitempty"`
+
+	Models     TraceModels  `json:"models"`
+	Timings    TraceTimings `json:"timings"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt time.Time    `json:"finished_at"`
+}
+
+// SearchRound は Librarian の検索ラウンド 1 回の記録
+type SearchRound struct {
+	Round         int      `json:"round"` // 1 始まり
+	QueriesText   []string `json:"queries_text,omitempty"`
+	QueriesVector []string `json:"queries_vector,omitempty"`
+	Rationale     string   `json:"rationale,omitempty"`
+	// Searches は実行した検索の数（ベクトル検索クエリの変形を含む）、FailedSearches はそのうち失敗・制限時間を超えた数
+	Searches       int `json:"searches"`
+	FailedSearches int `json:"failed_searches,omitempty"`
+	// Candidates は前のラウンドまでに選んでいない候補のチャンク数、Selected はそのうち MMR で選んだ数
+	Candidates       int   `json:"candidates"`
+	Selected         int   `json:"selected"`
+	TotalAccumulated int   `json:"total_accumulated"` // Librarian に返した累積の検索結果数
+	DurationMs       int64 `json:"duration_ms"`
+}
+
+// TraceEvidence は回答生成に渡したエビデンス 1 件の記録
+type TraceEvidence struct {
+	// TempIndex は累積の検索結果での位置（Librarian が指定した番号。前の版の出典を再利用した場合は nil）
+	TempIndex   *int      `json:"temp_index,omitempty"`
+	ChunkID     uuid.UUID `json:"chunk_id"`
+	FileID      uuid.UUID `json:"file_id"`
+	FileName    string    `json:"file_name,omitempty"`
+	PageNumber  *int      `json:"page_number,omitempty"`
+	WhyRelevant string    `json:"why_relevant,omitempty"`
+	// Fallback は Librarian がエビデンスを返さず、累積の検索結果の上位から選んだもの
+	Fallback bool `json:"fallback,omitempty"`
+}
+
+// TraceCacheHit は再利用した回答キャッシュのエントリ
+type TraceCacheHit struct {
+	CacheID    uuid.UUID  `json:"cache_id"`
+	SessionID  *uuid.UUID `json:"session_id,omitempty"` // キャッシュした回答を生成したセッション
+	Question   string     `json:"question"`
+	Similarity float64    `json:"similarity"`
+}
+
+// TraceConstraints は Librarian に送った推論の制約（0 は Librarian の既定値）
+type TraceConstraints struct {
+	MaxLoops   int   `json:"max_loops"`
+	MaxResults int   `json:"max_results"`
+	TimeoutMs  int64 `json:"timeout_ms"`
+}
+
+// TraceModels は回答生成に使ったモデル名（使わなかった機能は空）
+type TraceModels struct {
+	Answer         string `json:"answer,omitempty"`
+	Embedding      string `json:"embedding,omitempty"`
+	Rerank         string `json:"rerank,omitempty"`
+	QueryExpansion string `json:"query_expansion,omitempty"`
+}
+
+// TraceTimings は回答生成の各段階の所要時間（ミリ秒）
+type TraceTimings struct {
+	ThinkMs      int64 `json:"think_ms"`       // Librarian Think（検索を含む）
+	FirstTokenMs int64 `json:"first_token_ms"` // 回答のストリーミング開始から最初のテキストまで
+	AnswerMs     int64 `json:"answer_ms"`      // 回答のストリーミング生成
+	TotalMs      int64 `json:"total_ms"`
+}
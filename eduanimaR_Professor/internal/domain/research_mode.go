@@ -0,0 +1,23 @@
+package domain
+
+import "fmt"
+
+// ResearchMode は質問に答えるときに Librarian に調べさせる深さ。
+// 検索ラウンド数・検索結果数・制限時間の組（Librarian の Constraints）を選ぶ。
+type ResearchMode string
+
+const (
+	ResearchModeQuick    ResearchMode = "quick"    // 速く答える（検索ラウンド・検索結果を絞る）
+	ResearchModeStandard ResearchMode = "standard" // 既定
+	ResearchModeDeep     ResearchMode = "deep"     // 時間をかけて広く調べる
+)
+
+// ParseResearchMode は調べる深さの文字列を返す（不正な場合は ErrInvalidInput）。
+func ParseResearchMode(s string) (ResearchMode, error) {
+	switch m := ResearchMode(s); m {
+	case ResearchModeQuick, ResearchModeStandard, ResearchModeDeep:
+		return m, nil
+	default:
+		return "", fmt.Errorf("research mode %q: %w", s, ErrInvalidInput)
+	}
+}
@@ -12,6 +12,8 @@ type Subject struct {
 	UserID      uuid.UUID
 	Name        string
 	LMSCourseID *string // nullable（将来の LMS 連携用 / Phase 1 未使用）
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	// ResearchMode はこの科目の質問で Librarian に調べさせる深さ（質問ごとに上書き可能）
+	ResearchMode ResearchMode
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
 }
@@ -0,0 +1,20 @@
+package ports
+
+import "context"
+
+// FetchedDocument は外部 URL から取得したドキュメント
+type FetchedDocument struct {
+	URL         string // リダイレクト解決後の最終 URL
+	FileName    string // Content-Disposition または URL パスから推定したファイル名
+	ContentType string // レスポンスの Content-Type（未指定時は内容から推定）
+	Body        []byte
+}
+
+// DocumentFetcher は外部 URL から教材ドキュメントを取得する。
+// 実装は SSRF 対策として内部ネットワーク宛ての接続を拒否しなければならない。
+type DocumentFetcher interface {
+	// Fetch は rawURL を取得する。
+	// 内部ネットワーク宛ての場合は domain.ErrForbidden、
+	// maxBytes 超過・非 2xx 応答・不正な URL の場合は domain.ErrInvalidInput を返す
+	Fetch(ctx context.Context, rawURL string, maxBytes int64) (*FetchedDocument, error)
+}
@@ -2,6 +2,7 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -17,14 +18,30 @@ type LibrarianSearchRequest struct {
 
 // LibrarianSearchResponse は Professor から Librarian への検索結果
 type LibrarianSearchResponse struct {
+	// Results はそれまでの検索を含む累積の結果（前の検索の結果が先頭に同じ順で並ぶため TempIndex が安定する）
 	Results []domain.SearchResult
 }
 
+// LibrarianConstraints は Librarian の推論の制約（0 の項目は Librarian の既定値）
+type LibrarianConstraints struct {
+	MaxLoops   int           // 検索ラウンドの最大数
+	MaxResults int           // 1 クエリあたりの検索結果の上限
+	Timeout    time.Duration // 推論全体の制限時間
+}
+
+// Librarian の ErrorAction のエラー種別（LibrarianThinkResult.ErrorType）
+const (
+	LibrarianErrorLoopLimit    = "LOOP_LIMIT"    // 検索ラウンドの上限に達した（それまでの結果で回答できる）
+	LibrarianErrorTimeout      = "TIMEOUT"       // 推論の制限時間を超えた（それまでの結果で回答できる）
+	LibrarianErrorInvalidInput = "INVALID_INPUT" // 質問を扱えない（学生が質問を直す必要がある）
+	LibrarianErrorModelFailure = "MODEL_FAILURE" // Librarian の LLM 呼び出しに失敗した
+)
+
 // LibrarianThinkResult は Librarian の推論完了結果
 type LibrarianThinkResult struct {
 	Evidences     []LibrarianEvidence
-	CoverageNotes string // 充足している点・不確実な点の説明
-	IsPartial     bool   // max_retries 未達でも回答に進んだ場合 true
+	CoverageNotes string // 充足している点・不確実な点の説明（ErrorAction の場合はエラーの説明）
+	IsPartial     bool   // 検索を打ち切り、それまでの結果で回答に進んだ場合 true（LOOP_LIMIT / TIMEOUT）
 	ErrorType     string // エラー発生時のエラー種別（空文字の場合は正常）
 }
 
@@ -37,14 +54,17 @@ type LibrarianEvidence struct {
 // LibrarianClient は Professor から Librarian への gRPC 通信を抽象化する
 type LibrarianClient interface {
 	// Think は双方向ストリーミングで Librarian に推論を依頼する。
+	// constraints: 検索ラウンド数・検索結果数・制限時間の制約（調べる深さに応じて決める）
 	// onSearchRequest: Librarian が検索を要求するたびに呼ばれるコールバック
 	//   → Professor は subject_id/user_id による物理制約を強制してから検索を実行する
+	// 実装はそれまでの検索の履歴（クエリ・検索の意図・件数）を次のリクエストに含めて送る。
 	Think(
 		ctx context.Context,
 		requestID string,
 		userQuery string,
 		subjectID uuid.UUID,
 		userID uuid.UUID,
+		constraints LibrarianConstraints,
 		onSearchRequest func(req LibrarianSearchRequest) (*LibrarianSearchResponse, error),
 	) (*LibrarianThinkResult, error)
 }
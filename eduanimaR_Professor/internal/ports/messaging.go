@@ -10,6 +10,8 @@ type IngestMessage struct {
 	UserID      string `json:"user_id"`
 	StoragePath string `json:"storage_path"` // MinIO/GCS パス
 	MimeType    string `json:"mime_type"`
+	// OverwriteManualEdits が true の場合、手動修正されたチャンクも OCR 結果で置き換える
+	OverwriteManualEdits bool `json:"overwrite_manual_edits,omitempty"`
 }
 
 // MessagePublisher は Kafka プロデューサーを抽象化する
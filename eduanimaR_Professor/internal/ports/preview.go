@@ -0,0 +1,16 @@
+package ports
+
+import "context"
+
+// PageImage は 1 ページ分のプレビュー画像（PNG）
+type PageImage struct {
+	PageNumber int // 1 始まり
+	Data       []byte
+}
+
+// PageRenderer は教材のページごとのプレビュー画像（サムネイル）を生成する。
+type PageRenderer interface {
+	// RenderPages は先頭から最大 maxPages ページのプレビュー画像を PNG で返す。
+	// 対応していない形式の場合は (nil, nil) を返す
+	RenderPages(ctx context.Context, content []byte, mimeType string, maxPages int) ([]PageImage, error)
+}
@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+)
+
+// QueryExpander はベクトル検索クエリを書き換え・拡張する。
+// 言い換え・仮想的な回答文（HyDE）・日本語/英語の訳を生成し、短いクエリの検索精度を補う。
+type QueryExpander interface {
+	// ExpandQuery は query の変形を生成する（返り値の Query は query）
+	ExpandQuery(ctx context.Context, query string) (*domain.QueryExpansion, error)
+}
@@ -4,6 +4,7 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	pgvector "github.com/pgvector/pgvector-go"
@@ -25,6 +26,8 @@ type SubjectRepository interface {
 	GetByIDAndUserID(ctx context.Context, id, userID uuid.UUID) (*domain.Subject, error)
 	Create(ctx context.Context, subject *domain.Subject) error
 	UpdateName(ctx context.Context, id, userID uuid.UUID, name string) (*domain.Subject, error)
+	// UpdateResearchMode は科目の質問で Librarian に調べさせる深さを更新する
+	UpdateResearchMode(ctx context.Context, id, userID uuid.UUID, mode domain.ResearchMode) (*domain.Subject, error)
 	Delete(ctx context.Context, id, userID uuid.UUID) error
 }
 
@@ -33,20 +36,68 @@ type FileRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.File, error)
 	GetByIDAndUserID(ctx context.Context, id, userID uuid.UUID) (*domain.File, error)
 	ListBySubjectID(ctx context.Context, subjectID uuid.UUID) ([]*domain.File, error)
+	// ListAll は全ユーザーの教材を返す（ストレージ整合性チェック用）
+	ListAll(ctx context.Context) ([]*domain.File, error)
+	// Create は教材を作成する（同じ ID の教材が存在する場合は domain.ErrConflict）
 	Create(ctx context.Context, file *domain.File) error
 	UpdateStatus(ctx context.Context, id uuid.UUID, status domain.FileStatus, errMsg *string) (*domain.File, error)
+	// UpdateContent は差し替えた内容のメタデータ（教材名を含む）を記録し、status を pending に戻す
+	UpdateContent(ctx context.Context, id uuid.UUID, name, storagePath, mimeType string, size int64, contentHash string) (*domain.File, error)
+	// UpdatePreviews はページプレビューの保存先とページ数を記録する（previewPath=nil で未生成に戻す）
+	UpdatePreviews(ctx context.Context, id uuid.UUID, previewPath *string, pageCount int) (*domain.File, error)
 	Delete(ctx context.Context, id, userID uuid.UUID) error
 }
 
+// MaterialUploadRepository は直接アップロード予約の永続化操作を抽象化する
+type MaterialUploadRepository interface {
+	Create(ctx context.Context, upload *domain.MaterialUpload) error
+	GetByIDAndUserID(ctx context.Context, id, userID uuid.UUID) (*domain.MaterialUpload, error)
+	// MarkCompleted は未完了の予約を完了にする（完了済みの場合は domain.ErrConflict）
+	MarkCompleted(ctx context.Context, id uuid.UUID) (*domain.MaterialUpload, error)
+	// ListExpired は before より前に期限切れとなった未完了の予約を返す
+	ListExpired(ctx context.Context, before time.Time, limit int) ([]*domain.MaterialUpload, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	// AddPart は受信済みバイト数が expectedOffset と一致する場合のみパートを記録し、
+	// 受信済みバイト数を進めて有効期限を expiresAt に延長する（不一致の場合は domain.ErrConflict）
+	AddPart(ctx context.Context, id uuid.UUID, expectedOffset int64, part domain.MaterialUploadPart, expiresAt time.Time) error
+	ListParts(ctx context.Context, id uuid.UUID) ([]domain.MaterialUploadPart, error)
+}
+
 // ChunkRepository はチャンク（pgvector）の永続化・検索操作を抽象化する
 type ChunkRepository interface {
 	ListByFileID(ctx context.Context, fileID uuid.UUID) ([]*domain.Chunk, error)
+	// ListPageByFileID は chunk_index 順にページング取得する（閲覧用のため Embedding は含まない）
+	ListPageByFileID(ctx context.Context, fileID uuid.UUID, limit, offset int) ([]*domain.Chunk, error)
+	CountByFileID(ctx context.Context, fileID uuid.UUID) (int64, error)
+	// ListNeighbours はエビデンスの前後文脈として、chunk_index が indexFrom〜indexTo の範囲、
+	// または page（nil の場合は範囲のみ）が一致するチャンクを chunk_index 順に返す（Embedding は含まない）
+	ListNeighbours(ctx context.Context, fileID uuid.UUID, indexFrom, indexTo int, page *int) ([]*domain.Chunk, error)
+	// GetEmbeddings は指定チャンクの Embedding を chunk_id ごとに返す（検索結果の多様性の評価に使う）
+	GetEmbeddings(ctx context.Context, chunkIDs []uuid.UUID) (map[uuid.UUID]pgvector.Vector, error)
+	// GetByID はチャンクを取得する（存在しない場合は domain.ErrNotFound）
+	GetByID(ctx context.Context, id uuid.UUID) (*domain.Chunk, error)
 	BatchCreate(ctx context.Context, chunks []*domain.Chunk) error
-	// SearchByVector: HNSW コサイン類似度検索（subject_id で物理絞り込み）
-	SearchByVector(ctx context.Context, subjectID uuid.UUID, embedding pgvector.Vector, limit int) ([]*domain.SearchResult, error)
-	// SearchByText: PostgreSQL 全文検索（subject_id で物理絞り込み）
-	SearchByText(ctx context.Context, subjectID uuid.UUID, query string, limit int) ([]*domain.SearchResult, error)
+	// SearchByVector: HNSW コサイン類似度検索（所有権確認済みの subjectIDs で物理絞り込み、filter で教材・ページを絞り込み）
+	SearchByVector(ctx context.Context, subjectIDs []uuid.UUID, embedding pgvector.Vector, limit int, filter domain.SearchFilter) ([]*domain.SearchResult, error)
+	// SearchByText: PostgreSQL 全文検索（所有権確認済みの subjectIDs で物理絞り込み、filter で教材・ページを絞り込み）
+	SearchByText(ctx context.Context, subjectIDs []uuid.UUID, query string, limit int, filter domain.SearchFilter) ([]*domain.SearchResult, error)
 	DeleteByFileID(ctx context.Context, fileID uuid.UUID) error
+	// ReplaceByFileID は教材の既存チャンクを削除して chunks を保存する（1 トランザクション。失敗時は既存チャンクが残る）
+	ReplaceByFileID(ctx context.Context, fileID uuid.UUID, chunks []*domain.Chunk) error
+	// ApplyEdit は手動修正による変更と修正履歴の記録を 1 トランザクションで行う
+	// （edit.Before のチャンクが並行する修正で削除・変更されていた場合は domain.ErrConflict）
+	ApplyEdit(ctx context.Context, edit *domain.ChunkEdit, changes ChunkChanges) error
+	// ListEdits は修正履歴を新しい順にページング取得する
+	ListEdits(ctx context.Context, fileID uuid.UUID, limit, offset int) ([]*domain.ChunkEdit, error)
+	CountEdits(ctx context.Context, fileID uuid.UUID) (int64, error)
+}
+
+// ChunkChanges は手動修正でチャンクに適用する変更。Delete → Shift → Save の順に適用する。
+type ChunkChanges struct {
+	Delete    []uuid.UUID     // 削除するチャンク
+	ShiftFrom int             // chunk_index が ShiftFrom 以上のチャンクを ShiftBy だけずらす
+	ShiftBy   int             // 0 の場合はずらさない
+	Save      []*domain.Chunk // 作成または更新するチャンク（手動修正済みとして保存する）
 }
 
 // IngestJobRepository はインジェストジョブの永続化操作を抽象化する
@@ -58,12 +109,53 @@ type IngestJobRepository interface {
 }
 
 // QASessionRepository は質問応答セッションの永続化操作を抽象化する
+// 回答は版（domain.AnswerVersion）ごとに保存し、セッションの Answer / Sources / Feedback / Status は
+// 選ばれている版（SelectedVersion）の値を返す。
 type QASessionRepository interface {
+	// Create はセッションと最初の回答版（版 1）を作成する
 	Create(ctx context.Context, session *domain.QASession) error
 	GetByID(ctx context.Context, id uuid.UUID) (*domain.QASession, error)
 	GetByIDAndUserID(ctx context.Context, id, userID uuid.UUID) (*domain.QASession, error)
-	ListBySubjectID(ctx context.Context, subjectID, userID uuid.UUID, limit, offset int) ([]*domain.QASession, error)
-	CountBySubjectID(ctx context.Context, subjectID, userID uuid.UUID) (int64, error)
-	UpdateAnswer(ctx context.Context, id uuid.UUID, answer string, sources []domain.Source) (*domain.QASession, error)
+	// ListBySubjectID は filter に合うセッションを (CreatedAt, ID) の新しい順に filter.Limit 件まで返す
+	ListBySubjectID(ctx context.Context, subjectID, userID uuid.UUID, filter domain.QASessionFilter) ([]*domain.QASession, error)
+	// CountBySubjectID は質問に query を含むセッションの件数を返す（query が空の場合はすべて）
+	CountBySubjectID(ctx context.Context, subjectID, userID uuid.UUID, query string) (int64, error)
+	// UpdateAnswer は最新の回答版に回答と出典・推論の記録を保存し、状態を completed にする（trace が nil の場合は記録しない）
+	UpdateAnswer(ctx context.Context, id uuid.UUID, answer string, sources []domain.Source, trace *domain.ReasoningTrace) (*domain.QASession, error)
+	// MarkInterrupted は中断・エラーで終了した最新の回答版に途中までの回答・推論の記録と状態（cancelled / failed）を保存する
+	MarkInterrupted(ctx context.Context, id uuid.UUID, status domain.QASessionStatus, partialAnswer string, sources []domain.Source, trace *domain.ReasoningTrace) (*domain.QASession, error)
+	// UpdateQueryExpansions は検索クエリの書き換え・拡張の記録を保存する（監査用）
+	UpdateQueryExpansions(ctx context.Context, id uuid.UUID, expansions []domain.QueryExpansion) error
+	// UpdateFeedback は選ばれている回答版にフィードバックを記録する
 	UpdateFeedback(ctx context.Context, id, userID uuid.UUID, feedback int) (*domain.QASession, error)
+	// UpdateVersionFeedback は指定した回答版にフィードバックを記録する（版が無い場合は ErrNotFound）
+	UpdateVersionFeedback(ctx context.Context, id, userID uuid.UUID, version, feedback int) (*domain.QASession, error)
+	// AddVersion は回答生成中（running）の回答版を追加して選ぶ（retrieved: 検索し直して生成する版か）
+	AddVersion(ctx context.Context, id, userID uuid.UUID, retrieved bool) (*domain.QASession, error)
+	// SelectVersion は表示する回答版を選ぶ（版が無い場合は ErrNotFound）
+	SelectVersion(ctx context.Context, id, userID uuid.UUID, version int) (*domain.QASession, error)
+	// ListVersions はセッションの回答版を版の順に返す
+	ListVersions(ctx context.Context, id uuid.UUID) ([]*domain.AnswerVersion, error)
+	// GetTrace は回答版の推論の記録を返す（版が無い場合は ErrNotFound、記録が無い場合は nil）
+	GetTrace(ctx context.Context, id uuid.UUID, version int) (*domain.ReasoningTrace, error)
+}
+
+// AnswerCacheRepository は回答キャッシュの永続化を担う。
+type AnswerCacheRepository interface {
+	// MaterialVersion は subjectIDs の教材のバージョンを返す（教材の追加・削除・再処理・チャンク編集で変わる）
+	MaterialVersion(ctx context.Context, subjectIDs []uuid.UUID) (string, error)
+	// FindSimilar は key に一致し createdAfter 以降に作られたエントリのうち、
+	// 質問の埋め込みが最も近いものを返す（無い場合は ErrNotFound）
+	FindSimilar(ctx context.Context, key domain.AnswerCacheKey, embedding pgvector.Vector, createdAfter time.Time) (*domain.CachedAnswer, error)
+	// Save はエントリを保存し、同じ検索対象の古いバージョン・createdAfter より前のエントリを削除する
+	Save(ctx context.Context, entry *domain.CachedAnswer, createdAfter time.Time) error
+	RecordHit(ctx context.Context, id uuid.UUID) error
+}
+
+// EmbeddingCacheRepository は検索クエリの埋め込みキャッシュの永続化を担う。
+type EmbeddingCacheRepository interface {
+	// Get は key の埋め込みを返す（無い場合は ErrNotFound）
+	Get(ctx context.Context, key string) ([]float32, error)
+	// Put は埋め込みを保存する（同じ key が既にある場合は何もしない）
+	Put(ctx context.Context, key, model string, embedding []float32) error
 }
@@ -0,0 +1,11 @@
+package ports
+
+import "context"
+
+// Reranker は（質問, チャンク）の組の関連度を採点する。
+// ベクトル検索のコサイン類似度や全文検索の ts_rank より精度の高い並べ替えに使う。
+// LLM による実装のほか、ローカルの cross-encoder サーバーなどに差し替えられる。
+type Reranker interface {
+	// Rerank は documents の各要素と query の関連度を 0〜1 で返す（documents と同じ順・同じ件数）
+	Rerank(ctx context.Context, query string, documents []string) ([]float64, error)
+}
@@ -6,15 +6,59 @@ import (
 	"time"
 )
 
+// ObjectInfo はストレージ上のオブジェクトのメタデータ
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	StoragePath  string    // スキーム付きストレージパス（ListObjects のみ設定）
+	LastModified time.Time // ListObjects のみ設定
+}
+
 // ObjectStorage はオブジェクトストレージ操作を抽象化する。
-// Phase 1: MinIO（S3互換）実装 / Phase 2: GCS 実装に差し替え
+// 実装: MinIO（S3 互換）/ GCS / ローカルファイルシステム。
+//
+// ストレージパスはスキーム付き（"minio://bucket/key", "gs://bucket/key", "file:///path"）で、
+// key 引数にはオブジェクトキーとストレージパスのどちらも渡せる。
+// ストレージパスを渡した場合はスキームに対応するバックエンドで処理される。
 type ObjectStorage interface {
 	// Upload はファイルをアップロードし、ストレージパスを返す
 	Upload(ctx context.Context, key string, reader io.Reader, size int64, contentType string) (storagePath string, err error)
-	// Download はファイルをダウンロードする
+	// Download はファイルをダウンロードする（存在しない場合は domain.ErrNotFound）
 	Download(ctx context.Context, key string) (io.ReadCloser, error)
-	// Delete はファイルを削除する
+	// Delete はファイルを削除する（存在しない場合もエラーにしない）
 	Delete(ctx context.Context, key string) error
+	// Stat はオブジェクトのメタデータを返す（存在しない場合は domain.ErrNotFound）
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// StoragePath はオブジェクトキーに対応するストレージパスを返す
+	StoragePath(key string) string
 	// GetPresignedURL は署名付き一時 URL を生成する（クライアント向けダウンロード用）
 	GetPresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+	// GetPresignedPutURL は署名付き一時 PUT URL を生成する（クライアントからの直接アップロード用）
+	GetPresignedPutURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+	// ListObjects はキーが prefix で始まるオブジェクトを順に fn に渡す。
+	// マルチパートアップロードの一時オブジェクトは含まない。fn がエラーを返すと中断する
+	ListObjects(ctx context.Context, prefix string, fn func(ObjectInfo) error) error
+}
+
+// MultipartPart はマルチパートアップロードで送信済みのパート
+type MultipartPart struct {
+	PartNumber int
+	ETag       string
 }
+
+// MultipartStorage はマルチパートアップロードを抽象化する（再開可能アップロード用）。
+// 最終パート以外は MinMultipartPartSize 以上である必要がある（S3 の制約）。
+type MultipartStorage interface {
+	// CreateMultipartUpload はマルチパートアップロードを開始し、アップロード ID を返す
+	CreateMultipartUpload(ctx context.Context, key, contentType string) (uploadID string, err error)
+	// UploadPart は 1 パートを送信し、ETag を返す
+	UploadPart(ctx context.Context, key, uploadID string, partNumber int, reader io.Reader, size int64) (etag string, err error)
+	// CompleteMultipartUpload はパートを結合してオブジェクトを確定する
+	CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []MultipartPart) error
+	// AbortMultipartUpload は未完了のマルチパートアップロードを破棄する
+	AbortMultipartUpload(ctx context.Context, key, uploadID string) error
+}
+
+// MinMultipartPartSize は最終パート以外のパートに要求される最小サイズ（5 MiB）
+const MinMultipartPartSize = 5 << 20
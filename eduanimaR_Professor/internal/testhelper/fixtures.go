@@ -16,6 +16,7 @@ var (
 	FixtureJobID     = uuid.MustParse("00000000-0000-0000-0000-000000000004")
 	FixtureChunkID   = uuid.MustParse("00000000-0000-0000-0000-000000000005")
 	FixtureSessionID = uuid.MustParse("00000000-0000-0000-0000-000000000006")
+	FixtureUploadID  = uuid.MustParse("00000000-0000-0000-0000-000000000007")
 )
 
 // NewSubject はテスト用 Subject を生成する。
@@ -73,3 +74,23 @@ func NewFile(status domain.FileStatus) *domain.File {
 		UploadedAt:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
 	}
 }
+
+// NewMaterialUpload はテスト用 MaterialUpload を生成する。
+func NewMaterialUpload(opts ...func(*domain.MaterialUpload)) *domain.MaterialUpload {
+	u := &domain.MaterialUpload{
+		ID:         FixtureUploadID,
+		FileID:     FixtureFileID,
+		SubjectID:  FixtureSubjectID,
+		UserID:     FixtureUserID,
+		FileName:   "test.pdf",
+		StorageKey: "user/subject/file/test.pdf",
+		MimeType:   "application/pdf",
+		SizeBytes:  1024,
+		ExpiresAt:  time.Date(2026, 1, 1, 0, 15, 0, 0, time.UTC),
+		CreatedAt:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u
+}
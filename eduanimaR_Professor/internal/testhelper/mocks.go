@@ -42,6 +42,11 @@ func (m *MockSubjectRepository) UpdateName(ctx context.Context, id, userID uuid.
 	v, _ := args.Get(0).(*domain.Subject)
 	return v, args.Error(1)
 }
+func (m *MockSubjectRepository) UpdateResearchMode(ctx context.Context, id, userID uuid.UUID, mode domain.ResearchMode) (*domain.Subject, error) {
+	args := m.Called(ctx, id, userID, mode)
+	v, _ := args.Get(0).(*domain.Subject)
+	return v, args.Error(1)
+}
 func (m *MockSubjectRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
 	return m.Called(ctx, id, userID).Error(0)
 }
@@ -63,25 +68,58 @@ func (m *MockQASessionRepository) GetByIDAndUserID(ctx context.Context, id, user
 	v, _ := args.Get(0).(*domain.QASession)
 	return v, args.Error(1)
 }
-func (m *MockQASessionRepository) ListBySubjectID(ctx context.Context, subjectID, userID uuid.UUID, limit, offset int) ([]*domain.QASession, error) {
-	args := m.Called(ctx, subjectID, userID, limit, offset)
+func (m *MockQASessionRepository) ListBySubjectID(ctx context.Context, subjectID, userID uuid.UUID, filter domain.QASessionFilter) ([]*domain.QASession, error) {
+	args := m.Called(ctx, subjectID, userID, filter)
 	v, _ := args.Get(0).([]*domain.QASession)
 	return v, args.Error(1)
 }
-func (m *MockQASessionRepository) CountBySubjectID(ctx context.Context, subjectID, userID uuid.UUID) (int64, error) {
-	args := m.Called(ctx, subjectID, userID)
+func (m *MockQASessionRepository) CountBySubjectID(ctx context.Context, subjectID, userID uuid.UUID, query string) (int64, error) {
+	args := m.Called(ctx, subjectID, userID, query)
 	return args.Get(0).(int64), args.Error(1)
 }
-func (m *MockQASessionRepository) UpdateAnswer(ctx context.Context, id uuid.UUID, answer string, sources []domain.Source) (*domain.QASession, error) {
-	args := m.Called(ctx, id, answer, sources)
+func (m *MockQASessionRepository) UpdateAnswer(ctx context.Context, id uuid.UUID, answer string, sources []domain.Source, trace *domain.ReasoningTrace) (*domain.QASession, error) {
+	args := m.Called(ctx, id, answer, sources, trace)
+	v, _ := args.Get(0).(*domain.QASession)
+	return v, args.Error(1)
+}
+func (m *MockQASessionRepository) MarkInterrupted(ctx context.Context, id uuid.UUID, status domain.QASessionStatus, partialAnswer string, sources []domain.Source, trace *domain.ReasoningTrace) (*domain.QASession, error) {
+	args := m.Called(ctx, id, status, partialAnswer, sources, trace)
 	v, _ := args.Get(0).(*domain.QASession)
 	return v, args.Error(1)
 }
+func (m *MockQASessionRepository) UpdateQueryExpansions(ctx context.Context, id uuid.UUID, expansions []domain.QueryExpansion) error {
+	return m.Called(ctx, id, expansions).Error(0)
+}
 func (m *MockQASessionRepository) UpdateFeedback(ctx context.Context, id, userID uuid.UUID, feedback int) (*domain.QASession, error) {
 	args := m.Called(ctx, id, userID, feedback)
 	v, _ := args.Get(0).(*domain.QASession)
 	return v, args.Error(1)
 }
+func (m *MockQASessionRepository) UpdateVersionFeedback(ctx context.Context, id, userID uuid.UUID, version, feedback int) (*domain.QASession, error) {
+	args := m.Called(ctx, id, userID, version, feedback)
+	v, _ := args.Get(0).(*domain.QASession)
+	return v, args.Error(1)
+}
+func (m *MockQASessionRepository) AddVersion(ctx context.Context, id, userID uuid.UUID, retrieved bool) (*domain.QASession, error) {
+	args := m.Called(ctx, id, userID, retrieved)
+	v, _ := args.Get(0).(*domain.QASession)
+	return v, args.Error(1)
+}
+func (m *MockQASessionRepository) SelectVersion(ctx context.Context, id, userID uuid.UUID, version int) (*domain.QASession, error) {
+	args := m.Called(ctx, id, userID, version)
+	v, _ := args.Get(0).(*domain.QASession)
+	return v, args.Error(1)
+}
+func (m *MockQASessionRepository) ListVersions(ctx context.Context, id uuid.UUID) ([]*domain.AnswerVersion, error) {
+	args := m.Called(ctx, id)
+	v, _ := args.Get(0).([]*domain.AnswerVersion)
+	return v, args.Error(1)
+}
+func (m *MockQASessionRepository) GetTrace(ctx context.Context, id uuid.UUID, version int) (*domain.ReasoningTrace, error) {
+	args := m.Called(ctx, id, version)
+	v, _ := args.Get(0).(*domain.ReasoningTrace)
+	return v, args.Error(1)
+}
 
 // ─── ChunkRepository ──────────────────────────────────────────────
 
@@ -92,22 +130,62 @@ func (m *MockChunkRepository) ListByFileID(ctx context.Context, fileID uuid.UUID
 	v, _ := args.Get(0).([]*domain.Chunk)
 	return v, args.Error(1)
 }
+func (m *MockChunkRepository) ListPageByFileID(ctx context.Context, fileID uuid.UUID, limit, offset int) ([]*domain.Chunk, error) {
+	args := m.Called(ctx, fileID, limit, offset)
+	v, _ := args.Get(0).([]*domain.Chunk)
+	return v, args.Error(1)
+}
+func (m *MockChunkRepository) ListNeighbours(ctx context.Context, fileID uuid.UUID, indexFrom, indexTo int, page *int) ([]*domain.Chunk, error) {
+	args := m.Called(ctx, fileID, indexFrom, indexTo, page)
+	v, _ := args.Get(0).([]*domain.Chunk)
+	return v, args.Error(1)
+}
+func (m *MockChunkRepository) GetEmbeddings(ctx context.Context, chunkIDs []uuid.UUID) (map[uuid.UUID]pgvector.Vector, error) {
+	args := m.Called(ctx, chunkIDs)
+	v, _ := args.Get(0).(map[uuid.UUID]pgvector.Vector)
+	return v, args.Error(1)
+}
+func (m *MockChunkRepository) CountByFileID(ctx context.Context, fileID uuid.UUID) (int64, error) {
+	args := m.Called(ctx, fileID)
+	return args.Get(0).(int64), args.Error(1)
+}
+func (m *MockChunkRepository) GetByID(ctx context.Context, id uuid.UUID) (*domain.Chunk, error) {
+	args := m.Called(ctx, id)
+	v, _ := args.Get(0).(*domain.Chunk)
+	return v, args.Error(1)
+}
 func (m *MockChunkRepository) BatchCreate(ctx context.Context, chunks []*domain.Chunk) error {
 	return m.Called(ctx, chunks).Error(0)
 }
-func (m *MockChunkRepository) SearchByVector(ctx context.Context, subjectID uuid.UUID, embedding pgvector.Vector, limit int) ([]*domain.SearchResult, error) {
-	args := m.Called(ctx, subjectID, embedding, limit)
+func (m *MockChunkRepository) SearchByVector(ctx context.Context, subjectIDs []uuid.UUID, embedding pgvector.Vector, limit int, filter domain.SearchFilter) ([]*domain.SearchResult, error) {
+	args := m.Called(ctx, subjectIDs, embedding, limit, filter)
 	v, _ := args.Get(0).([]*domain.SearchResult)
 	return v, args.Error(1)
 }
-func (m *MockChunkRepository) SearchByText(ctx context.Context, subjectID uuid.UUID, query string, limit int) ([]*domain.SearchResult, error) {
-	args := m.Called(ctx, subjectID, query, limit)
+func (m *MockChunkRepository) SearchByText(ctx context.Context, subjectIDs []uuid.UUID, query string, limit int, filter domain.SearchFilter) ([]*domain.SearchResult, error) {
+	args := m.Called(ctx, subjectIDs, query, limit, filter)
 	v, _ := args.Get(0).([]*domain.SearchResult)
 	return v, args.Error(1)
 }
 func (m *MockChunkRepository) DeleteByFileID(ctx context.Context, fileID uuid.UUID) error {
 	return m.Called(ctx, fileID).Error(0)
 }
+func (m *MockChunkRepository) ApplyEdit(ctx context.Context, edit *domain.ChunkEdit, changes ports.ChunkChanges) error {
+	return m.Called(ctx, edit, changes).Error(0)
+}
+func (m *MockChunkRepository) ListEdits(ctx context.Context, fileID uuid.UUID, limit, offset int) ([]*domain.ChunkEdit, error) {
+	args := m.Called(ctx, fileID, limit, offset)
+	v, _ := args.Get(0).([]*domain.ChunkEdit)
+	return v, args.Error(1)
+}
+func (m *MockChunkRepository) CountEdits(ctx context.Context, fileID uuid.UUID) (int64, error) {
+	args := m.Called(ctx, fileID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockChunkRepository) ReplaceByFileID(ctx context.Context, fileID uuid.UUID, chunks []*domain.Chunk) error {
+	return m.Called(ctx, fileID, chunks).Error(0)
+}
 
 // ─── LLMClient ───────────────────────────────────────────────────
 
@@ -142,9 +220,10 @@ func (m *MockLibrarianClient) Think(
 	userQuery string,
 	subjectID uuid.UUID,
 	userID uuid.UUID,
+	constraints ports.LibrarianConstraints,
 	onSearchRequest func(req ports.LibrarianSearchRequest) (*ports.LibrarianSearchResponse, error),
 ) (*ports.LibrarianThinkResult, error) {
-	args := m.Called(ctx, requestID, userQuery, subjectID, userID, onSearchRequest)
+	args := m.Called(ctx, requestID, userQuery, subjectID, userID, constraints, onSearchRequest)
 	v, _ := args.Get(0).(*ports.LibrarianThinkResult)
 	return v, args.Error(1)
 }
@@ -168,6 +247,11 @@ func (m *MockFileRepository) ListBySubjectID(ctx context.Context, subjectID uuid
 	v, _ := args.Get(0).([]*domain.File)
 	return v, args.Error(1)
 }
+func (m *MockFileRepository) ListAll(ctx context.Context) ([]*domain.File, error) {
+	args := m.Called(ctx)
+	v, _ := args.Get(0).([]*domain.File)
+	return v, args.Error(1)
+}
 func (m *MockFileRepository) Create(ctx context.Context, file *domain.File) error {
 	return m.Called(ctx, file).Error(0)
 }
@@ -176,6 +260,16 @@ func (m *MockFileRepository) UpdateStatus(ctx context.Context, id uuid.UUID, sta
 	v, _ := args.Get(0).(*domain.File)
 	return v, args.Error(1)
 }
+func (m *MockFileRepository) UpdateContent(ctx context.Context, id uuid.UUID, name, storagePath, mimeType string, size int64, contentHash string) (*domain.File, error) {
+	args := m.Called(ctx, id, name, storagePath, mimeType, size, contentHash)
+	v, _ := args.Get(0).(*domain.File)
+	return v, args.Error(1)
+}
+func (m *MockFileRepository) UpdatePreviews(ctx context.Context, id uuid.UUID, previewPath *string, pageCount int) (*domain.File, error) {
+	args := m.Called(ctx, id, previewPath, pageCount)
+	v, _ := args.Get(0).(*domain.File)
+	return v, args.Error(1)
+}
 func (m *MockFileRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
 	return m.Called(ctx, id, userID).Error(0)
 }
@@ -203,6 +297,40 @@ func (m *MockIngestJobRepository) UpdateStatus(ctx context.Context, id uuid.UUID
 	return v, args.Error(1)
 }
 
+// ─── MaterialUploadRepository ────────────────────────────────────
+
+type MockMaterialUploadRepository struct{ mock.Mock }
+
+func (m *MockMaterialUploadRepository) Create(ctx context.Context, upload *domain.MaterialUpload) error {
+	return m.Called(ctx, upload).Error(0)
+}
+func (m *MockMaterialUploadRepository) GetByIDAndUserID(ctx context.Context, id, userID uuid.UUID) (*domain.MaterialUpload, error) {
+	args := m.Called(ctx, id, userID)
+	v, _ := args.Get(0).(*domain.MaterialUpload)
+	return v, args.Error(1)
+}
+func (m *MockMaterialUploadRepository) MarkCompleted(ctx context.Context, id uuid.UUID) (*domain.MaterialUpload, error) {
+	args := m.Called(ctx, id)
+	v, _ := args.Get(0).(*domain.MaterialUpload)
+	return v, args.Error(1)
+}
+func (m *MockMaterialUploadRepository) ListExpired(ctx context.Context, before time.Time, limit int) ([]*domain.MaterialUpload, error) {
+	args := m.Called(ctx, before, limit)
+	v, _ := args.Get(0).([]*domain.MaterialUpload)
+	return v, args.Error(1)
+}
+func (m *MockMaterialUploadRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return m.Called(ctx, id).Error(0)
+}
+func (m *MockMaterialUploadRepository) AddPart(ctx context.Context, id uuid.UUID, expectedOffset int64, part domain.MaterialUploadPart, expiresAt time.Time) error {
+	return m.Called(ctx, id, expectedOffset, part, expiresAt).Error(0)
+}
+func (m *MockMaterialUploadRepository) ListParts(ctx context.Context, id uuid.UUID) ([]domain.MaterialUploadPart, error) {
+	args := m.Called(ctx, id)
+	v, _ := args.Get(0).([]domain.MaterialUploadPart)
+	return v, args.Error(1)
+}
+
 // ─── ObjectStorage ────────────────────────────────────────────────
 
 type MockObjectStorage struct{ mock.Mock }
@@ -219,7 +347,133 @@ func (m *MockObjectStorage) Download(ctx context.Context, key string) (io.ReadCl
 func (m *MockObjectStorage) Delete(ctx context.Context, key string) error {
 	return m.Called(ctx, key).Error(0)
 }
+func (m *MockObjectStorage) ListObjects(ctx context.Context, prefix string, fn func(ports.ObjectInfo) error) error {
+	args := m.Called(ctx, prefix, fn)
+	if objects, ok := args.Get(0).([]ports.ObjectInfo); ok {
+		for _, o := range objects {
+			if err := fn(o); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+func (m *MockObjectStorage) StoragePath(key string) string {
+	return m.Called(key).String(0)
+}
+func (m *MockObjectStorage) Stat(ctx context.Context, key string) (*ports.ObjectInfo, error) {
+	args := m.Called(ctx, key)
+	v, _ := args.Get(0).(*ports.ObjectInfo)
+	return v, args.Error(1)
+}
 func (m *MockObjectStorage) GetPresignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
 	args := m.Called(ctx, key, expiry)
 	return args.String(0), args.Error(1)
 }
+func (m *MockObjectStorage) GetPresignedPutURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	args := m.Called(ctx, key, expiry)
+	return args.String(0), args.Error(1)
+}
+
+// ─── MultipartStorage ────────────────────────────────────────────
+
+type MockMultipartStorage struct{ mock.Mock }
+
+func (m *MockMultipartStorage) CreateMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	args := m.Called(ctx, key, contentType)
+	return args.String(0), args.Error(1)
+}
+func (m *MockMultipartStorage) UploadPart(ctx context.Context, key, uploadID string, partNumber int, reader io.Reader, size int64) (string, error) {
+	args := m.Called(ctx, key, uploadID, partNumber, reader, size)
+	return args.String(0), args.Error(1)
+}
+func (m *MockMultipartStorage) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []ports.MultipartPart) error {
+	return m.Called(ctx, key, uploadID, parts).Error(0)
+}
+func (m *MockMultipartStorage) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	return m.Called(ctx, key, uploadID).Error(0)
+}
+
+// ─── DocumentFetcher ─────────────────────────────────────────────
+
+type MockDocumentFetcher struct{ mock.Mock }
+
+func (m *MockDocumentFetcher) Fetch(ctx context.Context, rawURL string, maxBytes int64) (*ports.FetchedDocument, error) {
+	args := m.Called(ctx, rawURL, maxBytes)
+	v, _ := args.Get(0).(*ports.FetchedDocument)
+	return v, args.Error(1)
+}
+
+// ─── PageRenderer ────────────────────────────────────────────────
+
+type MockPageRenderer struct{ mock.Mock }
+
+func (m *MockPageRenderer) RenderPages(ctx context.Context, content []byte, mimeType string, maxPages int) ([]ports.PageImage, error) {
+	args := m.Called(ctx, content, mimeType, maxPages)
+	v, _ := args.Get(0).([]ports.PageImage)
+	return v, args.Error(1)
+}
+
+// ─── AnswerCacheRepository ───────────────────────────────────────
+
+type MockAnswerCacheRepository struct{ mock.Mock }
+
+func (m *MockAnswerCacheRepository) MaterialVersion(ctx context.Context, subjectIDs []uuid.UUID) (string, error) {
+	args := m.Called(ctx, subjectIDs)
+	return args.String(0), args.Error(1)
+}
+func (m *MockAnswerCacheRepository) FindSimilar(ctx context.Context, key domain.AnswerCacheKey, embedding pgvector.Vector, createdAfter time.Time) (*domain.CachedAnswer, error) {
+	args := m.Called(ctx, key, embedding, createdAfter)
+	v, _ := args.Get(0).(*domain.CachedAnswer)
+	return v, args.Error(1)
+}
+func (m *MockAnswerCacheRepository) Save(ctx context.Context, entry *domain.CachedAnswer, createdAfter time.Time) error {
+	return m.Called(ctx, entry, createdAfter).Error(0)
+}
+func (m *MockAnswerCacheRepository) RecordHit(ctx context.Context, id uuid.UUID) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+// ─── EmbeddingCacheRepository ────────────────────────────────────
+
+type MockEmbeddingCacheRepository struct{ mock.Mock }
+
+func (m *MockEmbeddingCacheRepository) Get(ctx context.Context, key string) ([]float32, error) {
+	args := m.Called(ctx, key)
+	v, _ := args.Get(0).([]float32)
+	return v, args.Error(1)
+}
+func (m *MockEmbeddingCacheRepository) Put(ctx context.Context, key, model string, embedding []float32) error {
+	return m.Called(ctx, key, model, embedding).Error(0)
+}
+
+// ─── Reranker ────────────────────────────────────────────────────
+
+type MockReranker struct{ mock.Mock }
+
+func (m *MockReranker) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	args := m.Called(ctx, query, documents)
+	v, _ := args.Get(0).([]float64)
+	return v, args.Error(1)
+}
+
+// ─── QueryExpander ───────────────────────────────────────────────
+
+type MockQueryExpander struct{ mock.Mock }
+
+func (m *MockQueryExpander) ExpandQuery(ctx context.Context, query string) (*domain.QueryExpansion, error) {
+	args := m.Called(ctx, query)
+	v, _ := args.Get(0).(*domain.QueryExpansion)
+	return v, args.Error(1)
+}
+
+// ─── MessagePublisher ────────────────────────────────────────────
+
+type MockMessagePublisher struct{ mock.Mock }
+
+func (m *MockMessagePublisher) PublishIngestJob(ctx context.Context, msg ports.IngestMessage) error {
+	return m.Called(ctx, msg).Error(0)
+}
+func (m *MockMessagePublisher) Close() error {
+	return m.Called().Error(0)
+}
@@ -0,0 +1,177 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	pgvector "github.com/pgvector/pgvector-go"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+)
+
+// AnswerCaching は回答キャッシュの設定。
+// 試験前など同じ subject で似た質問が続く場合に、Librarian の推論と回答生成を省いて生成済みの回答を返す。
+type AnswerCaching struct {
+	MinSimilarity float64       // 質問の埋め込みのコサイン類似度がこれ以上なら回答を再利用する（0 は無効）
+	TTL           time.Duration // エントリの有効期限（0 は無期限）
+}
+
+// createdAfter は有効なエントリの作成日時の下限を返す。
+func (c AnswerCaching) createdAfter(now time.Time) time.Time {
+	if c.TTL <= 0 {
+		return time.Time{}
+	}
+	return now.Add(-c.TTL)
+}
+
+// answerCacheLookup は 1 回の質問での回答キャッシュの検索結果。
+// キャッシュミスの場合も、生成した回答の保存に key と embedding を使う。
+type answerCacheLookup struct {
+	key       domain.AnswerCacheKey
+	question  string
+	embedding pgvector.Vector
+	hit       *domain.CachedAnswer // nil の場合はキャッシュミス
+}
+
+// useAnswerCache は回答キャッシュを使うかを返す。
+// 質問ごとに検索の多様性・調べる深さを指定した場合は、既定の設定で生成した回答と条件が異なるため検索も保存もしない。
+func (uc *ChatUseCase) useAnswerCache(opts AskOptions) bool {
+	return uc.answerCache != nil && uc.cfg.Cache.MinSimilarity > 0 && opts.Diversity == nil && opts.ResearchMode == ""
+}
+
+// lookupAnswerCache は検索対象の教材のバージョンと質問の埋め込みで回答キャッシュを検索する。
+// bypass の場合は検索せず、生成する回答を保存するためのキーだけを返す。
+// 教材のバージョン・埋め込みを取得できない場合は nil を返す（キャッシュを使わずに回答を生成する）。
+func (uc *ChatUseCase) lookupAnswerCache(ctx context.Context, scope []uuid.UUID, question string, bypass bool) *answerCacheLookup {
+	version, err := uc.answerCache.MaterialVersion(ctx, scope)
+	if err != nil {
+		slog.Warn("get material version failed, skipping answer cache", "error", err)
+		return nil
+	}
+	emb, err := uc.llm.GenerateEmbedding(ctx, question)
+	if err != nil {
+		slog.Warn("question embedding failed, skipping answer cache", "error", err)
+		return nil
+	}
+	lookup := &answerCacheLookup{
+		key:       domain.NewAnswerCacheKey(scope[0], scope[1:], version),
+		question:  question,
+		embedding: pgvector.NewVector(emb),
+	}
+	if bypass {
+		return lookup
+	}
+
+	entry, err := uc.answerCache.FindSimilar(ctx, lookup.key, lookup.embedding, uc.cfg.Cache.createdAfter(time.Now()))
+	switch {
+	case err == nil && entry.Similarity >= uc.cfg.Cache.MinSimilarity:
+		lookup.hit = entry
+	case err == nil:
+		slog.Info("answer cache miss", "subject_id", scope[0], "nearest_similarity", entry.Similarity)
+	case errors.Is(err, domain.ErrNotFound):
+		slog.Info("answer cache miss", "subject_id", scope[0])
+	default:
+		slog.Warn("find cached answer failed", "error", err)
+	}
+	return lookup
+}
+
+// replayCachedAnswer はキャッシュした回答と出典を SSE で送り、質問セッションに記録する。
+// 各イベントには cached: true を付け、推論の記録には再利用したエントリを残す。
+// 送信に失敗した場合は、通常の回答生成と同様に cancelled（クライアントの切断・中断）または failed として保存する。
+func (uc *ChatUseCase) replayCachedAnswer(
+	ctx context.Context,
+	session *domain.QASession,
+	hit *domain.CachedAnswer,
+	onEvent func(eventType domain.SSEEventType, data any) error,
+) (*domain.QASession, error) {
+	slog.Info("answer cache hit",
+		"session_id", session.ID,
+		"cache_id", hit.ID,
+		"similarity", hit.Similarity,
+	)
+	trace := &domain.ReasoningTrace{
+		Cache: &domain.TraceCacheHit{
+			CacheID:    hit.ID,
+			SessionID:  hit.SessionID,
+			Question:   hit.Question,
+			Similarity: hit.Similarity,
+		},
+		Models:    domain.TraceModels{Embedding: uc.cfg.Models.Embedding},
+		StartedAt: time.Now(),
+	}
+	// sent は送信できた出典（中断した場合に保存する）
+	sent := make([]domain.Source, 0, len(hit.Sources))
+	interrupted := func(sendErr error) (*domain.QASession, error) {
+		if ctx.Err() != nil {
+			return uc.finishCancelled(ctx, session, "", sent, trace, onEvent)
+		}
+		trace.Error = sendErr.Error()
+		uc.markFailed(ctx, session.ID, "", sent, trace)
+		return nil, fmt.Errorf("send cached answer: %w", sendErr)
+	}
+
+	if err := onEvent(domain.SSEEventThinking, map[string]any{
+		"session_id": session.ID.String(),
+		"message":    "Reusing an answer to a similar question...",
+		"cached":     true,
+	}); err != nil {
+		return interrupted(err)
+	}
+	for _, src := range hit.Sources {
+		evidence := evidenceEvent(src)
+		evidence["cached"] = true
+		if err := onEvent(domain.SSEEventEvidence, evidence); err != nil {
+			return interrupted(err)
+		}
+		sent = append(sent, src)
+	}
+	if err := onEvent(domain.SSEEventAnswer, map[string]any{"text": hit.Answer, "cached": true}); err != nil {
+		return interrupted(err)
+	}
+
+	if err := uc.answerCache.RecordHit(ctx, hit.ID); err != nil {
+		slog.Warn("record answer cache hit failed", "cache_id", hit.ID, "error", err)
+	}
+	finishTrace(trace)
+	updated, err := uc.qaSessionRepo.UpdateAnswer(ctx, session.ID, hit.Answer, hit.Sources, trace)
+	if err != nil {
+		slog.Error("failed to update qa session answer",
+			"session_id", session.ID,
+			"error", err,
+		)
+	} else if updated != nil {
+		session = updated
+	}
+
+	_ = onEvent(domain.SSEEventDone, map[string]any{
+		"session_id":      session.ID.String(),
+		"cached":          true,
+		"cached_question": hit.Question,
+	})
+	return session, nil
+}
+
+// saveAnswerCache は生成した回答を回答キャッシュに保存する（失敗はログのみ）。
+// 出典の無い回答（教材から答えられなかった回答）は保存しない。
+func (uc *ChatUseCase) saveAnswerCache(ctx context.Context, lookup *answerCacheLookup, session *domain.QASession, answer string, sources []domain.Source) {
+	if answer == "" || len(sources) == 0 {
+		return
+	}
+	entry := &domain.CachedAnswer{
+		ID:                uuid.New(),
+		Key:               lookup.key,
+		Question:          lookup.question,
+		QuestionEmbedding: lookup.embedding,
+		Answer:            answer,
+		Sources:           sources,
+		SessionID:         &session.ID,
+	}
+	if err := uc.answerCache.Save(ctx, entry, uc.cfg.Cache.createdAfter(time.Now())); err != nil {
+		slog.Warn("save answer cache failed", "session_id", session.ID, "error", err)
+	}
+}
@@ -2,23 +2,51 @@ package usecases
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
-	pgvector "github.com/pgvector/pgvector-go"
 
 	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
 	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
 )
 
 const (
-	chatSearchLimit   = 10 // 1クエリあたりの最大検索結果数
-	fallbackEvidenceN = 5  // Librarian がエビデンスを返さない場合のフォールバック件数
-	excerptMaxLen     = 300
+	chatSearchLimit    = 10 // 1クエリあたりの最大検索結果数（多様性を考慮した選択後）
+	chatCandidateLimit = 30 // 多様性を考慮した選択のために 1 クエリあたりに取得する候補数
+	fallbackEvidenceN  = 5  // Librarian がエビデンスを返さない場合のフォールバック件数
+	excerptMaxLen      = 300
 )
 
+// ChatConfig は ChatUseCase の検索・エビデンス設定
+type ChatConfig struct {
+	Expansion EvidenceExpansion
+	Diversity Diversification
+	Rerank    Reranking
+	Cache     AnswerCaching
+	Search    SearchExecution
+	// Research は調べる深さ（quick / standard / deep）ごとに Librarian に送る制約
+	Research ResearchModes
+	// Models は推論の記録（domain.ReasoningTrace）に残すモデル名。
+	// Rerank / QueryExpansion は Reranker・QueryExpander を使う場合のみ記録する
+	Models domain.TraceModels
+}
+
+// AskOptions は質問ごとの指定（ゼロ値は既定の動作）
+type AskOptions struct {
+	// ExtraSubjectIDs は subjectID に加えて横断検索する subject（所有権はすべて確認する）
+	ExtraSubjectIDs []uuid.UUID
+	// Diversity は多様性の重み（0〜1）。nil の場合は ChatConfig の値を使う（指定した場合は回答キャッシュを使わない）
+	Diversity *float64
+	// BypassCache は回答キャッシュを使わずに回答を生成する（生成した回答でキャッシュを更新する）
+	BypassCache bool
+	// ResearchMode は Librarian に調べさせる深さ。空の場合は科目の設定を使う（指定した場合は回答キャッシュを使わない）
+	ResearchMode domain.ResearchMode
+}
+
 // ChatUseCase は質問応答セッションのオーケストレーションを担う。
 type ChatUseCase struct {
 	subjectRepo   ports.SubjectRepository
@@ -26,6 +54,11 @@ type ChatUseCase struct {
 	chunkRepo     ports.ChunkRepository
 	llm           ports.LLMClient
 	librarian     ports.LibrarianClient
+	reranker      ports.Reranker              // nil の場合は検索結果を並べ替えない
+	expander      ports.QueryExpander         // nil の場合はベクトル検索クエリを拡張しない
+	answerCache   ports.AnswerCacheRepository // nil の場合は回答をキャッシュしない
+	running       *runningSessions
+	cfg           ChatConfig
 }
 
 // NewChatUseCase は ChatUseCase を生成する。
@@ -35,6 +68,10 @@ func NewChatUseCase(
 	chunkRepo ports.ChunkRepository,
 	llm ports.LLMClient,
 	librarian ports.LibrarianClient,
+	reranker ports.Reranker,
+	expander ports.QueryExpander,
+	answerCache ports.AnswerCacheRepository,
+	cfg ChatConfig,
 ) *ChatUseCase {
 	return &ChatUseCase{
 		subjectRepo:   subjectRepo,
@@ -42,6 +79,11 @@ func NewChatUseCase(
 		chunkRepo:     chunkRepo,
 		llm:           llm,
 		librarian:     librarian,
+		reranker:      reranker,
+		expander:      expander,
+		answerCache:   answerCache,
+		running:       newRunningSessions(),
+		cfg:           cfg,
 	}
 }
 
@@ -50,59 +92,272 @@ func NewChatUseCase(
 // Ask は質問応答セッションを実行し、SSEイベントをコールバックに逐次渡す。
 //
 // フロー:
-//  1. subject 所有権確認（subjectID + userID）
-//  2. QASession 作成（DB永続化）
-//  3. SSEEventThinking 送信
-//  4. LibrarianClient.Think 呼び出し（双方向ストリーミング）
-//     - onSearchRequest コールバックで全文検索・ベクトル検索を実行
+//  1. subject 所有権確認（subjectID + opts.ExtraSubjectIDs のすべてを userID で確認）
+//  2. 回答キャッシュ検索（教材のバージョン + 質問の埋め込みの類似度）
+//     - ヒットした場合は QASession を作成し、キャッシュした回答と出典を cached: true 付きで送って終了
+//  3. QASession 作成（DB永続化）
+//  4. SSEEventThinking 送信
+//  5. LibrarianClient.Think 呼び出し（双方向ストリーミング）
+//     - onSearchRequest コールバックで全文検索・ベクトル検索を並列に実行（確認済みの subject 集合で物理制約）
+//     - ベクトル検索クエリは言い換え・仮想回答（HyDE）・日英訳に拡張し、すべての検索結果を RRF で統合する
+//     - 検索結果の上位を Reranker で（質問, チャンク）の関連度により並べ替える
+//     - 検索結果は MMR で冗長なチャンク・同じ教材への偏りを抑えて選ぶ
 //     - SSEEventSearching 送信
-//  5. エビデンスチャンク選定 → SSEEventEvidence 送信
-//  6. LLM 回答ストリーミング生成 → SSEEventAnswer 送信
-//  7. QASession.Answer / Sources を永続化
-//  8. SSEEventDone 送信
+//  6. 拡張したクエリを QASession に記録（監査用）
+//  7. エビデンスチャンク選定・隣接チャンクで文脈を補う → SSEEventEvidence 送信
+//     - Librarian が質問を扱えない（INVALID_INPUT）場合は status: 400 の SSEEventError を送って終了
+//     - 検索を打ち切った（LOOP_LIMIT / TIMEOUT）場合・LLM 呼び出しに失敗した（MODEL_FAILURE）場合は SSEEventCaveat 送信
+//  8. LLM 回答ストリーミング生成 → SSEEventAnswer 送信
+//     - MODEL_FAILURE の場合は LLM を使わず、検索結果の上位の抜粋を回答とする
+//  9. QASession.Answer / Sources・推論の記録（検索ラウンド・エビデンス・モデル名・所要時間）を永続化・回答キャッシュに保存
+//  10. SSEEventDone 送信
 func (uc *ChatUseCase) Ask(
 	ctx context.Context,
 	subjectID, userID uuid.UUID,
 	question string,
+	opts AskOptions,
 	onEvent func(eventType domain.SSEEventType, data any) error,
 ) (*domain.QASession, error) {
-	// 1. subject 所有権確認
-	if _, err := uc.subjectRepo.GetByIDAndUserID(ctx, subjectID, userID); err != nil {
-		return nil, fmt.Errorf("get subject: %w", err)
+	diversity := uc.cfg.Diversity
+	if opts.Diversity != nil {
+		if err := validateDiversityWeight(*opts.Diversity); err != nil {
+			return nil, err
+		}
+		diversity.Weight = *opts.Diversity
+	}
+	if opts.ResearchMode != "" {
+		if _, err := domain.ParseResearchMode(string(opts.ResearchMode)); err != nil {
+			return nil, err
+		}
+	}
+
+	// 1. subject 所有権確認（横断対象の subject もすべて確認する）
+	scope, subject, err := resolveSubjectScope(ctx, uc.subjectRepo, subjectID, opts.ExtraSubjectIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 回答キャッシュ検索
+	var cacheLookup *answerCacheLookup
+	if uc.useAnswerCache(opts) {
+		cacheLookup = uc.lookupAnswerCache(ctx, scope, question, opts.BypassCache)
 	}
 
-	// 2. QASession 作成
+	// 3. QASession 作成
 	session := &domain.QASession{
-		ID:        uuid.New(),
-		UserID:    userID,
-		SubjectID: subjectID,
-		Question:  question,
+		ID:              uuid.New(),
+		UserID:          userID,
+		SubjectID:       subjectID,
+		ExtraSubjectIDs: scope[1:],
+		Question:        question,
+		Status:          domain.QASessionStatusRunning,
 	}
 	if err := uc.qaSessionRepo.Create(ctx, session); err != nil {
 		return nil, fmt.Errorf("create qa session: %w", err)
 	}
-	slog.Info("qa session created", "session_id", session.ID, "subject_id", subjectID)
+	slog.Info("qa session created", "session_id", session.ID, "subject_id", subjectID, "extra_subjects", len(scope)-1)
+	if cacheLookup != nil && cacheLookup.hit != nil {
+		return uc.replayCachedAnswer(ctx, session, cacheLookup.hit, onEvent)
+	}
+
+	// 実行中として登録する（新しいセッションのため、登録済みになることはない）
+	ctx, release, _ := uc.register(ctx, session.ID)
+	defer release()
+
+	return uc.generate(ctx, session, answerGeneration{
+		scope:     scope,
+		diversity: diversity,
+		mode:      resolveResearchMode(opts.ResearchMode, subject),
+		retrieve:  true,
+		cache:     cacheLookup,
+	}, onEvent)
+}
+
+// answerGeneration は回答生成（Ask・Regenerate）の指定
+type answerGeneration struct {
+	scope     []uuid.UUID
+	diversity Diversification
+	mode      domain.ResearchMode // Librarian に調べさせる深さ（ChatConfig.Research の制約を送る）
+	// retrieve が false の場合は Librarian を呼ばず、reuse の出典から回答を生成する
+	retrieve bool
+	reuse    []domain.Source
+	cache    *answerCacheLookup // nil の場合は回答キャッシュに保存しない
+	version  int                // 再生成する回答版（0 の場合は thinking / done に含めない）
+}
+
+// register はセッションを実行中として登録し、Cancel・クライアントの切断で中断できるコンテキストを返す。
+// 既に実行中の場合は false を返す。release は登録を外す（回答の保存後に呼ぶ）。
+func (uc *ChatUseCase) register(ctx context.Context, sessionID uuid.UUID) (context.Context, func(), bool) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	if !uc.running.add(sessionID, cancel) {
+		cancel(nil)
+		return nil, nil, false
+	}
+	return ctx, func() {
+		cancel(nil)
+		uc.running.remove(sessionID)
+	}, true
+}
+
+// generate はエビデンスを集めて（または再利用して）回答をストリーミング生成し、セッションの最新の回答版に保存する。
+// ctx は register で実行中として登録したコンテキスト。SSE の送信に失敗した場合は
+// Librarian・LLM のストリームを止め、途中までの回答を cancelled として保存する。
+func (uc *ChatUseCase) generate(
+	ctx context.Context,
+	session *domain.QASession,
+	gen answerGeneration,
+	onEvent func(eventType domain.SSEEventType, data any) error,
+) (*domain.QASession, error) {
+	send := onEvent
+	onEvent = func(eventType domain.SSEEventType, data any) error {
+		if err := send(eventType, data); err != nil {
+			uc.running.cancel(session.ID, errClientDisconnected)
+			return err
+		}
+		return nil
+	}
 
-	// 3. Librarian 推論開始通知
-	if err := onEvent(domain.SSEEventThinking, map[string]any{
+	trace := &domain.ReasoningTrace{
+		Retrieved: gen.retrieve,
+		Models:    uc.traceModels(gen.retrieve),
+		StartedAt: time.Now(),
+	}
+
+	// 4. Librarian 推論開始通知
+	thinking := map[string]any{
 		"session_id": session.ID.String(),
 		"message":    "Analyzing your question...",
-	}); err != nil {
-		return nil, err
 	}
+	if gen.version > 0 {
+		thinking["version"] = gen.version
+	}
+	if err := onEvent(domain.SSEEventThinking, thinking); err != nil {
+		return uc.finishCancelled(ctx, session, "", nil, trace, onEvent)
+	}
+
+	// 5〜7. エビデンス選定 & SSEEventEvidence 送信
+	var evidenceTexts []string
+	var sources []domain.Source
+	if gen.retrieve {
+		var err error
+		evidenceTexts, sources, err = uc.retrieveEvidence(ctx, session, gen, trace, onEvent)
+		if err != nil {
+			if ctx.Err() != nil {
+				return uc.finishCancelled(ctx, session, "", nil, trace, onEvent)
+			}
+			_ = onEvent(domain.SSEEventError, errorEvent(err))
+			trace.Error = err.Error()
+			uc.markFailed(ctx, session.ID, "", nil, trace)
+			return nil, fmt.Errorf("librarian think: %w", err)
+		}
+	} else {
+		evidenceTexts, sources = uc.reuseEvidence(ctx, gen.reuse, trace, onEvent)
+	}
+
+	// Librarian が検索を打ち切った・LLM 呼び出しに失敗した場合は、回答の前に注意を送る
+	if caveat := caveatEvent(trace); caveat != nil {
+		_ = onEvent(domain.SSEEventCaveat, caveat)
+	}
+
+	// 8. LLM 回答ストリーミング生成 → SSEEventAnswer
+	var answerBuf strings.Builder
+	answerStart, firstToken := time.Now(), true
+	// 中断した場合に保存する途中までの回答は、クライアントに送れたテキストまでとする
+	emit := func(text string) error {
+		if firstToken {
+			trace.Timings.FirstTokenMs = time.Since(answerStart).Milliseconds()
+			firstToken = false
+		}
+		if err := onEvent(domain.SSEEventAnswer, map[string]any{"text": text}); err != nil {
+			return err
+		}
+		answerBuf.WriteString(text)
+		return nil
+	}
+	var streamErr error
+	if trace.ErrorType == ports.LibrarianErrorModelFailure {
+		// 同じモデルでの生成も失敗する見込みが高いため、検索結果だけで回答する
+		streamErr = emit(retrievalOnlyAnswer(sources))
+	} else {
+		streamErr = uc.llm.GenerateAnswerStream(ctx, session.Question, evidenceTexts, emit)
+	}
+	trace.Timings.AnswerMs = time.Since(answerStart).Milliseconds()
+	if streamErr != nil {
+		if ctx.Err() != nil {
+			return uc.finishCancelled(ctx, session, answerBuf.String(), sources, trace, onEvent)
+		}
+		_ = onEvent(domain.SSEEventError, map[string]any{"message": streamErr.Error()})
+		trace.Error = streamErr.Error()
+		uc.markFailed(ctx, session.ID, answerBuf.String(), sources, trace)
+		return nil, fmt.Errorf("generate answer stream: %w", streamErr)
+	}
+
+	// 9. QASession.Answer / Sources・推論の記録を永続化・回答キャッシュに保存
+	finishTrace(trace)
+	updated, updateErr := uc.qaSessionRepo.UpdateAnswer(ctx, session.ID, answerBuf.String(), sources, trace)
+	if updateErr != nil {
+		// 永続化失敗はログのみ（クライアントへのストリーミングは完了済み）
+		slog.Error("failed to update qa session answer",
+			"session_id", session.ID,
+			"error", updateErr,
+		)
+	} else if updated != nil {
+		session = updated
+	}
+
+	// 10. 完了通知
+	done := map[string]any{
+		"session_id": session.ID.String(),
+	}
+	if gen.version > 0 {
+		done["version"] = gen.version
+	}
+	_ = onEvent(domain.SSEEventDone, done)
 
+	// 似た質問に再利用できるよう保存する（クライアントへのストリーミングは完了済み）
+	// Librarian が検索を打ち切った・失敗した回答は、次の質問では調べきれる場合があるため保存しない
+	if gen.cache != nil && !trace.IsPartial && trace.ErrorType == "" {
+		uc.saveAnswerCache(ctx, gen.cache, session, answerBuf.String(), sources)
+	}
+
+	return session, nil
+}
+
+// retrieveEvidence は Librarian Think（双方向ストリーミング）で検索を繰り返し、
+// 選ばれたエビデンスの本文（回答生成に渡す）と出典を返す。
+// 検索ラウンド・選んだエビデンス・Librarian の終了結果を trace に記録する。
+func (uc *ChatUseCase) retrieveEvidence(
+	ctx context.Context,
+	session *domain.QASession,
+	gen answerGeneration,
+	trace *domain.ReasoningTrace,
+	onEvent func(eventType domain.SSEEventType, data any) error,
+) ([]string, []domain.Source, error) {
 	// 累積検索結果（Librarian の TempIndex はこの配列のインデックスを指す）
 	var allResults []domain.SearchResult
 	seenChunks := make(map[uuid.UUID]struct{})
+	diverse := newDiversifier(uc.chunkRepo, gen.diversity)
+	expansions := newQueryExpansions(uc.expander)
+	constraints := uc.cfg.Research.constraints(gen.mode)
+	limit := searchLimit(constraints)
+	trace.ResearchMode = gen.mode
+	trace.Constraints = &domain.TraceConstraints{
+		MaxLoops:   constraints.MaxLoops,
+		MaxResults: constraints.MaxResults,
+		TimeoutMs:  constraints.Timeout.Milliseconds(),
+	}
 
-	// 4. Librarian Think（双方向ストリーミング）
+	// 5. Librarian Think（双方向ストリーミング）
+	thinkStart := time.Now()
 	thinkResult, err := uc.librarian.Think(
 		ctx,
 		session.ID.String(),
-		question,
-		subjectID,
-		userID,
+		session.Question,
+		session.SubjectID,
+		session.UserID,
+		constraints,
 		func(req ports.LibrarianSearchRequest) (*ports.LibrarianSearchResponse, error) {
+			roundStart := time.Now()
 			// 検索開始通知
 			if evErr := onEvent(domain.SSEEventSearching, map[string]any{
 				"queries_text":   req.QueriesText,
@@ -112,51 +367,74 @@ func (uc *ChatUseCase) Ask(
 				return nil, evErr
 			}
 
-			// (A) 全文検索（text queries）
-			for _, q := range req.QueriesText {
-				if q == "" {
-					continue
-				}
-				results, searchErr := uc.chunkRepo.SearchByText(ctx, subjectID, q, chatSearchLimit)
-				if searchErr != nil {
-					slog.Warn("text search error", "query", q, "error", searchErr)
-					continue
-				}
-				for _, r := range results {
-					if _, seen := seenChunks[r.ChunkID]; !seen {
-						seenChunks[r.ChunkID] = struct{}{}
-						allResults = append(allResults, *r)
+			// このラウンドの候補（各クエリでの順位から RRF で関連度を算出する）
+			var round []*mmrCandidate
+			roundIndex := make(map[uuid.UUID]*mmrCandidate)
+			addCandidates := func(results []*domain.SearchResult) {
+				for i, r := range results {
+					if _, seen := seenChunks[r.ChunkID]; seen {
+						continue
+					}
+					c, ok := roundIndex[r.ChunkID]
+					if !ok {
+						c = &mmrCandidate{result: *r}
+						roundIndex[r.ChunkID] = c
+						round = append(round, c)
 					}
+					c.relevance += 1.0 / float64(rrfK+i+1)
 				}
 			}
 
-			// (B) ベクトル検索（vector queries: 各クエリを embed → HNSW 検索）
-			for _, q := range req.QueriesVector {
-				if q == "" {
-					continue
-				}
-				emb, embErr := uc.llm.GenerateEmbedding(ctx, q)
-				if embErr != nil {
-					slog.Warn("embedding error", "query", q, "error", embErr)
-					continue
-				}
-				vec := pgvector.NewVector(emb)
-				results, searchErr := uc.chunkRepo.SearchByVector(ctx, subjectID, vec, chatSearchLimit)
-				if searchErr != nil {
-					slog.Warn("vector search error", "query", q, "error", searchErr)
-					continue
-				}
-				for _, r := range results {
-					if _, seen := seenChunks[r.ChunkID]; !seen {
-						seenChunks[r.ChunkID] = struct{}{}
-						allResults = append(allResults, *r)
-					}
+			// (A) 全文検索・ベクトル検索（各クエリと変形を embed → HNSW 検索）を並列に実行
+			// 結果は計画した順に加えるため、完了順によらず TempIndex が安定する
+			roundCtx, cancel := uc.cfg.Search.roundContext(ctx)
+			expansions.expand(roundCtx, req.QueriesVector, uc.cfg.Search.Concurrency)
+			searches := planRoundSearches(roundCtx, expansions, req.QueriesText, req.QueriesVector)
+			uc.runRoundSearches(roundCtx, gen.scope, searches)
+			cancel()
+			searched := make(map[int]struct{})
+			failed := 0
+			for _, s := range searches {
+				if s.ok {
+					addCandidates(s.results)
+					searched[s.query] = struct{}{}
+				} else {
+					failed++
 				}
 			}
+			queries := len(searched)
+
+			// (B) 上位の候補を Reranker で採点し直す（制限時間内に終わらない場合は RRF の関連度のまま）
+			cands := make([]mmrCandidate, len(round))
+			for i, c := range round {
+				cands[i] = *c
+			}
+			rerankCandidates(ctx, uc.reranker, uc.cfg.Rerank, session.Question, cands)
+
+			// (C) MMR で冗長なチャンク・同じ教材への偏りを抑えて選ぶ
+			picked := diverse.pick(ctx, cands, limit*queries)
+			for _, r := range picked {
+				seenChunks[r.ChunkID] = struct{}{}
+				allResults = append(allResults, r)
+			}
+
+			trace.Rounds = append(trace.Rounds, domain.SearchRound{
+				Round:            len(trace.Rounds) + 1,
+				QueriesText:      req.QueriesText,
+				QueriesVector:    req.QueriesVector,
+				Rationale:        req.Rationale,
+				Searches:         len(searches),
+				FailedSearches:   failed,
+				Candidates:       len(round),
+				Selected:         len(picked),
+				TotalAccumulated: len(allResults),
+				DurationMs:       time.Since(roundStart).Milliseconds(),
+			})
 
 			slog.Info("search round completed",
 				"text_queries", len(req.QueriesText),
 				"vector_queries", len(req.QueriesVector),
+				"candidates", len(round),
 				"total_accumulated", len(allResults),
 			)
 
@@ -164,14 +442,38 @@ func (uc *ChatUseCase) Ask(
 			return &ports.LibrarianSearchResponse{Results: allResults}, nil
 		},
 	)
+	trace.Timings.ThinkMs = time.Since(thinkStart).Milliseconds()
+	if thinkResult != nil {
+		trace.CoverageNotes = thinkResult.CoverageNotes
+		trace.IsPartial = thinkResult.IsPartial
+		trace.ErrorType = thinkResult.ErrorType
+	}
+
+	// 6. 拡張したクエリを記録（Think が失敗した場合も、実行した検索の監査のため記録する）
+	if len(expansions.log) > 0 {
+		if logErr := uc.qaSessionRepo.UpdateQueryExpansions(ctx, session.ID, expansions.log); logErr != nil {
+			slog.Error("failed to record query expansions",
+				"session_id", session.ID,
+				"error", logErr,
+			)
+		} else {
+			session.QueryExpansions = expansions.log
+		}
+	}
+
 	if err != nil {
-		_ = onEvent(domain.SSEEventError, map[string]any{"message": err.Error()})
-		return nil, fmt.Errorf("librarian think: %w", err)
+		return nil, nil, err
 	}
+	// 質問を扱えない場合は、学生が質問を直す必要があるため回答しない
+	if thinkResult.ErrorType == ports.LibrarianErrorInvalidInput {
+		return nil, nil, fmt.Errorf("librarian rejected the question: %s: %w", thinkResult.CoverageNotes, domain.ErrInvalidInput)
+	}
+	retrievalOnly := thinkResult.ErrorType == ports.LibrarianErrorModelFailure
 
-	// 5. エビデンス選定 & SSEEventEvidence 送信
+	// 7. エビデンス選定 & SSEEventEvidence 送信
 	evidenceTexts := make([]string, 0, len(thinkResult.Evidences))
 	sources := make([]domain.Source, 0, len(thinkResult.Evidences))
+	expander := newEvidenceExpander(uc.chunkRepo, uc.cfg.Expansion)
 
 	for _, ev := range thinkResult.Evidences {
 		if ev.TempIndex < 0 || ev.TempIndex >= len(allResults) {
@@ -182,102 +484,344 @@ func (uc *ChatUseCase) Ask(
 			continue
 		}
 		r := allResults[ev.TempIndex]
-		evidenceTexts = append(evidenceTexts, r.Content)
-
-		excerpt := r.Content
-		if len([]rune(excerpt)) > excerptMaxLen {
-			runes := []rune(excerpt)
-			excerpt = string(runes[:excerptMaxLen])
+		trace.Evidences = append(trace.Evidences, traceEvidence(r, ev.TempIndex, ev.WhyRelevant, false))
+		// 前後のチャンクで文脈を補う（ほかのエビデンスの文脈として渡し済みの場合は本文を重複させない）
+		text, evidenceContext := expander.expand(ctx, r)
+		if text != "" {
+			evidenceTexts = append(evidenceTexts, text)
 		}
 
-		sources = append(sources, domain.Source{
-			FileID:     r.FileID,
-			ChunkID:    r.ChunkID,
-			FileName:   r.FileName,
-			PageNumber: r.PageNumber,
-			Excerpt:    excerpt,
-		})
+		source := evidenceSource(r, evidenceContext, ev.WhyRelevant)
+		sources = append(sources, source)
 
-		_ = onEvent(domain.SSEEventEvidence, map[string]any{
-			"chunk_id":     r.ChunkID.String(),
-			"file_name":    r.FileName,
-			"why_relevant": ev.WhyRelevant,
-			"excerpt":      excerpt,
-		})
+		_ = onEvent(domain.SSEEventEvidence, evidenceEvent(source))
 	}
 
-	// エビデンスが0件の場合: 累積検索結果の上位N件をフォールバック
+	// エビデンスが0件の場合: 累積検索結果から多様性を考慮して上位N件をフォールバック
 	if len(evidenceTexts) == 0 && len(allResults) > 0 {
 		slog.Warn("no evidences from librarian, using fallback",
 			"fallback_n", fallbackEvidenceN,
 			"available", len(allResults),
 		)
-		top := allResults
-		if len(top) > fallbackEvidenceN {
-			top = top[:fallbackEvidenceN]
+		// 累積順（先に選ばれたものほど関連が高い）を関連度とする
+		cands := make([]mmrCandidate, len(allResults))
+		indexOf := make(map[uuid.UUID]int, len(allResults))
+		for i, r := range allResults {
+			cands[i] = mmrCandidate{result: r, relevance: 1.0 / float64(rrfK+i+1)}
+			indexOf[r.ChunkID] = i
 		}
-		for _, r := range top {
+		for _, r := range diverse.fresh().pick(ctx, cands, fallbackEvidenceN) {
 			evidenceTexts = append(evidenceTexts, r.Content)
+			trace.Evidences = append(trace.Evidences, traceEvidence(r, indexOf[r.ChunkID], "", true))
+			// 検索結果だけで回答する場合は、その抜粋が回答になるため出典として送る
+			if retrievalOnly {
+				source := evidenceSource(r, nil, "")
+				sources = append(sources, source)
+				_ = onEvent(domain.SSEEventEvidence, evidenceEvent(source))
+			}
 		}
 	}
+	if retrievalOnly && len(sources) == 0 {
+		return nil, nil, fmt.Errorf("librarian model failure with no search results: %s", thinkResult.CoverageNotes)
+	}
 
-	// 6. LLM 回答ストリーミング生成 → SSEEventAnswer
-	var answerBuf strings.Builder
-	streamErr := uc.llm.GenerateAnswerStream(ctx, question, evidenceTexts, func(text string) error {
-		answerBuf.WriteString(text)
-		return onEvent(domain.SSEEventAnswer, map[string]any{"text": text})
-	})
-	if streamErr != nil {
-		_ = onEvent(domain.SSEEventError, map[string]any{"message": streamErr.Error()})
-		return nil, fmt.Errorf("generate answer stream: %w", streamErr)
+	return evidenceTexts, sources, nil
+}
+
+// reuseEvidence は前の回答版の出典のチャンクを読み直し、エビデンスの本文と出典を返す（Librarian を呼ばない）。
+// 再処理・手動修正で無くなったチャンクは出典から外す。
+func (uc *ChatUseCase) reuseEvidence(
+	ctx context.Context,
+	reuse []domain.Source,
+	trace *domain.ReasoningTrace,
+	onEvent func(eventType domain.SSEEventType, data any) error,
+) ([]string, []domain.Source) {
+	evidenceTexts := make([]string, 0, len(reuse))
+	sources := make([]domain.Source, 0, len(reuse))
+	expander := newEvidenceExpander(uc.chunkRepo, uc.cfg.Expansion)
+
+	for _, src := range reuse {
+		ch, err := uc.chunkRepo.GetByID(ctx, src.ChunkID)
+		if err != nil {
+			slog.Warn("reused evidence chunk unavailable", "chunk_id", src.ChunkID, "error", err)
+			continue
+		}
+		text, evidenceContext := expander.expand(ctx, domain.SearchResult{
+			ChunkID:    ch.ID,
+			FileID:     ch.FileID,
+			SubjectID:  ch.SubjectID,
+			PageNumber: ch.PageNumber,
+			ChunkIndex: ch.ChunkIndex,
+			Content:    ch.Content,
+		})
+		if text != "" {
+			evidenceTexts = append(evidenceTexts, text)
+		}
+		src.Excerpt = excerptOf(ch.Content)
+		src.Context = evidenceContext
+		sources = append(sources, src)
+		trace.Evidences = append(trace.Evidences, domain.TraceEvidence{
+			ChunkID:     src.ChunkID,
+			FileID:      src.FileID,
+			FileName:    src.FileName,
+			PageNumber:  src.PageNumber,
+			WhyRelevant: src.WhyRelevant,
+		})
+		_ = onEvent(domain.SSEEventEvidence, evidenceEvent(src))
 	}
+	return evidenceTexts, sources
+}
 
-	// 7. QASession.Answer / Sources を永続化
-	updated, updateErr := uc.qaSessionRepo.UpdateAnswer(ctx, session.ID, answerBuf.String(), sources)
-	if updateErr != nil {
-		// 永続化失敗はログのみ（クライアントへのストリーミングは完了済み）
-		slog.Error("failed to update qa session answer",
+// traceModels は推論の記録に残すモデル名を返す（retrieve が false の場合は検索に使うモデルを除く）。
+func (uc *ChatUseCase) traceModels(retrieve bool) domain.TraceModels {
+	models := domain.TraceModels{Answer: uc.cfg.Models.Answer}
+	if !retrieve {
+		return models
+	}
+	models.Embedding = uc.cfg.Models.Embedding
+	if uc.reranker != nil {
+		models.Rerank = uc.cfg.Models.Rerank
+	}
+	if uc.expander != nil {
+		models.QueryExpansion = uc.cfg.Models.QueryExpansion
+	}
+	return models
+}
+
+// traceEvidence は回答生成に渡したエビデンスを推論の記録に変換する。
+func traceEvidence(r domain.SearchResult, tempIndex int, whyRelevant string, fallback bool) domain.TraceEvidence {
+	return domain.TraceEvidence{
+		TempIndex:   &tempIndex,
+		ChunkID:     r.ChunkID,
+		FileID:      r.FileID,
+		FileName:    r.FileName,
+		PageNumber:  r.PageNumber,
+		WhyRelevant: whyRelevant,
+		Fallback:    fallback,
+	}
+}
+
+// finishTrace は推論の記録に終了時刻と全体の所要時間を記録する。
+func finishTrace(trace *domain.ReasoningTrace) {
+	trace.FinishedAt = time.Now()
+	trace.Timings.TotalMs = trace.FinishedAt.Sub(trace.StartedAt).Milliseconds()
+}
+
+// excerptOf は出典の抜粋（先頭 excerptMaxLen 文字）を返す。
+func excerptOf(content string) string {
+	if runes := []rune(content); len(runes) > excerptMaxLen {
+		return string(runes[:excerptMaxLen])
+	}
+	return content
+}
+
+// finishCancelled は中断したセッションに途中までの回答と出典・推論の記録を cancelled として保存し、
+// 接続が残っていれば status: cancelled の完了通知を送る（中断はエラーとして扱わない）。
+func (uc *ChatUseCase) finishCancelled(
+	ctx context.Context,
+	session *domain.QASession,
+	partialAnswer string,
+	sources []domain.Source,
+	trace *domain.ReasoningTrace,
+	onEvent func(eventType domain.SSEEventType, data any) error,
+) (*domain.QASession, error) {
+	slog.Info("qa session cancelled",
+		"session_id", session.ID,
+		"cause", context.Cause(ctx),
+		"answer_len", len(partialAnswer),
+	)
+	// 中断済みの ctx では保存できないため、キャンセルを引き継がない
+	finishTrace(trace)
+	updated, err := uc.qaSessionRepo.MarkInterrupted(context.WithoutCancel(ctx), session.ID, domain.QASessionStatusCancelled, partialAnswer, sources, trace)
+	if err != nil {
+		slog.Error("failed to save cancelled qa session",
 			"session_id", session.ID,
-			"error", updateErr,
+			"error", err,
 		)
+		session.Status = domain.QASessionStatusCancelled
 	} else if updated != nil {
 		session = updated
 	}
-
-	// 8. 完了通知
 	_ = onEvent(domain.SSEEventDone, map[string]any{
 		"session_id": session.ID.String(),
+		"status":     string(domain.QASessionStatusCancelled),
 	})
-
 	return session, nil
 }
 
+// markFailed はエラーで終了したセッションに途中までの回答と出典・推論の記録を failed として保存する（失敗はログのみ）。
+func (uc *ChatUseCase) markFailed(ctx context.Context, sessionID uuid.UUID, partialAnswer string, sources []domain.Source, trace *domain.ReasoningTrace) {
+	finishTrace(trace)
+	if _, err := uc.qaSessionRepo.MarkInterrupted(context.WithoutCancel(ctx), sessionID, domain.QASessionStatusFailed, partialAnswer, sources, trace); err != nil {
+		slog.Error("failed to mark qa session failed",
+			"session_id", sessionID,
+			"error", err,
+		)
+	}
+}
+
+// evidenceSource は検索結果を出典に変換する。
+func evidenceSource(r domain.SearchResult, evidenceContext *domain.EvidenceContext, whyRelevant string) domain.Source {
+	return domain.Source{
+		SubjectID:   r.SubjectID,
+		SubjectName: r.SubjectName,
+		FileID:      r.FileID,
+		ChunkID:     r.ChunkID,
+		FileName:    r.FileName,
+		PageNumber:  r.PageNumber,
+		Excerpt:     excerptOf(r.Content),
+		PreviewURL:  pagePreviewURL(r.SubjectID, r.FileID, r.MimeType, r.PreviewPageCount, r.PageNumber),
+		Context:     evidenceContext,
+		WhyRelevant: whyRelevant,
+	}
+}
+
+// caveatEvent は回答の注意（SSEEventCaveat のデータ）を返す（注意が無い場合は nil）。
+// Librarian が検索を打ち切った場合は見つかった範囲での回答（partial）、
+// LLM 呼び出しに失敗した場合は検索結果のみの回答（retrieval_only）であることを示す。
+func caveatEvent(trace *domain.ReasoningTrace) map[string]any {
+	var kind, message string
+	switch {
+	case trace.ErrorType == ports.LibrarianErrorModelFailure:
+		kind, message = "retrieval_only", "AI による回答を生成できなかったため、質問に関連する資料の該当箇所を示します。"
+	case trace.IsPartial || trace.ErrorType != "":
+		kind, message = "partial", "資料を調べきれなかったため、見つかった範囲で回答します。"
+	default:
+		return nil
+	}
+	caveat := map[string]any{
+		"type":    kind,
+		"message": message,
+	}
+	if trace.ErrorType != "" {
+		caveat["error_type"] = trace.ErrorType
+	}
+	if trace.CoverageNotes != "" {
+		caveat["coverage_notes"] = trace.CoverageNotes
+	}
+	return caveat
+}
+
+// retrievalOnlyAnswer は検索結果のみの回答（出典ごとの教材名・ページと抜粋）を返す。
+func retrievalOnlyAnswer(sources []domain.Source) string {
+	var b strings.Builder
+	b.WriteString("質問に関連する資料の該当箇所です。\n")
+	for _, src := range sources {
+		b.WriteString("\n- ")
+		b.WriteString(src.FileName)
+		if src.PageNumber != nil {
+			fmt.Fprintf(&b, "（%d ページ）", *src.PageNumber)
+		}
+		b.WriteString(": ")
+		b.WriteString(strings.Join(strings.Fields(src.Excerpt), " "))
+	}
+	return b.String()
+}
+
+// errorEvent はエラーを SSEEventError のデータに変換する。
+// 質問を直す必要があるエラー（domain.ErrInvalidInput）は、再試行しても解決しないことを示すため status: 400 を付ける。
+func errorEvent(err error) map[string]any {
+	data := map[string]any{"message": err.Error()}
+	if errors.Is(err, domain.ErrInvalidInput) {
+		data["status"] = 400
+	}
+	return data
+}
+
+// evidenceEvent は出典を SSEEventEvidence のデータに変換する。
+func evidenceEvent(src domain.Source) map[string]any {
+	evidence := map[string]any{
+		"subject_id":   src.SubjectID.String(),
+		"subject_name": src.SubjectName,
+		"chunk_id":     src.ChunkID.String(),
+		"file_id":      src.FileID.String(),
+		"file_name":    src.FileName,
+		"excerpt":      src.Excerpt,
+	}
+	if src.PageNumber != nil {
+		evidence["page_number"] = *src.PageNumber
+	}
+	if src.PreviewURL != "" {
+		evidence["preview_url"] = src.PreviewURL
+	}
+	if src.Context != nil {
+		evidence["context"] = src.Context
+	}
+	if src.WhyRelevant != "" {
+		evidence["why_relevant"] = src.WhyRelevant
+	}
+	return evidence
+}
+
 // ─── ListSessions ─────────────────────────────────────────────────
 
-// ListSessions は指定 subject の QASession 一覧を返す。
+// MaxSessionPageSize は会話履歴の 1 ページの最大件数
+const MaxSessionPageSize = 100
+
+// SessionPage は会話履歴の 1 ページ
+type SessionPage struct {
+	Sessions []*domain.QASession
+	// Next は次のページの位置（最後のページの場合は nil）
+	Next *domain.QASessionCursor
+}
+
+// ListSessions は指定 subject の QASession を新しい順に filter.Limit 件（最大 MaxSessionPageSize）返す。
+// filter.After を指定した場合はその位置より古いセッションを返す（キーセットページング）。
 func (uc *ChatUseCase) ListSessions(
 	ctx context.Context,
 	subjectID, userID uuid.UUID,
-	limit, offset int,
-) ([]*domain.QASession, error) {
+	filter domain.QASessionFilter,
+) (*SessionPage, error) {
 	// subject 所有権確認
 	if _, err := uc.subjectRepo.GetByIDAndUserID(ctx, subjectID, userID); err != nil {
 		return nil, fmt.Errorf("get subject: %w", err)
 	}
-	return uc.qaSessionRepo.ListBySubjectID(ctx, subjectID, userID, limit, offset)
+	limit := min(filter.Limit, MaxSessionPageSize)
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive: %w", domain.ErrInvalidInput)
+	}
+	// 次のページの有無を判定するため 1 件多く取得する
+	filter.Limit = limit + 1
+	sessions, err := uc.qaSessionRepo.ListBySubjectID(ctx, subjectID, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	page := &SessionPage{Sessions: sessions}
+	if len(sessions) > limit {
+		page.Sessions = sessions[:limit]
+		next := domain.CursorAfter(sessions[limit-1])
+		page.Next = &next
+	}
+	return page, nil
 }
 
-// CountSessions は指定 subject の QASession 件数を返す。
+// CountSessions は指定 subject の QASession のうち質問に query を含むものの件数を返す（query が空の場合はすべて）。
 func (uc *ChatUseCase) CountSessions(
 	ctx context.Context,
 	subjectID, userID uuid.UUID,
+	query string,
 ) (int64, error) {
-	return uc.qaSessionRepo.CountBySubjectID(ctx, subjectID, userID)
+	return uc.qaSessionRepo.CountBySubjectID(ctx, subjectID, userID, query)
+}
+
+// ─── GetSession ───────────────────────────────────────────────────
+
+// GetSession は subject に属するユーザーの QASession（出典・エビデンスの選定理由を含む）を返す。
+// 別の subject のセッションは ErrNotFound を返す。
+func (uc *ChatUseCase) GetSession(ctx context.Context, subjectID, sessionID, userID uuid.UUID) (*domain.QASession, error) {
+	session, err := uc.qaSessionRepo.GetByIDAndUserID(ctx, sessionID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get qa session: %w", err)
+	}
+	if session.SubjectID != subjectID {
+		return nil, fmt.Errorf("qa session %s: %w", sessionID, domain.ErrNotFound)
+	}
+	return session, nil
 }
 
 // ─── UpdateFeedback ───────────────────────────────────────────────
 
-// UpdateFeedback は QASession にフィードバック（1: good / -1: bad）を記録する。
+// UpdateFeedback は QASession の選ばれている回答版にフィードバック（1: good / -1: bad）を記録する。
 func (uc *ChatUseCase) UpdateFeedback(
 	ctx context.Context,
 	sessionID, userID uuid.UUID,
@@ -285,3 +829,133 @@ func (uc *ChatUseCase) UpdateFeedback(
 ) (*domain.QASession, error) {
 	return uc.qaSessionRepo.UpdateFeedback(ctx, sessionID, userID, feedback)
 }
+
+// UpdateVersionFeedback は QASession の指定した回答版にフィードバックを記録する（版が無い場合は ErrNotFound）。
+func (uc *ChatUseCase) UpdateVersionFeedback(
+	ctx context.Context,
+	sessionID, userID uuid.UUID,
+	version, feedback int,
+) (*domain.QASession, error) {
+	return uc.qaSessionRepo.UpdateVersionFeedback(ctx, sessionID, userID, version, feedback)
+}
+
+// ─── Cancel ───────────────────────────────────────────────────────
+
+// Cancel は回答生成中の QASession を中断し、途中までの回答を保存したセッションを返す。
+// 回答生成中でない（完了済み・別のインスタンスで実行中）場合は ErrConflict を返す。
+func (uc *ChatUseCase) Cancel(ctx context.Context, subjectID, sessionID, userID uuid.UUID) (*domain.QASession, error) {
+	if _, err := uc.GetSession(ctx, subjectID, sessionID, userID); err != nil {
+		return nil, err
+	}
+	done, ok := uc.running.cancel(sessionID, errSessionCancelled)
+	if !ok {
+		return nil, fmt.Errorf("qa session %s is not running: %w", sessionID, domain.ErrConflict)
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return uc.qaSessionRepo.GetByIDAndUserID(ctx, sessionID, userID)
+}
+
+// ─── Regenerate ───────────────────────────────────────────────────
+
+// RegenerateOptions は回答の再生成の指定（ゼロ値は選ばれている版の出典から回答だけを生成し直す）
+type RegenerateOptions struct {
+	// Retrieve は Librarian による検索からやり直す（選ばれている版に出典が無い場合は常に検索する）
+	Retrieve bool
+	// ResearchMode は検索し直す場合に Librarian に調べさせる深さ。空の場合は科目の設定を使う
+	ResearchMode domain.ResearchMode
+}
+
+// Regenerate は QASession の回答を生成し直し、新しい回答版として保存する。
+// 新しい版は生成を始めた時点で選ばれ、SSE イベントは Ask と同じ（thinking / done に version を含む）。
+// 回答キャッシュは使わない。回答生成中の場合は ErrConflict を返す。
+func (uc *ChatUseCase) Regenerate(
+	ctx context.Context,
+	subjectID, sessionID, userID uuid.UUID,
+	opts RegenerateOptions,
+	onEvent func(eventType domain.SSEEventType, data any) error,
+) (*domain.QASession, error) {
+	if opts.ResearchMode != "" {
+		if _, err := domain.ParseResearchMode(string(opts.ResearchMode)); err != nil {
+			return nil, err
+		}
+	}
+	session, err := uc.GetSession(ctx, subjectID, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	// 質問時に確認した subject の所有権を確認し直す
+	scope, subject, err := resolveSubjectScope(ctx, uc.subjectRepo, session.SubjectID, session.ExtraSubjectIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, release, ok := uc.register(ctx, sessionID)
+	if !ok {
+		return nil, fmt.Errorf("qa session %s is running: %w", sessionID, domain.ErrConflict)
+	}
+	defer release()
+
+	reuse := session.Sources
+	retrieve := opts.Retrieve || len(reuse) == 0
+	session, err = uc.qaSessionRepo.AddVersion(ctx, sessionID, userID, retrieve)
+	if err != nil {
+		return nil, fmt.Errorf("add answer version: %w", err)
+	}
+	slog.Info("regenerating qa session answer", "session_id", sessionID, "version", session.LatestVersion, "retrieve", retrieve)
+
+	return uc.generate(ctx, session, answerGeneration{
+		scope:     scope,
+		diversity: uc.cfg.Diversity,
+		mode:      resolveResearchMode(opts.ResearchMode, subject),
+		retrieve:  retrieve,
+		reuse:     reuse,
+		version:   session.LatestVersion,
+	}, onEvent)
+}
+
+// ─── AnswerVersions ───────────────────────────────────────────────
+
+// ListVersions は QASession（選ばれている版の判定に使う）と回答版を版の順に返す。
+func (uc *ChatUseCase) ListVersions(ctx context.Context, subjectID, sessionID, userID uuid.UUID) (*domain.QASession, []*domain.AnswerVersion, error) {
+	session, err := uc.GetSession(ctx, subjectID, sessionID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	versions, err := uc.qaSessionRepo.ListVersions(ctx, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return session, versions, nil
+}
+
+// SelectVersion はクライアントに表示する回答版を選ぶ（版が無い場合は ErrNotFound）。
+func (uc *ChatUseCase) SelectVersion(ctx context.Context, subjectID, sessionID, userID uuid.UUID, version int) (*domain.QASession, error) {
+	if _, err := uc.GetSession(ctx, subjectID, sessionID, userID); err != nil {
+		return nil, err
+	}
+	return uc.qaSessionRepo.SelectVersion(ctx, sessionID, userID, version)
+}
+
+// ─── ReasoningTrace ───────────────────────────────────────────────
+
+// GetTrace は回答版の推論の記録（デバッグ用）と、その版の番号を返す。
+// version が 0 の場合は選ばれている版を返す。版が無い場合は ErrNotFound、
+// 記録が無い場合（記録を始める前の回答・生成中の回答）は nil を返す。
+func (uc *ChatUseCase) GetTrace(ctx context.Context, subjectID, sessionID, userID uuid.UUID, version int) (int, *domain.ReasoningTrace, error) {
+	session, err := uc.GetSession(ctx, subjectID, sessionID, userID)
+	if err != nil {
+		return 0, nil, err
+	}
+	if version == 0 {
+		version = session.SelectedVersion
+	}
+	trace, err := uc.qaSessionRepo.GetTrace(ctx, sessionID, version)
+	if err != nil {
+		return 0, nil, fmt.Errorf("get reasoning trace: %w", err)
+	}
+	return version, trace, nil
+}
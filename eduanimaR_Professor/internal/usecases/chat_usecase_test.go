@@ -3,8 +3,13 @@ package usecases_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
+	pgvector "github.com/pgvector/pgvector-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -41,7 +46,7 @@ func newChatUseCase(
 	llm *testhelper.MockLLMClient,
 	librarian *testhelper.MockLibrarianClient,
 ) *usecases.ChatUseCase {
-	return usecases.NewChatUseCase(subjectRepo, qaRepo, chunkRepo, llm, librarian)
+	return usecases.NewChatUseCase(subjectRepo, qaRepo, chunkRepo, llm, librarian, nil, nil, nil, usecases.ChatConfig{})
 }
 
 // ─── Ask 正常系 ──────────────────────────────────────────────────
@@ -72,17 +77,18 @@ func TestChatUseCase_Ask_Success(t *testing.T) {
 		CoverageNotes: "テスト推論",
 	}
 	librarianClient.On("Think",
-		ctx,
+		mock.Anything,
 		mock.AnythingOfType("string"), // session.ID.String()
 		question,
 		subjectID,
 		userID,
+		mock.Anything, // ports.LibrarianConstraints
 		mock.Anything, // onSearchRequest func
 	).Return(thinkResult, nil)
 
 	// LLM ストリーミング: "テスト回答" を1チャンクで返す
 	llmClient.On("GenerateAnswerStream",
-		ctx,
+		mock.Anything,
 		question,
 		mock.Anything, // []string（空スライス）
 		mock.Anything, // func(string) error
@@ -96,15 +102,16 @@ func TestChatUseCase_Ask_Success(t *testing.T) {
 		s.Answer = ptrStr("テスト回答")
 	})
 	qaRepo.On("UpdateAnswer",
-		ctx,
+		mock.Anything,
 		mock.Anything, // session.ID
 		"テスト回答",
 		mock.Anything, // []domain.Source
+		mock.Anything, // *domain.ReasoningTrace
 	).Return(updatedSession, nil)
 
 	onEvent, events := collectEvents()
 	uc := newChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient)
-	session, err := uc.Ask(ctx, subjectID, userID, question, onEvent)
+	session, err := uc.Ask(ctx, subjectID, userID, question, usecases.AskOptions{}, onEvent)
 
 	require.NoError(t, err)
 	require.NotNil(t, session)
@@ -120,40 +127,1349 @@ func TestChatUseCase_Ask_Success(t *testing.T) {
 	librarianClient.AssertExpectations(t)
 }
 
+// ─── Ask: 出典のプレビューリンク ────────────────────────────────
+
+func TestChatUseCase_Ask_SourcesLinkPagePreviews(t *testing.T) {
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	userID := testhelper.FixtureUserID
+	question := "テスト質問"
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	chunkRepo := &testhelper.MockChunkRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(nil)
+
+	withPreview := &domain.SearchResult{
+		ChunkID: testhelper.FixtureChunkID, FileID: testhelper.FixtureFileID, SubjectID: subjectID,
+		PageNumber: ptrInt(2), Content: "スライド2", FileName: "lecture.pdf",
+		MimeType: "application/pdf", PreviewPageCount: 5,
+	}
+	withoutPreview := &domain.SearchResult{
+		ChunkID: testhelper.FixtureSessionID, FileID: testhelper.FixtureUploadID, SubjectID: subjectID,
+		PageNumber: ptrInt(9), Content: "スライド9", FileName: "notes.pdf",
+		MimeType: "application/pdf", PreviewPageCount: 0,
+	}
+	chunkRepo.On("SearchByText", mock.Anything, []uuid.UUID{subjectID}, "スライド", mock.AnythingOfType("int"), domain.SearchFilter{}).
+		Return([]*domain.SearchResult{withPreview, withoutPreview}, nil)
+
+	librarianClient.On("Think", mock.Anything, mock.AnythingOfType("string"), question, subjectID, userID, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			onSearch := args.Get(6).(func(ports.LibrarianSearchRequest) (*ports.LibrarianSearchResponse, error))
+			_, _ = onSearch(ports.LibrarianSearchRequest{QueriesText: []string{"スライド"}})
+		}).
+		Return(&ports.LibrarianThinkResult{Evidences: []ports.LibrarianEvidence{
+			{TempIndex: 0, WhyRelevant: "定義"},
+			{TempIndex: 1, WhyRelevant: "補足"},
+		}}, nil)
+	llmClient.On("GenerateAnswerStream", mock.Anything, question, mock.Anything, mock.Anything).Return(nil)
+
+	var sources []domain.Source
+	qaRepo.On("UpdateAnswer", mock.Anything, mock.Anything, "", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { sources = args.Get(3).([]domain.Source) }).
+		Return(testhelper.NewQASession(), nil)
+
+	onEvent, _ := collectEvents()
+	uc := newChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient)
+	_, err := uc.Ask(ctx, subjectID, userID, question, usecases.AskOptions{}, onEvent)
+
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+	assert.Equal(t, "lecture.pdf", sources[0].FileName)
+	assert.Equal(t, domain.PagePreviewAPIPath(subjectID, testhelper.FixtureFileID, 2), sources[0].PreviewURL)
+	assert.Empty(t, sources[1].PreviewURL)
+	assert.Equal(t, "定義", sources[0].WhyRelevant)
+	assert.Equal(t, "補足", sources[1].WhyRelevant)
+}
+
+// ─── Ask: 隣接チャンクによる文脈補完 ───────────────────────────────
+
+// askWithEvidences は指定した検索結果をエビデンスとして Ask を実行し、
+// 回答生成に渡したエビデンス本文と保存した出典を返す。
+func askWithEvidences(
+	t *testing.T,
+	chunkRepo *testhelper.MockChunkRepository,
+	expansion usecases.EvidenceExpansion,
+	results []*domain.SearchResult,
+) ([]string, []domain.Source) {
+	t.Helper()
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	userID := testhelper.FixtureUserID
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(nil)
+	chunkRepo.On("SearchByText", mock.Anything, []uuid.UUID{subjectID}, "定義", mock.AnythingOfType("int"), domain.SearchFilter{}).
+		Return(results, nil)
+	evidences := make([]ports.LibrarianEvidence, len(results))
+	for i := range results {
+		evidences[i] = ports.LibrarianEvidence{TempIndex: i}
+	}
+	librarianClient.On("Think", mock.Anything, mock.AnythingOfType("string"), "質問", subjectID, userID, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			onSearch := args.Get(6).(func(ports.LibrarianSearchRequest) (*ports.LibrarianSearchResponse, error))
+			_, _ = onSearch(ports.LibrarianSearchRequest{QueriesText: []string{"定義"}})
+		}).
+		Return(&ports.LibrarianThinkResult{Evidences: evidences}, nil)
+
+	var texts []string
+	llmClient.On("GenerateAnswerStream", mock.Anything, "質問", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { texts = args.Get(2).([]string) }).
+		Return(nil)
+	var sources []domain.Source
+	qaRepo.On("UpdateAnswer", mock.Anything, mock.Anything, "", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { sources = args.Get(3).([]domain.Source) }).
+		Return(testhelper.NewQASession(), nil)
+
+	onEvent, _ := collectEvents()
+	uc := usecases.NewChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient, nil, nil, nil, usecases.ChatConfig{Expansion: expansion})
+	_, err := uc.Ask(ctx, subjectID, userID, "質問", usecases.AskOptions{}, onEvent)
+	require.NoError(t, err)
+	return texts, sources
+}
+
+// neighbourChunk はテスト用の隣接チャンクを生成する。
+func neighbourChunk(index, page int, content string) *domain.Chunk {
+	return &domain.Chunk{ID: uuid.New(), FileID: testhelper.FixtureFileID, ChunkIndex: index, PageNumber: &page, Content: content}
+}
+
+func TestChatUseCase_Ask_ExpandsEvidenceWithNeighbours(t *testing.T) {
+	chunkRepo := &testhelper.MockChunkRepository{}
+	prev := neighbourChunk(4, 2, "定義 2.1 (前半)")
+	next := neighbourChunk(6, 3, "= 0 (後半)")
+	evidence := &domain.SearchResult{
+		ChunkID: testhelper.FixtureChunkID, FileID: testhelper.FixtureFileID, SubjectID: testhelper.FixtureSubjectID,
+		ChunkIndex: 5, PageNumber: ptrInt(2), Content: "f(x)", FileName: "lecture.pdf",
+	}
+	self := neighbourChunk(5, 2, "f(x)")
+	self.ID = testhelper.FixtureChunkID
+	chunkRepo.On("ListNeighbours", mock.Anything, testhelper.FixtureFileID, 4, 6, (*int)(nil)).
+		Return([]*domain.Chunk{prev, self, next}, nil)
+
+	texts, sources := askWithEvidences(t, chunkRepo, usecases.EvidenceExpansion{Neighbours: 1}, []*domain.SearchResult{evidence})
+
+	assert.Equal(t, []string{"定義 2.1 (前半)\nf(x)\n= 0 (後半)"}, texts)
+	require.Len(t, sources, 1)
+	require.NotNil(t, sources[0].Context)
+	assert.Equal(t, []uuid.UUID{prev.ID, testhelper.FixtureChunkID, next.ID}, sources[0].Context.ChunkIDs)
+	assert.Equal(t, 4, sources[0].Context.ChunkIndexFrom)
+	assert.Equal(t, 6, sources[0].Context.ChunkIndexTo)
+	assert.Equal(t, 2, *sources[0].Context.PageFrom)
+	assert.Equal(t, 3, *sources[0].Context.PageTo)
+	// 出典の抜粋はエビデンス自身のまま
+	assert.Equal(t, "f(x)", sources[0].Excerpt)
+}
+
+func TestChatUseCase_Ask_EvidenceExpansionDedupAndBudget(t *testing.T) {
+	chunkRepo := &testhelper.MockChunkRepository{}
+	first := &domain.SearchResult{
+		ChunkID: uuid.New(), FileID: testhelper.FixtureFileID, SubjectID: testhelper.FixtureSubjectID,
+		ChunkIndex: 5, PageNumber: ptrInt(2), Content: "一つ目", FileName: "lecture.pdf",
+	}
+	second := &domain.SearchResult{
+		ChunkID: uuid.New(), FileID: testhelper.FixtureFileID, SubjectID: testhelper.FixtureSubjectID,
+		ChunkIndex: 6, PageNumber: ptrInt(2), Content: "二つ目", FileName: "lecture.pdf",
+	}
+	// 前のチャンクは予算（10 トークン）を超えるため加えない
+	long := neighbourChunk(4, 2, strings.Repeat("長", 20))
+	secondChunk := neighbourChunk(6, 2, "二つ目")
+	secondChunk.ID = second.ChunkID
+	chunkRepo.On("ListNeighbours", mock.Anything, testhelper.FixtureFileID, 4, 6, (*int)(nil)).
+		Return([]*domain.Chunk{long, secondChunk}, nil)
+
+	texts, sources := askWithEvidences(t, chunkRepo, usecases.EvidenceExpansion{Neighbours: 1, TokenBudget: 10},
+		[]*domain.SearchResult{first, second})
+
+	// 二つ目は一つ目の文脈として渡し済みのため本文を重複させない
+	assert.Equal(t, []string{"一つ目\n二つ目"}, texts)
+	require.Len(t, sources, 2)
+	require.NotNil(t, sources[0].Context)
+	assert.Equal(t, 5, sources[0].Context.ChunkIndexFrom)
+	assert.Equal(t, 6, sources[0].Context.ChunkIndexTo)
+	assert.Nil(t, sources[1].Context)
+	chunkRepo.AssertNumberOfCalls(t, "ListNeighbours", 1)
+}
+
+// ─── Ask: 多様性を考慮した選択（MMR） ──────────────────────────────
+
+// mmrFixture は同じ教材の似たチャンク 2 件とほかの教材のチャンク 1 件を返す。
+func mmrFixture() (results []*domain.SearchResult, embeddings map[uuid.UUID]pgvector.Vector) {
+	fileA, fileB := uuid.New(), uuid.New()
+	a1 := &domain.SearchResult{ChunkID: uuid.New(), FileID: fileA, Content: "A1", FileName: "a.pdf"}
+	a2 := &domain.SearchResult{ChunkID: uuid.New(), FileID: fileA, Content: "A2", FileName: "a.pdf"}
+	b1 := &domain.SearchResult{ChunkID: uuid.New(), FileID: fileB, Content: "B1", FileName: "b.pdf"}
+	return []*domain.SearchResult{a1, a2, b1}, map[uuid.UUID]pgvector.Vector{
+		a1.ChunkID: pgvector.NewVector([]float32{1, 0}),
+		a2.ChunkID: pgvector.NewVector([]float32{0.99, 0.01}),
+		b1.ChunkID: pgvector.NewVector([]float32{0, 1}),
+	}
+}
+
+// askForSearchResults は 1 回の検索ラウンドを実行し、Librarian に返した検索結果の本文と回答生成に渡したエビデンスを返す。
+// reranker が nil の場合は並べ替えない。
+func askForSearchResults(t *testing.T, reranker ports.Reranker, cfg usecases.ChatConfig, opts usecases.AskOptions, evidences []ports.LibrarianEvidence) (returned, texts []string) {
+	t.Helper()
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	userID := testhelper.FixtureUserID
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	chunkRepo := &testhelper.MockChunkRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	results, embeddings := mmrFixture()
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(nil)
+	chunkRepo.On("SearchByText", mock.Anything, []uuid.UUID{subjectID}, "定義", mock.AnythingOfType("int"), domain.SearchFilter{}).
+		Return(results, nil)
+	chunkRepo.On("GetEmbeddings", mock.Anything, mock.Anything).Return(embeddings, nil)
+	librarianClient.On("Think", mock.Anything, mock.AnythingOfType("string"), "質問", subjectID, userID, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			onSearch := args.Get(6).(func(ports.LibrarianSearchRequest) (*ports.LibrarianSearchResponse, error))
+			resp, _ := onSearch(ports.LibrarianSearchRequest{QueriesText: []string{"定義"}})
+			for _, r := range resp.Results {
+				returned = append(returned, r.Content)
+			}
+		}).
+		Return(&ports.LibrarianThinkResult{Evidences: evidences}, nil)
+	llmClient.On("GenerateAnswerStream", mock.Anything, "質問", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { texts = args.Get(2).([]string) }).
+		Return(nil)
+	qaRepo.On("UpdateAnswer", mock.Anything, mock.Anything, "", mock.Anything, mock.Anything).Return(testhelper.NewQASession(), nil)
+
+	onEvent, _ := collectEvents()
+	uc := usecases.NewChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient, reranker, nil, nil, cfg)
+	_, err := uc.Ask(ctx, subjectID, userID, "質問", opts, onEvent)
+	require.NoError(t, err)
+	return returned, texts
+}
+
+func TestChatUseCase_Ask_DiversityPenalizesRedundantChunks(t *testing.T) {
+	cfg := usecases.ChatConfig{Diversity: usecases.Diversification{Weight: 0.5}}
+
+	returned, _ := askForSearchResults(t, nil, cfg, usecases.AskOptions{}, []ports.LibrarianEvidence{{TempIndex: 0}})
+
+	// A2 は A1 とほぼ同じ内容のため、関連度が高くてもほかの教材の B1 より後に回す
+	assert.Equal(t, []string{"A1", "B1", "A2"}, returned)
+}
+
+func TestChatUseCase_Ask_DiversityPerRequestOverride(t *testing.T) {
+	cfg := usecases.ChatConfig{Diversity: usecases.Diversification{Weight: 0.5}}
+	zero := 0.0
+
+	returned, _ := askForSearchResults(t, nil, cfg, usecases.AskOptions{Diversity: &zero}, []ports.LibrarianEvidence{{TempIndex: 0}})
+
+	// 多様性の重み 0 は関連度順のまま
+	assert.Equal(t, []string{"A1", "A2", "B1"}, returned)
+}
+
+func TestChatUseCase_Ask_DiversityLimitsPerFile(t *testing.T) {
+	cfg := usecases.ChatConfig{Diversity: usecases.Diversification{MaxPerFile: 1}}
+
+	returned, _ := askForSearchResults(t, nil, cfg, usecases.AskOptions{}, []ports.LibrarianEvidence{{TempIndex: 0}})
+
+	assert.Equal(t, []string{"A1", "B1"}, returned)
+}
+
+func TestChatUseCase_Ask_FallbackEvidenceIsDiverse(t *testing.T) {
+	cfg := usecases.ChatConfig{Diversity: usecases.Diversification{Weight: 0.5}}
+	zero := 0.0
+
+	// 質問ごとの重みはフォールバックの選択にも使う
+	_, texts := askForSearchResults(t, nil, cfg, usecases.AskOptions{Diversity: &zero}, nil)
+	assert.Equal(t, []string{"A1", "A2", "B1"}, texts)
+
+	_, texts = askForSearchResults(t, nil, cfg, usecases.AskOptions{}, nil)
+	assert.Equal(t, []string{"A1", "B1", "A2"}, texts)
+}
+
+func TestChatUseCase_Ask_InvalidDiversity(t *testing.T) {
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	uc := newChatUseCase(subjectRepo, &testhelper.MockQASessionRepository{}, &testhelper.MockChunkRepository{},
+		&testhelper.MockLLMClient{}, &testhelper.MockLibrarianClient{})
+	onEvent, _ := collectEvents()
+	over := 1.5
+
+	_, err := uc.Ask(context.Background(), testhelper.FixtureSubjectID, testhelper.FixtureUserID, "質問",
+		usecases.AskOptions{Diversity: &over}, onEvent)
+
+	assert.True(t, errors.Is(err, domain.ErrInvalidInput))
+	subjectRepo.AssertNotCalled(t, "GetByIDAndUserID")
+}
+
+// ─── Ask: Reranker による並べ替え ──────────────────────────────────
+
+func TestChatUseCase_Ask_RerankReordersResults(t *testing.T) {
+	reranker := &testhelper.MockReranker{}
+	reranker.On("Rerank", mock.Anything, "質問", []string{"A1", "A2", "B1"}).Return([]float64{0.1, 0.5, 0.9}, nil)
+	cfg := usecases.ChatConfig{Rerank: usecases.Reranking{TopK: 20, Timeout: time.Second}}
+
+	returned, _ := askForSearchResults(t, reranker, cfg, usecases.AskOptions{}, []ports.LibrarianEvidence{{TempIndex: 0}})
+
+	assert.Equal(t, []string{"B1", "A2", "A1"}, returned)
+	reranker.AssertExpectations(t)
+}
+
+func TestChatUseCase_Ask_RerankOnlyTopK(t *testing.T) {
+	reranker := &testhelper.MockReranker{}
+	reranker.On("Rerank", mock.Anything, "質問", []string{"A1", "A2"}).Return([]float64{0.2, 0.8}, nil)
+	cfg := usecases.ChatConfig{Rerank: usecases.Reranking{TopK: 2}}
+
+	returned, _ := askForSearchResults(t, reranker, cfg, usecases.AskOptions{}, []ports.LibrarianEvidence{{TempIndex: 0}})
+
+	// 採点しなかった B1 は採点した候補より後に並ぶ
+	assert.Equal(t, []string{"A2", "A1", "B1"}, returned)
+	reranker.AssertExpectations(t)
+}
+
+func TestChatUseCase_Ask_RerankFailureKeepsSearchRanking(t *testing.T) {
+	reranker := &testhelper.MockReranker{}
+	reranker.On("Rerank", mock.Anything, "質問", mock.Anything).Return(nil, context.DeadlineExceeded)
+	cfg := usecases.ChatConfig{Rerank: usecases.Reranking{TopK: 20, Timeout: time.Millisecond}}
+
+	returned, _ := askForSearchResults(t, reranker, cfg, usecases.AskOptions{}, []ports.LibrarianEvidence{{TempIndex: 0}})
+
+	assert.Equal(t, []string{"A1", "A2", "B1"}, returned)
+}
+
+func TestChatUseCase_Ask_RerankScoreCountMismatch(t *testing.T) {
+	reranker := &testhelper.MockReranker{}
+	reranker.On("Rerank", mock.Anything, "質問", mock.Anything).Return([]float64{0.9}, nil)
+	cfg := usecases.ChatConfig{Rerank: usecases.Reranking{TopK: 20}}
+
+	returned, _ := askForSearchResults(t, reranker, cfg, usecases.AskOptions{}, []ports.LibrarianEvidence{{TempIndex: 0}})
+
+	assert.Equal(t, []string{"A1", "A2", "B1"}, returned)
+}
+
+// ─── Ask: ベクトル検索クエリの拡張 ──────────────────────────────────
+
+// askWithVectorQuery はベクトル検索クエリ "R²" で 2 回の検索ラウンドを実行し、各ラウンドで Librarian に返した検索結果の本文を返す。
+// 埋め込みは各クエリの文字列ごとに異なるベクトルを返し、searches の対応する結果で検索がヒットする。
+func askWithVectorQuery(t *testing.T, expander ports.QueryExpander, qaRepo *testhelper.MockQASessionRepository, searches map[string][]*domain.SearchResult) (rounds [][]string) {
+	t.Helper()
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	userID := testhelper.FixtureUserID
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	chunkRepo := &testhelper.MockChunkRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(nil)
+	dim := 0
+	for q, results := range searches {
+		emb := make([]float32, len(searches))
+		emb[dim] = 1
+		dim++
+		llmClient.On("GenerateEmbedding", mock.Anything, q).Return(emb, nil)
+		chunkRepo.On("SearchByVector", mock.Anything, []uuid.UUID{subjectID}, pgvector.NewVector(emb), mock.AnythingOfType("int"), domain.SearchFilter{}).
+			Return(results, nil)
+	}
+	librarianClient.On("Think", mock.Anything, mock.AnythingOfType("string"), "質問", subjectID, userID, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			onSearch := args.Get(6).(func(ports.LibrarianSearchRequest) (*ports.LibrarianSearchResponse, error))
+			for range 2 {
+				resp, _ := onSearch(ports.LibrarianSearchRequest{QueriesVector: []string{"R²"}})
+				var contents []string
+				for _, r := range resp.Results {
+					contents = append(contents, r.Content)
+				}
+				rounds = append(rounds, contents)
+			}
+		}).
+		Return(&ports.LibrarianThinkResult{Evidences: []ports.LibrarianEvidence{{TempIndex: 0}}}, nil)
+	llmClient.On("GenerateAnswerStream", mock.Anything, "質問", mock.Anything, mock.Anything).Return(nil)
+	qaRepo.On("UpdateAnswer", mock.Anything, mock.Anything, "", mock.Anything, mock.Anything).Return(testhelper.NewQASession(), nil)
+
+	onEvent, _ := collectEvents()
+	uc := usecases.NewChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient, nil, expander, nil, usecases.ChatConfig{})
+	_, err := uc.Ask(ctx, subjectID, userID, "質問", usecases.AskOptions{}, onEvent)
+	require.NoError(t, err)
+	llmClient.AssertExpectations(t)
+	return rounds
+}
+
+func TestChatUseCase_Ask_ExpandsVectorQueries(t *testing.T) {
+	fileID := uuid.New()
+	chunk := func(content string) *domain.SearchResult {
+		return &domain.SearchResult{ChunkID: uuid.New(), FileID: fileID, Content: content, FileName: "統計.pdf"}
+	}
+	definition, formula, english := chunk("決定係数の定義"), chunk("R² = 1 - SSE/SST"), chunk("coefficient of determination")
+	expansion := &domain.QueryExpansion{
+		Paraphrases:        []string{"決定係数", "R²"},
+		HypotheticalAnswer: "決定係数は回帰モデルの当てはまりの良さを表す指標である。",
+		Translations:       []string{"coefficient of determination"},
+	}
+	expander := &testhelper.MockQueryExpander{}
+	expander.On("ExpandQuery", mock.Anything, "R²").Return(expansion, nil).Once()
+
+	qaRepo := &testhelper.MockQASessionRepository{}
+	qaRepo.On("UpdateQueryExpansions", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	rounds := askWithVectorQuery(t, expander, qaRepo, map[string][]*domain.SearchResult{
+		"R²":   {formula},
+		"決定係数": {definition, formula},
+		"決定係数は回帰モデルの当てはまりの良さを表す指標である。": {definition},
+		"coefficient of determination": {english},
+	})
+
+	// 変形の検索結果も RRF で統合する（2 つの変形で 1 位の definition、1 位と 2 位の formula の順）
+	require.Len(t, rounds, 2)
+	assert.Equal(t, []string{"決定係数の定義", "R² = 1 - SSE/SST", "coefficient of determination"}, rounds[0])
+	// 同じクエリは再度拡張しない（Once）
+	assert.Equal(t, rounds[0], rounds[1])
+	expander.AssertExpectations(t)
+
+	// 元のクエリと重複する言い換えを含め、生成した変形をそのまま記録する
+	expansion.Query = "R²"
+	qaRepo.AssertCalled(t, "UpdateQueryExpansions", mock.Anything, mock.Anything, []domain.QueryExpansion{*expansion})
+}
+
+func TestChatUseCase_Ask_QueryExpansionFailureUsesOriginal(t *testing.T) {
+	formula := &domain.SearchResult{ChunkID: uuid.New(), FileID: uuid.New(), Content: "R² = 1 - SSE/SST", FileName: "統計.pdf"}
+	expander := &testhelper.MockQueryExpander{}
+	expander.On("ExpandQuery", mock.Anything, "R²").Return(nil, errors.New("quota exceeded")).Once()
+	qaRepo := &testhelper.MockQASessionRepository{}
+
+	rounds := askWithVectorQuery(t, expander, qaRepo, map[string][]*domain.SearchResult{
+		"R²": {formula},
+	})
+
+	assert.Equal(t, []string{"R² = 1 - SSE/SST"}, rounds[0])
+	expander.AssertExpectations(t)
+	qaRepo.AssertNotCalled(t, "UpdateQueryExpansions", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// ─── Ask: 検索ラウンドの並列実行 ──────────────────────────────────
+
+// askWithTextSearches は全文検索クエリ q1〜q3 の 1 回の検索ラウンドを実行し、Librarian に返した検索結果の本文を返す。
+// 各クエリは本文が同名のチャンク 1 件を返す。setup で個々の検索の振る舞いを上書きできる。
+func askWithTextSearches(t *testing.T, cfg usecases.ChatConfig, setup func(chunkRepo *testhelper.MockChunkRepository)) (returned []string) {
+	t.Helper()
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	userID := testhelper.FixtureUserID
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	chunkRepo := &testhelper.MockChunkRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(nil)
+	setup(chunkRepo)
+	for _, q := range []string{"q1", "q2", "q3"} {
+		chunkRepo.On("SearchByText", mock.Anything, []uuid.UUID{subjectID}, q, mock.AnythingOfType("int"), domain.SearchFilter{}).
+			Return([]*domain.SearchResult{{ChunkID: uuid.New(), FileID: uuid.New(), Content: q}}, nil)
+	}
+	librarianClient.On("Think", mock.Anything, mock.AnythingOfType("string"), "質問", subjectID, userID, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			onSearch := args.Get(6).(func(ports.LibrarianSearchRequest) (*ports.LibrarianSearchResponse, error))
+			resp, _ := onSearch(ports.LibrarianSearchRequest{QueriesText: []string{"q1", "q2", "q3"}})
+			for _, r := range resp.Results {
+				returned = append(returned, r.Content)
+			}
+		}).
+		Return(&ports.LibrarianThinkResult{Evidences: []ports.LibrarianEvidence{{TempIndex: 0}}}, nil)
+	llmClient.On("GenerateAnswerStream", mock.Anything, "質問", mock.Anything, mock.Anything).Return(nil)
+	qaRepo.On("UpdateAnswer", mock.Anything, mock.Anything, "", mock.Anything, mock.Anything).Return(testhelper.NewQASession(), nil)
+
+	onEvent, _ := collectEvents()
+	uc := usecases.NewChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient, nil, nil, nil, cfg)
+	_, err := uc.Ask(ctx, subjectID, userID, "質問", usecases.AskOptions{}, onEvent)
+	require.NoError(t, err)
+	return returned
+}
+
+func TestChatUseCase_Ask_ParallelSearchKeepsQueryOrder(t *testing.T) {
+	cfg := usecases.ChatConfig{Search: usecases.SearchExecution{Concurrency: 3}}
+	q3Started := make(chan struct{})
+	overlapped := false
+
+	returned := askWithTextSearches(t, cfg, func(chunkRepo *testhelper.MockChunkRepository) {
+		// q1 は q3 の検索が始まるまで終わらない（逐次実行の場合は待ちきれずに終わる）
+		chunkRepo.On("SearchByText", mock.Anything, mock.Anything, "q1", mock.Anything, mock.Anything).
+			Run(func(mock.Arguments) {
+				select {
+				case <-q3Started:
+					overlapped = true
+				case <-time.After(time.Second):
+				}
+			}).
+			Return([]*domain.SearchResult{{ChunkID: uuid.New(), FileID: uuid.New(), Content: "q1"}}, nil)
+		chunkRepo.On("SearchByText", mock.Anything, mock.Anything, "q3", mock.Anything, mock.Anything).
+			Run(func(mock.Arguments) { close(q3Started) }).
+			Return([]*domain.SearchResult{{ChunkID: uuid.New(), FileID: uuid.New(), Content: "q3"}}, nil)
+	})
+
+	assert.True(t, overlapped, "searches should run concurrently")
+	// 完了順（q3 → q1）によらず、クエリの指定順に並べる
+	assert.Equal(t, []string{"q1", "q2", "q3"}, returned)
+}
+
+func TestChatUseCase_Ask_SearchRoundTimeoutSkipsSlowQueries(t *testing.T) {
+	cfg := usecases.ChatConfig{Search: usecases.SearchExecution{Concurrency: 3, RoundTimeout: 50 * time.Millisecond}}
+
+	returned := askWithTextSearches(t, cfg, func(chunkRepo *testhelper.MockChunkRepository) {
+		chunkRepo.On("SearchByText", mock.Anything, mock.Anything, "q2", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { <-args.Get(0).(context.Context).Done() }).
+			Return(nil, context.DeadlineExceeded)
+	})
+
+	// 制限時間を超えた q2 は結果なしとして、ほかのクエリの結果を返す
+	assert.Equal(t, []string{"q1", "q3"}, returned)
+}
+
+// ─── Ask: 回答キャッシュ ──────────────────────────────────────────
+
+type sseEvent struct {
+	Type domain.SSEEventType
+	Data map[string]any
+}
+
+// askWithAnswerCache は回答キャッシュを有効にした ChatUseCase で質問し、送信した SSE イベントを返す。
+// キャッシュミスの場合は全文検索 1 件をエビデンスとして "回答" を生成する。
+func askWithAnswerCache(t *testing.T, cache *testhelper.MockAnswerCacheRepository, librarianClient *testhelper.MockLibrarianClient, qaRepo *testhelper.MockQASessionRepository, opts usecases.AskOptions) []sseEvent {
+	t.Helper()
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	userID := testhelper.FixtureUserID
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	chunkRepo := &testhelper.MockChunkRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(nil)
+	llmClient.On("GenerateEmbedding", mock.Anything, "質問").Return([]float32{1, 0}, nil).Maybe()
+	chunkRepo.On("SearchByText", mock.Anything, []uuid.UUID{subjectID}, "定義", mock.AnythingOfType("int"), domain.SearchFilter{}).
+		Return([]*domain.SearchResult{{ChunkID: uuid.New(), FileID: uuid.New(), SubjectID: subjectID, Content: "定義の本文", FileName: "講義.pdf"}}, nil).Maybe()
+	chunkRepo.On("GetEmbeddings", mock.Anything, mock.Anything).Return(map[uuid.UUID]pgvector.Vector{}, nil).Maybe()
+	librarianClient.On("Think", mock.Anything, mock.AnythingOfType("string"), "質問", subjectID, userID, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			onSearch := args.Get(6).(func(ports.LibrarianSearchRequest) (*ports.LibrarianSearchResponse, error))
+			_, _ = onSearch(ports.LibrarianSearchRequest{QueriesText: []string{"定義"}})
+		}).
+		Return(&ports.LibrarianThinkResult{Evidences: []ports.LibrarianEvidence{{TempIndex: 0}}}, nil).Maybe()
+	llmClient.On("GenerateAnswerStream", mock.Anything, "質問", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { _ = args.Get(3).(func(string) error)("回答") }).
+		Return(nil).Maybe()
+	qaRepo.On("UpdateAnswer", mock.Anything, mock.Anything, mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(testhelper.NewQASession(), nil)
+
+	var events []sseEvent
+	onEvent := func(et domain.SSEEventType, data any) error {
+		m, _ := data.(map[string]any)
+		events = append(events, sseEvent{Type: et, Data: m})
+		return nil
+	}
+	cfg := usecases.ChatConfig{Cache: usecases.AnswerCaching{MinSimilarity: 0.9, TTL: time.Hour}}
+	uc := usecases.NewChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient, nil, nil, cache, cfg)
+	_, err := uc.Ask(ctx, subjectID, userID, "質問", opts, onEvent)
+	require.NoError(t, err)
+	return events
+}
+
+func TestChatUseCase_Ask_AnswerCacheHit(t *testing.T) {
+	sources := []domain.Source{{SubjectID: testhelper.FixtureSubjectID, ChunkID: uuid.New(), FileID: uuid.New(), FileName: "講義.pdf", Excerpt: "定義の本文"}}
+	hit := &domain.CachedAnswer{ID: uuid.New(), Question: "似た質問", Answer: "キャッシュした回答", Sources: sources, Similarity: 0.97}
+	key := domain.NewAnswerCacheKey(testhelper.FixtureSubjectID, nil, "v1")
+
+	cache := &testhelper.MockAnswerCacheRepository{}
+	cache.On("MaterialVersion", mock.Anything, []uuid.UUID{testhelper.FixtureSubjectID}).Return("v1", nil)
+	cache.On("FindSimilar", mock.Anything, key, pgvector.NewVector([]float32{1, 0}), mock.AnythingOfType("time.Time")).Return(hit, nil)
+	cache.On("RecordHit", mock.Anything, hit.ID).Return(nil)
+	librarianClient := &testhelper.MockLibrarianClient{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+
+	events := askWithAnswerCache(t, cache, librarianClient, qaRepo, usecases.AskOptions{})
+
+	// Librarian の推論・回答生成を行わず、キャッシュした回答と出典を cached 付きで返す
+	librarianClient.AssertNotCalled(t, "Think", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	require.Len(t, events, 4)
+	assert.Equal(t, []domain.SSEEventType{domain.SSEEventThinking, domain.SSEEventEvidence, domain.SSEEventAnswer, domain.SSEEventDone},
+		[]domain.SSEEventType{events[0].Type, events[1].Type, events[2].Type, events[3].Type})
+	for _, ev := range events {
+		assert.Equal(t, true, ev.Data["cached"], ev.Type)
+	}
+	assert.Equal(t, "キャッシュした回答", events[2].Data["text"])
+	assert.Equal(t, "講義.pdf", events[1].Data["file_name"])
+	qaRepo.AssertCalled(t, "UpdateAnswer", mock.Anything, mock.Anything, "キャッシュした回答", sources, mock.Anything)
+	cache.AssertExpectations(t)
+	cache.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestChatUseCase_Ask_AnswerCacheMissSavesAnswer(t *testing.T) {
+	cache := &testhelper.MockAnswerCacheRepository{}
+	cache.On("MaterialVersion", mock.Anything, []uuid.UUID{testhelper.FixtureSubjectID}).Return("v1", nil)
+	cache.On("FindSimilar", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&domain.CachedAnswer{ID: uuid.New(), Answer: "別の質問への回答", Similarity: 0.5}, nil)
+	var saved *domain.CachedAnswer
+	cache.On("Save", mock.Anything, mock.AnythingOfType("*domain.CachedAnswer"), mock.AnythingOfType("time.Time")).
+		Run(func(args mock.Arguments) { saved = args.Get(1).(*domain.CachedAnswer) }).
+		Return(nil)
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	events := askWithAnswerCache(t, cache, librarianClient, &testhelper.MockQASessionRepository{}, usecases.AskOptions{})
+
+	// 類似度が下限未満のため回答を生成し、キャッシュに保存する
+	librarianClient.AssertCalled(t, "Think", mock.Anything, mock.Anything, "質問", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.NotContains(t, events[len(events)-1].Data, "cached")
+	require.NotNil(t, saved)
+	assert.Equal(t, domain.NewAnswerCacheKey(testhelper.FixtureSubjectID, nil, "v1"), saved.Key)
+	assert.Equal(t, "質問", saved.Question)
+	assert.Equal(t, "回答", saved.Answer)
+	assert.Equal(t, pgvector.NewVector([]float32{1, 0}), saved.QuestionEmbedding)
+	require.Len(t, saved.Sources, 1)
+	assert.Equal(t, "講義.pdf", saved.Sources[0].FileName)
+	assert.NotNil(t, saved.SessionID)
+}
+
+func TestChatUseCase_Ask_AnswerCacheBypass(t *testing.T) {
+	cache := &testhelper.MockAnswerCacheRepository{}
+	cache.On("MaterialVersion", mock.Anything, mock.Anything).Return("v1", nil)
+	cache.On("Save", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	askWithAnswerCache(t, cache, librarianClient, &testhelper.MockQASessionRepository{}, usecases.AskOptions{BypassCache: true})
+
+	// キャッシュを検索せずに回答を生成し、生成した回答でキャッシュを更新する
+	cache.AssertNotCalled(t, "FindSimilar", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	librarianClient.AssertCalled(t, "Think", mock.Anything, mock.Anything, "質問", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	cache.AssertCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestChatUseCase_Ask_AnswerCacheSkippedForCustomDiversity(t *testing.T) {
+	cache := &testhelper.MockAnswerCacheRepository{}
+	diversity := 0.8
+
+	askWithAnswerCache(t, cache, &testhelper.MockLibrarianClient{}, &testhelper.MockQASessionRepository{}, usecases.AskOptions{Diversity: &diversity})
+
+	cache.AssertNotCalled(t, "MaterialVersion", mock.Anything, mock.Anything)
+	cache.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestChatUseCase_Ask_AnswerCacheUnavailable(t *testing.T) {
+	cache := &testhelper.MockAnswerCacheRepository{}
+	cache.On("MaterialVersion", mock.Anything, mock.Anything).Return("", errors.New("db down"))
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	askWithAnswerCache(t, cache, librarianClient, &testhelper.MockQASessionRepository{}, usecases.AskOptions{})
+
+	// キャッシュを使えない場合も回答を生成する
+	librarianClient.AssertCalled(t, "Think", mock.Anything, mock.Anything, "質問", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	cache.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// replayCacheHit は回答キャッシュにヒットする質問を onEvent で送信し、質問セッションのリポジトリと結果を返す。
+func replayCacheHit(ctx context.Context, t *testing.T, sources []domain.Source, onEvent func(domain.SSEEventType, any) error) (*testhelper.MockQASessionRepository, *domain.QASession, error) {
+	t.Helper()
+	hit := &domain.CachedAnswer{ID: uuid.New(), Question: "似た質問", Answer: "キャッシュした回答", Sources: sources, Similarity: 0.97}
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	cache := &testhelper.MockAnswerCacheRepository{}
+	subjectRepo.On("GetByIDAndUserID", mock.Anything, testhelper.FixtureSubjectID, testhelper.FixtureUserID).Return(testhelper.NewSubject(), nil)
+	qaRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.QASession")).Return(nil)
+	qaRepo.On("MarkInterrupted", mock.Anything, mock.Anything, mock.Anything, "", mock.Anything, mock.Anything).
+		Return(testhelper.NewQASession(), nil)
+	llmClient.On("GenerateEmbedding", mock.Anything, "質問").Return([]float32{1, 0}, nil)
+	cache.On("MaterialVersion", mock.Anything, mock.Anything).Return("v1", nil)
+	cache.On("FindSimilar", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(hit, nil)
+
+	cfg := usecases.ChatConfig{Cache: usecases.AnswerCaching{MinSimilarity: 0.9, TTL: time.Hour}}
+	uc := usecases.NewChatUseCase(subjectRepo, qaRepo, &testhelper.MockChunkRepository{}, llmClient, &testhelper.MockLibrarianClient{}, nil, nil, cache, cfg)
+	session, err := uc.Ask(ctx, testhelper.FixtureSubjectID, testhelper.FixtureUserID, "質問", usecases.AskOptions{}, onEvent)
+	return qaRepo, session, err
+}
+
+func TestChatUseCase_Ask_AnswerCacheReplaySendFails(t *testing.T) {
+	sources := []domain.Source{{ChunkID: uuid.New(), FileName: "講義.pdf"}, {ChunkID: uuid.New(), FileName: "演習.pdf"}}
+	sendErr := errors.New("write: broken pipe")
+	var sent int
+	onEvent := func(et domain.SSEEventType, _ any) error {
+		if et == domain.SSEEventEvidence && sent == 1 {
+			return sendErr
+		}
+		if et == domain.SSEEventEvidence {
+			sent++
+		}
+		return nil
+	}
+
+	qaRepo, session, err := replayCacheHit(context.Background(), t, sources, onEvent)
+
+	// 送信エラーを無視せず、送れた出典までを failed として保存する
+	assert.Nil(t, session)
+	assert.ErrorIs(t, err, sendErr)
+	qaRepo.AssertCalled(t, "MarkInterrupted", mock.Anything, mock.Anything, domain.QASessionStatusFailed, "", sources[:1], mock.Anything)
+	qaRepo.AssertNotCalled(t, "UpdateAnswer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestChatUseCase_Ask_AnswerCacheReplayCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sources := []domain.Source{{ChunkID: uuid.New(), FileName: "講義.pdf"}}
+	var types []domain.SSEEventType
+	onEvent := func(et domain.SSEEventType, _ any) error {
+		types = append(types, et)
+		if et == domain.SSEEventAnswer {
+			// 回答の送信中にクライアントが切断した
+			cancel()
+			return context.Canceled
+		}
+		return nil
+	}
+
+	qaRepo, session, err := replayCacheHit(ctx, t, sources, onEvent)
+
+	// 通常の回答生成の中断と同様に cancelled として保存し、done を送る
+	require.NoError(t, err)
+	require.NotNil(t, session)
+	qaRepo.AssertCalled(t, "MarkInterrupted", mock.Anything, mock.Anything, domain.QASessionStatusCancelled, "", sources, mock.Anything)
+	qaRepo.AssertNotCalled(t, "UpdateAnswer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, domain.SSEEventDone, types[len(types)-1])
+}
+
 // ─── Ask: subject が見つからない ──────────────────────────────────
 
-func TestChatUseCase_Ask_SubjectNotFound(t *testing.T) {
+func TestChatUseCase_Ask_SubjectNotFound(t *testing.T) {
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	userID := testhelper.FixtureUserID
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	chunkRepo := &testhelper.MockChunkRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).
+		Return((*domain.Subject)(nil), domain.ErrNotFound)
+
+	onEvent, events := collectEvents()
+	uc := newChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient)
+	session, err := uc.Ask(ctx, subjectID, userID, "質問", usecases.AskOptions{}, onEvent)
+
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, domain.ErrNotFound))
+	assert.Nil(t, session)
+	// イベントは一切発生しないこと
+	assert.Empty(t, *events)
+
+	subjectRepo.AssertExpectations(t)
+	qaRepo.AssertNotCalled(t, "Create")
+	librarianClient.AssertNotCalled(t, "Think")
+}
+
+// ─── Ask: 複数 subject の横断 ─────────────────────────────────────
+
+func TestChatUseCase_Ask_AcrossSubjects(t *testing.T) {
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	otherID := uuid.MustParse("00000000-0000-0000-0000-0000000000a2")
+	userID := testhelper.FixtureUserID
+	question := "検定の考え方"
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	chunkRepo := &testhelper.MockChunkRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	subjectRepo.On("GetByIDAndUserID", ctx, otherID, userID).Return(testhelper.NewSubject(), nil)
+	var created *domain.QASession
+	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).
+		Run(func(args mock.Arguments) { created = args.Get(1).(*domain.QASession) }).
+		Return(nil)
+
+	fromOther := &domain.SearchResult{
+		ChunkID: testhelper.FixtureChunkID, FileID: testhelper.FixtureFileID, SubjectID: otherID,
+		SubjectName: "統計学II", Content: "仮説検定", FileName: "stats2.pdf",
+	}
+	// 重複した subject は 1 つにまとめて検索する
+	chunkRepo.On("SearchByText", mock.Anything, []uuid.UUID{subjectID, otherID}, "検定", mock.AnythingOfType("int"), domain.SearchFilter{}).
+		Return([]*domain.SearchResult{fromOther}, nil)
+
+	librarianClient.On("Think", mock.Anything, mock.AnythingOfType("string"), question, subjectID, userID, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			onSearch := args.Get(6).(func(ports.LibrarianSearchRequest) (*ports.LibrarianSearchResponse, error))
+			_, _ = onSearch(ports.LibrarianSearchRequest{QueriesText: []string{"検定"}})
+		}).
+		Return(&ports.LibrarianThinkResult{Evidences: []ports.LibrarianEvidence{{TempIndex: 0}}}, nil)
+	llmClient.On("GenerateAnswerStream", mock.Anything, question, mock.Anything, mock.Anything).Return(nil)
+
+	var sources []domain.Source
+	qaRepo.On("UpdateAnswer", mock.Anything, mock.Anything, "", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { sources = args.Get(3).([]domain.Source) }).
+		Return(testhelper.NewQASession(), nil)
+
+	onEvent, _ := collectEvents()
+	uc := newChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient)
+	_, err := uc.Ask(ctx, subjectID, userID, question, usecases.AskOptions{ExtraSubjectIDs: []uuid.UUID{otherID, subjectID, otherID}}, onEvent)
+
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{otherID}, created.ExtraSubjectIDs)
+	require.Len(t, sources, 1)
+	assert.Equal(t, otherID, sources[0].SubjectID)
+	assert.Equal(t, "統計学II", sources[0].SubjectName)
+	chunkRepo.AssertExpectations(t)
+}
+
+func TestChatUseCase_Ask_AcrossSubjects_NotOwned(t *testing.T) {
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	otherID := uuid.MustParse("00000000-0000-0000-0000-0000000000a2")
+	userID := testhelper.FixtureUserID
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	subjectRepo.On("GetByIDAndUserID", ctx, otherID, userID).Return(nil, domain.ErrNotFound)
+
+	onEvent, events := collectEvents()
+	uc := newChatUseCase(subjectRepo, qaRepo, &testhelper.MockChunkRepository{}, &testhelper.MockLLMClient{}, librarianClient)
+	_, err := uc.Ask(ctx, subjectID, userID, "質問", usecases.AskOptions{ExtraSubjectIDs: []uuid.UUID{otherID}}, onEvent)
+
+	assert.True(t, errors.Is(err, domain.ErrNotFound))
+	assert.Empty(t, *events)
+	qaRepo.AssertNotCalled(t, "Create")
+	librarianClient.AssertNotCalled(t, "Think")
+}
+
+func TestChatUseCase_Ask_TooManySubjects(t *testing.T) {
+	extra := make([]uuid.UUID, usecases.MaxScopeSubjects)
+	for i := range extra {
+		extra[i] = uuid.New()
+	}
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	uc := newChatUseCase(subjectRepo, &testhelper.MockQASessionRepository{}, &testhelper.MockChunkRepository{},
+		&testhelper.MockLLMClient{}, &testhelper.MockLibrarianClient{})
+	onEvent, _ := collectEvents()
+	_, err := uc.Ask(context.Background(), testhelper.FixtureSubjectID, testhelper.FixtureUserID, "質問", usecases.AskOptions{ExtraSubjectIDs: extra}, onEvent)
+
+	assert.True(t, errors.Is(err, domain.ErrInvalidInput))
+	subjectRepo.AssertNotCalled(t, "GetByIDAndUserID")
+}
+
+// ─── Ask: QASession 作成失敗 ─────────────────────────────────────
+
+func TestChatUseCase_Ask_CreateSessionFails(t *testing.T) {
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	userID := testhelper.FixtureUserID
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	chunkRepo := &testhelper.MockChunkRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	subject := testhelper.NewSubject()
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(subject, nil)
+
+	dbErr := errors.New("db connection error")
+	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(dbErr)
+
+	onEvent, _ := collectEvents()
+	uc := newChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient)
+	session, err := uc.Ask(ctx, subjectID, userID, "質問", usecases.AskOptions{}, onEvent)
+
+	assert.Error(t, err)
+	assert.Nil(t, session)
+	librarianClient.AssertNotCalled(t, "Think")
+}
+
+// ─── Ask: Librarian エラー時 SSEEventError を送信 ─────────────────
+
+func TestChatUseCase_Ask_LibrarianError_SendsSSEError(t *testing.T) {
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	userID := testhelper.FixtureUserID
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	chunkRepo := &testhelper.MockChunkRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	subject := testhelper.NewSubject()
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(subject, nil)
+	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(nil)
+
+	librarianErr := errors.New("librarian unavailable")
+	librarianClient.On("Think",
+		mock.Anything, mock.Anything, "質問", subjectID, userID, mock.Anything, mock.Anything,
+	).Return((*ports.LibrarianThinkResult)(nil), librarianErr)
+	qaRepo.On("MarkInterrupted", mock.Anything, mock.AnythingOfType("uuid.UUID"), domain.QASessionStatusFailed, "", []domain.Source(nil), mock.Anything).
+		Return(testhelper.NewQASession(), nil)
+
+	var gotErrorEvent bool
+	onEvent := func(et domain.SSEEventType, _ any) error {
+		if et == domain.SSEEventError {
+			gotErrorEvent = true
+		}
+		return nil
+	}
+
+	uc := newChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient)
+	session, err := uc.Ask(ctx, subjectID, userID, "質問", usecases.AskOptions{}, onEvent)
+
+	assert.Error(t, err)
+	assert.Nil(t, session)
+	assert.True(t, gotErrorEvent, "SSEEventError が送信されるべき")
+
+	librarianClient.AssertExpectations(t)
+	llmClient.AssertNotCalled(t, "GenerateAnswerStream")
+	qaRepo.AssertExpectations(t) // failed として記録する
+}
+
+// ─── Ask: LLM ストリームエラー時 SSEEventError を送信 ──────────────
+
+func TestChatUseCase_Ask_LLMStreamError_SendsSSEError(t *testing.T) {
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	userID := testhelper.FixtureUserID
+	question := "ストリームエラーテスト"
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	chunkRepo := &testhelper.MockChunkRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	subject := testhelper.NewSubject()
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(subject, nil)
+	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(nil)
+
+	thinkResult := &ports.LibrarianThinkResult{
+		Evidences:     []ports.LibrarianEvidence{},
+		CoverageNotes: "推論",
+	}
+	librarianClient.On("Think",
+		mock.Anything, mock.Anything, question, subjectID, userID, mock.Anything, mock.Anything,
+	).Return(thinkResult, nil)
+
+	streamErr := errors.New("LLM stream broken")
+	llmClient.On("GenerateAnswerStream",
+		mock.Anything, question, mock.Anything, mock.Anything,
+	).Return(streamErr)
+	qaRepo.On("MarkInterrupted", mock.Anything, mock.AnythingOfType("uuid.UUID"), domain.QASessionStatusFailed, "", mock.Anything, mock.Anything).
+		Return(testhelper.NewQASession(), nil)
+
+	var gotErrorEvent bool
+	onEvent := func(et domain.SSEEventType, _ any) error {
+		if et == domain.SSEEventError {
+			gotErrorEvent = true
+		}
+		return nil
+	}
+
+	uc := newChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient)
+	session, err := uc.Ask(ctx, subjectID, userID, question, usecases.AskOptions{}, onEvent)
+
+	assert.Error(t, err)
+	assert.Nil(t, session)
+	assert.True(t, gotErrorEvent, "SSEEventError が送信されるべき")
+
+	llmClient.AssertExpectations(t)
+	qaRepo.AssertNotCalled(t, "UpdateAnswer")
+	qaRepo.AssertExpectations(t) // failed として記録する
+}
+
+// ─── Ask: 推論の記録 ──────────────────────────────────────────────
+
+func TestChatUseCase_Ask_RecordsReasoningTrace(t *testing.T) {
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	userID := testhelper.FixtureUserID
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	chunkRepo := &testhelper.MockChunkRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(nil)
+	chunk := &domain.SearchResult{ChunkID: uuid.New(), FileID: uuid.New(), SubjectID: subjectID, Content: "定義の本文", FileName: "講義.pdf", PageNumber: ptrInt(3)}
+	chunkRepo.On("SearchByText", mock.Anything, []uuid.UUID{subjectID}, "決定係数", mock.AnythingOfType("int"), domain.SearchFilter{}).
+		Return([]*domain.SearchResult{chunk}, nil)
+	librarianClient.On("Think", mock.Anything, mock.AnythingOfType("string"), "質問", subjectID, userID, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			onSearch := args.Get(6).(func(ports.LibrarianSearchRequest) (*ports.LibrarianSearchResponse, error))
+			_, _ = onSearch(ports.LibrarianSearchRequest{QueriesText: []string{"決定係数"}, Rationale: "定義を探す"})
+		}).
+		Return(&ports.LibrarianThinkResult{
+			Evidences:     []ports.LibrarianEvidence{{TempIndex: 0, WhyRelevant: "定義が書かれている"}},
+			CoverageNotes: "例が見つからない",
+			IsPartial:     true,
+			ErrorType:     "LOOP_LIMIT",
+		}, nil)
+	llmClient.On("GenerateAnswerStream", mock.Anything, "質問", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { _ = args.Get(3).(func(string) error)("回答") }).
+		Return(nil)
+	var trace *domain.ReasoningTrace
+	qaRepo.On("UpdateAnswer", mock.Anything, mock.Anything, "回答", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { trace = args.Get(4).(*domain.ReasoningTrace) }).
+		Return(testhelper.NewQASession(), nil)
+
+	cfg := usecases.ChatConfig{Models: domain.TraceModels{Answer: "gen", Embedding: "emb", Rerank: "gen", QueryExpansion: "gen"}}
+	onEvent, _ := collectEvents()
+	uc := usecases.NewChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient, nil, nil, nil, cfg)
+	_, err := uc.Ask(ctx, subjectID, userID, "質問", usecases.AskOptions{}, onEvent)
+	require.NoError(t, err)
+
+	require.NotNil(t, trace)
+	assert.True(t, trace.Retrieved)
+	require.Len(t, trace.Rounds, 1)
+	round := trace.Rounds[0]
+	assert.Equal(t, 1, round.Round)
+	assert.Equal(t, []string{"決定係数"}, round.QueriesText)
+	assert.Equal(t, "定義を探す", round.Rationale)
+	assert.Equal(t, 1, round.Searches)
+	assert.Equal(t, 1, round.Candidates)
+	assert.Equal(t, 1, round.Selected)
+	assert.Equal(t, 1, round.TotalAccumulated)
+
+	require.Len(t, trace.Evidences, 1)
+	ev := trace.Evidences[0]
+	require.NotNil(t, ev.TempIndex)
+	assert.Equal(t, 0, *ev.TempIndex)
+	assert.Equal(t, chunk.ChunkID, ev.ChunkID)
+	assert.Equal(t, "定義が書かれている", ev.WhyRelevant)
+	assert.False(t, ev.Fallback)
+
+	assert.Equal(t, "例が見つからない", trace.CoverageNotes)
+	assert.True(t, trace.IsPartial)
+	assert.Equal(t, "LOOP_LIMIT", trace.ErrorType)
+	// Reranker・QueryExpander を使わない場合はそのモデル名を記録しない
+	assert.Equal(t, domain.TraceModels{Answer: "gen", Embedding: "emb"}, trace.Models)
+	assert.False(t, trace.FinishedAt.Before(trace.StartedAt))
+	assert.Empty(t, trace.Error)
+}
+
+func TestChatUseCase_Ask_FallbackEvidenceRecordedInTrace(t *testing.T) {
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	userID := testhelper.FixtureUserID
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	chunkRepo := &testhelper.MockChunkRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(nil)
+	chunk := &domain.SearchResult{ChunkID: uuid.New(), FileID: uuid.New(), SubjectID: subjectID, Content: "本文"}
+	chunkRepo.On("SearchByText", mock.Anything, []uuid.UUID{subjectID}, "q", mock.AnythingOfType("int"), domain.SearchFilter{}).
+		Return([]*domain.SearchResult{chunk}, nil)
+	librarianClient.On("Think", mock.Anything, mock.AnythingOfType("string"), "質問", subjectID, userID, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			onSearch := args.Get(6).(func(ports.LibrarianSearchRequest) (*ports.LibrarianSearchResponse, error))
+			_, _ = onSearch(ports.LibrarianSearchRequest{QueriesText: []string{"q"}})
+		}).
+		Return(&ports.LibrarianThinkResult{}, nil)
+	llmClient.On("GenerateAnswerStream", mock.Anything, "質問", []string{"本文"}, mock.Anything).Return(nil)
+	var trace *domain.ReasoningTrace
+	qaRepo.On("UpdateAnswer", mock.Anything, mock.Anything, "", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { trace = args.Get(4).(*domain.ReasoningTrace) }).
+		Return(testhelper.NewQASession(), nil)
+
+	onEvent, _ := collectEvents()
+	uc := newChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient)
+	_, err := uc.Ask(ctx, subjectID, userID, "質問", usecases.AskOptions{}, onEvent)
+	require.NoError(t, err)
+
+	require.NotNil(t, trace)
+	require.Len(t, trace.Evidences, 1)
+	assert.True(t, trace.Evidences[0].Fallback)
+	assert.Equal(t, chunk.ChunkID, trace.Evidences[0].ChunkID)
+	require.NotNil(t, trace.Evidences[0].TempIndex)
+	assert.Equal(t, 0, *trace.Evidences[0].TempIndex)
+}
+
+func TestChatUseCase_Ask_FailedRecordsTraceError(t *testing.T) {
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	userID := testhelper.FixtureUserID
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(nil)
+	librarianClient.On("Think", mock.Anything, mock.Anything, "質問", subjectID, userID, mock.Anything, mock.Anything).
+		Return(&ports.LibrarianThinkResult{}, nil)
+	llmClient.On("GenerateAnswerStream", mock.Anything, "質問", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { _ = args.Get(3).(func(string) error)("途中") }).
+		Return(errors.New("quota exceeded"))
+	var trace *domain.ReasoningTrace
+	qaRepo.On("MarkInterrupted", mock.Anything, mock.AnythingOfType("uuid.UUID"), domain.QASessionStatusFailed, "途中", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { trace = args.Get(5).(*domain.ReasoningTrace) }).
+		Return(testhelper.NewQASession(), nil)
+
+	onEvent, _ := collectEvents()
+	uc := newChatUseCase(subjectRepo, qaRepo, &testhelper.MockChunkRepository{}, llmClient, librarianClient)
+	_, err := uc.Ask(ctx, subjectID, userID, "質問", usecases.AskOptions{}, onEvent)
+	require.Error(t, err)
+
+	require.NotNil(t, trace)
+	assert.Equal(t, "quota exceeded", trace.Error)
+	assert.False(t, trace.FinishedAt.IsZero())
+}
+
+// ─── Ask: 調べる深さ（ResearchMode） ──────────────────────────────
+
+// researchModes は深さごとに区別できる制約を返す。
+func researchModes() usecases.ResearchModes {
+	return usecases.ResearchModes{
+		Quick:    ports.LibrarianConstraints{MaxLoops: 1, MaxResults: 2, Timeout: 10 * time.Second},
+		Standard: ports.LibrarianConstraints{MaxLoops: 3, MaxResults: 10, Timeout: 30 * time.Second},
+		Deep:     ports.LibrarianConstraints{MaxLoops: 6, MaxResults: 20, Timeout: 90 * time.Second},
+	}
+}
+
+// askWithResearchMode は科目 subject に質問し、Librarian に送った制約と推論の記録を返す。
+// 1 回の検索ラウンドで 5 件の候補を返す。
+func askWithResearchMode(t *testing.T, subject *domain.Subject, opts usecases.AskOptions) (ports.LibrarianConstraints, *domain.ReasoningTrace) {
+	t.Helper()
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	userID := testhelper.FixtureUserID
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	chunkRepo := &testhelper.MockChunkRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(subject, nil)
+	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(nil)
+	var results []*domain.SearchResult
+	for i := 0; i < 5; i++ {
+		results = append(results, &domain.SearchResult{ChunkID: uuid.New(), FileID: uuid.New(), SubjectID: subjectID, Content: fmt.Sprintf("本文%d", i)})
+	}
+	chunkRepo.On("SearchByText", mock.Anything, []uuid.UUID{subjectID}, "q", mock.AnythingOfType("int"), domain.SearchFilter{}).
+		Return(results, nil)
+	var constraints ports.LibrarianConstraints
+	librarianClient.On("Think", mock.Anything, mock.AnythingOfType("string"), "質問", subjectID, userID, mock.AnythingOfType("ports.LibrarianConstraints"), mock.Anything).
+		Run(func(args mock.Arguments) {
+			constraints = args.Get(5).(ports.LibrarianConstraints)
+			onSearch := args.Get(6).(func(ports.LibrarianSearchRequest) (*ports.LibrarianSearchResponse, error))
+			_, _ = onSearch(ports.LibrarianSearchRequest{QueriesText: []string{"q"}})
+		}).
+		Return(&ports.LibrarianThinkResult{}, nil)
+	llmClient.On("GenerateAnswerStream", mock.Anything, "質問", mock.Anything, mock.Anything).Return(nil)
+	var trace *domain.ReasoningTrace
+	qaRepo.On("UpdateAnswer", mock.Anything, mock.Anything, "", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { trace = args.Get(4).(*domain.ReasoningTrace) }).
+		Return(testhelper.NewQASession(), nil)
+
+	onEvent, _ := collectEvents()
+	uc := usecases.NewChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient, nil, nil, nil, usecases.ChatConfig{Research: researchModes()})
+	_, err := uc.Ask(ctx, subjectID, userID, "質問", opts, onEvent)
+	require.NoError(t, err)
+	require.NotNil(t, trace)
+	return constraints, trace
+}
+
+func TestChatUseCase_Ask_ResearchModeDefaultsToStandard(t *testing.T) {
+	constraints, trace := askWithResearchMode(t, testhelper.NewSubject(), usecases.AskOptions{})
+
+	assert.Equal(t, researchModes().Standard, constraints)
+	assert.Equal(t, domain.ResearchModeStandard, trace.ResearchMode)
+	assert.Equal(t, &domain.TraceConstraints{MaxLoops: 3, MaxResults: 10, TimeoutMs: 30000}, trace.Constraints)
+}
+
+func TestChatUseCase_Ask_ResearchModeFromSubject(t *testing.T) {
+	subject := testhelper.NewSubject(func(s *domain.Subject) { s.ResearchMode = domain.ResearchModeDeep })
+
+	constraints, trace := askWithResearchMode(t, subject, usecases.AskOptions{})
+
+	assert.Equal(t, researchModes().Deep, constraints)
+	assert.Equal(t, domain.ResearchModeDeep, trace.ResearchMode)
+}
+
+func TestChatUseCase_Ask_ResearchModeRequestOverridesSubject(t *testing.T) {
+	subject := testhelper.NewSubject(func(s *domain.Subject) { s.ResearchMode = domain.ResearchModeDeep })
+
+	constraints, trace := askWithResearchMode(t, subject, usecases.AskOptions{ResearchMode: domain.ResearchModeQuick})
+
+	assert.Equal(t, researchModes().Quick, constraints)
+	assert.Equal(t, domain.ResearchModeQuick, trace.ResearchMode)
+	// quick の MaxResults（2 件）までしか選ばない
+	require.Len(t, trace.Rounds, 1)
+	assert.Equal(t, 5, trace.Rounds[0].Candidates)
+	assert.Equal(t, 2, trace.Rounds[0].Selected)
+}
+
+func TestChatUseCase_Ask_InvalidResearchMode(t *testing.T) {
+	uc := newChatUseCase(&testhelper.MockSubjectRepository{}, &testhelper.MockQASessionRepository{}, &testhelper.MockChunkRepository{}, &testhelper.MockLLMClient{}, &testhelper.MockLibrarianClient{})
+	onEvent, _ := collectEvents()
+
+	_, err := uc.Ask(context.Background(), testhelper.FixtureSubjectID, testhelper.FixtureUserID, "質問", usecases.AskOptions{ResearchMode: "thorough"}, onEvent)
+
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+}
+
+func TestChatUseCase_Ask_AnswerCacheSkippedForResearchMode(t *testing.T) {
+	cache := &testhelper.MockAnswerCacheRepository{}
+
+	askWithAnswerCache(t, cache, &testhelper.MockLibrarianClient{}, &testhelper.MockQASessionRepository{}, usecases.AskOptions{ResearchMode: domain.ResearchModeDeep})
+
+	cache.AssertNotCalled(t, "MaterialVersion", mock.Anything, mock.Anything)
+	cache.AssertNotCalled(t, "Save", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// ─── Ask: Librarian のエラー種別 ──────────────────────────────────
+
+// askWithLibrarianOutcome は 1 回の全文検索（講義.pdf 3 ページの 1 件）の後に Librarian が result を返す質問を実行し、
+// 送信した SSE イベントと Ask のエラーを返す。LLM は "回答" を生成する。
+func askWithLibrarianOutcome(t *testing.T, result *ports.LibrarianThinkResult, llmClient *testhelper.MockLLMClient, qaRepo *testhelper.MockQASessionRepository) ([]sseEvent, error) {
+	t.Helper()
 	ctx := context.Background()
 	subjectID := testhelper.FixtureSubjectID
 	userID := testhelper.FixtureUserID
 
 	subjectRepo := &testhelper.MockSubjectRepository{}
-	qaRepo := &testhelper.MockQASessionRepository{}
 	chunkRepo := &testhelper.MockChunkRepository{}
-	llmClient := &testhelper.MockLLMClient{}
 	librarianClient := &testhelper.MockLibrarianClient{}
 
-	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).
-		Return((*domain.Subject)(nil), domain.ErrNotFound)
-
-	onEvent, events := collectEvents()
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(nil)
+	page := 3
+	chunkRepo.On("SearchByText", mock.Anything, []uuid.UUID{subjectID}, "定義", mock.AnythingOfType("int"), domain.SearchFilter{}).
+		Return([]*domain.SearchResult{{ChunkID: uuid.New(), FileID: uuid.New(), SubjectID: subjectID, Content: "定義の本文", FileName: "講義.pdf", PageNumber: &page}}, nil)
+	librarianClient.On("Think", mock.Anything, mock.AnythingOfType("string"), "質問", subjectID, userID, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			onSearch := args.Get(6).(func(ports.LibrarianSearchRequest) (*ports.LibrarianSearchResponse, error))
+			_, _ = onSearch(ports.LibrarianSearchRequest{QueriesText: []string{"定義"}})
+		}).
+		Return(result, nil)
+	llmClient.On("GenerateAnswerStream", mock.Anything, "質問", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { _ = args.Get(3).(func(string) error)("回答") }).
+		Return(nil).Maybe()
+	qaRepo.On("UpdateAnswer", mock.Anything, mock.Anything, mock.AnythingOfType("string"), mock.Anything, mock.Anything).Return(testhelper.NewQASession(), nil).Maybe()
+	qaRepo.On("MarkInterrupted", mock.Anything, mock.Anything, domain.QASessionStatusFailed, "", mock.Anything, mock.Anything).Return(testhelper.NewQASession(), nil).Maybe()
+
+	var events []sseEvent
+	onEvent := func(et domain.SSEEventType, data any) error {
+		m, _ := data.(map[string]any)
+		events = append(events, sseEvent{Type: et, Data: m})
+		return nil
+	}
 	uc := newChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient)
-	session, err := uc.Ask(ctx, subjectID, userID, "質問", onEvent)
+	_, err := uc.Ask(ctx, subjectID, userID, "質問", usecases.AskOptions{}, onEvent)
+	return events, err
+}
 
-	assert.Error(t, err)
-	assert.True(t, errors.Is(err, domain.ErrNotFound))
-	assert.Nil(t, session)
-	// イベントは一切発生しないこと
-	assert.Empty(t, *events)
+// eventTypes は SSE イベントの種別を送信順に返す。
+func eventTypes(events []sseEvent) []domain.SSEEventType {
+	types := make([]domain.SSEEventType, len(events))
+	for i, ev := range events {
+		types[i] = ev.Type
+	}
+	return types
+}
 
-	subjectRepo.AssertExpectations(t)
-	qaRepo.AssertNotCalled(t, "Create")
-	librarianClient.AssertNotCalled(t, "Think")
+func TestChatUseCase_Ask_PartialCoverageSendsCaveat(t *testing.T) {
+	llmClient := &testhelper.MockLLMClient{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+
+	events, err := askWithLibrarianOutcome(t, &ports.LibrarianThinkResult{
+		Evidences:     []ports.LibrarianEvidence{{TempIndex: 0}},
+		CoverageNotes: "例が見つからない",
+		IsPartial:     true,
+		ErrorType:     ports.LibrarianErrorLoopLimit,
+	}, llmClient, qaRepo)
+	require.NoError(t, err)
+
+	// 見つかった範囲で回答し、回答の前に注意を送る
+	assert.Equal(t, []domain.SSEEventType{
+		domain.SSEEventThinking, domain.SSEEventSearching, domain.SSEEventEvidence,
+		domain.SSEEventCaveat, domain.SSEEventAnswer, domain.SSEEventDone,
+	}, eventTypes(events))
+	caveat := events[3].Data
+	assert.Equal(t, "partial", caveat["type"])
+	assert.Equal(t, "例が見つからない", caveat["coverage_notes"])
+	assert.Equal(t, ports.LibrarianErrorLoopLimit, caveat["error_type"])
+	assert.NotEmpty(t, caveat["message"])
+	llmClient.AssertCalled(t, "GenerateAnswerStream", mock.Anything, "質問", []string{"定義の本文"}, mock.Anything)
+	qaRepo.AssertCalled(t, "UpdateAnswer", mock.Anything, mock.Anything, "回答", mock.Anything, mock.Anything)
 }
 
-// ─── Ask: QASession 作成失敗 ─────────────────────────────────────
+func TestChatUseCase_Ask_ModelFailureAnswersFromSearchResults(t *testing.T) {
+	llmClient := &testhelper.MockLLMClient{}
+	qaRepo := &testhelper.MockQASessionRepository{}
 
-func TestChatUseCase_Ask_CreateSessionFails(t *testing.T) {
+	events, err := askWithLibrarianOutcome(t, &ports.LibrarianThinkResult{
+		CoverageNotes: "gemini unavailable",
+		ErrorType:     ports.LibrarianErrorModelFailure,
+	}, llmClient, qaRepo)
+	require.NoError(t, err)
+
+	// LLM を使わず、検索結果の抜粋を出典付きで回答する
+	assert.Equal(t, []domain.SSEEventType{
+		domain.SSEEventThinking, domain.SSEEventSearching, domain.SSEEventEvidence,
+		domain.SSEEventCaveat, domain.SSEEventAnswer, domain.SSEEventDone,
+	}, eventTypes(events))
+	assert.Equal(t, "講義.pdf", events[2].Data["file_name"])
+	assert.Equal(t, "retrieval_only", events[3].Data["type"])
+	assert.Equal(t, ports.LibrarianErrorModelFailure, events[3].Data["error_type"])
+	answer := events[4].Data["text"].(string)
+	assert.Contains(t, answer, "講義.pdf（3 ページ）: 定義の本文")
+	llmClient.AssertNotCalled(t, "GenerateAnswerStream", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	qaRepo.AssertCalled(t, "UpdateAnswer", mock.Anything, mock.Anything, answer, mock.MatchedBy(func(sources []domain.Source) bool {
+		return len(sources) == 1 && sources[0].FileName == "講義.pdf"
+	}), mock.Anything)
+}
+
+func TestChatUseCase_Ask_InvalidInputSendsClientError(t *testing.T) {
+	llmClient := &testhelper.MockLLMClient{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+
+	events, err := askWithLibrarianOutcome(t, &ports.LibrarianThinkResult{
+		CoverageNotes: "質問が空です",
+		ErrorType:     ports.LibrarianErrorInvalidInput,
+	}, llmClient, qaRepo)
+
+	// 回答せず、質問を直す必要があることを status: 400 のエラーで伝える
+	require.ErrorIs(t, err, domain.ErrInvalidInput)
+	assert.Equal(t, []domain.SSEEventType{
+		domain.SSEEventThinking, domain.SSEEventSearching, domain.SSEEventError,
+	}, eventTypes(events))
+	assert.Equal(t, 400, events[2].Data["status"])
+	assert.Contains(t, events[2].Data["message"], "質問が空です")
+	llmClient.AssertNotCalled(t, "GenerateAnswerStream", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	qaRepo.AssertCalled(t, "MarkInterrupted", mock.Anything, mock.Anything, domain.QASessionStatusFailed, "", mock.Anything, mock.Anything)
+	qaRepo.AssertNotCalled(t, "UpdateAnswer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// ─── Ask: 中断（クライアントの切断・Cancel） ──────────────────────
+
+func TestChatUseCase_Ask_ClientDisconnectCancelsStream(t *testing.T) {
 	ctx := context.Background()
 	subjectID := testhelper.FixtureSubjectID
 	userID := testhelper.FixtureUserID
@@ -164,24 +1480,47 @@ func TestChatUseCase_Ask_CreateSessionFails(t *testing.T) {
 	llmClient := &testhelper.MockLLMClient{}
 	librarianClient := &testhelper.MockLibrarianClient{}
 
-	subject := testhelper.NewSubject()
-	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(subject, nil)
-
-	dbErr := errors.New("db connection error")
-	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(dbErr)
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(nil)
+	librarianClient.On("Think", mock.Anything, mock.Anything, "質問", subjectID, userID, mock.Anything, mock.Anything).
+		Return(&ports.LibrarianThinkResult{}, nil)
+	var streamCtx context.Context
+	llmClient.On("GenerateAnswerStream", mock.Anything, "質問", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			streamCtx = args.Get(0).(context.Context)
+			onChunk := args.Get(3).(func(string) error)
+			_ = onChunk("途中までの")
+			_ = onChunk("回答")
+		}).
+		Return(context.Canceled)
+	cancelled := testhelper.NewQASession(func(s *domain.QASession) { s.Status = domain.QASessionStatusCancelled })
+	qaRepo.On("MarkInterrupted", mock.Anything, mock.AnythingOfType("uuid.UUID"), domain.QASessionStatusCancelled, "途中までの", mock.Anything, mock.Anything).
+		Return(cancelled, nil)
+
+	// 2 つ目の回答チャンクの送信でクライアントの切断を検知する
+	answers := 0
+	onEvent := func(et domain.SSEEventType, _ any) error {
+		if et == domain.SSEEventAnswer {
+			answers++
+			if answers > 1 {
+				return errors.New("write: broken pipe")
+			}
+		}
+		return nil
+	}
 
-	onEvent, _ := collectEvents()
 	uc := newChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient)
-	session, err := uc.Ask(ctx, subjectID, userID, "質問", onEvent)
+	session, err := uc.Ask(ctx, subjectID, userID, "質問", usecases.AskOptions{}, onEvent)
 
-	assert.Error(t, err)
-	assert.Nil(t, session)
-	librarianClient.AssertNotCalled(t, "Think")
+	require.NoError(t, err)
+	assert.Equal(t, domain.QASessionStatusCancelled, session.Status)
+	require.NotNil(t, streamCtx)
+	assert.ErrorIs(t, streamCtx.Err(), context.Canceled, "LLM ストリームを中断するべき")
+	qaRepo.AssertExpectations(t)
+	qaRepo.AssertNotCalled(t, "UpdateAnswer", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
 
-// ─── Ask: Librarian エラー時 SSEEventError を送信 ─────────────────
-
-func TestChatUseCase_Ask_LibrarianError_SendsSSEError(t *testing.T) {
+func TestChatUseCase_Cancel_StopsRunningSession(t *testing.T) {
 	ctx := context.Background()
 	subjectID := testhelper.FixtureSubjectID
 	userID := testhelper.FixtureUserID
@@ -192,41 +1531,99 @@ func TestChatUseCase_Ask_LibrarianError_SendsSSEError(t *testing.T) {
 	llmClient := &testhelper.MockLLMClient{}
 	librarianClient := &testhelper.MockLibrarianClient{}
 
-	subject := testhelper.NewSubject()
-	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(subject, nil)
-	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(nil)
-
-	librarianErr := errors.New("librarian unavailable")
-	librarianClient.On("Think",
-		ctx, mock.Anything, "質問", subjectID, userID, mock.Anything,
-	).Return((*ports.LibrarianThinkResult)(nil), librarianErr)
-
-	var gotErrorEvent bool
-	onEvent := func(et domain.SSEEventType, _ any) error {
-		if et == domain.SSEEventError {
-			gotErrorEvent = true
+	sessionIDs := make(chan uuid.UUID, 1)
+	thinking := make(chan struct{})
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).
+		Run(func(args mock.Arguments) { sessionIDs <- args.Get(1).(*domain.QASession).ID }).
+		Return(nil)
+	// Librarian は中断されるまで推論を続ける
+	librarianClient.On("Think", mock.Anything, mock.Anything, "質問", subjectID, userID, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			close(thinking)
+			<-args.Get(0).(context.Context).Done()
+		}).
+		Return((*ports.LibrarianThinkResult)(nil), context.Canceled)
+	cancelled := testhelper.NewQASession(func(s *domain.QASession) { s.Status = domain.QASessionStatusCancelled })
+	qaRepo.On("MarkInterrupted", mock.Anything, mock.AnythingOfType("uuid.UUID"), domain.QASessionStatusCancelled, "", []domain.Source(nil), mock.Anything).
+		Return(cancelled, nil)
+	qaRepo.On("GetByIDAndUserID", ctx, mock.AnythingOfType("uuid.UUID"), userID).Return(cancelled, nil)
+
+	var doneData map[string]any
+	onEvent := func(et domain.SSEEventType, data any) error {
+		if et == domain.SSEEventDone {
+			doneData = data.(map[string]any)
 		}
 		return nil
 	}
 
 	uc := newChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient)
-	session, err := uc.Ask(ctx, subjectID, userID, "質問", onEvent)
+	askErr := make(chan error, 1)
+	go func() {
+		_, err := uc.Ask(ctx, subjectID, userID, "質問", usecases.AskOptions{}, onEvent)
+		askErr <- err
+	}()
+	sessionID := <-sessionIDs
+	<-thinking
 
-	assert.Error(t, err)
-	assert.Nil(t, session)
-	assert.True(t, gotErrorEvent, "SSEEventError が送信されるべき")
+	session, err := uc.Cancel(ctx, subjectID, sessionID, userID)
 
-	librarianClient.AssertExpectations(t)
-	llmClient.AssertNotCalled(t, "GenerateAnswerStream")
+	require.NoError(t, err)
+	assert.Equal(t, domain.QASessionStatusCancelled, session.Status)
+	require.NoError(t, <-askErr)
+	assert.Equal(t, "cancelled", doneData["status"])
+	llmClient.AssertNotCalled(t, "GenerateAnswerStream", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	qaRepo.AssertExpectations(t)
 }
 
-// ─── Ask: LLM ストリームエラー時 SSEEventError を送信 ──────────────
+func TestChatUseCase_Cancel_NotRunning(t *testing.T) {
+	ctx := context.Background()
+	qaRepo := &testhelper.MockQASessionRepository{}
+	qaRepo.On("GetByIDAndUserID", ctx, testhelper.FixtureSessionID, testhelper.FixtureUserID).Return(testhelper.NewQASession(), nil)
 
-func TestChatUseCase_Ask_LLMStreamError_SendsSSEError(t *testing.T) {
+	uc := newChatUseCase(&testhelper.MockSubjectRepository{}, qaRepo, &testhelper.MockChunkRepository{}, &testhelper.MockLLMClient{}, &testhelper.MockLibrarianClient{})
+	_, err := uc.Cancel(ctx, testhelper.FixtureSubjectID, testhelper.FixtureSessionID, testhelper.FixtureUserID)
+
+	assert.ErrorIs(t, err, domain.ErrConflict)
+}
+
+func TestChatUseCase_Cancel_OtherSubject(t *testing.T) {
+	ctx := context.Background()
+	qaRepo := &testhelper.MockQASessionRepository{}
+	qaRepo.On("GetByIDAndUserID", ctx, testhelper.FixtureSessionID, testhelper.FixtureUserID).Return(testhelper.NewQASession(), nil)
+
+	uc := newChatUseCase(&testhelper.MockSubjectRepository{}, qaRepo, &testhelper.MockChunkRepository{}, &testhelper.MockLLMClient{}, &testhelper.MockLibrarianClient{})
+	_, err := uc.Cancel(ctx, uuid.New(), testhelper.FixtureSessionID, testhelper.FixtureUserID)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+// ─── Regenerate ───────────────────────────────────────────────────
+
+// answeredSession は回答版 1 の回答と出典を持つセッションを返す。
+func answeredSession(sources ...domain.Source) *domain.QASession {
+	return testhelper.NewQASession(func(s *domain.QASession) {
+		s.Answer = ptrStr("最初の回答")
+		s.Sources = sources
+		s.Status = domain.QASessionStatusCompleted
+		s.SelectedVersion, s.LatestVersion = 1, 1
+	})
+}
+
+// newVersion は回答版 2 を生成中のセッションを返す。
+func newVersion(s *domain.QASession) *domain.QASession {
+	v := *s
+	v.Answer, v.Sources = nil, nil
+	v.Status = domain.QASessionStatusRunning
+	v.SelectedVersion, v.LatestVersion = 2, 2
+	return &v
+}
+
+func TestChatUseCase_Regenerate_ReusesSources(t *testing.T) {
 	ctx := context.Background()
 	subjectID := testhelper.FixtureSubjectID
+	sessionID := testhelper.FixtureSessionID
 	userID := testhelper.FixtureUserID
-	question := "ストリームエラーテスト"
 
 	subjectRepo := &testhelper.MockSubjectRepository{}
 	qaRepo := &testhelper.MockQASessionRepository{}
@@ -234,40 +1631,195 @@ func TestChatUseCase_Ask_LLMStreamError_SendsSSEError(t *testing.T) {
 	llmClient := &testhelper.MockLLMClient{}
 	librarianClient := &testhelper.MockLibrarianClient{}
 
-	subject := testhelper.NewSubject()
-	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(subject, nil)
-	qaRepo.On("Create", ctx, mock.AnythingOfType("*domain.QASession")).Return(nil)
-
-	thinkResult := &ports.LibrarianThinkResult{
-		Evidences:     []ports.LibrarianEvidence{},
-		CoverageNotes: "推論",
+	kept := &domain.Chunk{ID: uuid.New(), FileID: testhelper.FixtureFileID, SubjectID: subjectID, ChunkIndex: 3, Content: "残っているチャンク"}
+	removed := uuid.New()
+	session := answeredSession(
+		domain.Source{SubjectID: subjectID, FileID: kept.FileID, ChunkID: kept.ID, FileName: "a.pdf", Excerpt: "古い抜粋"},
+		domain.Source{SubjectID: subjectID, FileID: kept.FileID, ChunkID: removed, FileName: "a.pdf"},
+	)
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	qaRepo.On("GetByIDAndUserID", ctx, sessionID, userID).Return(session, nil)
+	qaRepo.On("AddVersion", mock.Anything, sessionID, userID, false).Return(newVersion(session), nil)
+	chunkRepo.On("GetByID", mock.Anything, kept.ID).Return(kept, nil)
+	// 再処理で無くなったチャンクは出典から外す
+	chunkRepo.On("GetByID", mock.Anything, removed).Return((*domain.Chunk)(nil), domain.ErrNotFound)
+	llmClient.On("GenerateAnswerStream", mock.Anything, "テスト質問", []string{"残っているチャンク"}, mock.Anything).
+		Return(nil).
+		Run(func(args mock.Arguments) { _ = args.Get(3).(func(string) error)("二つ目の回答") })
+	var saved []domain.Source
+	qaRepo.On("UpdateAnswer", mock.Anything, sessionID, "二つ目の回答", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { saved = args.Get(3).([]domain.Source) }).
+		Return(newVersion(session), nil)
+
+	var events []sseEvent
+	onEvent := func(et domain.SSEEventType, data any) error {
+		events = append(events, sseEvent{et, data.(map[string]any)})
+		return nil
 	}
-	librarianClient.On("Think",
-		ctx, mock.Anything, question, subjectID, userID, mock.Anything,
-	).Return(thinkResult, nil)
 
-	streamErr := errors.New("LLM stream broken")
-	llmClient.On("GenerateAnswerStream",
-		ctx, question, mock.Anything, mock.Anything,
-	).Return(streamErr)
+	uc := newChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient)
+	_, err := uc.Regenerate(ctx, subjectID, sessionID, userID, usecases.RegenerateOptions{}, onEvent)
 
-	var gotErrorEvent bool
-	onEvent := func(et domain.SSEEventType, _ any) error {
-		if et == domain.SSEEventError {
-			gotErrorEvent = true
-		}
-		return nil
-	}
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	assert.Equal(t, kept.ID, saved[0].ChunkID)
+	assert.Equal(t, "残っているチャンク", saved[0].Excerpt)
+	require.NotEmpty(t, events)
+	assert.Equal(t, 2, events[0].Data["version"])
+	assert.Equal(t, domain.SSEEventDone, events[len(events)-1].Type)
+	assert.Equal(t, 2, events[len(events)-1].Data["version"])
+	librarianClient.AssertNotCalled(t, "Think", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	qaRepo.AssertExpectations(t)
+	llmClient.AssertExpectations(t)
+}
+
+func TestChatUseCase_Regenerate_Retrieve(t *testing.T) {
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	sessionID := testhelper.FixtureSessionID
+	userID := testhelper.FixtureUserID
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	chunkRepo := &testhelper.MockChunkRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	session := answeredSession(domain.Source{SubjectID: subjectID, ChunkID: uuid.New()})
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	qaRepo.On("GetByIDAndUserID", ctx, sessionID, userID).Return(session, nil)
+	qaRepo.On("AddVersion", mock.Anything, sessionID, userID, true).Return(newVersion(session), nil)
+	librarianClient.On("Think", mock.Anything, sessionID.String(), "テスト質問", subjectID, userID, mock.Anything, mock.Anything).
+		Return(&ports.LibrarianThinkResult{}, nil)
+	llmClient.On("GenerateAnswerStream", mock.Anything, "テスト質問", mock.Anything, mock.Anything).Return(nil)
+	qaRepo.On("UpdateAnswer", mock.Anything, sessionID, "", mock.Anything, mock.Anything).Return(newVersion(session), nil)
 
+	onEvent, _ := collectEvents()
 	uc := newChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient)
-	session, err := uc.Ask(ctx, subjectID, userID, question, onEvent)
+	_, err := uc.Regenerate(ctx, subjectID, sessionID, userID, usecases.RegenerateOptions{Retrieve: true}, onEvent)
 
-	assert.Error(t, err)
-	assert.Nil(t, session)
-	assert.True(t, gotErrorEvent, "SSEEventError が送信されるべき")
+	require.NoError(t, err)
+	chunkRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	librarianClient.AssertExpectations(t)
+	qaRepo.AssertExpectations(t)
+}
 
-	llmClient.AssertExpectations(t)
-	qaRepo.AssertNotCalled(t, "UpdateAnswer")
+func TestChatUseCase_Regenerate_WithoutSourcesRetrieves(t *testing.T) {
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	sessionID := testhelper.FixtureSessionID
+	userID := testhelper.FixtureUserID
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	session := answeredSession()
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	qaRepo.On("GetByIDAndUserID", ctx, sessionID, userID).Return(session, nil)
+	// 前の版に出典が無い場合は再利用できないため、検索からやり直す
+	qaRepo.On("AddVersion", mock.Anything, sessionID, userID, true).Return(newVersion(session), nil)
+	librarianClient.On("Think", mock.Anything, sessionID.String(), "テスト質問", subjectID, userID, mock.Anything, mock.Anything).
+		Return(&ports.LibrarianThinkResult{}, nil)
+	llmClient.On("GenerateAnswerStream", mock.Anything, "テスト質問", mock.Anything, mock.Anything).Return(nil)
+	qaRepo.On("UpdateAnswer", mock.Anything, sessionID, "", mock.Anything, mock.Anything).Return(newVersion(session), nil)
+
+	onEvent, _ := collectEvents()
+	uc := newChatUseCase(subjectRepo, qaRepo, &testhelper.MockChunkRepository{}, llmClient, librarianClient)
+	_, err := uc.Regenerate(ctx, subjectID, sessionID, userID, usecases.RegenerateOptions{}, onEvent)
+
+	require.NoError(t, err)
+	librarianClient.AssertExpectations(t)
+	qaRepo.AssertExpectations(t)
+}
+
+func TestChatUseCase_Regenerate_WhileRunning(t *testing.T) {
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	sessionID := testhelper.FixtureSessionID
+	userID := testhelper.FixtureUserID
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	llmClient := &testhelper.MockLLMClient{}
+	librarianClient := &testhelper.MockLibrarianClient{}
+
+	session := answeredSession()
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+	qaRepo.On("GetByIDAndUserID", ctx, sessionID, userID).Return(session, nil)
+	qaRepo.On("AddVersion", mock.Anything, sessionID, userID, true).Return(newVersion(session), nil).Once()
+	// 1 回目の再生成は中断されるまで推論を続ける
+	thinking := make(chan struct{})
+	librarianClient.On("Think", mock.Anything, sessionID.String(), "テスト質問", subjectID, userID, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			close(thinking)
+			<-args.Get(0).(context.Context).Done()
+		}).
+		Return((*ports.LibrarianThinkResult)(nil), context.Canceled)
+	qaRepo.On("MarkInterrupted", mock.Anything, sessionID, domain.QASessionStatusCancelled, "", []domain.Source(nil), mock.Anything).
+		Return(newVersion(session), nil)
+
+	onEvent, _ := collectEvents()
+	uc := newChatUseCase(subjectRepo, qaRepo, &testhelper.MockChunkRepository{}, llmClient, librarianClient)
+	first := make(chan error, 1)
+	go func() {
+		_, err := uc.Regenerate(ctx, subjectID, sessionID, userID, usecases.RegenerateOptions{Retrieve: true}, onEvent)
+		first <- err
+	}()
+	<-thinking
+
+	_, err := uc.Regenerate(ctx, subjectID, sessionID, userID, usecases.RegenerateOptions{Retrieve: true}, onEvent)
+	assert.ErrorIs(t, err, domain.ErrConflict)
+
+	_, err = uc.Cancel(ctx, subjectID, sessionID, userID)
+	require.NoError(t, err)
+	require.NoError(t, <-first)
+	qaRepo.AssertExpectations(t)
+}
+
+func TestChatUseCase_Regenerate_OtherSubject(t *testing.T) {
+	ctx := context.Background()
+	qaRepo := &testhelper.MockQASessionRepository{}
+	qaRepo.On("GetByIDAndUserID", ctx, testhelper.FixtureSessionID, testhelper.FixtureUserID).Return(answeredSession(), nil)
+
+	onEvent, events := collectEvents()
+	uc := newChatUseCase(&testhelper.MockSubjectRepository{}, qaRepo, &testhelper.MockChunkRepository{}, &testhelper.MockLLMClient{}, &testhelper.MockLibrarianClient{})
+	_, err := uc.Regenerate(ctx, uuid.New(), testhelper.FixtureSessionID, testhelper.FixtureUserID, usecases.RegenerateOptions{}, onEvent)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.Empty(t, *events)
+	qaRepo.AssertNotCalled(t, "AddVersion", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// ─── AnswerVersions ───────────────────────────────────────────────
+
+func TestChatUseCase_SelectVersion(t *testing.T) {
+	ctx := context.Background()
+	qaRepo := &testhelper.MockQASessionRepository{}
+	session := answeredSession()
+	qaRepo.On("GetByIDAndUserID", ctx, testhelper.FixtureSessionID, testhelper.FixtureUserID).Return(session, nil)
+	selected := newVersion(session)
+	qaRepo.On("SelectVersion", ctx, testhelper.FixtureSessionID, testhelper.FixtureUserID, 2).Return(selected, nil)
+
+	uc := newChatUseCase(&testhelper.MockSubjectRepository{}, qaRepo, &testhelper.MockChunkRepository{}, &testhelper.MockLLMClient{}, &testhelper.MockLibrarianClient{})
+	result, err := uc.SelectVersion(ctx, testhelper.FixtureSubjectID, testhelper.FixtureSessionID, testhelper.FixtureUserID, 2)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.SelectedVersion)
+	qaRepo.AssertExpectations(t)
+}
+
+func TestChatUseCase_ListVersions_OtherSubject(t *testing.T) {
+	ctx := context.Background()
+	qaRepo := &testhelper.MockQASessionRepository{}
+	qaRepo.On("GetByIDAndUserID", ctx, testhelper.FixtureSessionID, testhelper.FixtureUserID).Return(answeredSession(), nil)
+
+	uc := newChatUseCase(&testhelper.MockSubjectRepository{}, qaRepo, &testhelper.MockChunkRepository{}, &testhelper.MockLLMClient{}, &testhelper.MockLibrarianClient{})
+	_, _, err := uc.ListVersions(ctx, uuid.New(), testhelper.FixtureSessionID, testhelper.FixtureUserID)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	qaRepo.AssertNotCalled(t, "ListVersions", mock.Anything, mock.Anything)
 }
 
 // ─── ListSessions ─────────────────────────────────────────────────
@@ -287,17 +1839,57 @@ func TestChatUseCase_ListSessions_Success(t *testing.T) {
 	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(subject, nil)
 
 	sessions := []*domain.QASession{testhelper.NewQASession()}
-	qaRepo.On("ListBySubjectID", ctx, subjectID, userID, 20, 0).Return(sessions, nil)
+	// 次のページの有無を判定するため 1 件多く取得する
+	qaRepo.On("ListBySubjectID", ctx, subjectID, userID, domain.QASessionFilter{Limit: 21}).Return(sessions, nil)
 
 	uc := newChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient)
-	result, err := uc.ListSessions(ctx, subjectID, userID, 20, 0)
+	result, err := uc.ListSessions(ctx, subjectID, userID, domain.QASessionFilter{Limit: 20})
 
 	require.NoError(t, err)
-	assert.Len(t, result, 1)
+	assert.Len(t, result.Sessions, 1)
+	assert.Nil(t, result.Next)
 	subjectRepo.AssertExpectations(t)
 	qaRepo.AssertExpectations(t)
 }
 
+func TestChatUseCase_ListSessions_NextCursor(t *testing.T) {
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	userID := testhelper.FixtureUserID
+
+	subjectRepo := &testhelper.MockSubjectRepository{}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	subjectRepo.On("GetByIDAndUserID", ctx, subjectID, userID).Return(testhelper.NewSubject(), nil)
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sessions := make([]*domain.QASession, 3)
+	for i := range sessions {
+		sessions[i] = testhelper.NewQASession(func(s *domain.QASession) {
+			s.ID = uuid.New()
+			s.CreatedAt = base.Add(-time.Duration(i) * time.Minute)
+		})
+	}
+	after := domain.QASessionCursor{CreatedAt: base.Add(time.Hour), ID: uuid.New()}
+	qaRepo.On("ListBySubjectID", ctx, subjectID, userID, domain.QASessionFilter{Query: "回帰", After: &after, Limit: 3}).
+		Return(sessions, nil)
+
+	uc := newChatUseCase(subjectRepo, qaRepo, &testhelper.MockChunkRepository{}, &testhelper.MockLLMClient{}, &testhelper.MockLibrarianClient{})
+	result, err := uc.ListSessions(ctx, subjectID, userID, domain.QASessionFilter{Query: "回帰", After: &after, Limit: 2})
+
+	require.NoError(t, err)
+	require.Len(t, result.Sessions, 2)
+	require.NotNil(t, result.Next)
+	assert.Equal(t, domain.CursorAfter(sessions[1]), *result.Next)
+
+	// cursor はクライアントに渡す文字列から同じ位置に戻せる
+	parsed, err := domain.ParseQASessionCursor(result.Next.String())
+	require.NoError(t, err)
+	assert.True(t, parsed.CreatedAt.Equal(sessions[1].CreatedAt))
+	assert.Equal(t, sessions[1].ID, parsed.ID)
+	_, err = domain.ParseQASessionCursor("not-a-cursor")
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+}
+
 func TestChatUseCase_ListSessions_SubjectNotFound(t *testing.T) {
 	ctx := context.Background()
 	subjectID := testhelper.FixtureSubjectID
@@ -313,7 +1905,7 @@ func TestChatUseCase_ListSessions_SubjectNotFound(t *testing.T) {
 		Return((*domain.Subject)(nil), domain.ErrForbidden)
 
 	uc := newChatUseCase(subjectRepo, qaRepo, chunkRepo, llmClient, librarianClient)
-	result, err := uc.ListSessions(ctx, subjectID, userID, 20, 0)
+	result, err := uc.ListSessions(ctx, subjectID, userID, domain.QASessionFilter{Limit: 20})
 
 	assert.Error(t, err)
 	assert.True(t, errors.Is(err, domain.ErrForbidden))
@@ -321,6 +1913,24 @@ func TestChatUseCase_ListSessions_SubjectNotFound(t *testing.T) {
 	qaRepo.AssertNotCalled(t, "ListBySubjectID")
 }
 
+// ─── GetSession ───────────────────────────────────────────────────
+
+func TestChatUseCase_GetSession(t *testing.T) {
+	ctx := context.Background()
+	qaRepo := &testhelper.MockQASessionRepository{}
+	session := answeredSession(domain.Source{ChunkID: testhelper.FixtureChunkID, WhyRelevant: "定義"})
+	qaRepo.On("GetByIDAndUserID", ctx, testhelper.FixtureSessionID, testhelper.FixtureUserID).Return(session, nil)
+
+	uc := newChatUseCase(&testhelper.MockSubjectRepository{}, qaRepo, &testhelper.MockChunkRepository{}, &testhelper.MockLLMClient{}, &testhelper.MockLibrarianClient{})
+	result, err := uc.GetSession(ctx, testhelper.FixtureSubjectID, testhelper.FixtureSessionID, testhelper.FixtureUserID)
+
+	require.NoError(t, err)
+	assert.Equal(t, "定義", result.Sources[0].WhyRelevant)
+
+	_, err = uc.GetSession(ctx, uuid.New(), testhelper.FixtureSessionID, testhelper.FixtureUserID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
 // ─── UpdateFeedback ───────────────────────────────────────────────
 
 func TestChatUseCase_UpdateFeedback_Success(t *testing.T) {
@@ -369,3 +1979,56 @@ func TestChatUseCase_UpdateFeedback_NotFound(t *testing.T) {
 	assert.True(t, errors.Is(err, domain.ErrNotFound))
 	assert.Nil(t, result)
 }
+
+func TestChatUseCase_UpdateVersionFeedback(t *testing.T) {
+	ctx := context.Background()
+	sessionID := testhelper.FixtureSessionID
+	userID := testhelper.FixtureUserID
+
+	qaRepo := &testhelper.MockQASessionRepository{}
+	qaRepo.On("UpdateVersionFeedback", ctx, sessionID, userID, 1, -1).Return(answeredSession(), nil)
+
+	uc := newChatUseCase(&testhelper.MockSubjectRepository{}, qaRepo, &testhelper.MockChunkRepository{}, &testhelper.MockLLMClient{}, &testhelper.MockLibrarianClient{})
+	_, err := uc.UpdateVersionFeedback(ctx, sessionID, userID, 1, -1)
+
+	require.NoError(t, err)
+	qaRepo.AssertExpectations(t)
+}
+
+// ─── GetTrace ─────────────────────────────────────────────────────
+
+func TestChatUseCase_GetTrace_DefaultsToSelectedVersion(t *testing.T) {
+	ctx := context.Background()
+	subjectID := testhelper.FixtureSubjectID
+	sessionID := testhelper.FixtureSessionID
+	userID := testhelper.FixtureUserID
+
+	session := answeredSession()
+	session.SelectedVersion = 2
+	trace := &domain.ReasoningTrace{Retrieved: true, CoverageNotes: "十分"}
+	qaRepo := &testhelper.MockQASessionRepository{}
+	qaRepo.On("GetByIDAndUserID", ctx, sessionID, userID).Return(session, nil)
+	qaRepo.On("GetTrace", ctx, sessionID, 2).Return(trace, nil)
+
+	uc := newChatUseCase(&testhelper.MockSubjectRepository{}, qaRepo, &testhelper.MockChunkRepository{}, &testhelper.MockLLMClient{}, &testhelper.MockLibrarianClient{})
+	version, got, err := uc.GetTrace(ctx, subjectID, sessionID, userID, 0)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+	assert.Equal(t, trace, got)
+}
+
+func TestChatUseCase_GetTrace_OtherSubject(t *testing.T) {
+	ctx := context.Background()
+	sessionID := testhelper.FixtureSessionID
+	userID := testhelper.FixtureUserID
+
+	qaRepo := &testhelper.MockQASessionRepository{}
+	qaRepo.On("GetByIDAndUserID", ctx, sessionID, userID).Return(answeredSession(), nil)
+
+	uc := newChatUseCase(&testhelper.MockSubjectRepository{}, qaRepo, &testhelper.MockChunkRepository{}, &testhelper.MockLLMClient{}, &testhelper.MockLibrarianClient{})
+	_, _, err := uc.GetTrace(ctx, uuid.New(), sessionID, userID, 1)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	qaRepo.AssertNotCalled(t, "GetTrace", mock.Anything, mock.Anything, mock.Anything)
+}
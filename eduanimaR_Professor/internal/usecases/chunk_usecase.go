@@ -0,0 +1,382 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	pgvector "github.com/pgvector/pgvector-go"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+// MaxChunkPageSize はチャンク一覧で一度に取得できる件数の上限
+const MaxChunkPageSize = 100
+
+// ChunkUseCase は教材から抽出されたチャンクの閲覧・手動修正を担う。
+// 検索精度が低い場合に、抽出結果（OCR の取りこぼし・文字化け）を確認し、
+// 数式や表の崩れを再アップロードせずに直すために使う。
+type ChunkUseCase struct {
+	files  ports.FileRepository
+	chunks ports.ChunkRepository
+	llm    ports.LLMClient
+}
+
+// NewChunkUseCase は ChunkUseCase を生成する。
+func NewChunkUseCase(files ports.FileRepository, chunks ports.ChunkRepository, llm ports.LLMClient) *ChunkUseCase {
+	return &ChunkUseCase{files: files, chunks: chunks, llm: llm}
+}
+
+// ─── ListByMaterial ───────────────────────────────────────────────
+
+// ListChunksOutput はチャンク一覧の結果
+type ListChunksOutput struct {
+	Chunks []*domain.Chunk
+	Total  int64
+}
+
+// ListByMaterial は教材のチャンクを chunk_index 順にページング取得する。
+// 他ユーザー・他 subject の教材は ErrNotFound を返す。
+func (uc *ChunkUseCase) ListByMaterial(
+	ctx context.Context,
+	subjectID, fileID, userID uuid.UUID,
+	limit, offset int,
+) (*ListChunksOutput, error) {
+	if limit <= 0 || limit > MaxChunkPageSize || offset < 0 {
+		return nil, fmt.Errorf("limit must be 1-%d and offset non-negative: %w", MaxChunkPageSize, domain.ErrInvalidInput)
+	}
+	if _, err := uc.ownedFile(ctx, subjectID, fileID, userID); err != nil {
+		return nil, fmt.Errorf("get file: %w", err)
+	}
+
+	chunks, err := uc.chunks.ListPageByFileID(ctx, fileID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list chunks: %w", err)
+	}
+	total, err := uc.chunks.CountByFileID(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("count chunks: %w", err)
+	}
+	return &ListChunksOutput{Chunks: chunks, Total: total}, nil
+}
+
+// ownedFile はユーザーが所有し、指定 subject に属する教材を返す。
+func (uc *ChunkUseCase) ownedFile(ctx context.Context, subjectID, fileID, userID uuid.UUID) (*domain.File, error) {
+	file, err := uc.files.GetByIDAndUserID(ctx, fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if file.SubjectID != subjectID {
+		return nil, domain.ErrNotFound
+	}
+	return file, nil
+}
+
+// ─── 手動修正 ─────────────────────────────────────────────────────
+
+// EditChunkInput はチャンク内容の修正の入力値
+type EditChunkInput struct {
+	SubjectID uuid.UUID
+	FileID    uuid.UUID
+	ChunkID   uuid.UUID
+	UserID    uuid.UUID
+	Content   string
+}
+
+// Edit はチャンクの内容を修正し、Embedding を再生成する。
+// 内容が変わらない場合は何もせずに現在のチャンクを返す。
+func (uc *ChunkUseCase) Edit(ctx context.Context, in EditChunkInput) (*domain.Chunk, error) {
+	content, err := normalizeChunkContent(in.Content)
+	if err != nil {
+		return nil, err
+	}
+	chunk, err := uc.editableChunk(ctx, in.SubjectID, in.FileID, in.ChunkID, in.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if chunk.Content == content {
+		return chunk, nil
+	}
+
+	before := domain.NewChunkSnapshot(chunk)
+	edited, err := uc.reembed(ctx, chunk, content)
+	if err != nil {
+		return nil, err
+	}
+	edit := uc.newEdit(in.FileID, in.UserID, domain.ChunkEditOperationEdit,
+		[]domain.ChunkSnapshot{before}, []*domain.Chunk{edited})
+	if err := uc.chunks.ApplyEdit(ctx, edit, ports.ChunkChanges{Save: []*domain.Chunk{edited}}); err != nil {
+		return nil, fmt.Errorf("apply edit: %w", err)
+	}
+	return edited, nil
+}
+
+// SplitChunkInput はチャンク分割の入力値
+type SplitChunkInput struct {
+	SubjectID uuid.UUID
+	FileID    uuid.UUID
+	ChunkID   uuid.UUID
+	UserID    uuid.UUID
+	Parts     []string // 分割後の各チャンクの内容（2 つ以上、先頭から順に並ぶ）
+}
+
+// Split は 1 つのチャンクを複数に分割する。
+// 先頭の部分は元のチャンクを更新し、残りは直後の連番に新規作成する（後続のチャンクは連番をずらす）。
+func (uc *ChunkUseCase) Split(ctx context.Context, in SplitChunkInput) ([]*domain.Chunk, error) {
+	if len(in.Parts) < 2 {
+		return nil, fmt.Errorf("split requires at least 2 parts: %w", domain.ErrInvalidInput)
+	}
+	parts := make([]string, len(in.Parts))
+	for i, p := range in.Parts {
+		content, err := normalizeChunkContent(p)
+		if err != nil {
+			return nil, err
+		}
+		parts[i] = content
+	}
+	chunk, err := uc.editableChunk(ctx, in.SubjectID, in.FileID, in.ChunkID, in.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := domain.NewChunkSnapshot(chunk)
+	result := make([]*domain.Chunk, 0, len(parts))
+	for i, content := range parts {
+		base := *chunk
+		if i > 0 {
+			base.ID = uuid.New()
+			base.ChunkIndex = chunk.ChunkIndex + i
+			base.CreatedAt = time.Now().UTC()
+		}
+		c, err := uc.reembed(ctx, &base, content)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, c)
+	}
+
+	edit := uc.newEdit(in.FileID, in.UserID, domain.ChunkEditOperationSplit,
+		[]domain.ChunkSnapshot{before}, result)
+	changes := ports.ChunkChanges{
+		ShiftFrom: chunk.ChunkIndex + 1,
+		ShiftBy:   len(parts) - 1,
+		Save:      result,
+	}
+	if err := uc.chunks.ApplyEdit(ctx, edit, changes); err != nil {
+		return nil, fmt.Errorf("apply split: %w", err)
+	}
+	return result, nil
+}
+
+// MergeChunksInput はチャンク結合の入力値
+type MergeChunksInput struct {
+	SubjectID uuid.UUID
+	FileID    uuid.UUID
+	UserID    uuid.UUID
+	ChunkIDs  []uuid.UUID // 結合するチャンク（連番が連続している必要がある。順不同）
+	Content   *string     // 結合後の内容（nil の場合は各チャンクを空行区切りで連結する）
+}
+
+// Merge は連続する複数のチャンクを 1 つに結合する。
+// 連番が最も小さいチャンクを更新し、残りは削除する（後続のチャンクは連番を詰める）。
+func (uc *ChunkUseCase) Merge(ctx context.Context, in MergeChunksInput) (*domain.Chunk, error) {
+	if len(in.ChunkIDs) < 2 {
+		return nil, fmt.Errorf("merge requires at least 2 chunks: %w", domain.ErrInvalidInput)
+	}
+	if _, err := uc.editableFile(ctx, in.SubjectID, in.FileID, in.UserID); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uuid.UUID]struct{}, len(in.ChunkIDs))
+	chunks := make([]*domain.Chunk, 0, len(in.ChunkIDs))
+	for _, id := range in.ChunkIDs {
+		if _, dup := seen[id]; dup {
+			return nil, fmt.Errorf("duplicate chunk %s: %w", id, domain.ErrInvalidInput)
+		}
+		seen[id] = struct{}{}
+		c, err := uc.fileChunk(ctx, in.FileID, id)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c)
+	}
+	slices.SortFunc(chunks, func(a, b *domain.Chunk) int { return a.ChunkIndex - b.ChunkIndex })
+	for i := 1; i < len(chunks); i++ {
+		if chunks[i].ChunkIndex != chunks[i-1].ChunkIndex+1 {
+			return nil, fmt.Errorf("chunks to merge must be consecutive: %w", domain.ErrInvalidInput)
+		}
+	}
+
+	var content string
+	if in.Content != nil {
+		c, err := normalizeChunkContent(*in.Content)
+		if err != nil {
+			return nil, err
+		}
+		content = c
+	} else {
+		texts := make([]string, len(chunks))
+		for i, c := range chunks {
+			texts[i] = c.Content
+		}
+		content = strings.Join(texts, "\n\n")
+	}
+
+	before := make([]domain.ChunkSnapshot, len(chunks))
+	deleted := make([]uuid.UUID, 0, len(chunks)-1)
+	for i, c := range chunks {
+		before[i] = domain.NewChunkSnapshot(c)
+		if i > 0 {
+			deleted = append(deleted, c.ID)
+		}
+	}
+	merged, err := uc.reembed(ctx, chunks[0], content)
+	if err != nil {
+		return nil, err
+	}
+
+	edit := uc.newEdit(in.FileID, in.UserID, domain.ChunkEditOperationMerge, before, []*domain.Chunk{merged})
+	changes := ports.ChunkChanges{
+		Delete:    deleted,
+		ShiftFrom: chunks[len(chunks)-1].ChunkIndex + 1,
+		ShiftBy:   -len(deleted),
+		Save:      []*domain.Chunk{merged},
+	}
+	if err := uc.chunks.ApplyEdit(ctx, edit, changes); err != nil {
+		return nil, fmt.Errorf("apply merge: %w", err)
+	}
+	return merged, nil
+}
+
+// Delete はチャンクを削除する（後続のチャンクは連番を詰める）。
+func (uc *ChunkUseCase) Delete(ctx context.Context, subjectID, fileID, chunkID, userID uuid.UUID) error {
+	chunk, err := uc.editableChunk(ctx, subjectID, fileID, chunkID, userID)
+	if err != nil {
+		return err
+	}
+	edit := uc.newEdit(fileID, userID, domain.ChunkEditOperationDelete,
+		[]domain.ChunkSnapshot{domain.NewChunkSnapshot(chunk)}, nil)
+	changes := ports.ChunkChanges{
+		Delete:    []uuid.UUID{chunk.ID},
+		ShiftFrom: chunk.ChunkIndex + 1,
+		ShiftBy:   -1,
+	}
+	if err := uc.chunks.ApplyEdit(ctx, edit, changes); err != nil {
+		return fmt.Errorf("apply delete: %w", err)
+	}
+	return nil
+}
+
+// ListChunkEditsOutput は修正履歴一覧の結果
+type ListChunkEditsOutput struct {
+	Edits []*domain.ChunkEdit
+	Total int64
+}
+
+// ListEdits は教材のチャンク修正履歴を新しい順にページング取得する。
+func (uc *ChunkUseCase) ListEdits(
+	ctx context.Context,
+	subjectID, fileID, userID uuid.UUID,
+	limit, offset int,
+) (*ListChunkEditsOutput, error) {
+	if limit <= 0 || limit > MaxChunkPageSize || offset < 0 {
+		return nil, fmt.Errorf("limit must be 1-%d and offset non-negative: %w", MaxChunkPageSize, domain.ErrInvalidInput)
+	}
+	if _, err := uc.ownedFile(ctx, subjectID, fileID, userID); err != nil {
+		return nil, fmt.Errorf("get file: %w", err)
+	}
+	edits, err := uc.chunks.ListEdits(ctx, fileID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list chunk edits: %w", err)
+	}
+	total, err := uc.chunks.CountEdits(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("count chunk edits: %w", err)
+	}
+	return &ListChunkEditsOutput{Edits: edits, Total: total}, nil
+}
+
+// ─── ヘルパー ─────────────────────────────────────────────────────
+
+// editableFile は手動修正できる教材を返す。
+// OCR/Embedding の処理中は処理結果でチャンクが置き換わるため domain.ErrConflict を返す。
+func (uc *ChunkUseCase) editableFile(ctx context.Context, subjectID, fileID, userID uuid.UUID) (*domain.File, error) {
+	file, err := uc.ownedFile(ctx, subjectID, fileID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get file: %w", err)
+	}
+	if file.IsInProgress() {
+		return nil, fmt.Errorf("material is being processed: %w", domain.ErrConflict)
+	}
+	return file, nil
+}
+
+// editableChunk は手動修正できる教材に属するチャンクを返す。
+func (uc *ChunkUseCase) editableChunk(ctx context.Context, subjectID, fileID, chunkID, userID uuid.UUID) (*domain.Chunk, error) {
+	if _, err := uc.editableFile(ctx, subjectID, fileID, userID); err != nil {
+		return nil, err
+	}
+	return uc.fileChunk(ctx, fileID, chunkID)
+}
+
+// fileChunk は教材に属するチャンクを返す（他の教材のチャンクは ErrNotFound）。
+func (uc *ChunkUseCase) fileChunk(ctx context.Context, fileID, chunkID uuid.UUID) (*domain.Chunk, error) {
+	chunk, err := uc.chunks.GetByID(ctx, chunkID)
+	if err != nil {
+		return nil, fmt.Errorf("get chunk: %w", err)
+	}
+	if chunk.FileID != fileID {
+		return nil, fmt.Errorf("get chunk: %w", domain.ErrNotFound)
+	}
+	return chunk, nil
+}
+
+// reembed は内容を差し替えたチャンクを返す（Embedding は新しい内容で再生成する）。
+func (uc *ChunkUseCase) reembed(ctx context.Context, chunk *domain.Chunk, content string) (*domain.Chunk, error) {
+	emb, err := uc.llm.GenerateEmbedding(ctx, content)
+	if err != nil {
+		return nil, fmt.Errorf("generate embedding: %w", err)
+	}
+	now := time.Now().UTC()
+	c := *chunk
+	c.Content = content
+	c.Embedding = pgvector.NewVector(emb)
+	c.ManuallyEdited = true
+	c.UpdatedAt = &now
+	return &c, nil
+}
+
+// newEdit は修正履歴のレコードを生成する。
+func (uc *ChunkUseCase) newEdit(
+	fileID, userID uuid.UUID,
+	op domain.ChunkEditOperation,
+	before []domain.ChunkSnapshot,
+	after []*domain.Chunk,
+) *domain.ChunkEdit {
+	snapshots := make([]domain.ChunkSnapshot, len(after))
+	for i, c := range after {
+		snapshots[i] = domain.NewChunkSnapshot(c)
+	}
+	return &domain.ChunkEdit{
+		ID:        uuid.New(),
+		FileID:    fileID,
+		UserID:    userID,
+		Operation: op,
+		Before:    before,
+		After:     snapshots,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// normalizeChunkContent は前後の空白を除いたチャンク内容を返す（空の場合は ErrInvalidInput）。
+func normalizeChunkContent(content string) (string, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", fmt.Errorf("chunk content is required: %w", domain.ErrInvalidInput)
+	}
+	return content, nil
+}
@@ -0,0 +1,361 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/testhelper"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/usecases"
+)
+
+// ─── ListByMaterial ───────────────────────────────────────────────
+
+func TestChunkUseCase_ListByMaterial_Success(t *testing.T) {
+	ctx := context.Background()
+	files := &testhelper.MockFileRepository{}
+	chunks := &testhelper.MockChunkRepository{}
+
+	page := 3
+	files.On("GetByIDAndUserID", ctx, testhelper.FixtureFileID, testhelper.FixtureUserID).
+		Return(testhelper.NewFile(domain.FileStatusReady), nil)
+	chunks.On("ListPageByFileID", ctx, testhelper.FixtureFileID, 10, 20).
+		Return([]*domain.Chunk{{
+			ID:         uuid.New(),
+			FileID:     testhelper.FixtureFileID,
+			PageNumber: &page,
+			ChunkIndex: 20,
+			Content:    "微分方程式の解の一意性について説明する。",
+		}}, nil)
+	chunks.On("CountByFileID", ctx, testhelper.FixtureFileID).Return(int64(21), nil)
+
+	uc := usecases.NewChunkUseCase(files, chunks, &testhelper.MockLLMClient{})
+	out, err := uc.ListByMaterial(ctx, testhelper.FixtureSubjectID, testhelper.FixtureFileID, testhelper.FixtureUserID, 10, 20)
+
+	require.NoError(t, err)
+	require.Len(t, out.Chunks, 1)
+	assert.Equal(t, 20, out.Chunks[0].ChunkIndex)
+	assert.Equal(t, int64(21), out.Total)
+	chunks.AssertExpectations(t)
+}
+
+func TestChunkUseCase_ListByMaterial_OtherSubject(t *testing.T) {
+	ctx := context.Background()
+	files := &testhelper.MockFileRepository{}
+	chunks := &testhelper.MockChunkRepository{}
+
+	files.On("GetByIDAndUserID", ctx, testhelper.FixtureFileID, testhelper.FixtureUserID).
+		Return(testhelper.NewFile(domain.FileStatusReady), nil)
+
+	uc := usecases.NewChunkUseCase(files, chunks, &testhelper.MockLLMClient{})
+	_, err := uc.ListByMaterial(ctx, uuid.New(), testhelper.FixtureFileID, testhelper.FixtureUserID, 20, 0)
+
+	assert.True(t, errors.Is(err, domain.ErrNotFound))
+	chunks.AssertNotCalled(t, "ListPageByFileID")
+}
+
+func TestChunkUseCase_ListByMaterial_InvalidLimit(t *testing.T) {
+	uc := usecases.NewChunkUseCase(&testhelper.MockFileRepository{}, &testhelper.MockChunkRepository{}, &testhelper.MockLLMClient{})
+	_, err := uc.ListByMaterial(context.Background(), testhelper.FixtureSubjectID, testhelper.FixtureFileID,
+		testhelper.FixtureUserID, usecases.MaxChunkPageSize+1, 0)
+
+	assert.True(t, errors.Is(err, domain.ErrInvalidInput))
+}
+
+// ─── 手動修正 ─────────────────────────────────────────────────────
+
+// chunkDeps は ChunkUseCase のテスト用依存一式。
+type chunkDeps struct {
+	files  *testhelper.MockFileRepository
+	chunks *testhelper.MockChunkRepository
+	llm    *testhelper.MockLLMClient
+}
+
+// newChunkDeps は処理済みの教材（FixtureFileID）を所有している状態の依存を返す。
+func newChunkDeps(ctx context.Context) *chunkDeps {
+	d := &chunkDeps{
+		files:  &testhelper.MockFileRepository{},
+		chunks: &testhelper.MockChunkRepository{},
+		llm:    &testhelper.MockLLMClient{},
+	}
+	d.files.On("GetByIDAndUserID", ctx, testhelper.FixtureFileID, testhelper.FixtureUserID).
+		Return(testhelper.NewFile(domain.FileStatusReady), nil)
+	return d
+}
+
+func (d *chunkDeps) useCase() *usecases.ChunkUseCase {
+	return usecases.NewChunkUseCase(d.files, d.chunks, d.llm)
+}
+
+// givenChunk は教材に属するチャンクを GetByID で返すよう設定する。
+func (d *chunkDeps) givenChunk(ctx context.Context, index int, content string) *domain.Chunk {
+	page := 2
+	c := &domain.Chunk{
+		ID:         uuid.New(),
+		FileID:     testhelper.FixtureFileID,
+		SubjectID:  testhelper.FixtureSubjectID,
+		PageNumber: &page,
+		ChunkIndex: index,
+		Content:    content,
+	}
+	d.chunks.On("GetByID", ctx, c.ID).Return(c, nil)
+	return c
+}
+
+// captureApplyEdit は ApplyEdit に渡された履歴と変更を記録する。
+func (d *chunkDeps) captureApplyEdit(ctx context.Context) (*domain.ChunkEdit, *ports.ChunkChanges) {
+	edit := &domain.ChunkEdit{}
+	changes := &ports.ChunkChanges{}
+	d.chunks.On("ApplyEdit", ctx, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			*edit = *args.Get(1).(*domain.ChunkEdit)
+			*changes = args.Get(2).(ports.ChunkChanges)
+		}).
+		Return(nil)
+	return edit, changes
+}
+
+func TestChunkUseCase_Edit_ReembedsAndRecordsHistory(t *testing.T) {
+	ctx := context.Background()
+	d := newChunkDeps(ctx)
+	chunk := d.givenChunk(ctx, 4, "f(x) = x2 + 1")
+	d.llm.On("GenerateEmbedding", ctx, "f(x) = x^2 + 1").Return(make([]float32, 768), nil)
+	edit, changes := d.captureApplyEdit(ctx)
+
+	out, err := d.useCase().Edit(ctx, usecases.EditChunkInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		FileID:    testhelper.FixtureFileID,
+		ChunkID:   chunk.ID,
+		UserID:    testhelper.FixtureUserID,
+		Content:   "  f(x) = x^2 + 1\n",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "f(x) = x^2 + 1", out.Content)
+	assert.True(t, out.ManuallyEdited)
+	assert.NotNil(t, out.UpdatedAt)
+	assert.Equal(t, chunk.ID, out.ID)
+
+	assert.Equal(t, domain.ChunkEditOperationEdit, edit.Operation)
+	assert.Equal(t, testhelper.FixtureUserID, edit.UserID)
+	require.Len(t, edit.Before, 1)
+	assert.Equal(t, "f(x) = x2 + 1", edit.Before[0].Content)
+	require.Len(t, edit.After, 1)
+	assert.Equal(t, "f(x) = x^2 + 1", edit.After[0].Content)
+	assert.Empty(t, changes.Delete)
+	assert.Zero(t, changes.ShiftBy)
+	require.Len(t, changes.Save, 1)
+	d.llm.AssertExpectations(t)
+}
+
+func TestChunkUseCase_Edit_UnchangedContentIsNoop(t *testing.T) {
+	ctx := context.Background()
+	d := newChunkDeps(ctx)
+	chunk := d.givenChunk(ctx, 0, "変更なし")
+
+	out, err := d.useCase().Edit(ctx, usecases.EditChunkInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		FileID:    testhelper.FixtureFileID,
+		ChunkID:   chunk.ID,
+		UserID:    testhelper.FixtureUserID,
+		Content:   "変更なし",
+	})
+
+	require.NoError(t, err)
+	assert.Same(t, chunk, out)
+	d.llm.AssertNotCalled(t, "GenerateEmbedding", mock.Anything, mock.Anything)
+	d.chunks.AssertNotCalled(t, "ApplyEdit", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestChunkUseCase_Edit_RejectsWhileProcessing(t *testing.T) {
+	ctx := context.Background()
+	d := &chunkDeps{
+		files:  &testhelper.MockFileRepository{},
+		chunks: &testhelper.MockChunkRepository{},
+		llm:    &testhelper.MockLLMClient{},
+	}
+	d.files.On("GetByIDAndUserID", ctx, testhelper.FixtureFileID, testhelper.FixtureUserID).
+		Return(testhelper.NewFile(domain.FileStatusProcessing), nil)
+
+	_, err := d.useCase().Edit(ctx, usecases.EditChunkInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		FileID:    testhelper.FixtureFileID,
+		ChunkID:   uuid.New(),
+		UserID:    testhelper.FixtureUserID,
+		Content:   "修正",
+	})
+
+	assert.True(t, errors.Is(err, domain.ErrConflict))
+}
+
+func TestChunkUseCase_Edit_ChunkOfOtherFile(t *testing.T) {
+	ctx := context.Background()
+	d := newChunkDeps(ctx)
+	other := &domain.Chunk{ID: uuid.New(), FileID: uuid.New(), Content: "他の教材"}
+	d.chunks.On("GetByID", ctx, other.ID).Return(other, nil)
+
+	_, err := d.useCase().Edit(ctx, usecases.EditChunkInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		FileID:    testhelper.FixtureFileID,
+		ChunkID:   other.ID,
+		UserID:    testhelper.FixtureUserID,
+		Content:   "修正",
+	})
+
+	assert.True(t, errors.Is(err, domain.ErrNotFound))
+}
+
+func TestChunkUseCase_Split_ShiftsFollowingChunks(t *testing.T) {
+	ctx := context.Background()
+	d := newChunkDeps(ctx)
+	chunk := d.givenChunk(ctx, 3, "定義1 定理2")
+	d.llm.On("GenerateEmbedding", ctx, mock.AnythingOfType("string")).Return(make([]float32, 768), nil)
+	edit, changes := d.captureApplyEdit(ctx)
+
+	out, err := d.useCase().Split(ctx, usecases.SplitChunkInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		FileID:    testhelper.FixtureFileID,
+		ChunkID:   chunk.ID,
+		UserID:    testhelper.FixtureUserID,
+		Parts:     []string{"定義1", "定理2", "証明"},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, out, 3)
+	assert.Equal(t, chunk.ID, out[0].ID)
+	assert.NotEqual(t, chunk.ID, out[1].ID)
+	for i, c := range out {
+		assert.Equal(t, 3+i, c.ChunkIndex)
+		assert.Equal(t, chunk.PageNumber, c.PageNumber)
+		assert.True(t, c.ManuallyEdited)
+	}
+	assert.Equal(t, domain.ChunkEditOperationSplit, edit.Operation)
+	assert.Len(t, edit.After, 3)
+	assert.Equal(t, 4, changes.ShiftFrom)
+	assert.Equal(t, 2, changes.ShiftBy)
+	d.llm.AssertNumberOfCalls(t, "GenerateEmbedding", 3)
+}
+
+func TestChunkUseCase_Split_RequiresTwoParts(t *testing.T) {
+	ctx := context.Background()
+	d := newChunkDeps(ctx)
+
+	_, err := d.useCase().Split(ctx, usecases.SplitChunkInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		FileID:    testhelper.FixtureFileID,
+		ChunkID:   uuid.New(),
+		UserID:    testhelper.FixtureUserID,
+		Parts:     []string{"1 つだけ"},
+	})
+
+	assert.True(t, errors.Is(err, domain.ErrInvalidInput))
+}
+
+func TestChunkUseCase_Merge_ConsecutiveChunks(t *testing.T) {
+	ctx := context.Background()
+	d := newChunkDeps(ctx)
+	first := d.givenChunk(ctx, 5, "表の前半")
+	second := d.givenChunk(ctx, 6, "表の後半")
+	d.llm.On("GenerateEmbedding", ctx, "表の前半\n\n表の後半").Return(make([]float32, 768), nil)
+	edit, changes := d.captureApplyEdit(ctx)
+
+	// 指定順に依らず連番順に結合する
+	out, err := d.useCase().Merge(ctx, usecases.MergeChunksInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		FileID:    testhelper.FixtureFileID,
+		UserID:    testhelper.FixtureUserID,
+		ChunkIDs:  []uuid.UUID{second.ID, first.ID},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, out.ID)
+	assert.Equal(t, 5, out.ChunkIndex)
+	assert.Equal(t, "表の前半\n\n表の後半", out.Content)
+	assert.Equal(t, domain.ChunkEditOperationMerge, edit.Operation)
+	assert.Len(t, edit.Before, 2)
+	assert.Equal(t, []uuid.UUID{second.ID}, changes.Delete)
+	assert.Equal(t, 7, changes.ShiftFrom)
+	assert.Equal(t, -1, changes.ShiftBy)
+}
+
+func TestChunkUseCase_Merge_RejectsNonConsecutive(t *testing.T) {
+	ctx := context.Background()
+	d := newChunkDeps(ctx)
+	a := d.givenChunk(ctx, 1, "A")
+	b := d.givenChunk(ctx, 3, "B")
+
+	_, err := d.useCase().Merge(ctx, usecases.MergeChunksInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		FileID:    testhelper.FixtureFileID,
+		UserID:    testhelper.FixtureUserID,
+		ChunkIDs:  []uuid.UUID{a.ID, b.ID},
+	})
+
+	assert.True(t, errors.Is(err, domain.ErrInvalidInput))
+	d.chunks.AssertNotCalled(t, "ApplyEdit", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestChunkUseCase_Delete_RecordsHistory(t *testing.T) {
+	ctx := context.Background()
+	d := newChunkDeps(ctx)
+	chunk := d.givenChunk(ctx, 8, "ノイズ")
+	edit, changes := d.captureApplyEdit(ctx)
+
+	err := d.useCase().Delete(ctx, testhelper.FixtureSubjectID, testhelper.FixtureFileID, chunk.ID, testhelper.FixtureUserID)
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.ChunkEditOperationDelete, edit.Operation)
+	assert.Equal(t, "ノイズ", edit.Before[0].Content)
+	assert.Empty(t, edit.After)
+	assert.Equal(t, []uuid.UUID{chunk.ID}, changes.Delete)
+	assert.Equal(t, 9, changes.ShiftFrom)
+	assert.Equal(t, -1, changes.ShiftBy)
+	assert.Empty(t, changes.Save)
+}
+
+func TestChunkUseCase_Delete_ConcurrentEditConflicts(t *testing.T) {
+	ctx := context.Background()
+	d := newChunkDeps(ctx)
+	chunk := d.givenChunk(ctx, 8, "ノイズ")
+	// 読み取り後に別の修正でチャンクが変わっていた場合、ApplyEdit が ErrConflict を返す
+	d.chunks.On("ApplyEdit", ctx, mock.Anything, mock.Anything).Return(domain.ErrConflict)
+
+	err := d.useCase().Delete(ctx, testhelper.FixtureSubjectID, testhelper.FixtureFileID, chunk.ID, testhelper.FixtureUserID)
+
+	assert.True(t, errors.Is(err, domain.ErrConflict))
+}
+
+// ─── AssessExtraction ─────────────────────────────────────────────
+
+func TestAssessExtraction(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		suspect bool
+		reasons []string
+	}{
+		{"正常な日本語", "フーリエ変換は周期関数を三角関数の和で表す手法である。", false, []string{}},
+		{"数式を含む", "関数 f(x) = x^2 + 2x + 1 の微分は f'(x) = 2x + 2 である。", false, []string{}},
+		{"短すぎる", "第3章", true, []string{domain.ExtractionTooShort}},
+		{"文字化け", "ã�ã�ã�ã�ã�ã�ã�ã�ã�ã�ã�", true,
+			[]string{domain.ExtractionInvalidCharacters, domain.ExtractionLowReadableRatio}},
+		{"罫線ノイズ", "表1 " + strings.Repeat("━", 30) + " 実験結果の一覧", true,
+			[]string{domain.ExtractionLowReadableRatio, domain.ExtractionRepeatedRun}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := domain.AssessExtraction(tt.content)
+			assert.Equal(t, tt.suspect, q.Suspect)
+			assert.Equal(t, tt.reasons, q.Reasons)
+			assert.Equal(t, len([]rune(tt.content)), q.CharCount)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+
+	"github.com/google/uuid"
+	pgvector "github.com/pgvector/pgvector-go"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+// Diversification は検索結果の多様性を考慮した選択（MMR: Maximal Marginal Relevance）の設定。
+// 同じスライドの似たチャンクばかりが選ばれ、ほかの教材が埋もれるのを防ぐ。
+type Diversification struct {
+	Weight     float64 // 冗長性へのペナルティの重み（0: 関連度のみ 〜 1: 多様性のみ）
+	MaxPerFile int     // 1 教材から選ぶ最大件数（0 は無制限）
+}
+
+// validateDiversityWeight は多様性の重みが 0〜1 の範囲か検証する。
+func validateDiversityWeight(w float64) error {
+	if math.IsNaN(w) || w < 0 || w > 1 {
+		return fmt.Errorf("diversity must be between 0 and 1: %w", domain.ErrInvalidInput)
+	}
+	return nil
+}
+
+// mmrCandidate は MMR の選択候補
+type mmrCandidate struct {
+	result    domain.SearchResult
+	relevance float64 // 関連度（大きいほど関連が高い。候補間で比較できれば尺度は問わない）
+}
+
+// diversifier は 1 回の回答生成の中で MMR による選択を行う。
+// 選択済みのチャンクは以降の選択でも冗長性・教材ごとの件数の評価に含める。
+type diversifier struct {
+	chunks     ports.ChunkRepository
+	cfg        Diversification
+	embeddings map[uuid.UUID]pgvector.Vector // 取得済みの Embedding（検索ラウンドをまたいで再利用する）
+	selected   []uuid.UUID
+	perFile    map[uuid.UUID]int
+}
+
+func newDiversifier(chunks ports.ChunkRepository, cfg Diversification) *diversifier {
+	return &diversifier{
+		chunks:     chunks,
+		cfg:        cfg,
+		embeddings: make(map[uuid.UUID]pgvector.Vector),
+		perFile:    make(map[uuid.UUID]int),
+	}
+}
+
+// fresh は取得済みの Embedding を引き継ぎ、選択状態を空にした diversifier を返す。
+func (d *diversifier) fresh() *diversifier {
+	nd := newDiversifier(d.chunks, d.cfg)
+	nd.embeddings = d.embeddings
+	return nd
+}
+
+// pick は候補から最大 n 件を選ぶ。
+// 関連度を最大値で 0〜1 に正規化し、選択済みチャンクとの最大コサイン類似度を sim として
+// (1-Weight)·relevance − Weight·sim が最大の候補を順に選ぶ（同点は候補の並び順）。
+// 1 教材あたり MaxPerFile 件を超える候補は選ばない。
+// Embedding を取得できない候補は冗長性 0 とみなす。
+func (d *diversifier) pick(ctx context.Context, cands []mmrCandidate, n int) []domain.SearchResult {
+	if d.cfg.Weight > 0 {
+		d.loadEmbeddings(ctx, cands)
+	}
+	maxRel := 0.0
+	for _, c := range cands {
+		maxRel = max(maxRel, c.relevance)
+	}
+
+	remaining := make([]bool, len(cands))
+	for i := range remaining {
+		remaining[i] = true
+	}
+	var picked []domain.SearchResult
+	for len(picked) < n {
+		best, bestScore := -1, math.Inf(-1)
+		for i, c := range cands {
+			if !remaining[i] {
+				continue
+			}
+			if d.cfg.MaxPerFile > 0 && d.perFile[c.result.FileID] >= d.cfg.MaxPerFile {
+				remaining[i] = false
+				continue
+			}
+			rel := 0.0
+			if maxRel > 0 {
+				rel = c.relevance / maxRel
+			}
+			score := (1-d.cfg.Weight)*rel - d.cfg.Weight*d.maxSimilarity(c.result.ChunkID)
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+		remaining[best] = false
+		r := cands[best].result
+		d.selected = append(d.selected, r.ChunkID)
+		d.perFile[r.FileID]++
+		picked = append(picked, r)
+	}
+	return picked
+}
+
+// loadEmbeddings は未取得の候補の Embedding を取得する（失敗時は冗長性を評価せずに続行）。
+func (d *diversifier) loadEmbeddings(ctx context.Context, cands []mmrCandidate) {
+	var ids []uuid.UUID
+	for _, c := range cands {
+		if _, ok := d.embeddings[c.result.ChunkID]; !ok {
+			ids = append(ids, c.result.ChunkID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	embs, err := d.chunks.GetEmbeddings(ctx, ids)
+	if err != nil {
+		slog.Warn("get chunk embeddings failed, skipping redundancy penalty", "chunks", len(ids), "error", err)
+		return
+	}
+	for id, v := range embs {
+		d.embeddings[id] = v
+	}
+}
+
+// maxSimilarity は選択済みチャンクとの最大コサイン類似度を返す（選択済みが無い場合は 0）。
+func (d *diversifier) maxSimilarity(id uuid.UUID) float64 {
+	v, ok := d.embeddings[id]
+	if !ok {
+		return 0
+	}
+	best := 0.0
+	for _, sid := range d.selected {
+		if sv, ok := d.embeddings[sid]; ok {
+			best = max(best, cosineSimilarity(v.Slice(), sv.Slice()))
+		}
+	}
+	return best
+}
+
+// cosineSimilarity は 2 つのベクトルのコサイン類似度を返す（次元が異なる・ゼロベクトルの場合は 0）。
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		na += x * x
+		nb += y * y
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
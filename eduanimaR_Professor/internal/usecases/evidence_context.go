@@ -0,0 +1,131 @@
+package usecases
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+// EvidenceExpansion はエビデンスを同じ教材の隣接チャンクで補う設定（ゼロ値は補わない）。
+type EvidenceExpansion struct {
+	Neighbours  int  // エビデンスの前後に加えるチャンク数（chunk_index ±N）
+	SamePage    bool // エビデンスと同じページのチャンクも加える
+	TokenBudget int  // 回答生成に渡すエビデンス全体の推定トークン数の上限（0 は無制限）
+}
+
+func (e EvidenceExpansion) enabled() bool {
+	return e.Neighbours > 0 || e.SamePage
+}
+
+// evidenceExpander は 1 回の回答生成でエビデンスを隣接チャンクで補う。
+// 同じチャンクは一度しか渡さず（重複除去）、推定トークン数が予算を超える隣接チャンクは加えない。
+// 選定されたエビデンス自体は予算を超えても必ず渡す。
+type evidenceExpander struct {
+	chunks ports.ChunkRepository
+	cfg    EvidenceExpansion
+	used   map[uuid.UUID]struct{}
+	tokens int
+}
+
+func newEvidenceExpander(chunks ports.ChunkRepository, cfg EvidenceExpansion) *evidenceExpander {
+	return &evidenceExpander{chunks: chunks, cfg: cfg, used: make(map[uuid.UUID]struct{})}
+}
+
+// expand はエビデンス r を前後の文脈で補った本文と、補った範囲を返す。
+// r が既にほかのエビデンスの文脈として渡されている場合は本文を空で返す。
+// 隣接チャンクを加えなかった場合、範囲は nil。
+func (x *evidenceExpander) expand(ctx context.Context, r domain.SearchResult) (string, *domain.EvidenceContext) {
+	if _, ok := x.used[r.ChunkID]; ok {
+		return "", nil
+	}
+	x.used[r.ChunkID] = struct{}{}
+	x.tokens += estimateTokens(r.Content)
+	if !x.cfg.enabled() {
+		return r.Content, nil
+	}
+
+	var page *int
+	if x.cfg.SamePage {
+		page = r.PageNumber
+	}
+	neighbours, err := x.chunks.ListNeighbours(ctx, r.FileID, r.ChunkIndex-x.cfg.Neighbours, r.ChunkIndex+x.cfg.Neighbours, page)
+	if err != nil {
+		slog.Warn("list neighbour chunks failed, using evidence only", "chunk_id", r.ChunkID, "error", err)
+		return r.Content, nil
+	}
+
+	// エビデンスに近いチャンクから予算の範囲で加える（予算を超えるチャンクは飛ばす）
+	candidates := slices.DeleteFunc(neighbours, func(ch *domain.Chunk) bool {
+		_, ok := x.used[ch.ID]
+		return ok || ch.ID == r.ChunkID
+	})
+	slices.SortStableFunc(candidates, func(a, b *domain.Chunk) int {
+		return cmp.Or(
+			cmp.Compare(chunkDistance(a, r.ChunkIndex), chunkDistance(b, r.ChunkIndex)),
+			cmp.Compare(a.ChunkIndex, b.ChunkIndex),
+		)
+	})
+	selected := []*domain.Chunk{{ID: r.ChunkID, ChunkIndex: r.ChunkIndex, PageNumber: r.PageNumber, Content: r.Content}}
+	for _, ch := range candidates {
+		t := estimateTokens(ch.Content)
+		if x.cfg.TokenBudget > 0 && x.tokens+t > x.cfg.TokenBudget {
+			continue
+		}
+		x.used[ch.ID] = struct{}{}
+		x.tokens += t
+		selected = append(selected, ch)
+	}
+	if len(selected) == 1 {
+		return r.Content, nil
+	}
+
+	slices.SortFunc(selected, func(a, b *domain.Chunk) int { return cmp.Compare(a.ChunkIndex, b.ChunkIndex) })
+	ec := &domain.EvidenceContext{
+		ChunkIndexFrom: selected[0].ChunkIndex,
+		ChunkIndexTo:   selected[len(selected)-1].ChunkIndex,
+	}
+	parts := make([]string, len(selected))
+	for i, ch := range selected {
+		parts[i] = ch.Content
+		ec.ChunkIDs = append(ec.ChunkIDs, ch.ID)
+		if p := ch.PageNumber; p != nil {
+			if ec.PageFrom == nil || *p < *ec.PageFrom {
+				ec.PageFrom = p
+			}
+			if ec.PageTo == nil || *p > *ec.PageTo {
+				ec.PageTo = p
+			}
+		}
+	}
+	return strings.Join(parts, "\n"), ec
+}
+
+// chunkDistance はエビデンスからの chunk_index の距離を返す。
+func chunkDistance(ch *domain.Chunk, index int) int {
+	if d := ch.ChunkIndex - index; d >= 0 {
+		return d
+	}
+	return index - ch.ChunkIndex
+}
+
+// estimateTokens はテキストの推定トークン数を返す。
+// 日本語などの非 ASCII 文字は 1 文字 ≒ 1 トークン、ASCII は 4 文字 ≒ 1 トークンとして概算する。
+func estimateTokens(s string) int {
+	ascii, other := 0, 0
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return other + (ascii+3)/4
+}
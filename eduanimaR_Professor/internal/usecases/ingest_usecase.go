@@ -1,10 +1,13 @@
 package usecases
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"log/slog"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,14 +17,18 @@ import (
 	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
 )
 
+// maxPreviewPages はページプレビューを生成する最大ページ数
+const maxPreviewPages = 300
+
 // IngestUseCase は OCR/Embedding パイプラインのビジネスロジックを担う。
 // Kafka コンシューマーがメッセージを受信するたびに ProcessJob を呼び出す。
 type IngestUseCase struct {
-	files   ports.FileRepository
-	jobs    ports.IngestJobRepository
-	chunks  ports.ChunkRepository
-	storage ports.ObjectStorage
-	llm     ports.LLMClient
+	files    ports.FileRepository
+	jobs     ports.IngestJobRepository
+	chunks   ports.ChunkRepository
+	storage  ports.ObjectStorage
+	llm      ports.LLMClient
+	renderer ports.PageRenderer // nil の場合はページプレビューを生成しない
 }
 
 // NewIngestUseCase は IngestUseCase を生成する。
@@ -31,13 +38,15 @@ func NewIngestUseCase(
 	chunks ports.ChunkRepository,
 	storage ports.ObjectStorage,
 	llm ports.LLMClient,
+	renderer ports.PageRenderer,
 ) *IngestUseCase {
 	return &IngestUseCase{
-		files:   files,
-		jobs:    jobs,
-		chunks:  chunks,
-		storage: storage,
-		llm:     llm,
+		files:    files,
+		jobs:     jobs,
+		chunks:   chunks,
+		storage:  storage,
+		llm:      llm,
+		renderer: renderer,
 	}
 }
 
@@ -49,8 +58,10 @@ func NewIngestUseCase(
 //  3. MinIO からファイルをダウンロード
 //  4. LLM.OCRAndChunk でテキスト抽出・チャンク分割
 //  5. 各チャンクの Embedding 生成（失敗チャンクはスキップ）
-//  6. ChunkRepository.BatchCreate でバルク保存
-//  7. FileStatus → "ready", IngestJob → "completed"
+//  6. ChunkRepository.ReplaceByFileID で既存チャンクと置き換えて保存（1 トランザクション）
+//     （手動修正されたチャンクは OverwriteManualEdits が指定されない限り残す）
+//  7. ページプレビュー画像を生成して保存（失敗しても処理は継続）
+//  8. FileStatus → "ready", IngestJob → "completed"
 //
 // エラー時: FileStatus → "failed", IngestJob → "failed"（defer で確実に実行）
 func (uc *IngestUseCase) ProcessJob(ctx context.Context, msg ports.IngestMessage) error {
@@ -98,7 +109,8 @@ func (uc *IngestUseCase) ProcessJob(ctx context.Context, msg ports.IngestMessage
 	}()
 
 	// 2. FileStatus → "processing"
-	if _, err := uc.files.UpdateStatus(ctx, fileID, domain.FileStatusProcessing, nil); err != nil {
+	file, err := uc.files.UpdateStatus(ctx, fileID, domain.FileStatusProcessing, nil)
+	if err != nil {
 		processErr = fmt.Errorf("update file processing: %w", err)
 		return processErr
 	}
@@ -176,8 +188,16 @@ func (uc *IngestUseCase) ProcessJob(ctx context.Context, msg ports.IngestMessage
 		processErr = fmt.Errorf("all chunks failed embedding for file %s", fileID)
 		return processErr
 	}
-	if err := uc.chunks.BatchCreate(ctx, chunks); err != nil {
-		processErr = fmt.Errorf("batch create chunks: %w", err)
+	// 再処理（URL 再取得・リトライ）時は既存チャンクを置き換える（保存に失敗した場合は既存チャンクが残る）
+	if !msg.OverwriteManualEdits {
+		chunks, err = uc.keepManualEdits(ctx, fileID, chunks)
+		if err != nil {
+			processErr = fmt.Errorf("keep manually edited chunks: %w", err)
+			return processErr
+		}
+	}
+	if err := uc.chunks.ReplaceByFileID(ctx, fileID, chunks); err != nil {
+		processErr = fmt.Errorf("replace chunks: %w", err)
 		return processErr
 	}
 	slog.Info("chunks saved to db",
@@ -185,7 +205,18 @@ func (uc *IngestUseCase) ProcessJob(ctx context.Context, msg ports.IngestMessage
 		"count", len(chunks),
 	)
 
-	// 7. FileStatus → "ready", IngestJob → "completed"
+	// 7. ページプレビュー生成（エビデンス表示用。失敗は警告のみ）
+	if uc.renderer != nil {
+		if err := uc.generatePreviews(ctx, fileID, file, msg, fileContent); err != nil {
+			slog.Warn("page preview generation failed",
+				"job_id", jobID,
+				"file_id", fileID,
+				"error", err,
+			)
+		}
+	}
+
+	// 8. FileStatus → "ready", IngestJob → "completed"
 	if _, err := uc.files.UpdateStatus(ctx, fileID, domain.FileStatusReady, nil); err != nil {
 		processErr = fmt.Errorf("update file ready: %w", err)
 		return processErr
@@ -205,3 +236,100 @@ func (uc *IngestUseCase) ProcessJob(ctx context.Context, msg ports.IngestMessage
 	)
 	return nil
 }
+
+// keepManualEdits は既存の手動修正済みチャンクを新しいチャンクに取り込む。
+// 手動修正済みチャンクがあるページ（ページ番号が無い教材は全体）は OCR 結果を採用せず、
+// 修正済みチャンクをそのまま残す。結果はページ順に並べ、chunk_index を振り直す。
+func (uc *IngestUseCase) keepManualEdits(ctx context.Context, fileID uuid.UUID, chunks []*domain.Chunk) ([]*domain.Chunk, error) {
+	existing, err := uc.chunks.ListByFileID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	editedPages := make(map[int]struct{})
+	var edited []*domain.Chunk
+	for _, c := range existing {
+		if c.ManuallyEdited {
+			edited = append(edited, c)
+			editedPages[chunkPageKey(c)] = struct{}{}
+		}
+	}
+	if len(edited) == 0 {
+		return chunks, nil
+	}
+
+	merged := slices.Clone(edited)
+	for _, c := range chunks {
+		if _, ok := editedPages[chunkPageKey(c)]; !ok {
+			merged = append(merged, c)
+		}
+	}
+	// 同じページのチャンクはすべて同じ由来（修正済み or OCR）のため、元の連番で並べてよい
+	slices.SortStableFunc(merged, func(a, b *domain.Chunk) int {
+		if d := chunkPageKey(a) - chunkPageKey(b); d != 0 {
+			return d
+		}
+		return a.ChunkIndex - b.ChunkIndex
+	})
+	for i, c := range merged {
+		c.ChunkIndex = i
+	}
+	slog.Info("manually edited chunks kept",
+		"file_id", fileID,
+		"kept_chunks", len(edited),
+		"edited_pages", len(editedPages),
+	)
+	return merged, nil
+}
+
+// chunkPageKey はページ単位の比較に使うキーを返す（ページ番号が無い場合は 0）。
+func chunkPageKey(c *domain.Chunk) int {
+	if c.PageNumber == nil {
+		return 0
+	}
+	return *c.PageNumber
+}
+
+// generatePreviews はページごとのプレビュー画像を生成してストレージに保存し、
+// 保存先とページ数をファイルに記録する。
+// 再処理でページ数が減った場合は、前回生成した余分なページ画像を削除する。
+// previous は処理開始時点のファイル（前回のプレビュー情報を含む）。
+func (uc *IngestUseCase) generatePreviews(ctx context.Context, fileID uuid.UUID, previous *domain.File, msg ports.IngestMessage, content []byte) error {
+	pages, err := uc.renderer.RenderPages(ctx, content, msg.MimeType, maxPreviewPages)
+	if err != nil {
+		return fmt.Errorf("render pages: %w", err)
+	}
+
+	var previewPath *string
+	for _, page := range pages {
+		name := domain.PagePreviewName(page.PageNumber)
+		key := fmt.Sprintf("%s/%s/%s/previews/%s", msg.UserID, msg.SubjectID, msg.FileID, name)
+		stored, err := uc.storage.Upload(ctx, key, bytes.NewReader(page.Data), int64(len(page.Data)), "image/png")
+		if err != nil {
+			return fmt.Errorf("upload preview page %d: %w", page.PageNumber, err)
+		}
+		if previewPath == nil {
+			dir := strings.TrimSuffix(stored, "/"+name)
+			previewPath = &dir
+		}
+	}
+
+	updated, err := uc.files.UpdatePreviews(ctx, fileID, previewPath, len(pages))
+	if err != nil {
+		return fmt.Errorf("update previews: %w", err)
+	}
+
+	// 前回のプレビューのうち今回生成しなかったページを削除する
+	if previous != nil && previous.PreviewPath != nil {
+		for page := 1; page <= previous.PreviewPageCount; page++ {
+			if updated.HasPagePreview(page) && updated.PagePreviewPath(page) == previous.PagePreviewPath(page) {
+				continue
+			}
+			if err := uc.storage.Delete(ctx, previous.PagePreviewPath(page)); err != nil {
+				slog.Warn("stale preview delete failed", "file_id", fileID, "page", page, "error", err)
+			}
+		}
+	}
+
+	slog.Info("page previews generated", "file_id", fileID, "pages", len(pages))
+	return nil
+}
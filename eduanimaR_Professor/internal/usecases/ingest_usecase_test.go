@@ -5,8 +5,10 @@ import (
 	"context"
 	"errors"
 	"io"
+	"strings"
 	"testing"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -27,7 +29,7 @@ func newIngestUseCase(
 	storage *testhelper.MockObjectStorage,
 	llm *testhelper.MockLLMClient,
 ) *usecases.IngestUseCase {
-	return usecases.NewIngestUseCase(files, jobs, chunks, storage, llm)
+	return usecases.NewIngestUseCase(files, jobs, chunks, storage, llm, nil)
 }
 
 // validIngestMessage は標準的なテスト用 IngestMessage を返す。
@@ -88,7 +90,8 @@ func TestIngestUseCase_ProcessJob_Success(t *testing.T) {
 	llmClient.On("GenerateEmbedding", ctx, "チャンク2のテキスト").Return(emb2, nil)
 
 	// 6. DB バルク保存
-	chunks.On("BatchCreate", ctx, mock.Anything).Return(nil)
+	chunks.On("ListByFileID", ctx, testhelper.FixtureFileID).Return([]*domain.Chunk{}, nil)
+	chunks.On("ReplaceByFileID", ctx, testhelper.FixtureFileID, mock.Anything).Return(nil)
 
 	// 7. FileStatus → ready
 	files.On("UpdateStatus", ctx, fileID, domain.FileStatusReady, (*string)(nil)).
@@ -110,6 +113,112 @@ func TestIngestUseCase_ProcessJob_Success(t *testing.T) {
 	chunks.AssertExpectations(t)
 }
 
+// ─── ProcessJob: ページプレビュー ─────────────────────────────────
+
+func TestIngestUseCase_ProcessJob_GeneratesPagePreviews(t *testing.T) {
+	ctx := context.Background()
+	msg := validIngestMessage()
+	jobID := testhelper.FixtureJobID
+	fileID := testhelper.FixtureFileID
+
+	files := &testhelper.MockFileRepository{}
+	jobs := &testhelper.MockIngestJobRepository{}
+	chunks := &testhelper.MockChunkRepository{}
+	storage := &testhelper.MockObjectStorage{}
+	llmClient := &testhelper.MockLLMClient{}
+	renderer := &testhelper.MockPageRenderer{}
+
+	// 前回の処理で 3 ページ分のプレビューが生成済み
+	oldPreviewPath := "minio://eduanima/old/previews"
+	previous := testhelper.NewFile(domain.FileStatusProcessing)
+	previous.PreviewPath = &oldPreviewPath
+	previous.PreviewPageCount = 3
+
+	jobs.On("UpdateStatus", ctx, jobID, domain.JobStatusProcessing, (*string)(nil)).
+		Return(testhelper.NewIngestJob(domain.JobStatusProcessing), nil)
+	files.On("UpdateStatus", ctx, fileID, domain.FileStatusProcessing, (*string)(nil)).
+		Return(previous, nil)
+	storage.On("Download", ctx, msg.StoragePath).Return(io.NopCloser(bytes.NewReader(fakePDFContent)), nil)
+	llmClient.On("OCRAndChunk", ctx, fakePDFContent, msg.MimeType).Return(&ports.OCRResult{
+		Chunks: []ports.ChunkData{{Index: 0, Content: "チャンク1のテキスト"}},
+	}, nil)
+	llmClient.On("GenerateEmbedding", ctx, "チャンク1のテキスト").Return(make([]float32, 768), nil)
+	chunks.On("ListByFileID", ctx, fileID).Return([]*domain.Chunk{}, nil)
+	chunks.On("ReplaceByFileID", ctx, fileID, mock.Anything).Return(nil)
+
+	// プレビュー: 2 ページを生成して保存し、前回の 3 ページ分を削除する
+	renderer.On("RenderPages", ctx, fakePDFContent, msg.MimeType, mock.AnythingOfType("int")).Return([]ports.PageImage{
+		{PageNumber: 1, Data: []byte("png-1")},
+		{PageNumber: 2, Data: []byte("png-2")},
+	}, nil)
+	keyPrefix := msg.UserID + "/" + msg.SubjectID + "/" + msg.FileID + "/previews/"
+	storage.On("Upload", ctx, keyPrefix+"page-0001.png", mock.Anything, int64(5), "image/png").
+		Return("minio://eduanima/"+keyPrefix+"page-0001.png", nil)
+	storage.On("Upload", ctx, keyPrefix+"page-0002.png", mock.Anything, int64(5), "image/png").
+		Return("minio://eduanima/"+keyPrefix+"page-0002.png", nil)
+	newPreviewPath := "minio://eduanima/" + strings.TrimSuffix(keyPrefix, "/")
+	updated := testhelper.NewFile(domain.FileStatusProcessing)
+	updated.PreviewPath = &newPreviewPath
+	updated.PreviewPageCount = 2
+	files.On("UpdatePreviews", ctx, fileID, &newPreviewPath, 2).Return(updated, nil)
+	for _, page := range []string{"page-0001.png", "page-0002.png", "page-0003.png"} {
+		storage.On("Delete", ctx, oldPreviewPath+"/"+page).Return(nil)
+	}
+
+	files.On("UpdateStatus", ctx, fileID, domain.FileStatusReady, (*string)(nil)).
+		Return(testhelper.NewFile(domain.FileStatusReady), nil)
+	jobs.On("UpdateStatus", ctx, jobID, domain.JobStatusCompleted, (*string)(nil)).
+		Return(testhelper.NewIngestJob(domain.JobStatusCompleted), nil)
+
+	uc := usecases.NewIngestUseCase(files, jobs, chunks, storage, llmClient, renderer)
+	err := uc.ProcessJob(ctx, msg)
+
+	require.NoError(t, err)
+	renderer.AssertExpectations(t)
+	storage.AssertExpectations(t)
+	files.AssertExpectations(t)
+}
+
+func TestIngestUseCase_ProcessJob_PreviewFailureDoesNotFailJob(t *testing.T) {
+	ctx := context.Background()
+	msg := validIngestMessage()
+	jobID := testhelper.FixtureJobID
+	fileID := testhelper.FixtureFileID
+
+	files := &testhelper.MockFileRepository{}
+	jobs := &testhelper.MockIngestJobRepository{}
+	chunks := &testhelper.MockChunkRepository{}
+	storage := &testhelper.MockObjectStorage{}
+	llmClient := &testhelper.MockLLMClient{}
+	renderer := &testhelper.MockPageRenderer{}
+
+	jobs.On("UpdateStatus", ctx, jobID, domain.JobStatusProcessing, (*string)(nil)).
+		Return(testhelper.NewIngestJob(domain.JobStatusProcessing), nil)
+	files.On("UpdateStatus", ctx, fileID, domain.FileStatusProcessing, (*string)(nil)).
+		Return(testhelper.NewFile(domain.FileStatusProcessing), nil)
+	storage.On("Download", ctx, msg.StoragePath).Return(io.NopCloser(bytes.NewReader(fakePDFContent)), nil)
+	llmClient.On("OCRAndChunk", ctx, fakePDFContent, msg.MimeType).Return(&ports.OCRResult{
+		Chunks: []ports.ChunkData{{Index: 0, Content: "チャンク1のテキスト"}},
+	}, nil)
+	llmClient.On("GenerateEmbedding", ctx, "チャンク1のテキスト").Return(make([]float32, 768), nil)
+	chunks.On("ListByFileID", ctx, fileID).Return([]*domain.Chunk{}, nil)
+	chunks.On("ReplaceByFileID", ctx, fileID, mock.Anything).Return(nil)
+	renderer.On("RenderPages", ctx, fakePDFContent, msg.MimeType, mock.AnythingOfType("int")).
+		Return(nil, errors.New("pdftoppm: exit status 1"))
+	files.On("UpdateStatus", ctx, fileID, domain.FileStatusReady, (*string)(nil)).
+		Return(testhelper.NewFile(domain.FileStatusReady), nil)
+	jobs.On("UpdateStatus", ctx, jobID, domain.JobStatusCompleted, (*string)(nil)).
+		Return(testhelper.NewIngestJob(domain.JobStatusCompleted), nil)
+
+	uc := usecases.NewIngestUseCase(files, jobs, chunks, storage, llmClient, renderer)
+	err := uc.ProcessJob(ctx, msg)
+
+	require.NoError(t, err)
+	files.AssertNotCalled(t, "UpdatePreviews", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	files.AssertExpectations(t)
+	jobs.AssertExpectations(t)
+}
+
 // ─── ProcessJob: 不正な UUID ─────────────────────────────────────
 
 func TestIngestUseCase_ProcessJob_InvalidJobID(t *testing.T) {
@@ -231,7 +340,7 @@ func TestIngestUseCase_ProcessJob_OCRProducesNoChunks(t *testing.T) {
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "no chunks")
-	chunks.AssertNotCalled(t, "BatchCreate")
+	chunks.AssertNotCalled(t, "ReplaceByFileID", mock.Anything, mock.Anything, mock.Anything)
 }
 
 // ─── ProcessJob: Embedding 全チャンク失敗 ────────────────────────
@@ -279,12 +388,12 @@ func TestIngestUseCase_ProcessJob_AllEmbeddingsFail(t *testing.T) {
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "all chunks failed embedding")
-	chunks.AssertNotCalled(t, "BatchCreate")
+	chunks.AssertNotCalled(t, "ReplaceByFileID", mock.Anything, mock.Anything, mock.Anything)
 }
 
-// ─── ProcessJob: BatchCreate 失敗 ────────────────────────────────
+// ─── ProcessJob: チャンク置き換え失敗 ────────────────────────────
 
-func TestIngestUseCase_ProcessJob_BatchCreateFails(t *testing.T) {
+func TestIngestUseCase_ProcessJob_ReplaceChunksFails(t *testing.T) {
 	ctx := context.Background()
 	msg := validIngestMessage()
 	jobID := testhelper.FixtureJobID
@@ -313,9 +422,10 @@ func TestIngestUseCase_ProcessJob_BatchCreateFails(t *testing.T) {
 	llmClient.On("OCRAndChunk", ctx, fakePDFContent, msg.MimeType).Return(ocrResult, nil)
 	llmClient.On("GenerateEmbedding", ctx, "チャンクX").Return(make([]float32, 768), nil)
 
-	// DB バルク保存が失敗
+	// DB への置き換え保存が失敗（トランザクションはロールバックされ既存チャンクは残る）
 	dbErr := errors.New("db write error")
-	chunks.On("BatchCreate", ctx, mock.Anything).Return(dbErr)
+	chunks.On("ListByFileID", ctx, testhelper.FixtureFileID).Return([]*domain.Chunk{}, nil)
+	chunks.On("ReplaceByFileID", ctx, testhelper.FixtureFileID, mock.Anything).Return(dbErr)
 
 	// defer: job → failed, file → failed
 	jobs.On("UpdateStatus", ctx, jobID, domain.JobStatusFailed, mock.Anything).
@@ -327,6 +437,133 @@ func TestIngestUseCase_ProcessJob_BatchCreateFails(t *testing.T) {
 	err := uc.ProcessJob(ctx, msg)
 
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "batch create chunks")
+	assert.ErrorIs(t, err, dbErr)
+	assert.Contains(t, err.Error(), "replace chunks")
 	files.AssertCalled(t, "UpdateStatus", ctx, fileID, domain.FileStatusFailed, mock.Anything)
+	// 既存チャンクをトランザクション外で削除していないこと
+	chunks.AssertNotCalled(t, "DeleteByFileID", mock.Anything, mock.Anything)
+	chunks.AssertNotCalled(t, "BatchCreate", mock.Anything, mock.Anything)
+}
+
+// ─── ProcessJob: 手動修正済みチャンク ─────────────────────────────
+
+// setupReprocess は page 1 に手動修正済みチャンク、page 2 に OCR 由来のチャンクがある教材の
+// 再処理（OCR 結果: page 1 に 1 件、page 2 に 2 件）を準備する。
+func setupReprocess(ctx context.Context, msg ports.IngestMessage) (
+	*testhelper.MockFileRepository,
+	*testhelper.MockIngestJobRepository,
+	*testhelper.MockChunkRepository,
+	*testhelper.MockObjectStorage,
+	*testhelper.MockLLMClient,
+	*domain.Chunk,
+) {
+	files := &testhelper.MockFileRepository{}
+	jobs := &testhelper.MockIngestJobRepository{}
+	chunks := &testhelper.MockChunkRepository{}
+	storage := &testhelper.MockObjectStorage{}
+	llmClient := &testhelper.MockLLMClient{}
+
+	jobs.On("UpdateStatus", ctx, testhelper.FixtureJobID, mock.Anything, (*string)(nil)).
+		Return(testhelper.NewIngestJob(domain.JobStatusProcessing), nil)
+	files.On("UpdateStatus", ctx, testhelper.FixtureFileID, mock.Anything, (*string)(nil)).
+		Return(testhelper.NewFile(domain.FileStatusProcessing), nil)
+	storage.On("Download", ctx, msg.StoragePath).Return(io.NopCloser(bytes.NewReader(fakePDFContent)), nil)
+
+	page1, page2 := 1, 2
+	llmClient.On("OCRAndChunk", ctx, fakePDFContent, msg.MimeType).Return(&ports.OCRResult{
+		Chunks: []ports.ChunkData{
+			{Index: 0, Content: "崩れた数式 x2+1", PageNumber: &page1},
+			{Index: 1, Content: "2ページ目の前半", PageNumber: &page2},
+			{Index: 2, Content: "2ページ目の後半", PageNumber: &page2},
+		},
+	}, nil)
+	llmClient.On("GenerateEmbedding", ctx, mock.AnythingOfType("string")).Return(make([]float32, 768), nil)
+
+	edited := &domain.Chunk{
+		ID:             uuid.New(),
+		FileID:         testhelper.FixtureFileID,
+		SubjectID:      testhelper.FixtureSubjectID,
+		PageNumber:     &page1,
+		ChunkIndex:     0,
+		Content:        "数式 x^2 + 1",
+		ManuallyEdited: true,
+	}
+	chunks.On("ListByFileID", ctx, testhelper.FixtureFileID).Return([]*domain.Chunk{
+		edited,
+		{ID: uuid.New(), FileID: testhelper.FixtureFileID, PageNumber: &page2, ChunkIndex: 1, Content: "古い2ページ目"},
+	}, nil)
+	return files, jobs, chunks, storage, llmClient, edited
+}
+
+func TestIngestUseCase_ProcessJob_KeepsManuallyEditedChunks(t *testing.T) {
+	ctx := context.Background()
+	msg := validIngestMessage()
+	files, jobs, chunks, storage, llmClient, edited := setupReprocess(ctx, msg)
+
+	var saved []*domain.Chunk
+	chunks.On("ReplaceByFileID", ctx, testhelper.FixtureFileID, mock.Anything).
+		Run(func(args mock.Arguments) { saved = args.Get(2).([]*domain.Chunk) }).
+		Return(nil)
+
+	uc := newIngestUseCase(files, jobs, chunks, storage, llmClient)
+	require.NoError(t, uc.ProcessJob(ctx, msg))
+
+	// page 1 は手動修正済みチャンクを残し、page 2 は OCR 結果で置き換える
+	require.Len(t, saved, 3)
+	assert.Equal(t, edited.ID, saved[0].ID)
+	assert.Equal(t, "数式 x^2 + 1", saved[0].Content)
+	assert.True(t, saved[0].ManuallyEdited)
+	assert.Equal(t, "2ページ目の前半", saved[1].Content)
+	assert.Equal(t, "2ページ目の後半", saved[2].Content)
+	for i, c := range saved {
+		assert.Equal(t, i, c.ChunkIndex)
+	}
+}
+
+func TestIngestUseCase_ProcessJob_OverwriteManualEdits(t *testing.T) {
+	ctx := context.Background()
+	msg := validIngestMessage()
+	msg.OverwriteManualEdits = true
+	files, jobs, chunks, storage, llmClient, _ := setupReprocess(ctx, msg)
+
+	var saved []*domain.Chunk
+	chunks.On("ReplaceByFileID", ctx, testhelper.FixtureFileID, mock.Anything).
+		Run(func(args mock.Arguments) { saved = args.Get(2).([]*domain.Chunk) }).
+		Return(nil)
+
+	uc := newIngestUseCase(files, jobs, chunks, storage, llmClient)
+	require.NoError(t, uc.ProcessJob(ctx, msg))
+
+	require.Len(t, saved, 3)
+	assert.Equal(t, "崩れた数式 x2+1", saved[0].Content)
+	assert.False(t, saved[0].ManuallyEdited)
+	chunks.AssertNotCalled(t, "ListByFileID", ctx, testhelper.FixtureFileID)
+}
+
+func TestIngestUseCase_ProcessJob_ReplaceFailureKeepsManualEdits(t *testing.T) {
+	ctx := context.Background()
+	msg := validIngestMessage()
+	files, jobs, chunks, storage, llmClient, edited := setupReprocess(ctx, msg)
+
+	// 置き換えのトランザクションが失敗（ロールバックされ既存チャンクはそのまま残る）
+	dbErr := errors.New("db write error")
+	var attempted []*domain.Chunk
+	chunks.On("ReplaceByFileID", ctx, testhelper.FixtureFileID, mock.Anything).
+		Run(func(args mock.Arguments) { attempted = args.Get(2).([]*domain.Chunk) }).
+		Return(dbErr)
+	jobs.On("UpdateStatus", ctx, testhelper.FixtureJobID, domain.JobStatusFailed, mock.Anything).
+		Return(testhelper.NewIngestJob(domain.JobStatusFailed), nil)
+	files.On("UpdateStatus", ctx, testhelper.FixtureFileID, domain.FileStatusFailed, mock.Anything).
+		Return(testhelper.NewFile(domain.FileStatusFailed), nil)
+
+	uc := newIngestUseCase(files, jobs, chunks, storage, llmClient)
+	err := uc.ProcessJob(ctx, msg)
+
+	require.ErrorIs(t, err, dbErr)
+	// 手動修正済みチャンクは置き換え対象に含めたうえで、削除は置き換えと同じトランザクションでのみ行う
+	require.NotEmpty(t, attempted)
+	assert.Equal(t, edited.ID, attempted[0].ID)
+	chunks.AssertNotCalled(t, "DeleteByFileID", mock.Anything, mock.Anything)
+	chunks.AssertNotCalled(t, "BatchCreate", mock.Anything, mock.Anything)
+	files.AssertCalled(t, "UpdateStatus", ctx, testhelper.FixtureFileID, domain.FileStatusFailed, mock.Anything)
 }
@@ -0,0 +1,108 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+)
+
+// downloadURLExpiry は教材・プレビュー画像の presigned GET URL の有効期限
+const downloadURLExpiry = 15 * time.Minute
+
+// DownloadURLOutput は教材ダウンロード URL の発行結果
+type DownloadURLOutput struct {
+	File       *domain.File
+	URL        string // presigned GET URL（PDF でページ指定がある場合は "#page=N" 付き）
+	ExpiresAt  time.Time
+	PageNumber *int
+	PreviewURL string // 指定ページのプレビュー画像 API のパス（無い場合は空）
+}
+
+// GetDownloadURL は教材の一時的なダウンロード URL を発行する。
+// page を指定した場合、PDF ビューアで該当ページを開くアンカーを付ける。
+func (uc *MaterialUseCase) GetDownloadURL(ctx context.Context, subjectID, fileID, userID uuid.UUID, page *int) (*DownloadURLOutput, error) {
+	file, err := uc.ownedFile(ctx, subjectID, fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if page != nil {
+		if *page < 1 || (file.PreviewPageCount > 0 && *page > file.PreviewPageCount) {
+			return nil, fmt.Errorf("page %d is out of range: %w", *page, domain.ErrInvalidInput)
+		}
+	}
+
+	u, err := uc.storage.GetPresignedURL(ctx, file.StoragePath, downloadURLExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("presign get url: %w", err)
+	}
+	out := &DownloadURLOutput{
+		File:       file,
+		URL:        u,
+		ExpiresAt:  time.Now().UTC().Add(downloadURLExpiry),
+		PageNumber: page,
+	}
+	if page != nil {
+		if file.MimeType == "application/pdf" {
+			out.URL = fmt.Sprintf("%s#page=%d", u, *page)
+		}
+		out.PreviewURL = pagePreviewURL(file.SubjectID, file.ID, file.MimeType, file.PreviewPageCount, page)
+	}
+	return out, nil
+}
+
+// GetPagePreviewURL は教材ページのプレビュー画像の presigned GET URL を発行する。
+// 画像教材は 1 枚のスライドとして扱い、原本をそのままプレビューとして返す。
+// プレビューが生成されていないページは domain.ErrNotFound を返す。
+func (uc *MaterialUseCase) GetPagePreviewURL(ctx context.Context, subjectID, fileID, userID uuid.UUID, page int) (string, error) {
+	file, err := uc.ownedFile(ctx, subjectID, fileID, userID)
+	if err != nil {
+		return "", err
+	}
+
+	var location string
+	switch {
+	case file.HasPagePreview(page):
+		location = file.PagePreviewPath(page)
+	case file.IsImage() && page == 1:
+		location = file.StoragePath
+	default:
+		return "", fmt.Errorf("preview for page %d: %w", page, domain.ErrNotFound)
+	}
+
+	u, err := uc.storage.GetPresignedURL(ctx, location, downloadURLExpiry)
+	if err != nil {
+		return "", fmt.Errorf("presign preview url: %w", err)
+	}
+	return u, nil
+}
+
+// ownedFile はユーザーが所有し、指定 subject に属する教材を返す。
+// 他 subject の教材は存在を隠すため domain.ErrNotFound を返す。
+func (uc *MaterialUseCase) ownedFile(ctx context.Context, subjectID, fileID, userID uuid.UUID) (*domain.File, error) {
+	file, err := uc.files.GetByIDAndUserID(ctx, fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if file.SubjectID != subjectID {
+		return nil, domain.ErrNotFound
+	}
+	return file, nil
+}
+
+// pagePreviewURL は出典として表示するページのプレビュー画像 API のパスを返す。
+// プレビュー画像が無い場合は空文字を返す。
+func pagePreviewURL(subjectID, fileID uuid.UUID, mimeType string, previewPageCount int, page *int) string {
+	switch {
+	case page != nil && *page >= 1 && *page <= previewPageCount:
+		return domain.PagePreviewAPIPath(subjectID, fileID, *page)
+	case strings.HasPrefix(mimeType, "image/"):
+		return domain.PagePreviewAPIPath(subjectID, fileID, 1)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,379 @@
+package usecases
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"golang.org/x/text/encoding/japanese"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+const (
+	maxZipImportEntries    = 500       // 1 つの ZIP に含められる最大エントリ数
+	maxZipImportTotalBytes = 4 << 30   // 展開後の合計サイズ上限（4 GiB、ZIP 爆弾対策）
+	maxURLImportSizeBytes  = 100 << 20 // URL 取り込みで受け付ける最大サイズ（100 MiB）
+)
+
+// ZipEntryStatus は ZIP 一括取り込みにおけるエントリごとの処理結果
+type ZipEntryStatus string
+
+const (
+	ZipEntryImported    ZipEntryStatus = "imported"     // File / IngestJob を作成済み
+	ZipEntryUnsupported ZipEntryStatus = "unsupported"  // OCR パイプラインが扱えない種別
+	ZipEntryTooLarge    ZipEntryStatus = "too_large"    // サイズ上限超過
+	ZipEntryInvalidName ZipEntryStatus = "invalid_name" // ファイル名が長すぎる・制御文字を含む等
+	ZipEntryFailed      ZipEntryStatus = "failed"       // ストレージ・DB エラー
+)
+
+// materialExtensions は拡張子から MIME タイプを判定する。
+// ZIP エントリは Content-Type を持たないため拡張子で判定する。
+var materialExtensions = map[string]string{
+	".pdf":  "application/pdf",
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".webp": "image/webp",
+}
+
+// mimeTypeExtensions は MIME タイプから教材名に補う拡張子を引く。
+// materialExtensions の逆引きだが、".jpg" / ".jpeg" のように複数ある場合に備えて明示する。
+var mimeTypeExtensions = map[string]string{
+	"application/pdf": ".pdf",
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/webp":      ".webp",
+}
+
+// ImportZipInput は ZIP 一括取り込みの入力値
+type ImportZipInput struct {
+	SubjectID uuid.UUID
+	UserID    uuid.UUID
+	Reader    io.ReaderAt
+	Size      int64
+}
+
+// ZipEntryResult は ZIP エントリ 1 件の取り込み結果
+type ZipEntryResult struct {
+	Path   string // ZIP 内のパス（正規化済み）
+	Status ZipEntryStatus
+	File   *domain.File // Status=imported のときのみ
+	Reason string       // Status!=imported のときの理由
+}
+
+// ImportZipOutput は ZIP 一括取り込みの結果
+type ImportZipOutput struct {
+	Entries []ZipEntryResult
+}
+
+// ImportZip は ZIP を展開し、対応形式のエントリごとに File と IngestJob を作成する。
+// ZIP 内のフォルダパスは File.FolderPath に保持する。
+// 非対応・サイズ超過・個別の失敗はエラーにせず、エントリごとの結果として返す。
+func (uc *MaterialUseCase) ImportZip(ctx context.Context, in ImportZipInput) (*ImportZipOutput, error) {
+	// subject の所有権確認
+	if _, err := uc.subjects.GetByIDAndUserID(ctx, in.SubjectID, in.UserID); err != nil {
+		return nil, err
+	}
+
+	zr, err := zip.NewReader(in.Reader, in.Size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %w", domain.ErrInvalidInput)
+	}
+	if len(zr.File) > maxZipImportEntries {
+		return nil, fmt.Errorf("zip archive has more than %d entries: %w", maxZipImportEntries, domain.ErrInvalidInput)
+	}
+
+	out := &ImportZipOutput{}
+	var totalBytes uint64
+	for _, zf := range zr.File {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		name := zipEntryName(zf)
+		if zf.FileInfo().IsDir() || isZipMetadataEntry(name) {
+			continue
+		}
+
+		totalBytes += zf.UncompressedSize64
+		if totalBytes > maxZipImportTotalBytes {
+			out.Entries = append(out.Entries, ZipEntryResult{
+				Path:   name,
+				Status: ZipEntryTooLarge,
+				Reason: fmt.Sprintf("archive exceeds total size limit of %d bytes", int64(maxZipImportTotalBytes)),
+			})
+			continue
+		}
+		out.Entries = append(out.Entries, uc.importZipEntry(ctx, in, zf, name))
+	}
+	return out, nil
+}
+
+// importZipEntry は ZIP エントリ 1 件をストレージへ配置し、教材として登録する。
+func (uc *MaterialUseCase) importZipEntry(ctx context.Context, in ImportZipInput, zf *zip.File, name string) ZipEntryResult {
+	res := ZipEntryResult{Path: name}
+
+	dir, base := path.Split(name)
+	// 直接アップロードと同じ規則でファイル名を検証する
+	if _, err := materialFileName(base); err != nil {
+		res.Status = ZipEntryInvalidName
+		res.Reason = invalidFileNameReason(base)
+		return res
+	}
+	mimeType, ok := materialExtensions[strings.ToLower(path.Ext(base))]
+	if !ok {
+		res.Status = ZipEntryUnsupported
+		res.Reason = "unsupported file type"
+		return res
+	}
+	size := int64(zf.UncompressedSize64)
+	if size > maxMaterialSizeBytes {
+		res.Status = ZipEntryTooLarge
+		res.Reason = fmt.Sprintf("file exceeds %d bytes", int64(maxMaterialSizeBytes))
+		return res
+	}
+	if size == 0 {
+		res.Status = ZipEntryUnsupported
+		res.Reason = "empty file"
+		return res
+	}
+
+	rc, err := zf.Open()
+	if err != nil {
+		res.Status = ZipEntryFailed
+		res.Reason = "failed to read entry"
+		return res
+	}
+	defer rc.Close()
+
+	fileID := uuid.New()
+	// MinIO キー: {userID}/{subjectID}/{fileID}/{fileName}
+	key := fmt.Sprintf("%s/%s/%s/%s", in.UserID, in.SubjectID, fileID, base)
+
+	storagePath, err := uc.storage.Upload(ctx, key, io.LimitReader(rc, size), size, mimeType)
+	if err != nil {
+		slog.Warn("zip import: storage upload failed", "entry", name, "error", err)
+		res.Status = ZipEntryFailed
+		res.Reason = "storage upload failed"
+		return res
+	}
+
+	file := &domain.File{
+		ID:          fileID,
+		SubjectID:   in.SubjectID,
+		UserID:      in.UserID,
+		Name:        base,
+		StoragePath: storagePath,
+		MimeType:    mimeType,
+		SizeBytes:   size,
+		Status:      domain.FileStatusPending,
+		UploadedAt:  time.Now().UTC(),
+	}
+	if dir = strings.TrimSuffix(dir, "/"); dir != "" {
+		file.FolderPath = &dir
+	}
+	if err := uc.registerFile(ctx, file); err != nil {
+		slog.Warn("zip import: register file failed", "entry", name, "error", err)
+		if delErr := uc.storage.Delete(ctx, storagePath); delErr != nil {
+			slog.Warn("zip import: failed to delete object", "path", storagePath, "error", delErr)
+		}
+		res.Status = ZipEntryFailed
+		res.Reason = "failed to register material"
+		return res
+	}
+
+	res.Status = ZipEntryImported
+	res.File = file
+	return res
+}
+
+// zipEntryName はエントリ名を UTF-8・スラッシュ区切りの相対パスに正規化する。
+// 日本語 Windows で作成された ZIP は UTF-8 フラグなしの Shift_JIS 名を含むため変換する。
+// ".." や先頭の "/" は除去され、ZIP 外を指すパスにはならない。
+func zipEntryName(zf *zip.File) string {
+	name := zf.Name
+	if !utf8.ValidString(name) {
+		if decoded, err := japanese.ShiftJIS.NewDecoder().String(name); err == nil {
+			name = decoded
+		}
+	}
+	name = strings.ReplaceAll(name, "\\", "/")
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// isZipMetadataEntry は OS が自動生成するメタデータエントリかどうかを返す。
+// これらは取り込み結果にも含めない。
+func isZipMetadataEntry(name string) bool {
+	if name == "__MACOSX" || strings.HasPrefix(name, "__MACOSX/") {
+		return true
+	}
+	base := path.Base(name)
+	return strings.HasPrefix(base, ".") || strings.EqualFold(base, "Thumbs.db")
+}
+
+// ─── URL 取り込み ──────────────────────────────────────────────────
+
+// ImportURLInput は URL からの教材取り込みの入力値
+type ImportURLInput struct {
+	SubjectID uuid.UUID
+	UserID    uuid.UUID
+	URL       string
+}
+
+// ImportURL は URL からドキュメントを取得して教材として登録し、OCR/Embedding ジョブを投入する。
+// 取得元 URL と内容のハッシュを File に記録し、RefetchURL での変更検知に使う。
+func (uc *MaterialUseCase) ImportURL(ctx context.Context, in ImportURLInput) (*domain.File, error) {
+	// subject の所有権確認
+	if _, err := uc.subjects.GetByIDAndUserID(ctx, in.SubjectID, in.UserID); err != nil {
+		return nil, err
+	}
+
+	doc, mimeType, err := uc.fetchMaterial(ctx, in.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	fileID := uuid.New()
+	name := urlMaterialName(doc.FileName, mimeType)
+	// MinIO キー: {userID}/{subjectID}/{fileID}/{fileName}
+	key := fmt.Sprintf("%s/%s/%s/%s", in.UserID, in.SubjectID, fileID, name)
+	size := int64(len(doc.Body))
+
+	storagePath, err := uc.storage.Upload(ctx, key, bytes.NewReader(doc.Body), size, mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceURL := in.URL
+	hash := contentHash(doc.Body)
+	file := &domain.File{
+		ID:          fileID,
+		SubjectID:   in.SubjectID,
+		UserID:      in.UserID,
+		Name:        name,
+		StoragePath: storagePath,
+		MimeType:    mimeType,
+		SizeBytes:   size,
+		Status:      domain.FileStatusPending,
+		UploadedAt:  time.Now().UTC(),
+		SourceURL:   &sourceURL,
+		ContentHash: &hash,
+	}
+	if err := uc.registerFile(ctx, file); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// RefetchOutput は URL 再取得の結果
+type RefetchOutput struct {
+	File    *domain.File
+	Changed bool // 内容が変わり再処理を投入した場合 true
+}
+
+// RefetchURL は URL から取り込んだ教材を再取得し、内容が変わっていれば
+// オブジェクトを差し替えて OCR/Embedding を再実行する（手動修正されたチャンクは残す）。
+// 処理中の教材に対しては domain.ErrConflict を返す。
+func (uc *MaterialUseCase) RefetchURL(ctx context.Context, subjectID, fileID, userID uuid.UUID) (*RefetchOutput, error) {
+	file, err := uc.files.GetByIDAndUserID(ctx, fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if file.SubjectID != subjectID {
+		return nil, domain.ErrNotFound
+	}
+	if file.SourceURL == nil {
+		return nil, fmt.Errorf("material was not imported from a url: %w", domain.ErrInvalidInput)
+	}
+	if file.IsInProgress() {
+		return nil, fmt.Errorf("material is being processed: %w", domain.ErrConflict)
+	}
+
+	doc, mimeType, err := uc.fetchMaterial(ctx, *file.SourceURL)
+	if err != nil {
+		return nil, err
+	}
+	hash := contentHash(doc.Body)
+	if file.ContentHash != nil && *file.ContentHash == hash {
+		return &RefetchOutput{File: file, Changed: false}, nil
+	}
+
+	// 同じキーに上書きする（現在のバックエンドに書き込むため、ストレージパスは更新する）。
+	// 種別が変わった場合は拡張子が合うよう教材名を決め直すため、キーも変わる
+	name := file.Name
+	if mimeType != file.MimeType {
+		name = urlMaterialName(doc.FileName, mimeType)
+	}
+	key := fmt.Sprintf("%s/%s/%s/%s", file.UserID, file.SubjectID, file.ID, name)
+	size := int64(len(doc.Body))
+	storagePath, err := uc.storage.Upload(ctx, key, bytes.NewReader(doc.Body), size, mimeType)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := uc.files.UpdateContent(ctx, file.ID, name, storagePath, mimeType, size, hash)
+	if err != nil {
+		return nil, err
+	}
+	// キーやバックエンドが変わった場合は、差し替え前のオブジェクトを削除する（失敗はログのみ。残った場合は整合性チェックで削除される）
+	if storagePath != file.StoragePath {
+		if err := uc.storage.Delete(ctx, file.StoragePath); err != nil {
+			slog.Warn("failed to delete replaced material object", "file_id", file.ID, "storage_path", file.StoragePath, "error", err)
+		}
+	}
+	if err := uc.enqueueIngest(ctx, updated, false); err != nil {
+		return nil, err
+	}
+	slog.Info("url material changed, reprocessing", "file_id", file.ID, "source_url", *file.SourceURL)
+	return &RefetchOutput{File: updated, Changed: true}, nil
+}
+
+// fetchMaterial は URL からドキュメントを取得し、教材として受け付け可能か検証する。
+func (uc *MaterialUseCase) fetchMaterial(ctx context.Context, rawURL string) (*ports.FetchedDocument, string, error) {
+	if rawURL == "" {
+		return nil, "", fmt.Errorf("url is required: %w", domain.ErrInvalidInput)
+	}
+	doc, err := uc.fetcher.Fetch(ctx, rawURL, maxURLImportSizeBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	mimeType := mediaType(doc.ContentType)
+	if err := validateMaterial(mimeType, int64(len(doc.Body))); err != nil {
+		return nil, "", err
+	}
+	return doc, mimeType, nil
+}
+
+// urlMaterialName は取得したドキュメントの教材名を決める。
+// ファイル名が推定できない、または拡張子が種別と合わない場合は拡張子を補う。
+// 直接アップロードと同じ規則で受け付けられない名前は "document" に置き換える。
+func urlMaterialName(name, mimeType string) string {
+	ext := mimeTypeExtensions[mimeType]
+	if name == "" {
+		name = "document"
+	}
+	if materialExtensions[strings.ToLower(path.Ext(name))] != mimeType {
+		name += ext
+	}
+	if _, err := materialFileName(name); err != nil {
+		return "document" + ext
+	}
+	return name
+}
+
+// contentHash は内容の SHA-256 を 16 進で返す。
+func contentHash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
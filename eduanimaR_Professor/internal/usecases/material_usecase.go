@@ -2,10 +2,16 @@ package usecases
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 
@@ -13,29 +19,54 @@ import (
 	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
 )
 
+const (
+	maxMaterialSizeBytes   = 512 << 20        // 直接アップロードで受け付ける最大サイズ（512 MiB）
+	uploadURLExpiry        = 15 * time.Minute // presigned PUT URL の有効期限
+	uploadCleanupGrace     = 1 * time.Hour    // 期限切れ後、転送中の PUT を考慮して削除を待つ猶予
+	uploadCleanupBatchSize = 100
+	resumableUploadTTL     = 24 * time.Hour // 再開可能アップロードの有効期限（パート受信ごとに延長）
+)
+
+// supportedMaterialTypes は OCR パイプラインが処理可能な MIME タイプ
+var supportedMaterialTypes = map[string]struct{}{
+	"application/pdf": {},
+	"image/png":       {},
+	"image/jpeg":      {},
+	"image/webp":      {},
+}
+
 // MaterialUseCase は教材（ファイル）に関するビジネスロジックを提供する。
 type MaterialUseCase struct {
 	files     ports.FileRepository
 	jobs      ports.IngestJobRepository
+	uploads   ports.MaterialUploadRepository
 	storage   ports.ObjectStorage
+	multipart ports.MultipartStorage
 	publisher ports.MessagePublisher
 	subjects  ports.SubjectRepository
+	fetcher   ports.DocumentFetcher
 }
 
 // NewMaterialUseCase は MaterialUseCase を生成する。
 func NewMaterialUseCase(
 	files ports.FileRepository,
 	jobs ports.IngestJobRepository,
+	uploads ports.MaterialUploadRepository,
 	storage ports.ObjectStorage,
+	multipart ports.MultipartStorage,
 	publisher ports.MessagePublisher,
 	subjects ports.SubjectRepository,
+	fetcher ports.DocumentFetcher,
 ) *MaterialUseCase {
 	return &MaterialUseCase{
 		files:     files,
 		jobs:      jobs,
+		uploads:   uploads,
 		storage:   storage,
+		multipart: multipart,
 		publisher: publisher,
 		subjects:  subjects,
+		fetcher:   fetcher,
 	}
 }
 
@@ -85,31 +116,75 @@ func (uc *MaterialUseCase) Upload(ctx context.Context, in UploadMaterialInput) (
 		Status:      domain.FileStatusPending,
 		UploadedAt:  time.Now().UTC(),
 	}
+	if err := uc.registerFile(ctx, file); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// registerFile は File を永続化し、非同期 OCR/Embedding ジョブを登録する。
+// ストレージへの配置が完了したファイルに対して呼び出す。
+func (uc *MaterialUseCase) registerFile(ctx context.Context, file *domain.File) error {
 	if err := uc.files.Create(ctx, file); err != nil {
+		return err
+	}
+	if err := uc.enqueueIngest(ctx, file, false); err != nil {
+		// ジョブの無い教材を残さず、再試行で同じ File ID から登録し直せるようにする
+		if delErr := uc.files.Delete(ctx, file.ID, file.UserID); delErr != nil {
+			slog.Warn("failed to roll back file registration", "file_id", file.ID, "error", delErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Reprocess は教材の OCR/Embedding を再実行する。
+// 手動修正されたチャンクは overwriteManualEdits が true の場合のみ置き換える。
+// 処理中の教材に対しては domain.ErrConflict を返す。
+func (uc *MaterialUseCase) Reprocess(ctx context.Context, subjectID, fileID, userID uuid.UUID, overwriteManualEdits bool) (*domain.File, error) {
+	file, err := uc.ownedFile(ctx, subjectID, fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if file.IsInProgress() {
+		return nil, fmt.Errorf("material is being processed: %w", domain.ErrConflict)
+	}
+	updated, err := uc.files.UpdateStatus(ctx, file.ID, domain.FileStatusPending, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.enqueueIngest(ctx, updated, overwriteManualEdits); err != nil {
 		return nil, err
 	}
+	slog.Info("material reprocess requested", "file_id", file.ID, "overwrite_manual_edits", overwriteManualEdits)
+	return updated, nil
+}
 
+// enqueueIngest は File に対する非同期 OCR/Embedding ジョブを登録する。
+// overwriteManualEdits が true の場合、手動修正されたチャンクも OCR 結果で置き換える。
+func (uc *MaterialUseCase) enqueueIngest(ctx context.Context, file *domain.File, overwriteManualEdits bool) error {
 	// 非同期 OCR/Embedding ジョブを作成
 	job := &domain.IngestJob{
 		ID:         uuid.New(),
-		FileID:     fileID,
+		FileID:     file.ID,
 		Status:     domain.JobStatusPending,
 		RetryCount: 0,
 		MaxRetries: 3,
 		CreatedAt:  time.Now().UTC(),
 	}
 	if err := uc.jobs.Create(ctx, job); err != nil {
-		return nil, err
+		return err
 	}
 
 	// Kafka メッセージ送信（失敗してもユーザーにはエラーを返さない）
 	msg := ports.IngestMessage{
-		JobID:       job.ID.String(),
-		FileID:      fileID.String(),
-		SubjectID:   in.SubjectID.String(),
-		UserID:      in.UserID.String(),
-		StoragePath: storagePath,
-		MimeType:    in.MimeType,
+		JobID:                job.ID.String(),
+		FileID:               file.ID.String(),
+		SubjectID:            file.SubjectID.String(),
+		UserID:               file.UserID.String(),
+		StoragePath:          file.StoragePath,
+		MimeType:             file.MimeType,
+		OverwriteManualEdits: overwriteManualEdits,
 	}
 	if err := uc.publisher.PublishIngestJob(ctx, msg); err != nil {
 		// Kafka 失敗はログだけ（ワーカーが DB をスキャンしてリカバリ可能）
@@ -118,19 +193,462 @@ func (uc *MaterialUseCase) Upload(ctx context.Context, in UploadMaterialInput) (
 			"error", err,
 		)
 	}
+	return nil
+}
+
+// ─── 直接アップロード（presigned URL） ─────────────────────────────
+
+// RequestUploadInput は直接アップロード開始の入力値
+type RequestUploadInput struct {
+	SubjectID uuid.UUID
+	UserID    uuid.UUID
+	FileName  string
+	MimeType  string
+	Size      int64
+}
+
+// RequestUploadOutput は直接アップロード開始の結果
+type RequestUploadOutput struct {
+	Upload    *domain.MaterialUpload
+	UploadURL string // クライアントが PUT する presigned URL
+}
+
+// RequestUpload は直接アップロード用の presigned PUT URL を発行し、予約レコードを作成する。
+// クライアントは UploadURL へ Content-Type を付けて PUT した後、CompleteUpload を呼ぶ。
+func (uc *MaterialUseCase) RequestUpload(ctx context.Context, in RequestUploadInput) (*RequestUploadOutput, error) {
+	fileName, err := materialFileName(in.FileName)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateMaterial(in.MimeType, in.Size); err != nil {
+		return nil, err
+	}
+	// subject の所有権確認
+	if _, err := uc.subjects.GetByIDAndUserID(ctx, in.SubjectID, in.UserID); err != nil {
+		return nil, err
+	}
+
+	fileID := uuid.New()
+	key := fmt.Sprintf("%s/%s/%s/%s", in.UserID, in.SubjectID, fileID, fileName)
+
+	uploadURL, err := uc.storage.GetPresignedPutURL(ctx, key, uploadURLExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("presign put url: %w", err)
+	}
+
+	upload := &domain.MaterialUpload{
+		ID:         uuid.New(),
+		FileID:     fileID,
+		SubjectID:  in.SubjectID,
+		UserID:     in.UserID,
+		FileName:   fileName,
+		StorageKey: key,
+		MimeType:   in.MimeType,
+		SizeBytes:  in.Size,
+		ExpiresAt:  time.Now().UTC().Add(uploadURLExpiry),
+	}
+	if err := uc.uploads.Create(ctx, upload); err != nil {
+		return nil, err
+	}
+
+	return &RequestUploadOutput{Upload: upload, UploadURL: uploadURL}, nil
+}
+
+// CompleteUpload はバケットに配置されたオブジェクトのサイズ・種別を検証し、
+// File と IngestJob を作成する。
+// 再開可能アップロードの場合は、オブジェクトがまだ無ければマルチパートアップロードを確定する。
+// 検証に失敗したオブジェクトは削除され、予約は破棄される。
+// 予約は教材の登録に成功した後で完了にするため、登録に失敗した場合は再試行できる。
+func (uc *MaterialUseCase) CompleteUpload(ctx context.Context, subjectID, uploadID, userID uuid.UUID) (*domain.File, error) {
+	upload, err := uc.uploads.GetByIDAndUserID(ctx, uploadID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.SubjectID != subjectID {
+		return nil, domain.ErrNotFound
+	}
+	if upload.IsCompleted() {
+		return nil, fmt.Errorf("upload already completed: %w", domain.ErrConflict)
+	}
+	// 前回の呼び出しで教材を登録した後、予約の完了だけを記録できなかった場合
+	registered, err := uc.registeredUploadFile(ctx, upload)
+	if err != nil {
+		return nil, err
+	}
+	if registered != nil {
+		if _, err := uc.uploads.MarkCompleted(ctx, upload.ID); err != nil {
+			return nil, err
+		}
+		return registered, nil
+	}
+
+	storagePath := uc.storage.StoragePath(upload.StorageKey)
+	info, err := uc.storage.Stat(ctx, storagePath)
+	if errors.Is(err, domain.ErrNotFound) && upload.IsResumable() {
+		// 前回の呼び出しで確定済みの場合はオブジェクトが存在するため、確定し直さない
+		if err := uc.finalizeMultipart(ctx, upload); err != nil {
+			return nil, err
+		}
+		info, err = uc.storage.Stat(ctx, storagePath)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, fmt.Errorf("object has not been uploaded: %w", domain.ErrInvalidInput)
+		}
+		return nil, fmt.Errorf("stat object: %w", err)
+	}
+
+	if err := uc.verifyUploadedObject(ctx, upload, storagePath, info); err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			uc.discardUpload(ctx, upload)
+		}
+		return nil, err
+	}
 
+	file := &domain.File{
+		ID:          upload.FileID,
+		SubjectID:   upload.SubjectID,
+		UserID:      upload.UserID,
+		Name:        upload.FileName,
+		StoragePath: storagePath,
+		MimeType:    upload.MimeType,
+		SizeBytes:   info.Size,
+		Status:      domain.FileStatusPending,
+		UploadedAt:  time.Now().UTC(),
+	}
+	// 二重 complete の競合は File ID の重複として検出する（domain.ErrConflict）
+	if err := uc.registerFile(ctx, file); err != nil {
+		return nil, err
+	}
+	if _, err := uc.uploads.MarkCompleted(ctx, upload.ID); err != nil {
+		// 教材は登録済み。再試行（registeredUploadFile）か期限切れ掃除で予約を片付ける
+		return nil, err
+	}
 	return file, nil
 }
 
+// registeredUploadFile は予約に対応する教材が登録済みであれば返す（未登録の場合は nil）。
+func (uc *MaterialUseCase) registeredUploadFile(ctx context.Context, upload *domain.MaterialUpload) (*domain.File, error) {
+	file, err := uc.files.GetByIDAndUserID(ctx, upload.FileID, upload.UserID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// CleanupExpiredUploads は期限切れのまま complete されなかった予約と
+// そのオブジェクトを削除し、削除件数を返す。
+// バックグラウンドで定期的に呼び出すことを想定する。
+func (uc *MaterialUseCase) CleanupExpiredUploads(ctx context.Context, now time.Time) (int, error) {
+	expired, err := uc.uploads.ListExpired(ctx, now.Add(-uploadCleanupGrace), uploadCleanupBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("list expired uploads: %w", err)
+	}
+	removed := 0
+	for _, u := range expired {
+		registered, err := uc.registeredUploadFile(ctx, u)
+		if err != nil {
+			slog.Warn("failed to look up uploaded material", "upload_id", u.ID, "error", err)
+			continue
+		}
+		// 教材として登録済みのオブジェクトは消さず、予約だけを削除する
+		if registered != nil {
+			if err := uc.uploads.Delete(ctx, u.ID); err != nil {
+				slog.Warn("failed to delete expired upload", "upload_id", u.ID, "error", err)
+				continue
+			}
+			removed++
+			continue
+		}
+		if err := uc.removeUploadObject(ctx, u); err != nil {
+			// オブジェクトが残っている可能性があるため予約は残し、次回再試行する
+			slog.Warn("failed to delete orphaned upload object",
+				"upload_id", u.ID,
+				"key", u.StorageKey,
+				"error", err,
+			)
+			continue
+		}
+		if err := uc.uploads.Delete(ctx, u.ID); err != nil {
+			slog.Warn("failed to delete expired upload", "upload_id", u.ID, "error", err)
+			continue
+		}
+		removed++
+	}
+	if removed > 0 {
+		slog.Info("expired uploads cleaned up", "count", removed)
+	}
+	return removed, nil
+}
+
+// discardUpload は検証に失敗したアップロードのオブジェクトと予約を削除する。
+func (uc *MaterialUseCase) discardUpload(ctx context.Context, upload *domain.MaterialUpload) {
+	if err := uc.removeUploadObject(ctx, upload); err != nil {
+		slog.Warn("storage delete failed", "key", upload.StorageKey, "error", err)
+		// 予約を残しておけば期限切れ掃除で再度削除される
+		return
+	}
+	if err := uc.uploads.Delete(ctx, upload.ID); err != nil {
+		slog.Warn("failed to delete rejected upload", "upload_id", upload.ID, "error", err)
+	}
+}
+
+// removeUploadObject は予約に紐づくストレージ上のデータを削除する。
+// 再開可能アップロードで未確定の場合はマルチパートアップロードを破棄する。
+func (uc *MaterialUseCase) removeUploadObject(ctx context.Context, upload *domain.MaterialUpload) error {
+	if upload.IsResumable() && upload.UploadedBytes < upload.SizeBytes {
+		return uc.multipart.AbortMultipartUpload(ctx, upload.StorageKey, *upload.MultipartUploadID)
+	}
+	return uc.storage.Delete(ctx, uc.storage.StoragePath(upload.StorageKey))
+}
+
+// ─── 再開可能アップロード（マルチパート） ───────────────────────────
+
+// CreateResumableUpload は再開可能アップロードを開始する。
+// クライアントは AppendUploadChunk でバイト範囲を順に送信し、
+// 中断した場合は GetUpload で受信済みオフセットを確認して再開する。
+// 全バイト受信後に CompleteUpload で確定する。
+func (uc *MaterialUseCase) CreateResumableUpload(ctx context.Context, in RequestUploadInput) (*domain.MaterialUpload, error) {
+	fileName, err := materialFileName(in.FileName)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateMaterial(in.MimeType, in.Size); err != nil {
+		return nil, err
+	}
+	// subject の所有権確認
+	if _, err := uc.subjects.GetByIDAndUserID(ctx, in.SubjectID, in.UserID); err != nil {
+		return nil, err
+	}
+
+	fileID := uuid.New()
+	key := fmt.Sprintf("%s/%s/%s/%s", in.UserID, in.SubjectID, fileID, fileName)
+
+	multipartID, err := uc.multipart.CreateMultipartUpload(ctx, key, in.MimeType)
+	if err != nil {
+		return nil, fmt.Errorf("create multipart upload: %w", err)
+	}
+
+	upload := &domain.MaterialUpload{
+		ID:                uuid.New(),
+		FileID:            fileID,
+		SubjectID:         in.SubjectID,
+		UserID:            in.UserID,
+		FileName:          fileName,
+		StorageKey:        key,
+		MimeType:          in.MimeType,
+		SizeBytes:         in.Size,
+		ExpiresAt:         time.Now().UTC().Add(resumableUploadTTL),
+		MultipartUploadID: &multipartID,
+	}
+	if err := uc.uploads.Create(ctx, upload); err != nil {
+		if abortErr := uc.multipart.AbortMultipartUpload(ctx, key, multipartID); abortErr != nil {
+			slog.Warn("failed to abort multipart upload", "key", key, "error", abortErr)
+		}
+		return nil, err
+	}
+	return upload, nil
+}
+
+// GetUpload はアップロード予約（受信済みオフセットを含む）を返す。
+func (uc *MaterialUseCase) GetUpload(ctx context.Context, subjectID, uploadID, userID uuid.UUID) (*domain.MaterialUpload, error) {
+	upload, err := uc.uploads.GetByIDAndUserID(ctx, uploadID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.SubjectID != subjectID {
+		return nil, domain.ErrNotFound
+	}
+	return upload, nil
+}
+
+// AppendUploadChunkInput は再開可能アップロードへのバイト範囲送信の入力値
+type AppendUploadChunkInput struct {
+	SubjectID uuid.UUID
+	UploadID  uuid.UUID
+	UserID    uuid.UUID
+	Offset    int64 // クライアントが送信するバイト範囲の先頭（受信済みオフセットと一致する必要がある）
+	Size      int64
+	Reader    io.Reader
+}
+
+// AppendUploadChunk は再開可能アップロードにバイト範囲を 1 パートとして追記する。
+// Offset が受信済みオフセットと一致しない場合は domain.ErrConflict を返す。
+// 最終パート以外は ports.MinMultipartPartSize 以上である必要がある。
+func (uc *MaterialUseCase) AppendUploadChunk(ctx context.Context, in AppendUploadChunkInput) (*domain.MaterialUpload, error) {
+	upload, err := uc.GetUpload(ctx, in.SubjectID, in.UploadID, in.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !upload.IsResumable() {
+		return nil, fmt.Errorf("upload is not resumable: %w", domain.ErrInvalidInput)
+	}
+	if upload.IsCompleted() {
+		return nil, fmt.Errorf("upload already completed: %w", domain.ErrConflict)
+	}
+	if upload.IsExpired(time.Now().UTC()) {
+		return nil, fmt.Errorf("upload expired: %w", domain.ErrNotFound)
+	}
+	if in.Offset != upload.UploadedBytes {
+		return nil, fmt.Errorf("offset %d does not match received offset %d: %w",
+			in.Offset, upload.UploadedBytes, domain.ErrConflict)
+	}
+	end := in.Offset + in.Size
+	if in.Size <= 0 || end > upload.SizeBytes {
+		return nil, fmt.Errorf("chunk exceeds declared size %d: %w", upload.SizeBytes, domain.ErrInvalidInput)
+	}
+	if end < upload.SizeBytes && in.Size < ports.MinMultipartPartSize {
+		return nil, fmt.Errorf("non-final chunk must be at least %d bytes: %w",
+			ports.MinMultipartPartSize, domain.ErrInvalidInput)
+	}
+
+	parts, err := uc.uploads.ListParts(ctx, upload.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list upload parts: %w", err)
+	}
+	partNumber := len(parts) + 1
+
+	etag, err := uc.multipart.UploadPart(ctx, upload.StorageKey, *upload.MultipartUploadID, partNumber, in.Reader, in.Size)
+	if err != nil {
+		return nil, fmt.Errorf("upload part %d: %w", partNumber, err)
+	}
+
+	expiresAt := time.Now().UTC().Add(resumableUploadTTL)
+	part := domain.MaterialUploadPart{PartNumber: partNumber, ETag: etag, SizeBytes: in.Size}
+	if err := uc.uploads.AddPart(ctx, upload.ID, in.Offset, part, expiresAt); err != nil {
+		return nil, err
+	}
+
+	upload.UploadedBytes = end
+	upload.ExpiresAt = expiresAt
+	return upload, nil
+}
+
+// AbortUpload はアップロード予約を破棄し、ストレージ上のデータを削除する。
+func (uc *MaterialUseCase) AbortUpload(ctx context.Context, subjectID, uploadID, userID uuid.UUID) error {
+	upload, err := uc.GetUpload(ctx, subjectID, uploadID, userID)
+	if err != nil {
+		return err
+	}
+	if upload.IsCompleted() {
+		return fmt.Errorf("upload already completed: %w", domain.ErrConflict)
+	}
+	if err := uc.removeUploadObject(ctx, upload); err != nil {
+		return fmt.Errorf("remove upload object: %w", err)
+	}
+	return uc.uploads.Delete(ctx, upload.ID)
+}
+
+// finalizeMultipart は全バイト受信済みの再開可能アップロードを確定する。
+func (uc *MaterialUseCase) finalizeMultipart(ctx context.Context, upload *domain.MaterialUpload) error {
+	if upload.UploadedBytes != upload.SizeBytes {
+		return fmt.Errorf("upload incomplete: received %d of %d bytes: %w",
+			upload.UploadedBytes, upload.SizeBytes, domain.ErrInvalidInput)
+	}
+	parts, err := uc.uploads.ListParts(ctx, upload.ID)
+	if err != nil {
+		return fmt.Errorf("list upload parts: %w", err)
+	}
+	completed := make([]ports.MultipartPart, len(parts))
+	for i, p := range parts {
+		completed[i] = ports.MultipartPart{PartNumber: p.PartNumber, ETag: p.ETag}
+	}
+	if err := uc.multipart.CompleteMultipartUpload(ctx, upload.StorageKey, *upload.MultipartUploadID, completed); err != nil {
+		return fmt.Errorf("complete multipart upload: %w", err)
+	}
+	return nil
+}
+
+// maxMaterialFileNameBytes は教材ファイル名の最大バイト長（一般的なファイルシステムの上限に合わせる）
+const maxMaterialFileNameBytes = 255
+
+// materialFileName はクライアントが申告したファイル名を検証し、ストレージキーに使える名前を返す。
+// 長すぎる名前・パス区切り（"/" と "\"）・".."・制御文字を含む名前は domain.ErrInvalidInput を返す。
+func materialFileName(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("file_name is required: %w", domain.ErrInvalidInput)
+	}
+	if reason := invalidFileNameReason(name); reason != "" {
+		return "", fmt.Errorf("invalid file_name %q: %s: %w", name, reason, domain.ErrInvalidInput)
+	}
+	return path.Base(name), nil
+}
+
+// invalidFileNameReason は name をファイル名として受け付けられない理由を返す。受け付けられる場合は空文字を返す。
+func invalidFileNameReason(name string) string {
+	switch {
+	case len(name) > maxMaterialFileNameBytes:
+		return fmt.Sprintf("name exceeds %d bytes", maxMaterialFileNameBytes)
+	case name == "." || strings.ContainsAny(name, `/\`) || strings.Contains(name, ".."):
+		return `name contains a path separator or ".."`
+	case strings.IndexFunc(name, unicode.IsControl) >= 0:
+		return "name contains control characters"
+	}
+	return ""
+}
+
+// validateMaterial は教材として受け付け可能な MIME タイプ・サイズかを検証する。
+func validateMaterial(mimeType string, size int64) error {
+	if _, ok := supportedMaterialTypes[mediaType(mimeType)]; !ok {
+		return fmt.Errorf("unsupported mime type %q: %w", mimeType, domain.ErrInvalidInput)
+	}
+	if size <= 0 || size > maxMaterialSizeBytes {
+		return fmt.Errorf("size must be between 1 and %d bytes: %w", maxMaterialSizeBytes, domain.ErrInvalidInput)
+	}
+	return nil
+}
+
+// verifyUploadedObject は実オブジェクトが予約時の申告と一致するかを検証する。
+// 種別は PUT 時の Content-Type ではなく、オブジェクトの先頭バイトから判定する。
+// 申告と一致しない場合は domain.ErrInvalidInput を返す。
+func (uc *MaterialUseCase) verifyUploadedObject(ctx context.Context, upload *domain.MaterialUpload, storagePath string, info *ports.ObjectInfo) error {
+	if info.Size != upload.SizeBytes {
+		return fmt.Errorf("uploaded size %d does not match declared size %d: %w",
+			info.Size, upload.SizeBytes, domain.ErrInvalidInput)
+	}
+	rc, err := uc.storage.Download(ctx, storagePath)
+	if err != nil {
+		return fmt.Errorf("download object: %w", err)
+	}
+	defer rc.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(rc, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("read object: %w", err)
+	}
+	if detected := mediaType(http.DetectContentType(head[:n])); detected != mediaType(upload.MimeType) {
+		return fmt.Errorf("uploaded content type %q does not match declared type %q: %w",
+			detected, upload.MimeType, domain.ErrInvalidInput)
+	}
+	return nil
+}
+
+// mediaType は Content-Type からパラメータを除いたメディアタイプを返す。
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mt
+}
+
 // Delete は教材ファイルをストレージと DB から削除する。
 func (uc *MaterialUseCase) Delete(ctx context.Context, fileID, userID uuid.UUID) error {
 	file, err := uc.files.GetByIDAndUserID(ctx, fileID, userID)
 	if err != nil {
 		return err
 	}
-	// ストレージから削除
+	// ストレージから削除（失敗して残ったオブジェクトは StorageGCUseCase が孤立オブジェクトとして回収する）
 	if err := uc.storage.Delete(ctx, file.StoragePath); err != nil {
 		slog.Warn("storage delete failed", "key", file.StoragePath, "error", err)
 	}
+	for page := 1; file.HasPagePreview(page); page++ {
+		if err := uc.storage.Delete(ctx, file.PagePreviewPath(page)); err != nil {
+			slog.Warn("preview delete failed", "key", file.PagePreviewPath(page), "error", err)
+		}
+	}
 	return uc.files.Delete(ctx, fileID, userID)
 }
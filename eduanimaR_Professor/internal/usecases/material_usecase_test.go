@@ -0,0 +1,1184 @@
+package usecases_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/japanese"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/testhelper"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/usecases"
+)
+
+// ─── テストヘルパー ────────────────────────────────────────────────
+
+// materialDeps は MaterialUseCase のテスト用依存一式。
+type materialDeps struct {
+	files     *testhelper.MockFileRepository
+	jobs      *testhelper.MockIngestJobRepository
+	uploads   *testhelper.MockMaterialUploadRepository
+	storage   *testhelper.MockObjectStorage
+	multipart *testhelper.MockMultipartStorage
+	publisher *testhelper.MockMessagePublisher
+	subjects  *testhelper.MockSubjectRepository
+	fetcher   *testhelper.MockDocumentFetcher
+}
+
+func newMaterialDeps() *materialDeps {
+	return &materialDeps{
+		files:     &testhelper.MockFileRepository{},
+		jobs:      &testhelper.MockIngestJobRepository{},
+		uploads:   &testhelper.MockMaterialUploadRepository{},
+		storage:   &testhelper.MockObjectStorage{},
+		multipart: &testhelper.MockMultipartStorage{},
+		publisher: &testhelper.MockMessagePublisher{},
+		subjects:  &testhelper.MockSubjectRepository{},
+		fetcher:   &testhelper.MockDocumentFetcher{},
+	}
+}
+
+// newMaterialUseCase はテスト用依存を注入した MaterialUseCase を返す。
+func newMaterialUseCase(d *materialDeps) *usecases.MaterialUseCase {
+	return usecases.NewMaterialUseCase(d.files, d.jobs, d.uploads, d.storage, d.multipart, d.publisher, d.subjects, d.fetcher)
+}
+
+// ─── RequestUpload ────────────────────────────────────────────────
+
+func TestMaterialUseCase_RequestUpload_Success(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+
+	d.subjects.On("GetByIDAndUserID", ctx, testhelper.FixtureSubjectID, testhelper.FixtureUserID).
+		Return(testhelper.NewSubject(), nil)
+	d.storage.On("GetPresignedPutURL", ctx, mock.AnythingOfType("string"), mock.AnythingOfType("time.Duration")).
+		Return("https://minio.local/presigned", nil)
+	d.uploads.On("Create", ctx, mock.AnythingOfType("*domain.MaterialUpload")).Return(nil)
+
+	uc := newMaterialUseCase(d)
+	out, err := uc.RequestUpload(ctx, usecases.RequestUploadInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		UserID:    testhelper.FixtureUserID,
+		FileName:  "lecture01.pdf",
+		MimeType:  "application/pdf",
+		Size:      2048,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://minio.local/presigned", out.UploadURL)
+	assert.Contains(t, out.Upload.StorageKey, out.Upload.FileID.String())
+	assert.Equal(t, int64(2048), out.Upload.SizeBytes)
+	d.uploads.AssertExpectations(t)
+	d.storage.AssertExpectations(t)
+}
+
+func TestMaterialUseCase_RequestUpload_UnsupportedType(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+
+	uc := newMaterialUseCase(d)
+	out, err := uc.RequestUpload(ctx, usecases.RequestUploadInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		UserID:    testhelper.FixtureUserID,
+		FileName:  "malware.exe",
+		MimeType:  "application/x-msdownload",
+		Size:      2048,
+	})
+
+	assert.Nil(t, out)
+	assert.True(t, errors.Is(err, domain.ErrInvalidInput))
+	d.storage.AssertNotCalled(t, "GetPresignedPutURL")
+	d.uploads.AssertNotCalled(t, "Create")
+}
+
+func TestMaterialUseCase_RequestUpload_InvalidFileName(t *testing.T) {
+	names := []string{
+		"../../other-user/subject/file.pdf",
+		"subject/file.pdf",
+		`..\file.pdf`,
+		"..",
+		".",
+		"lecture\x00.pdf",
+		"lecture\n01.pdf",
+		strings.Repeat("a", 252) + ".pdf", // 256 バイト
+	}
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			d := newMaterialDeps()
+
+			uc := newMaterialUseCase(d)
+			out, err := uc.RequestUpload(ctx, usecases.RequestUploadInput{
+				SubjectID: testhelper.FixtureSubjectID,
+				UserID:    testhelper.FixtureUserID,
+				FileName:  name,
+				MimeType:  "application/pdf",
+				Size:      2048,
+			})
+
+			assert.Nil(t, out)
+			assert.ErrorIs(t, err, domain.ErrInvalidInput)
+			d.storage.AssertNotCalled(t, "GetPresignedPutURL")
+			d.uploads.AssertNotCalled(t, "Create")
+		})
+	}
+}
+
+// ─── CompleteUpload ───────────────────────────────────────────────
+
+// testObjectPath は MockObjectStorage.StoragePath が返すストレージパス。
+func testObjectPath(key string) string {
+	return "minio://eduanima/" + key
+}
+
+// expectUploadedObject は予約のキーに本文 body のオブジェクトが配置されている状態をモックに設定する。
+// Content-Type は申告どおりの値を返す（種別の検証は本文で行う）。
+func expectUploadedObject(ctx context.Context, d *materialDeps, upload *domain.MaterialUpload, body string) {
+	path := testObjectPath(upload.StorageKey)
+	d.storage.On("StoragePath", upload.StorageKey).Return(path)
+	d.storage.On("Stat", ctx, path).Return(&ports.ObjectInfo{
+		Key:         upload.StorageKey,
+		Size:        int64(len(body)),
+		ContentType: upload.MimeType,
+	}, nil)
+	d.storage.On("Download", ctx, path).Return(io.NopCloser(strings.NewReader(body)), nil).Once()
+}
+
+// pdfBody は size バイトの PDF らしい本文を返す。
+func pdfBody(size int64) string {
+	return "%PDF-1.7\n" + strings.Repeat("x", int(size)-len("%PDF-1.7\n"))
+}
+
+func TestMaterialUseCase_CompleteUpload_Success(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	upload := testhelper.NewMaterialUpload()
+
+	d.uploads.On("GetByIDAndUserID", ctx, upload.ID, upload.UserID).Return(upload, nil)
+	d.files.On("GetByIDAndUserID", ctx, upload.FileID, upload.UserID).Return(nil, domain.ErrNotFound)
+	expectUploadedObject(ctx, d, upload, pdfBody(upload.SizeBytes))
+	d.files.On("Create", ctx, mock.AnythingOfType("*domain.File")).Return(nil)
+	d.jobs.On("Create", ctx, mock.AnythingOfType("*domain.IngestJob")).Return(nil)
+	d.publisher.On("PublishIngestJob", ctx, mock.AnythingOfType("ports.IngestMessage")).Return(nil)
+	d.uploads.On("MarkCompleted", ctx, upload.ID).Return(upload, nil)
+
+	uc := newMaterialUseCase(d)
+	file, err := uc.CompleteUpload(ctx, upload.SubjectID, upload.ID, upload.UserID)
+
+	require.NoError(t, err)
+	assert.Equal(t, upload.FileID, file.ID)
+	assert.Equal(t, testObjectPath(upload.StorageKey), file.StoragePath)
+	assert.Equal(t, domain.FileStatusPending, file.Status)
+	d.uploads.AssertExpectations(t)
+	d.files.AssertExpectations(t)
+	d.jobs.AssertExpectations(t)
+	d.publisher.AssertExpectations(t)
+}
+
+func TestMaterialUseCase_CompleteUpload_SizeMismatch_DiscardsObject(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	upload := testhelper.NewMaterialUpload()
+
+	d.uploads.On("GetByIDAndUserID", ctx, upload.ID, upload.UserID).Return(upload, nil)
+	d.files.On("GetByIDAndUserID", ctx, upload.FileID, upload.UserID).Return(nil, domain.ErrNotFound)
+	d.storage.On("StoragePath", upload.StorageKey).Return(testObjectPath(upload.StorageKey))
+	d.storage.On("Stat", ctx, testObjectPath(upload.StorageKey)).Return(&ports.ObjectInfo{
+		Key:         upload.StorageKey,
+		Size:        upload.SizeBytes * 10,
+		ContentType: "application/pdf",
+	}, nil)
+	d.storage.On("Delete", ctx, testObjectPath(upload.StorageKey)).Return(nil)
+	d.uploads.On("Delete", ctx, upload.ID).Return(nil)
+
+	uc := newMaterialUseCase(d)
+	file, err := uc.CompleteUpload(ctx, upload.SubjectID, upload.ID, upload.UserID)
+
+	assert.Nil(t, file)
+	assert.True(t, errors.Is(err, domain.ErrInvalidInput))
+	d.storage.AssertExpectations(t)
+	d.uploads.AssertExpectations(t)
+	d.files.AssertNotCalled(t, "Create")
+}
+
+func TestMaterialUseCase_CompleteUpload_ContentMismatch_DiscardsObject(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	// PDF と申告し、PUT の Content-Type も application/pdf だが中身は実行ファイル
+	body := "MZ\x90\x00" + strings.Repeat("\x00", 60)
+	upload := testhelper.NewMaterialUpload(func(u *domain.MaterialUpload) {
+		u.SizeBytes = int64(len(body))
+	})
+
+	d.uploads.On("GetByIDAndUserID", ctx, upload.ID, upload.UserID).Return(upload, nil)
+	d.files.On("GetByIDAndUserID", ctx, upload.FileID, upload.UserID).Return(nil, domain.ErrNotFound)
+	expectUploadedObject(ctx, d, upload, body)
+	d.storage.On("Delete", ctx, testObjectPath(upload.StorageKey)).Return(nil)
+	d.uploads.On("Delete", ctx, upload.ID).Return(nil)
+
+	uc := newMaterialUseCase(d)
+	file, err := uc.CompleteUpload(ctx, upload.SubjectID, upload.ID, upload.UserID)
+
+	assert.Nil(t, file)
+	assert.True(t, errors.Is(err, domain.ErrInvalidInput))
+	d.storage.AssertExpectations(t)
+	d.uploads.AssertExpectations(t)
+	d.uploads.AssertNotCalled(t, "MarkCompleted", mock.Anything, mock.Anything)
+	d.files.AssertNotCalled(t, "Create")
+}
+
+func TestMaterialUseCase_CompleteUpload_ObjectMissing(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	upload := testhelper.NewMaterialUpload()
+
+	d.uploads.On("GetByIDAndUserID", ctx, upload.ID, upload.UserID).Return(upload, nil)
+	d.files.On("GetByIDAndUserID", ctx, upload.FileID, upload.UserID).Return(nil, domain.ErrNotFound)
+	d.storage.On("StoragePath", upload.StorageKey).Return(testObjectPath(upload.StorageKey))
+	d.storage.On("Stat", ctx, testObjectPath(upload.StorageKey)).Return((*ports.ObjectInfo)(nil), domain.ErrNotFound)
+
+	uc := newMaterialUseCase(d)
+	file, err := uc.CompleteUpload(ctx, upload.SubjectID, upload.ID, upload.UserID)
+
+	assert.Nil(t, file)
+	assert.True(t, errors.Is(err, domain.ErrInvalidInput))
+	d.uploads.AssertNotCalled(t, "MarkCompleted")
+	d.storage.AssertNotCalled(t, "Delete")
+}
+
+func TestMaterialUseCase_CompleteUpload_AlreadyCompleted(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	completedAt := time.Date(2026, 1, 1, 0, 5, 0, 0, time.UTC)
+	upload := testhelper.NewMaterialUpload(func(u *domain.MaterialUpload) {
+		u.CompletedAt = &completedAt
+	})
+
+	d.uploads.On("GetByIDAndUserID", ctx, upload.ID, upload.UserID).Return(upload, nil)
+
+	uc := newMaterialUseCase(d)
+	file, err := uc.CompleteUpload(ctx, upload.SubjectID, upload.ID, upload.UserID)
+
+	assert.Nil(t, file)
+	assert.True(t, errors.Is(err, domain.ErrConflict))
+	d.storage.AssertNotCalled(t, "Stat")
+}
+
+func TestMaterialUseCase_CompleteUpload_RegisterFails_RetrySucceeds(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	upload := testhelper.NewMaterialUpload()
+
+	d.uploads.On("GetByIDAndUserID", ctx, upload.ID, upload.UserID).Return(upload, nil)
+	d.files.On("GetByIDAndUserID", ctx, upload.FileID, upload.UserID).Return(nil, domain.ErrNotFound)
+	expectUploadedObject(ctx, d, upload, pdfBody(upload.SizeBytes))
+	d.storage.On("Download", ctx, testObjectPath(upload.StorageKey)).
+		Return(io.NopCloser(strings.NewReader(pdfBody(upload.SizeBytes))), nil).Once()
+	d.files.On("Create", ctx, mock.AnythingOfType("*domain.File")).Return(nil)
+	// 1 回目はジョブの作成に失敗し、登録した教材を取り消す
+	d.jobs.On("Create", ctx, mock.AnythingOfType("*domain.IngestJob")).Return(errors.New("db unavailable")).Once()
+	d.files.On("Delete", ctx, upload.FileID, upload.UserID).Return(nil).Once()
+	d.jobs.On("Create", ctx, mock.AnythingOfType("*domain.IngestJob")).Return(nil).Once()
+	d.publisher.On("PublishIngestJob", ctx, mock.AnythingOfType("ports.IngestMessage")).Return(nil)
+	d.uploads.On("MarkCompleted", ctx, upload.ID).Return(upload, nil).Once()
+
+	uc := newMaterialUseCase(d)
+	file, err := uc.CompleteUpload(ctx, upload.SubjectID, upload.ID, upload.UserID)
+	require.Error(t, err)
+	assert.Nil(t, file)
+	// 予約は完了になっておらず、オブジェクトも残っている
+	d.uploads.AssertNotCalled(t, "MarkCompleted", mock.Anything, mock.Anything)
+	d.storage.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+
+	file, err = uc.CompleteUpload(ctx, upload.SubjectID, upload.ID, upload.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, upload.FileID, file.ID)
+	d.files.AssertNumberOfCalls(t, "Create", 2)
+	d.uploads.AssertExpectations(t)
+	d.files.AssertExpectations(t)
+	d.jobs.AssertExpectations(t)
+}
+
+func TestMaterialUseCase_CompleteUpload_AlreadyRegistered_MarksCompleted(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	upload := testhelper.NewMaterialUpload()
+	// 前回の呼び出しで教材を登録したが、予約の完了を記録できなかった
+	registered := testhelper.NewFile(domain.FileStatusPending)
+	registered.ID = upload.FileID
+
+	d.uploads.On("GetByIDAndUserID", ctx, upload.ID, upload.UserID).Return(upload, nil)
+	d.files.On("GetByIDAndUserID", ctx, upload.FileID, upload.UserID).Return(registered, nil)
+	d.uploads.On("MarkCompleted", ctx, upload.ID).Return(upload, nil)
+
+	uc := newMaterialUseCase(d)
+	file, err := uc.CompleteUpload(ctx, upload.SubjectID, upload.ID, upload.UserID)
+
+	require.NoError(t, err)
+	assert.Equal(t, registered, file)
+	d.uploads.AssertExpectations(t)
+	d.files.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	d.jobs.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	d.storage.AssertNotCalled(t, "Stat", mock.Anything, mock.Anything)
+}
+
+// ─── CleanupExpiredUploads ────────────────────────────────────────
+
+func TestMaterialUseCase_CleanupExpiredUploads(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	ok := testhelper.NewMaterialUpload()
+	failing := testhelper.NewMaterialUpload(func(u *domain.MaterialUpload) {
+		u.ID = testhelper.FixtureJobID
+		u.FileID = uuid.New()
+		u.StorageKey = "user/subject/other/broken.pdf"
+	})
+
+	d.uploads.On("ListExpired", ctx, mock.AnythingOfType("time.Time"), mock.AnythingOfType("int")).
+		Return([]*domain.MaterialUpload{ok, failing}, nil)
+	d.files.On("GetByIDAndUserID", ctx, mock.Anything, mock.Anything).Return(nil, domain.ErrNotFound)
+	d.storage.On("StoragePath", ok.StorageKey).Return(testObjectPath(ok.StorageKey))
+	d.storage.On("StoragePath", failing.StorageKey).Return(testObjectPath(failing.StorageKey))
+	d.storage.On("Delete", ctx, testObjectPath(ok.StorageKey)).Return(nil)
+	d.storage.On("Delete", ctx, testObjectPath(failing.StorageKey)).Return(errors.New("storage unavailable"))
+	d.uploads.On("Delete", ctx, ok.ID).Return(nil)
+
+	uc := newMaterialUseCase(d)
+	removed, err := uc.CleanupExpiredUploads(ctx, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	// ストレージ削除に失敗した予約は次回再試行のため残す
+	d.uploads.AssertNotCalled(t, "Delete", ctx, failing.ID)
+	d.storage.AssertExpectations(t)
+}
+
+func TestMaterialUseCase_CleanupExpiredUploads_KeepsRegisteredObject(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	// 教材の登録後に予約の完了を記録できなかった予約
+	upload := testhelper.NewMaterialUpload()
+	registered := testhelper.NewFile(domain.FileStatusPending)
+	registered.ID = upload.FileID
+
+	d.uploads.On("ListExpired", ctx, mock.AnythingOfType("time.Time"), mock.AnythingOfType("int")).
+		Return([]*domain.MaterialUpload{upload}, nil)
+	d.files.On("GetByIDAndUserID", ctx, upload.FileID, upload.UserID).Return(registered, nil)
+	d.uploads.On("Delete", ctx, upload.ID).Return(nil)
+
+	uc := newMaterialUseCase(d)
+	removed, err := uc.CleanupExpiredUploads(ctx, now)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	d.uploads.AssertExpectations(t)
+	d.storage.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+// ─── 再開可能アップロード ─────────────────────────────────────────
+
+// newResumableUpload は受信済みバイト数を指定した再開可能アップロード予約を返す。
+func newResumableUpload(size, uploaded int64) *domain.MaterialUpload {
+	multipartID := "multipart-1"
+	return testhelper.NewMaterialUpload(func(u *domain.MaterialUpload) {
+		u.MultipartUploadID = &multipartID
+		u.SizeBytes = size
+		u.UploadedBytes = uploaded
+		u.ExpiresAt = time.Now().UTC().Add(time.Hour)
+	})
+}
+
+func TestMaterialUseCase_CreateResumableUpload_InvalidFileName(t *testing.T) {
+	for _, name := range []string{"../../other-user/subject/file.pdf", `sub\file.pdf`, "lecture\t01.pdf"} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			d := newMaterialDeps()
+
+			uc := newMaterialUseCase(d)
+			upload, err := uc.CreateResumableUpload(ctx, usecases.RequestUploadInput{
+				SubjectID: testhelper.FixtureSubjectID,
+				UserID:    testhelper.FixtureUserID,
+				FileName:  name,
+				MimeType:  "application/pdf",
+				Size:      2048,
+			})
+
+			assert.Nil(t, upload)
+			assert.ErrorIs(t, err, domain.ErrInvalidInput)
+			d.multipart.AssertNotCalled(t, "CreateMultipartUpload")
+			d.uploads.AssertNotCalled(t, "Create")
+		})
+	}
+}
+
+func TestMaterialUseCase_AppendUploadChunk_Success(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	upload := newResumableUpload(ports.MinMultipartPartSize+100, ports.MinMultipartPartSize)
+	body := strings.NewReader(strings.Repeat("x", 100))
+
+	d.uploads.On("GetByIDAndUserID", ctx, upload.ID, upload.UserID).Return(upload, nil)
+	d.uploads.On("ListParts", ctx, upload.ID).Return([]domain.MaterialUploadPart{
+		{PartNumber: 1, ETag: "etag-1", SizeBytes: ports.MinMultipartPartSize},
+	}, nil)
+	d.multipart.On("UploadPart", ctx, upload.StorageKey, "multipart-1", 2, body, int64(100)).
+		Return("etag-2", nil)
+	d.uploads.On("AddPart", ctx, upload.ID, int64(ports.MinMultipartPartSize),
+		domain.MaterialUploadPart{PartNumber: 2, ETag: "etag-2", SizeBytes: 100},
+		mock.AnythingOfType("time.Time")).Return(nil)
+
+	uc := newMaterialUseCase(d)
+	got, err := uc.AppendUploadChunk(ctx, usecases.AppendUploadChunkInput{
+		SubjectID: upload.SubjectID,
+		UploadID:  upload.ID,
+		UserID:    upload.UserID,
+		Offset:    ports.MinMultipartPartSize,
+		Size:      100,
+		Reader:    body,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, upload.SizeBytes, got.UploadedBytes)
+	d.multipart.AssertExpectations(t)
+	d.uploads.AssertExpectations(t)
+}
+
+func TestMaterialUseCase_AppendUploadChunk_OffsetMismatch(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	upload := newResumableUpload(ports.MinMultipartPartSize*2, ports.MinMultipartPartSize)
+
+	d.uploads.On("GetByIDAndUserID", ctx, upload.ID, upload.UserID).Return(upload, nil)
+
+	uc := newMaterialUseCase(d)
+	got, err := uc.AppendUploadChunk(ctx, usecases.AppendUploadChunkInput{
+		SubjectID: upload.SubjectID,
+		UploadID:  upload.ID,
+		UserID:    upload.UserID,
+		Offset:    0,
+		Size:      ports.MinMultipartPartSize,
+		Reader:    strings.NewReader(""),
+	})
+
+	assert.Nil(t, got)
+	assert.True(t, errors.Is(err, domain.ErrConflict))
+	d.multipart.AssertNotCalled(t, "UploadPart")
+}
+
+func TestMaterialUseCase_AppendUploadChunk_NonFinalPartTooSmall(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	upload := newResumableUpload(ports.MinMultipartPartSize*2, 0)
+
+	d.uploads.On("GetByIDAndUserID", ctx, upload.ID, upload.UserID).Return(upload, nil)
+
+	uc := newMaterialUseCase(d)
+	got, err := uc.AppendUploadChunk(ctx, usecases.AppendUploadChunkInput{
+		SubjectID: upload.SubjectID,
+		UploadID:  upload.ID,
+		UserID:    upload.UserID,
+		Offset:    0,
+		Size:      1024,
+		Reader:    strings.NewReader(strings.Repeat("x", 1024)),
+	})
+
+	assert.Nil(t, got)
+	assert.True(t, errors.Is(err, domain.ErrInvalidInput))
+	d.multipart.AssertNotCalled(t, "UploadPart")
+}
+
+func TestMaterialUseCase_CompleteUpload_Resumable(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	upload := newResumableUpload(1024, 1024)
+
+	d.uploads.On("GetByIDAndUserID", ctx, upload.ID, upload.UserID).Return(upload, nil)
+	d.files.On("GetByIDAndUserID", ctx, upload.FileID, upload.UserID).Return(nil, domain.ErrNotFound)
+	d.uploads.On("ListParts", ctx, upload.ID).Return([]domain.MaterialUploadPart{
+		{PartNumber: 1, ETag: "etag-1", SizeBytes: 1024},
+	}, nil)
+	d.multipart.On("CompleteMultipartUpload", ctx, upload.StorageKey, "multipart-1",
+		[]ports.MultipartPart{{PartNumber: 1, ETag: "etag-1"}}).Return(nil)
+	// 確定前はオブジェクトが存在しない
+	d.storage.On("Stat", ctx, testObjectPath(upload.StorageKey)).Return((*ports.ObjectInfo)(nil), domain.ErrNotFound).Once()
+	expectUploadedObject(ctx, d, upload, pdfBody(1024))
+	d.uploads.On("MarkCompleted", ctx, upload.ID).Return(upload, nil)
+	d.files.On("Create", ctx, mock.AnythingOfType("*domain.File")).Return(nil)
+	d.jobs.On("Create", ctx, mock.AnythingOfType("*domain.IngestJob")).Return(nil)
+	d.publisher.On("PublishIngestJob", ctx, mock.AnythingOfType("ports.IngestMessage")).Return(nil)
+
+	uc := newMaterialUseCase(d)
+	file, err := uc.CompleteUpload(ctx, upload.SubjectID, upload.ID, upload.UserID)
+
+	require.NoError(t, err)
+	assert.Equal(t, upload.FileID, file.ID)
+	d.multipart.AssertExpectations(t)
+}
+
+func TestMaterialUseCase_CompleteUpload_ResumableRetry_SkipsFinalize(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	upload := newResumableUpload(1024, 1024)
+
+	d.uploads.On("GetByIDAndUserID", ctx, upload.ID, upload.UserID).Return(upload, nil)
+	d.files.On("GetByIDAndUserID", ctx, upload.FileID, upload.UserID).Return(nil, domain.ErrNotFound)
+	d.uploads.On("ListParts", ctx, upload.ID).Return([]domain.MaterialUploadPart{
+		{PartNumber: 1, ETag: "etag-1", SizeBytes: 1024},
+	}, nil)
+	// マルチパートアップロードは 1 回目の呼び出しでだけ確定できる
+	d.multipart.On("CompleteMultipartUpload", ctx, upload.StorageKey, "multipart-1",
+		[]ports.MultipartPart{{PartNumber: 1, ETag: "etag-1"}}).Return(nil).Once()
+	d.storage.On("Stat", ctx, testObjectPath(upload.StorageKey)).Return((*ports.ObjectInfo)(nil), domain.ErrNotFound).Once()
+	expectUploadedObject(ctx, d, upload, pdfBody(1024))
+	d.storage.On("Download", ctx, testObjectPath(upload.StorageKey)).
+		Return(io.NopCloser(strings.NewReader(pdfBody(1024))), nil).Once()
+	d.files.On("Create", ctx, mock.AnythingOfType("*domain.File")).Return(nil)
+	// 1 回目は確定後の登録に失敗する
+	d.jobs.On("Create", ctx, mock.AnythingOfType("*domain.IngestJob")).Return(errors.New("db unavailable")).Once()
+	d.files.On("Delete", ctx, upload.FileID, upload.UserID).Return(nil).Once()
+	d.jobs.On("Create", ctx, mock.AnythingOfType("*domain.IngestJob")).Return(nil).Once()
+	d.publisher.On("PublishIngestJob", ctx, mock.AnythingOfType("ports.IngestMessage")).Return(nil)
+	d.uploads.On("MarkCompleted", ctx, upload.ID).Return(upload, nil).Once()
+
+	uc := newMaterialUseCase(d)
+	_, err := uc.CompleteUpload(ctx, upload.SubjectID, upload.ID, upload.UserID)
+	require.Error(t, err)
+
+	file, err := uc.CompleteUpload(ctx, upload.SubjectID, upload.ID, upload.UserID)
+
+	require.NoError(t, err)
+	assert.Equal(t, upload.FileID, file.ID)
+	d.multipart.AssertNumberOfCalls(t, "CompleteMultipartUpload", 1)
+	d.multipart.AssertNotCalled(t, "AbortMultipartUpload", mock.Anything, mock.Anything, mock.Anything)
+	d.uploads.AssertExpectations(t)
+}
+
+func TestMaterialUseCase_CompleteUpload_ResumableIncomplete(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	upload := newResumableUpload(ports.MinMultipartPartSize*2, ports.MinMultipartPartSize)
+
+	d.uploads.On("GetByIDAndUserID", ctx, upload.ID, upload.UserID).Return(upload, nil)
+	d.files.On("GetByIDAndUserID", ctx, upload.FileID, upload.UserID).Return(nil, domain.ErrNotFound)
+	d.storage.On("StoragePath", upload.StorageKey).Return(testObjectPath(upload.StorageKey))
+	d.storage.On("Stat", ctx, testObjectPath(upload.StorageKey)).Return((*ports.ObjectInfo)(nil), domain.ErrNotFound)
+
+	uc := newMaterialUseCase(d)
+	file, err := uc.CompleteUpload(ctx, upload.SubjectID, upload.ID, upload.UserID)
+
+	assert.Nil(t, file)
+	assert.True(t, errors.Is(err, domain.ErrInvalidInput))
+	d.multipart.AssertNotCalled(t, "CompleteMultipartUpload")
+}
+
+// ─── ImportZip ────────────────────────────────────────────────────
+
+// buildZip は name → 内容 の ZIP をメモリ上に作成する。
+func buildZip(t *testing.T, entries map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range entries {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return bytes.NewReader(buf.Bytes())
+}
+
+// zipResultByPath は取り込み結果をパスで引けるようにする。
+func zipResultByPath(out *usecases.ImportZipOutput) map[string]usecases.ZipEntryResult {
+	m := make(map[string]usecases.ZipEntryResult, len(out.Entries))
+	for _, e := range out.Entries {
+		m[e.Path] = e
+	}
+	return m
+}
+
+func TestMaterialUseCase_ImportZip_Success(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	zr := buildZip(t, map[string]string{
+		"第1回/スライド/intro.pdf":   "%PDF-1.7 intro",
+		"board.JPG":            "jpeg-bytes",
+		"第1回/notes.docx":       "docx-bytes",
+		"__MACOSX/._intro.pdf": "resource-fork",
+		"第1回/.DS_Store":        "finder",
+		"第1回/スライド/empty.png":   "",
+	})
+
+	d.subjects.On("GetByIDAndUserID", ctx, testhelper.FixtureSubjectID, testhelper.FixtureUserID).
+		Return(testhelper.NewSubject(), nil)
+	d.storage.On("Upload", ctx, mock.AnythingOfType("string"), mock.Anything, mock.AnythingOfType("int64"), mock.AnythingOfType("string")).
+		Return("stored-key", nil).Twice()
+	d.files.On("Create", ctx, mock.AnythingOfType("*domain.File")).Return(nil).Twice()
+	d.jobs.On("Create", ctx, mock.AnythingOfType("*domain.IngestJob")).Return(nil).Twice()
+	d.publisher.On("PublishIngestJob", ctx, mock.AnythingOfType("ports.IngestMessage")).Return(nil).Twice()
+
+	uc := newMaterialUseCase(d)
+	out, err := uc.ImportZip(ctx, usecases.ImportZipInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		UserID:    testhelper.FixtureUserID,
+		Reader:    zr,
+		Size:      zr.Size(),
+	})
+
+	require.NoError(t, err)
+	// OS のメタデータエントリは結果に含めない
+	require.Len(t, out.Entries, 4)
+	results := zipResultByPath(out)
+
+	pdf := results["第1回/スライド/intro.pdf"]
+	assert.Equal(t, usecases.ZipEntryImported, pdf.Status)
+	require.NotNil(t, pdf.File)
+	assert.Equal(t, "intro.pdf", pdf.File.Name)
+	assert.Equal(t, "application/pdf", pdf.File.MimeType)
+	require.NotNil(t, pdf.File.FolderPath)
+	assert.Equal(t, "第1回/スライド", *pdf.File.FolderPath)
+
+	jpg := results["board.JPG"]
+	assert.Equal(t, usecases.ZipEntryImported, jpg.Status)
+	assert.Equal(t, "image/jpeg", jpg.File.MimeType)
+	assert.Nil(t, jpg.File.FolderPath)
+
+	assert.Equal(t, usecases.ZipEntryUnsupported, results["第1回/notes.docx"].Status)
+	assert.Equal(t, usecases.ZipEntryUnsupported, results["第1回/スライド/empty.png"].Status)
+	d.storage.AssertExpectations(t)
+	d.files.AssertExpectations(t)
+	d.jobs.AssertExpectations(t)
+}
+
+func TestMaterialUseCase_ImportZip_OversizedEntry(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+
+	// ヘッダー上のサイズのみ巨大なエントリ（展開前に判定されるため本体は読まれない）
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	_, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "huge.pdf",
+		Method:             zip.Store,
+		UncompressedSize64: 1 << 30,
+	})
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	zr := bytes.NewReader(buf.Bytes())
+
+	d.subjects.On("GetByIDAndUserID", ctx, testhelper.FixtureSubjectID, testhelper.FixtureUserID).
+		Return(testhelper.NewSubject(), nil)
+
+	uc := newMaterialUseCase(d)
+	out, err := uc.ImportZip(ctx, usecases.ImportZipInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		UserID:    testhelper.FixtureUserID,
+		Reader:    zr,
+		Size:      zr.Size(),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, out.Entries, 1)
+	assert.Equal(t, usecases.ZipEntryTooLarge, out.Entries[0].Status)
+	d.storage.AssertNotCalled(t, "Upload")
+	d.files.AssertNotCalled(t, "Create")
+}
+
+func TestMaterialUseCase_ImportZip_InvalidArchive(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	zr := bytes.NewReader([]byte("not a zip"))
+
+	d.subjects.On("GetByIDAndUserID", ctx, testhelper.FixtureSubjectID, testhelper.FixtureUserID).
+		Return(testhelper.NewSubject(), nil)
+
+	uc := newMaterialUseCase(d)
+	out, err := uc.ImportZip(ctx, usecases.ImportZipInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		UserID:    testhelper.FixtureUserID,
+		Reader:    zr,
+		Size:      zr.Size(),
+	})
+
+	assert.Nil(t, out)
+	assert.True(t, errors.Is(err, domain.ErrInvalidInput))
+}
+
+func TestMaterialUseCase_ImportZip_ShiftJISEntryName(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+
+	// 日本語 Windows の「圧縮フォルダー」は UTF-8 フラグなしの Shift_JIS 名を書き込む
+	sjisName, err := japanese.ShiftJIS.NewEncoder().String("資料/講義.pdf")
+	require.NoError(t, err)
+	zr := buildZip(t, map[string]string{sjisName: "%PDF-1.7"})
+
+	d.subjects.On("GetByIDAndUserID", ctx, testhelper.FixtureSubjectID, testhelper.FixtureUserID).
+		Return(testhelper.NewSubject(), nil)
+	d.storage.On("Upload", ctx, mock.MatchedBy(func(key string) bool {
+		return strings.HasSuffix(key, "/講義.pdf")
+	}), mock.Anything, int64(8), "application/pdf").Return("stored-key", nil)
+	d.files.On("Create", ctx, mock.AnythingOfType("*domain.File")).Return(nil)
+	d.jobs.On("Create", ctx, mock.AnythingOfType("*domain.IngestJob")).Return(nil)
+	d.publisher.On("PublishIngestJob", ctx, mock.AnythingOfType("ports.IngestMessage")).Return(nil)
+
+	uc := newMaterialUseCase(d)
+	out, err := uc.ImportZip(ctx, usecases.ImportZipInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		UserID:    testhelper.FixtureUserID,
+		Reader:    zr,
+		Size:      zr.Size(),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, out.Entries, 1)
+	assert.Equal(t, "資料/講義.pdf", out.Entries[0].Path)
+	assert.Equal(t, "資料", *out.Entries[0].File.FolderPath)
+	d.storage.AssertExpectations(t)
+}
+
+func TestMaterialUseCase_ImportZip_InvalidEntryName(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	longName := strings.Repeat("あ", 85) + ".pdf" // 259 バイト
+	zr := buildZip(t, map[string]string{
+		"第1回/lecture\x01.pdf": "%PDF-1.7 control",
+		"第1回/" + longName:     "%PDF-1.7 long",
+		"第1回/a..b.pdf":        "%PDF-1.7 dots",
+	})
+
+	d.subjects.On("GetByIDAndUserID", ctx, testhelper.FixtureSubjectID, testhelper.FixtureUserID).
+		Return(testhelper.NewSubject(), nil)
+
+	uc := newMaterialUseCase(d)
+	out, err := uc.ImportZip(ctx, usecases.ImportZipInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		UserID:    testhelper.FixtureUserID,
+		Reader:    zr,
+		Size:      zr.Size(),
+	})
+
+	require.NoError(t, err)
+	require.Len(t, out.Entries, 3)
+	results := zipResultByPath(out)
+
+	control := results["第1回/lecture\x01.pdf"]
+	assert.Equal(t, usecases.ZipEntryInvalidName, control.Status)
+	assert.Equal(t, "name contains control characters", control.Reason)
+	long := results["第1回/"+longName]
+	assert.Equal(t, usecases.ZipEntryInvalidName, long.Status)
+	assert.Equal(t, "name exceeds 255 bytes", long.Reason)
+	assert.Equal(t, usecases.ZipEntryInvalidName, results["第1回/a..b.pdf"].Status)
+	d.storage.AssertNotCalled(t, "Upload")
+	d.files.AssertNotCalled(t, "Create")
+}
+
+// ─── ImportURL / RefetchURL ───────────────────────────────────────
+
+const testSourceURL = "https://univ.example.ac.jp/lecture/handout01.pdf"
+
+// newURLFile は URL から取り込み済みの教材を返す。
+func newURLFile(content string) *domain.File {
+	f := testhelper.NewFile(domain.FileStatusReady)
+	sourceURL := testSourceURL
+	sum := sha256.Sum256([]byte(content))
+	hash := hex.EncodeToString(sum[:])
+	f.Name = "handout01.pdf"
+	f.SourceURL = &sourceURL
+	f.ContentHash = &hash
+	return f
+}
+
+func TestMaterialUseCase_ImportURL_Success(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	body := []byte("%PDF-1.7 handout")
+
+	d.subjects.On("GetByIDAndUserID", ctx, testhelper.FixtureSubjectID, testhelper.FixtureUserID).
+		Return(testhelper.NewSubject(), nil)
+	d.fetcher.On("Fetch", ctx, testSourceURL, mock.AnythingOfType("int64")).Return(&ports.FetchedDocument{
+		URL:         testSourceURL,
+		FileName:    "handout01.pdf",
+		ContentType: "application/pdf",
+		Body:        body,
+	}, nil)
+	d.storage.On("Upload", ctx, mock.AnythingOfType("string"), mock.Anything, int64(len(body)), "application/pdf").
+		Return("stored-key", nil)
+	d.files.On("Create", ctx, mock.AnythingOfType("*domain.File")).Return(nil)
+	d.jobs.On("Create", ctx, mock.AnythingOfType("*domain.IngestJob")).Return(nil)
+	d.publisher.On("PublishIngestJob", ctx, mock.AnythingOfType("ports.IngestMessage")).Return(nil)
+
+	uc := newMaterialUseCase(d)
+	file, err := uc.ImportURL(ctx, usecases.ImportURLInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		UserID:    testhelper.FixtureUserID,
+		URL:       testSourceURL,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "handout01.pdf", file.Name)
+	require.NotNil(t, file.SourceURL)
+	assert.Equal(t, testSourceURL, *file.SourceURL)
+	require.NotNil(t, file.ContentHash)
+	assert.Len(t, *file.ContentHash, 64)
+	d.storage.AssertExpectations(t)
+	d.jobs.AssertExpectations(t)
+}
+
+func TestMaterialUseCase_ImportURL_FileName(t *testing.T) {
+	tests := []struct {
+		name        string
+		fileName    string
+		contentType string
+		want        string
+	}{
+		{"keeps matching extension", "handout01.pdf", "application/pdf", "handout01.pdf"},
+		{"keeps jpeg extension", "board.jpeg", "image/jpeg", "board.jpeg"},
+		{"appends extension", "download", "application/pdf", "download.pdf"},
+		{"appends jpg for jpeg", "board", "image/jpeg", "board.jpg"},
+		{"appends webp", "slide.png", "image/webp", "slide.png.webp"},
+		{"no file name", "", "application/pdf", "document.pdf"},
+		{"control characters", "handout\x01.pdf", "application/pdf", "document.pdf"},
+		{"backslash", `..\handout.pdf`, "application/pdf", "document.pdf"},
+		{"too long", strings.Repeat("a", 254), "application/pdf", "document.pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			d := newMaterialDeps()
+			body := []byte("content")
+
+			d.subjects.On("GetByIDAndUserID", ctx, testhelper.FixtureSubjectID, testhelper.FixtureUserID).
+				Return(testhelper.NewSubject(), nil)
+			d.fetcher.On("Fetch", ctx, testSourceURL, mock.AnythingOfType("int64")).Return(&ports.FetchedDocument{
+				URL:         testSourceURL,
+				FileName:    tt.fileName,
+				ContentType: tt.contentType,
+				Body:        body,
+			}, nil)
+			d.storage.On("Upload", ctx, mock.MatchedBy(func(key string) bool {
+				return strings.HasSuffix(key, "/"+tt.want)
+			}), mock.Anything, int64(len(body)), tt.contentType).Return("stored-key", nil)
+			d.files.On("Create", ctx, mock.AnythingOfType("*domain.File")).Return(nil)
+			d.jobs.On("Create", ctx, mock.AnythingOfType("*domain.IngestJob")).Return(nil)
+			d.publisher.On("PublishIngestJob", ctx, mock.AnythingOfType("ports.IngestMessage")).Return(nil)
+
+			uc := newMaterialUseCase(d)
+			file, err := uc.ImportURL(ctx, usecases.ImportURLInput{
+				SubjectID: testhelper.FixtureSubjectID,
+				UserID:    testhelper.FixtureUserID,
+				URL:       testSourceURL,
+			})
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, file.Name)
+			d.storage.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMaterialUseCase_ImportURL_UnsupportedContentType(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+
+	d.subjects.On("GetByIDAndUserID", ctx, testhelper.FixtureSubjectID, testhelper.FixtureUserID).
+		Return(testhelper.NewSubject(), nil)
+	d.fetcher.On("Fetch", ctx, testSourceURL, mock.AnythingOfType("int64")).Return(&ports.FetchedDocument{
+		URL:         testSourceURL,
+		ContentType: "text/html; charset=utf-8",
+		Body:        []byte("<html></html>"),
+	}, nil)
+
+	uc := newMaterialUseCase(d)
+	file, err := uc.ImportURL(ctx, usecases.ImportURLInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		UserID:    testhelper.FixtureUserID,
+		URL:       testSourceURL,
+	})
+
+	assert.Nil(t, file)
+	assert.True(t, errors.Is(err, domain.ErrInvalidInput))
+	d.storage.AssertNotCalled(t, "Upload")
+}
+
+func TestMaterialUseCase_ImportURL_BlockedDestination(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	internalURL := "http://169.254.169.254/latest/meta-data/"
+
+	d.subjects.On("GetByIDAndUserID", ctx, testhelper.FixtureSubjectID, testhelper.FixtureUserID).
+		Return(testhelper.NewSubject(), nil)
+	d.fetcher.On("Fetch", ctx, internalURL, mock.AnythingOfType("int64")).
+		Return((*ports.FetchedDocument)(nil), fmt.Errorf("destination address is not allowed: %w", domain.ErrForbidden))
+
+	uc := newMaterialUseCase(d)
+	file, err := uc.ImportURL(ctx, usecases.ImportURLInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		UserID:    testhelper.FixtureUserID,
+		URL:       internalURL,
+	})
+
+	assert.Nil(t, file)
+	assert.True(t, errors.Is(err, domain.ErrForbidden))
+	d.files.AssertNotCalled(t, "Create")
+}
+
+func TestMaterialUseCase_RefetchURL_Unchanged(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	file := newURLFile("%PDF-1.7 v1")
+
+	d.files.On("GetByIDAndUserID", ctx, file.ID, file.UserID).Return(file, nil)
+	d.fetcher.On("Fetch", ctx, testSourceURL, mock.AnythingOfType("int64")).Return(&ports.FetchedDocument{
+		URL:         testSourceURL,
+		ContentType: "application/pdf",
+		Body:        []byte("%PDF-1.7 v1"),
+	}, nil)
+
+	uc := newMaterialUseCase(d)
+	out, err := uc.RefetchURL(ctx, file.SubjectID, file.ID, file.UserID)
+
+	require.NoError(t, err)
+	assert.False(t, out.Changed)
+	d.storage.AssertNotCalled(t, "Upload")
+	d.jobs.AssertNotCalled(t, "Create")
+}
+
+func TestMaterialUseCase_RefetchURL_Changed_Reprocesses(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	file := newURLFile("%PDF-1.7 v1")
+	newBody := []byte("%PDF-1.7 v2 with errata")
+	sum := sha256.Sum256(newBody)
+	newHash := hex.EncodeToString(sum[:])
+	updated := newURLFile(string(newBody))
+	updated.Status = domain.FileStatusPending
+
+	d.files.On("GetByIDAndUserID", ctx, file.ID, file.UserID).Return(file, nil)
+	d.fetcher.On("Fetch", ctx, testSourceURL, mock.AnythingOfType("int64")).Return(&ports.FetchedDocument{
+		URL:         testSourceURL,
+		ContentType: "application/pdf",
+		Body:        newBody,
+	}, nil)
+	d.storage.On("Upload", ctx, mock.MatchedBy(func(key string) bool {
+		return strings.HasSuffix(key, file.ID.String()+"/handout01.pdf")
+	}), mock.Anything, int64(len(newBody)), "application/pdf").Return(file.StoragePath, nil)
+	d.files.On("UpdateContent", ctx, file.ID, "handout01.pdf", file.StoragePath, "application/pdf", int64(len(newBody)), newHash).Return(updated, nil)
+	d.jobs.On("Create", ctx, mock.AnythingOfType("*domain.IngestJob")).Return(nil)
+	d.publisher.On("PublishIngestJob", ctx, mock.AnythingOfType("ports.IngestMessage")).Return(nil)
+
+	uc := newMaterialUseCase(d)
+	out, err := uc.RefetchURL(ctx, file.SubjectID, file.ID, file.UserID)
+
+	require.NoError(t, err)
+	assert.True(t, out.Changed)
+	assert.Equal(t, domain.FileStatusPending, out.File.Status)
+	d.storage.AssertExpectations(t)
+	d.storage.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	d.files.AssertExpectations(t)
+	d.jobs.AssertExpectations(t)
+}
+
+func TestMaterialUseCase_RefetchURL_MimeTypeChanged_RenamesMaterial(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	file := newURLFile("%PDF-1.7 v1")
+	file.MimeType = "application/pdf"
+	// 同じ URL の内容が PDF から画像に差し替えられた
+	newBody := []byte("\x89PNG\r\n\x1a\n slide")
+	png := "image/png"
+	sum := sha256.Sum256(newBody)
+	newHash := hex.EncodeToString(sum[:])
+	newPath := "minio://materials/user/subject/" + file.ID.String() + "/handout01.png"
+
+	d.files.On("GetByIDAndUserID", ctx, file.ID, file.UserID).Return(file, nil)
+	d.fetcher.On("Fetch", ctx, testSourceURL, mock.AnythingOfType("int64")).Return(&ports.FetchedDocument{
+		URL:         testSourceURL,
+		FileName:    "handout01",
+		ContentType: png,
+		Body:        newBody,
+	}, nil)
+	d.storage.On("Upload", ctx, mock.MatchedBy(func(key string) bool {
+		return strings.HasSuffix(key, file.ID.String()+"/handout01.png")
+	}), mock.Anything, int64(len(newBody)), png).Return(newPath, nil)
+	d.files.On("UpdateContent", ctx, file.ID, "handout01.png", newPath, png, int64(len(newBody)), newHash).
+		Return(newURLFile(string(newBody)), nil)
+	// 旧キーのオブジェクトは残さない
+	d.storage.On("Delete", ctx, file.StoragePath).Return(nil)
+	d.jobs.On("Create", ctx, mock.AnythingOfType("*domain.IngestJob")).Return(nil)
+	d.publisher.On("PublishIngestJob", ctx, mock.AnythingOfType("ports.IngestMessage")).Return(nil)
+
+	uc := newMaterialUseCase(d)
+	out, err := uc.RefetchURL(ctx, file.SubjectID, file.ID, file.UserID)
+
+	require.NoError(t, err)
+	assert.True(t, out.Changed)
+	d.storage.AssertExpectations(t)
+	d.files.AssertExpectations(t)
+}
+
+func TestMaterialUseCase_RefetchURL_InProgress(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	file := newURLFile("%PDF-1.7 v1")
+	file.Status = domain.FileStatusProcessing
+
+	d.files.On("GetByIDAndUserID", ctx, file.ID, file.UserID).Return(file, nil)
+
+	uc := newMaterialUseCase(d)
+	out, err := uc.RefetchURL(ctx, file.SubjectID, file.ID, file.UserID)
+
+	assert.Nil(t, out)
+	assert.True(t, errors.Is(err, domain.ErrConflict))
+	d.fetcher.AssertNotCalled(t, "Fetch")
+}
+
+// ─── Reprocess ────────────────────────────────────────────────────
+
+func TestMaterialUseCase_Reprocess_OverwriteManualEdits(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	pending := testhelper.NewFile(domain.FileStatusPending)
+
+	d.files.On("GetByIDAndUserID", ctx, testhelper.FixtureFileID, testhelper.FixtureUserID).
+		Return(testhelper.NewFile(domain.FileStatusReady), nil)
+	d.files.On("UpdateStatus", ctx, testhelper.FixtureFileID, domain.FileStatusPending, (*string)(nil)).
+		Return(pending, nil)
+	d.jobs.On("Create", ctx, mock.AnythingOfType("*domain.IngestJob")).Return(nil)
+	d.publisher.On("PublishIngestJob", ctx, mock.MatchedBy(func(msg ports.IngestMessage) bool {
+		return msg.FileID == testhelper.FixtureFileID.String() && msg.OverwriteManualEdits
+	})).Return(nil)
+
+	uc := newMaterialUseCase(d)
+	out, err := uc.Reprocess(ctx, testhelper.FixtureSubjectID, testhelper.FixtureFileID, testhelper.FixtureUserID, true)
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.FileStatusPending, out.Status)
+	d.publisher.AssertExpectations(t)
+}
+
+func TestMaterialUseCase_Reprocess_InProgress(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+
+	d.files.On("GetByIDAndUserID", ctx, testhelper.FixtureFileID, testhelper.FixtureUserID).
+		Return(testhelper.NewFile(domain.FileStatusProcessing), nil)
+
+	uc := newMaterialUseCase(d)
+	_, err := uc.Reprocess(ctx, testhelper.FixtureSubjectID, testhelper.FixtureFileID, testhelper.FixtureUserID, false)
+
+	assert.True(t, errors.Is(err, domain.ErrConflict))
+	d.jobs.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+// ─── GetDownloadURL / GetPagePreviewURL ───────────────────────────
+
+// newFileWithPreviews は指定ページ数のプレビューが生成済みの教材を返す。
+func newFileWithPreviews(pages int) *domain.File {
+	f := testhelper.NewFile(domain.FileStatusReady)
+	previewPath := "minio://eduanima/previews/" + f.ID.String()
+	f.PreviewPath = &previewPath
+	f.PreviewPageCount = pages
+	return f
+}
+
+func TestMaterialUseCase_GetDownloadURL_WithPageAnchor(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	file := newFileWithPreviews(5)
+	page := 3
+
+	d.files.On("GetByIDAndUserID", ctx, file.ID, file.UserID).Return(file, nil)
+	d.storage.On("GetPresignedURL", ctx, file.StoragePath, mock.AnythingOfType("time.Duration")).
+		Return("https://minio.local/test.pdf?X-Amz-Signature=abc", nil)
+
+	uc := newMaterialUseCase(d)
+	out, err := uc.GetDownloadURL(ctx, file.SubjectID, file.ID, file.UserID, &page)
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://minio.local/test.pdf?X-Amz-Signature=abc#page=3", out.URL)
+	assert.Equal(t, domain.PagePreviewAPIPath(file.SubjectID, file.ID, 3), out.PreviewURL)
+	assert.True(t, out.ExpiresAt.After(time.Now()))
+}
+
+func TestMaterialUseCase_GetDownloadURL_OtherSubject(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	file := newFileWithPreviews(5)
+
+	d.files.On("GetByIDAndUserID", ctx, file.ID, file.UserID).Return(file, nil)
+
+	uc := newMaterialUseCase(d)
+	out, err := uc.GetDownloadURL(ctx, uuid.New(), file.ID, file.UserID, nil)
+
+	assert.Nil(t, out)
+	assert.True(t, errors.Is(err, domain.ErrNotFound))
+	d.storage.AssertNotCalled(t, "GetPresignedURL")
+}
+
+func TestMaterialUseCase_GetDownloadURL_PageOutOfRange(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	file := newFileWithPreviews(5)
+	page := 6
+
+	d.files.On("GetByIDAndUserID", ctx, file.ID, file.UserID).Return(file, nil)
+
+	uc := newMaterialUseCase(d)
+	out, err := uc.GetDownloadURL(ctx, file.SubjectID, file.ID, file.UserID, &page)
+
+	assert.Nil(t, out)
+	assert.True(t, errors.Is(err, domain.ErrInvalidInput))
+}
+
+func TestMaterialUseCase_GetPagePreviewURL(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	file := newFileWithPreviews(2)
+
+	d.files.On("GetByIDAndUserID", ctx, file.ID, file.UserID).Return(file, nil)
+	d.storage.On("GetPresignedURL", ctx, *file.PreviewPath+"/page-0002.png", mock.AnythingOfType("time.Duration")).
+		Return("https://minio.local/page-0002.png", nil)
+
+	uc := newMaterialUseCase(d)
+	u, err := uc.GetPagePreviewURL(ctx, file.SubjectID, file.ID, file.UserID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "https://minio.local/page-0002.png", u)
+
+	_, err = uc.GetPagePreviewURL(ctx, file.SubjectID, file.ID, file.UserID, 3)
+	assert.True(t, errors.Is(err, domain.ErrNotFound))
+}
+
+func TestMaterialUseCase_GetPagePreviewURL_ImageUsesOriginal(t *testing.T) {
+	ctx := context.Background()
+	d := newMaterialDeps()
+	file := testhelper.NewFile(domain.FileStatusReady)
+	file.MimeType = "image/png"
+
+	d.files.On("GetByIDAndUserID", ctx, file.ID, file.UserID).Return(file, nil)
+	d.storage.On("GetPresignedURL", ctx, file.StoragePath, mock.AnythingOfType("time.Duration")).
+		Return("https://minio.local/slide.png", nil)
+
+	uc := newMaterialUseCase(d)
+	u, err := uc.GetPagePreviewURL(ctx, file.SubjectID, file.ID, file.UserID, 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, "https://minio.local/slide.png", u)
+}
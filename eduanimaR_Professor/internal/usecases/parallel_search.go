@@ -0,0 +1,103 @@
+package usecases
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	pgvector "github.com/pgvector/pgvector-go"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+)
+
+// SearchExecution は Librarian の 1 検索ラウンド内のクエリの実行設定。
+type SearchExecution struct {
+	Concurrency  int           // 同時に実行する検索（拡張・埋め込み・検索）の最大数（1 以下は逐次実行）
+	RoundTimeout time.Duration // 1 ラウンドの制限時間（超えた検索は結果なしとして続行。0 は無制限）
+}
+
+// roundContext は 1 ラウンドの制限時間を付けたコンテキストを返す。
+func (s SearchExecution) roundContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.RoundTimeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, s.RoundTimeout)
+}
+
+// runBounded は fn(0)〜fn(n-1) を最大 limit 並列で実行し、すべての完了を待つ（limit が 1 以下の場合は逐次実行）。
+func runBounded(n, limit int, fn func(i int)) {
+	if limit <= 1 {
+		for i := range n {
+			fn(i)
+		}
+		return
+	}
+	var g errgroup.Group
+	g.SetLimit(limit)
+	for i := range n {
+		g.Go(func() error {
+			fn(i)
+			return nil
+		})
+	}
+	_ = g.Wait()
+}
+
+// roundSearch は 1 検索ラウンド内の 1 件の検索（全文検索のクエリ、またはベクトル検索のクエリの 1 変形）
+type roundSearch struct {
+	text   string // 全文検索のクエリ（空の場合はベクトル検索）
+	vector string // ベクトル検索のクエリの変形
+	query  int    // 元のクエリの番号（ベクトル検索の変形を元のクエリごとに数えるため）
+
+	results []*domain.SearchResult
+	ok      bool
+}
+
+// planRoundSearches はラウンドの検索を全文検索 → ベクトル検索（クエリごとの変形）の順に並べる。
+// 結果はこの順に RRF へ加えるため、実行の完了順によらず候補の順序が決まる。
+func planRoundSearches(ctx context.Context, expansions *queryExpansions, textQueries, vectorQueries []string) []*roundSearch {
+	var searches []*roundSearch
+	for i, q := range textQueries {
+		if q != "" {
+			searches = append(searches, &roundSearch{text: q, query: i})
+		}
+	}
+	for i, q := range vectorQueries {
+		if q == "" {
+			continue
+		}
+		for _, v := range expansions.queries(ctx, q) {
+			searches = append(searches, &roundSearch{vector: v, query: len(textQueries) + i})
+		}
+	}
+	return searches
+}
+
+// runRoundSearches はラウンドの検索を最大 Concurrency 並列で実行する（失敗した検索は ok が false のまま）。
+func (uc *ChatUseCase) runRoundSearches(ctx context.Context, scope []uuid.UUID, searches []*roundSearch) {
+	runBounded(len(searches), uc.cfg.Search.Concurrency, func(i int) {
+		s := searches[i]
+		if s.vector == "" {
+			results, err := uc.chunkRepo.SearchByText(ctx, scope, s.text, chatCandidateLimit, domain.SearchFilter{})
+			if err != nil {
+				slog.Warn("text search error", "query", s.text, "error", err)
+				return
+			}
+			s.results, s.ok = results, true
+			return
+		}
+		emb, err := uc.llm.GenerateEmbedding(ctx, s.vector)
+		if err != nil {
+			slog.Warn("embedding error", "query", s.vector, "error", err)
+			return
+		}
+		results, err := uc.chunkRepo.SearchByVector(ctx, scope, pgvector.NewVector(emb), chatCandidateLimit, domain.SearchFilter{})
+		if err != nil {
+			slog.Warn("vector search error", "query", s.vector, "error", err)
+			return
+		}
+		s.results, s.ok = results, true
+	})
+}
@@ -0,0 +1,74 @@
+package usecases
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+// queryExpansions は 1 回の回答生成の中でベクトル検索クエリを書き換え・拡張する。
+// 同じクエリは一度だけ拡張し、生成した変形は監査用に記録する。
+type queryExpansions struct {
+	expander ports.QueryExpander // nil の場合は拡張しない
+	variants map[string][]string
+	log      []domain.QueryExpansion
+}
+
+func newQueryExpansions(expander ports.QueryExpander) *queryExpansions {
+	return &queryExpansions{expander: expander, variants: make(map[string][]string)}
+}
+
+// expand は未拡張のクエリを最大 concurrency 並列で拡張する。
+// 拡張の完了順によらず、変形はクエリの指定順に記録する。
+func (x *queryExpansions) expand(ctx context.Context, queries []string, concurrency int) {
+	if x.expander == nil {
+		return
+	}
+	var pending []string
+	seen := make(map[string]struct{})
+	for _, q := range queries {
+		if _, done := x.variants[q]; done {
+			continue
+		}
+		if _, dup := seen[q]; dup || q == "" {
+			continue
+		}
+		seen[q] = struct{}{}
+		pending = append(pending, q)
+	}
+
+	expanded := make([]*domain.QueryExpansion, len(pending))
+	runBounded(len(pending), concurrency, func(i int) {
+		exp, err := x.expander.ExpandQuery(ctx, pending[i])
+		if err != nil {
+			slog.Warn("query expansion failed, using original query", "query", pending[i], "error", err)
+			return
+		}
+		expanded[i] = exp
+	})
+
+	for i, q := range pending {
+		var variants []string
+		if exp := expanded[i]; exp != nil {
+			exp.Query = q
+			variants = exp.Variants()
+			x.log = append(x.log, *exp)
+			slog.Info("query expanded", "query", q, "variants", variants)
+		}
+		x.variants[q] = variants
+	}
+}
+
+// queries は query と、その変形（言い換え・仮想回答・日英訳）を返す。
+// 拡張に失敗した場合は query のみを返す。
+func (x *queryExpansions) queries(ctx context.Context, query string) []string {
+	if x.expander == nil {
+		return []string{query}
+	}
+	if _, ok := x.variants[query]; !ok {
+		x.expand(ctx, []string{query}, 1)
+	}
+	return append([]string{query}, x.variants[query]...)
+}
@@ -0,0 +1,58 @@
+package usecases
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+// Reranking は検索結果を Reranker で並べ替える設定。
+type Reranking struct {
+	TopK    int           // 採点する候補の最大件数（関連度の上位から。0 は並べ替えない）
+	Timeout time.Duration // 1 回の採点の制限時間（0 は制限なし）
+}
+
+// rerankCandidates は関連度の上位 TopK 件の候補を reranker で採点し、関連度を採点結果（0〜1）で置き換える。
+// 採点しなかった候補は関連度を 0 とし、採点した候補より後に並べる。
+// cands は関連度の降順に並べ替える。失敗・タイムアウト時は元の関連度のまま続行する。
+func rerankCandidates(ctx context.Context, reranker ports.Reranker, cfg Reranking, question string, cands []mmrCandidate) {
+	slices.SortStableFunc(cands, func(a, b mmrCandidate) int { return cmp.Compare(b.relevance, a.relevance) })
+	if reranker == nil || cfg.TopK <= 0 || len(cands) == 0 {
+		return
+	}
+
+	top := cands[:min(cfg.TopK, len(cands))]
+	docs := make([]string, len(top))
+	for i, c := range top {
+		docs[i] = c.result.Content
+	}
+	if cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	scores, err := reranker.Rerank(ctx, question, docs)
+	if err == nil && len(scores) != len(docs) {
+		err = fmt.Errorf("got %d scores for %d candidates", len(scores), len(docs))
+	}
+	if err != nil {
+		slog.Warn("rerank failed, using search ranking", "candidates", len(docs), "elapsed", time.Since(start), "error", err)
+		return
+	}
+
+	for i := range cands {
+		if i < len(top) {
+			cands[i].relevance = scores[i]
+		} else {
+			cands[i].relevance = 0
+		}
+	}
+	slices.SortStableFunc(cands, func(a, b mmrCandidate) int { return cmp.Compare(b.relevance, a.relevance) })
+	slog.Info("rerank completed", "candidates", len(docs), "elapsed", time.Since(start))
+}
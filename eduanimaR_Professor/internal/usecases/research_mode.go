@@ -0,0 +1,46 @@
+package usecases
+
+import (
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+// ResearchModes は調べる深さ（domain.ResearchMode）ごとの Librarian の制約。
+// 質問ごとの指定 → 科目の設定 → standard の順に深さを決め、その制約で Librarian に推論を依頼する。
+type ResearchModes struct {
+	Quick    ports.LibrarianConstraints
+	Standard ports.LibrarianConstraints
+	Deep     ports.LibrarianConstraints
+}
+
+// constraints は mode の制約を返す（空・不明の場合は Standard）。
+func (r ResearchModes) constraints(mode domain.ResearchMode) ports.LibrarianConstraints {
+	switch mode {
+	case domain.ResearchModeQuick:
+		return r.Quick
+	case domain.ResearchModeDeep:
+		return r.Deep
+	default:
+		return r.Standard
+	}
+}
+
+// resolveResearchMode は質問の調べる深さを返す（requested が空の場合は科目の設定、それも無い場合は standard）。
+func resolveResearchMode(requested domain.ResearchMode, subject *domain.Subject) domain.ResearchMode {
+	switch {
+	case requested != "":
+		return requested
+	case subject != nil && subject.ResearchMode != "":
+		return subject.ResearchMode
+	default:
+		return domain.ResearchModeStandard
+	}
+}
+
+// searchLimit は 1 クエリあたりに選ぶ検索結果数を返す（制約に指定が無い場合は chatSearchLimit）。
+func searchLimit(c ports.LibrarianConstraints) int {
+	if c.MaxResults > 0 {
+		return c.MaxResults
+	}
+	return chatSearchLimit
+}
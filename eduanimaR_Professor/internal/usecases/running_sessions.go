@@ -0,0 +1,65 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// errSessionCancelled はユーザーが Cancel で回答生成を中断したことを表す（context.Cause）
+	errSessionCancelled = errors.New("cancelled by user")
+	// errClientDisconnected は SSE の送信に失敗した（クライアントが切断した）ことを表す（context.Cause）
+	errClientDisconnected = errors.New("client disconnected")
+)
+
+// runningSessions はこのプロセスで回答生成中の質問セッションを管理する。
+// 別のインスタンスで実行中のセッションは中断できない。
+type runningSessions struct {
+	mu       sync.Mutex
+	sessions map[uuid.UUID]*runningSession
+}
+
+type runningSession struct {
+	cancel context.CancelCauseFunc
+	done   chan struct{} // 途中までの回答を保存して回答生成（Ask・Regenerate）が終わると閉じる
+}
+
+func newRunningSessions() *runningSessions {
+	return &runningSessions{sessions: make(map[uuid.UUID]*runningSession)}
+}
+
+// add はセッションを実行中として登録する（既に実行中の場合は false）。
+func (r *runningSessions) add(id uuid.UUID, cancel context.CancelCauseFunc) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.sessions[id]; ok {
+		return false
+	}
+	r.sessions[id] = &runningSession{cancel: cancel, done: make(chan struct{})}
+	return true
+}
+
+// remove はセッションの登録を外し、中断を待っている Cancel に完了を知らせる。
+func (r *runningSessions) remove(id uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.sessions[id]; ok {
+		close(s.done)
+		delete(r.sessions, id)
+	}
+}
+
+// cancel は実行中のセッションを cause で中断し、回答生成の終了を待つチャネルを返す（実行中でない場合は false）。
+func (r *runningSessions) cancel(id uuid.UUID, cause error) (<-chan struct{}, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.sessions[id]
+	if !ok {
+		return nil, false
+	}
+	s.cancel(cause)
+	return s.done, true
+}
@@ -0,0 +1,284 @@
+package usecases
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	pgvector "github.com/pgvector/pgvector-go"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+const (
+	DefaultSearchLimit = 20  // 検索結果のデフォルト件数
+	MaxSearchLimit     = 50  // 検索結果の最大件数
+	maxSearchQueryLen  = 500 // 検索クエリの最大文字数
+
+	searchCandidateLimit = 50  // 各検索方式から取得する候補数
+	rrfK                 = 60  // Reciprocal Rank Fusion の定数（上位の順位差を緩やかにする）
+	searchSnippetLen     = 200 // ハイライト付き抜粋の最大文字数
+)
+
+// SearchUseCase は LLM を使わない教材の直接検索を担う。
+// 全文検索とベクトル検索の結果を Reciprocal Rank Fusion で統合し、
+// 「どの教材のどのページに書いてあるか」を素早く返す。
+type SearchUseCase struct {
+	subjects ports.SubjectRepository
+	chunks   ports.ChunkRepository
+	llm      ports.LLMClient
+}
+
+// NewSearchUseCase は SearchUseCase を生成する。
+func NewSearchUseCase(subjects ports.SubjectRepository, chunks ports.ChunkRepository, llm ports.LLMClient) *SearchUseCase {
+	return &SearchUseCase{subjects: subjects, chunks: chunks, llm: llm}
+}
+
+// SearchInput は検索の入力値
+type SearchInput struct {
+	SubjectID uuid.UUID
+	// ExtraSubjectIDs は SubjectID に加えて横断検索する subject（所有権はすべて確認する）
+	ExtraSubjectIDs []uuid.UUID
+	UserID          uuid.UUID
+	Query           string
+	Filter          domain.SearchFilter
+	Limit           int // 0 の場合は DefaultSearchLimit
+}
+
+// HighlightSegment はハイライト付き抜粋の断片。
+// 断片を順に連結すると抜粋になり、Match が true の断片がクエリに一致した箇所。
+type HighlightSegment struct {
+	Text  string
+	Match bool
+}
+
+// SearchHit は検索結果の 1 件
+type SearchHit struct {
+	Result      *domain.SearchResult
+	Score       float64 // RRF スコア（全文検索・ベクトル検索の順位から算出）
+	TextRank    int     // 全文検索での順位（1 始まり。ヒットしない場合は 0）
+	TextScore   float64 // 全文検索の ts_rank
+	VectorRank  int     // ベクトル検索での順位（1 始まり。ヒットしない場合は 0）
+	VectorScore float64 // ベクトル検索のコサイン類似度
+	Highlights  []HighlightSegment
+	PreviewURL  string // ページプレビュー画像 API のパス（プレビューが無い場合は空）
+}
+
+// SearchOutput は検索結果
+type SearchOutput struct {
+	Hits []SearchHit
+	// VectorSearch はベクトル検索を実行できたか（Embedding 生成に失敗した場合は全文検索のみ）
+	VectorSearch bool
+}
+
+// Search は subject（ExtraSubjectIDs を指定した場合は複数 subject）内のチャンクをハイブリッド検索する。
+//
+// フロー:
+//  1. 入力検証・subject 所有権確認（横断対象の subject もすべて確認する）
+//  2. 全文検索（ts_rank 順）
+//  3. クエリの Embedding を生成してベクトル検索（失敗時は全文検索のみで続行）
+//  4. 両方の順位から RRF スコアを算出して並べ替え、上位 Limit 件にハイライトを付けて返す
+func (uc *SearchUseCase) Search(ctx context.Context, in SearchInput) (*SearchOutput, error) {
+	query, limit, err := validateSearchInput(in)
+	if err != nil {
+		return nil, err
+	}
+	scope, _, err := resolveSubjectScope(ctx, uc.subjects, in.SubjectID, in.ExtraSubjectIDs, in.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 全文検索
+	textResults, err := uc.chunks.SearchByText(ctx, scope, query, searchCandidateLimit, in.Filter)
+	if err != nil {
+		return nil, fmt.Errorf("text search: %w", err)
+	}
+
+	// 3. ベクトル検索
+	out := &SearchOutput{}
+	var vectorResults []*domain.SearchResult
+	emb, err := uc.llm.GenerateEmbedding(ctx, query)
+	if err != nil {
+		slog.Warn("query embedding failed, falling back to text search", "subject_id", in.SubjectID, "error", err)
+	} else {
+		vectorResults, err = uc.chunks.SearchByVector(ctx, scope, pgvector.NewVector(emb), searchCandidateLimit, in.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("vector search: %w", err)
+		}
+		out.VectorSearch = true
+	}
+
+	// 4. RRF で統合
+	hits := make(map[uuid.UUID]*SearchHit)
+	hitFor := func(r *domain.SearchResult) *SearchHit {
+		h, ok := hits[r.ChunkID]
+		if !ok {
+			h = &SearchHit{Result: r}
+			hits[r.ChunkID] = h
+		}
+		return h
+	}
+	for i, r := range textResults {
+		h := hitFor(r)
+		h.TextRank = i + 1
+		h.TextScore = r.Score
+		h.Score += 1.0 / float64(rrfK+i+1)
+	}
+	for i, r := range vectorResults {
+		h := hitFor(r)
+		h.VectorRank = i + 1
+		h.VectorScore = r.Score
+		h.Score += 1.0 / float64(rrfK+i+1)
+	}
+
+	ranked := make([]*SearchHit, 0, len(hits))
+	for _, h := range hits {
+		ranked = append(ranked, h)
+	}
+	// 同点の場合も結果が毎回同じ順になるよう、教材名・ページ・連番で並べる
+	slices.SortFunc(ranked, func(a, b *SearchHit) int {
+		return cmp.Or(
+			cmp.Compare(b.Score, a.Score),
+			cmp.Compare(a.Result.FileName, b.Result.FileName),
+			cmp.Compare(searchPageKey(a.Result), searchPageKey(b.Result)),
+			cmp.Compare(a.Result.ChunkIndex, b.Result.ChunkIndex),
+			cmp.Compare(a.Result.ChunkID.String(), b.Result.ChunkID.String()),
+		)
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	terms := searchTerms(query)
+	out.Hits = make([]SearchHit, len(ranked))
+	for i, h := range ranked {
+		r := h.Result
+		h.Highlights = highlight(r.Content, terms, searchSnippetLen)
+		h.PreviewURL = pagePreviewURL(r.SubjectID, r.FileID, r.MimeType, r.PreviewPageCount, r.PageNumber)
+		out.Hits[i] = *h
+	}
+	return out, nil
+}
+
+// validateSearchInput は検索条件を検証し、正規化したクエリと件数を返す。
+func validateSearchInput(in SearchInput) (string, int, error) {
+	query := strings.TrimSpace(in.Query)
+	if query == "" {
+		return "", 0, fmt.Errorf("query is required: %w", domain.ErrInvalidInput)
+	}
+	if utf8.RuneCountInString(query) > maxSearchQueryLen {
+		return "", 0, fmt.Errorf("query must be at most %d characters: %w", maxSearchQueryLen, domain.ErrInvalidInput)
+	}
+	limit := in.Limit
+	if limit == 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit < 0 || limit > MaxSearchLimit {
+		return "", 0, fmt.Errorf("limit must be 1-%d: %w", MaxSearchLimit, domain.ErrInvalidInput)
+	}
+	from, to := in.Filter.PageFrom, in.Filter.PageTo
+	if (from != nil && *from < 1) || (to != nil && *to < 1) {
+		return "", 0, fmt.Errorf("page range must start at 1: %w", domain.ErrInvalidInput)
+	}
+	if from != nil && to != nil && *from > *to {
+		return "", 0, fmt.Errorf("page_from must not exceed page_to: %w", domain.ErrInvalidInput)
+	}
+	return query, limit, nil
+}
+
+// searchPageKey は並べ替え用のページ番号を返す（ページ番号が無い場合は 0）。
+func searchPageKey(r *domain.SearchResult) int {
+	if r.PageNumber == nil {
+		return 0
+	}
+	return *r.PageNumber
+}
+
+// searchTerms はクエリを空白で区切ったハイライト対象の語を返す（小文字化・重複除去、長い語が先）。
+func searchTerms(query string) []string {
+	var terms []string
+	for _, t := range strings.Fields(strings.Map(unicode.ToLower, query)) {
+		if !slices.Contains(terms, t) {
+			terms = append(terms, t)
+		}
+	}
+	slices.SortStableFunc(terms, func(a, b string) int {
+		return utf8.RuneCountInString(b) - utf8.RuneCountInString(a)
+	})
+	return terms
+}
+
+// highlight は content のうち最初に語が一致する付近を最大 maxLen 文字切り出し、
+// 一致箇所を Match として区切った断片を返す。一致しない場合は先頭を切り出す。
+// 大文字・小文字は区別しない。切り詰めた場合は前後に "…" を付ける。
+func highlight(content string, terms []string, maxLen int) []HighlightSegment {
+	runes := []rune(content)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	termRunes := make([][]rune, len(terms))
+	for i, t := range terms {
+		termRunes[i] = []rune(t)
+	}
+	// matchAt は位置 i から一致する最長の語の長さを返す（一致しない場合は 0）
+	matchAt := func(i int) int {
+		for _, t := range termRunes {
+			if len(t) > 0 && i+len(t) <= len(lower) && slices.Equal(lower[i:i+len(t)], t) {
+				return len(t)
+			}
+		}
+		return 0
+	}
+
+	start := 0
+	for i := range lower {
+		if matchAt(i) > 0 {
+			start = max(0, i-maxLen/4)
+			break
+		}
+	}
+	end := min(len(runes), start+maxLen)
+	if end-start < maxLen {
+		start = max(0, end-maxLen)
+	}
+
+	var segs []HighlightSegment
+	appendSeg := func(text string, match bool) {
+		if text == "" {
+			return
+		}
+		if n := len(segs); n > 0 && segs[n-1].Match == match {
+			segs[n-1].Text += text
+			return
+		}
+		segs = append(segs, HighlightSegment{Text: text, Match: match})
+	}
+	if start > 0 {
+		appendSeg("…", false)
+	}
+	plainFrom := start
+	for i := start; i < end; {
+		n := matchAt(i)
+		if n == 0 || i+n > end {
+			i++
+			continue
+		}
+		appendSeg(string(runes[plainFrom:i]), false)
+		appendSeg(string(runes[i:i+n]), true)
+		i += n
+		plainFrom = i
+	}
+	appendSeg(string(runes[plainFrom:end]), false)
+	if end < len(runes) {
+		appendSeg("…", false)
+	}
+	return segs
+}
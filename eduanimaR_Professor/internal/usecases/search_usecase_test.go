@@ -0,0 +1,181 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/testhelper"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/usecases"
+)
+
+// searchResult はテスト用の検索結果を生成する。
+func searchResult(name string, page int, content string, score float64) *domain.SearchResult {
+	return &domain.SearchResult{
+		ChunkID:    uuid.New(),
+		FileID:     testhelper.FixtureFileID,
+		SubjectID:  testhelper.FixtureSubjectID,
+		PageNumber: &page,
+		Content:    content,
+		FileName:   name,
+		Score:      score,
+	}
+}
+
+func TestSearchUseCase_Search_FusesTextAndVectorResults(t *testing.T) {
+	ctx := context.Background()
+	subjects := &testhelper.MockSubjectRepository{}
+	chunks := &testhelper.MockChunkRepository{}
+	llm := &testhelper.MockLLMClient{}
+
+	both := searchResult("線形代数.pdf", 12, "固有値 と固有ベクトルの定義", 0.8)
+	textOnly := searchResult("線形代数.pdf", 3, "行列の固有値を求める", 0.5)
+	vectorOnly := searchResult("演習.pdf", 1, "対角化の例題", 0.7)
+
+	page := 10
+	filter := domain.SearchFilter{FileIDs: []uuid.UUID{testhelper.FixtureFileID}, PageFrom: &page}
+	subjects.On("GetByIDAndUserID", ctx, testhelper.FixtureSubjectID, testhelper.FixtureUserID).
+		Return(testhelper.NewSubject(), nil)
+	chunks.On("SearchByText", ctx, []uuid.UUID{testhelper.FixtureSubjectID}, "固有値", mock.AnythingOfType("int"), filter).
+		Return([]*domain.SearchResult{textOnly, both}, nil)
+	llm.On("GenerateEmbedding", ctx, "固有値").Return([]float32{0.1, 0.2}, nil)
+	chunks.On("SearchByVector", ctx, []uuid.UUID{testhelper.FixtureSubjectID}, mock.Anything, mock.AnythingOfType("int"), filter).
+		Return([]*domain.SearchResult{vectorOnly, both}, nil)
+
+	uc := usecases.NewSearchUseCase(subjects, chunks, llm)
+	out, err := uc.Search(ctx, usecases.SearchInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		UserID:    testhelper.FixtureUserID,
+		Query:     " 固有値 ",
+		Filter:    filter,
+	})
+
+	require.NoError(t, err)
+	assert.True(t, out.VectorSearch)
+	require.Len(t, out.Hits, 3)
+	// 両方でヒットしたチャンクが最上位
+	assert.Equal(t, both.ChunkID, out.Hits[0].Result.ChunkID)
+	assert.Equal(t, 2, out.Hits[0].TextRank)
+	assert.Equal(t, 2, out.Hits[0].VectorRank)
+	assert.Equal(t, 0.8, out.Hits[0].VectorScore)
+	// 同点（各 1 位）は教材名順（"演習.pdf" < "線形代数.pdf"）
+	assert.Equal(t, vectorOnly.ChunkID, out.Hits[1].Result.ChunkID)
+	assert.Equal(t, 0, out.Hits[1].TextRank)
+	assert.Equal(t, textOnly.ChunkID, out.Hits[2].Result.ChunkID)
+	assert.Equal(t, 0, out.Hits[2].VectorRank)
+	chunks.AssertExpectations(t)
+}
+
+func TestSearchUseCase_Search_Highlights(t *testing.T) {
+	ctx := context.Background()
+	subjects := &testhelper.MockSubjectRepository{}
+	chunks := &testhelper.MockChunkRepository{}
+	llm := &testhelper.MockLLMClient{}
+
+	long := searchResult("講義.pdf", 2, strings.Repeat("前置き。", 100)+"Fourier 変換の性質"+strings.Repeat("後書き。", 100), 0.3)
+	subjects.On("GetByIDAndUserID", ctx, testhelper.FixtureSubjectID, testhelper.FixtureUserID).
+		Return(testhelper.NewSubject(), nil)
+	chunks.On("SearchByText", ctx, []uuid.UUID{testhelper.FixtureSubjectID}, "fourier 変換", mock.Anything, mock.Anything).
+		Return([]*domain.SearchResult{long}, nil)
+	llm.On("GenerateEmbedding", ctx, mock.Anything).Return([]float32{0.1}, nil)
+	chunks.On("SearchByVector", ctx, []uuid.UUID{testhelper.FixtureSubjectID}, mock.Anything, mock.Anything, mock.Anything).
+		Return([]*domain.SearchResult{}, nil)
+
+	uc := usecases.NewSearchUseCase(subjects, chunks, llm)
+	out, err := uc.Search(ctx, usecases.SearchInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		UserID:    testhelper.FixtureUserID,
+		Query:     "fourier 変換",
+	})
+
+	require.NoError(t, err)
+	require.Len(t, out.Hits, 1)
+	var matched []string
+	var snippet strings.Builder
+	for _, s := range out.Hits[0].Highlights {
+		if s.Match {
+			matched = append(matched, s.Text)
+		}
+		snippet.WriteString(s.Text)
+	}
+	assert.Equal(t, []string{"Fourier", "変換"}, matched)
+	assert.True(t, strings.HasPrefix(snippet.String(), "…"))
+	assert.True(t, strings.HasSuffix(snippet.String(), "…"))
+}
+
+func TestSearchUseCase_Search_EmbeddingFailureFallsBackToText(t *testing.T) {
+	ctx := context.Background()
+	subjects := &testhelper.MockSubjectRepository{}
+	chunks := &testhelper.MockChunkRepository{}
+	llm := &testhelper.MockLLMClient{}
+
+	r := searchResult("講義.pdf", 1, "ラプラス変換", 0.2)
+	subjects.On("GetByIDAndUserID", ctx, testhelper.FixtureSubjectID, testhelper.FixtureUserID).
+		Return(testhelper.NewSubject(), nil)
+	chunks.On("SearchByText", ctx, []uuid.UUID{testhelper.FixtureSubjectID}, "ラプラス", mock.Anything, mock.Anything).
+		Return([]*domain.SearchResult{r}, nil)
+	llm.On("GenerateEmbedding", ctx, "ラプラス").Return(nil, errors.New("quota exceeded"))
+
+	uc := usecases.NewSearchUseCase(subjects, chunks, llm)
+	out, err := uc.Search(ctx, usecases.SearchInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		UserID:    testhelper.FixtureUserID,
+		Query:     "ラプラス",
+	})
+
+	require.NoError(t, err)
+	assert.False(t, out.VectorSearch)
+	require.Len(t, out.Hits, 1)
+	assert.Equal(t, 1, out.Hits[0].TextRank)
+	chunks.AssertNotCalled(t, "SearchByVector")
+}
+
+func TestSearchUseCase_Search_InvalidInput(t *testing.T) {
+	from, to := 5, 2
+	zero := 0
+	tests := []struct {
+		name string
+		in   usecases.SearchInput
+	}{
+		{"empty query", usecases.SearchInput{Query: "  "}},
+		{"too long query", usecases.SearchInput{Query: strings.Repeat("あ", 501)}},
+		{"limit over max", usecases.SearchInput{Query: "x", Limit: usecases.MaxSearchLimit + 1}},
+		{"reversed page range", usecases.SearchInput{Query: "x", Filter: domain.SearchFilter{PageFrom: &from, PageTo: &to}}},
+		{"page zero", usecases.SearchInput{Query: "x", Filter: domain.SearchFilter{PageFrom: &zero}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subjects := &testhelper.MockSubjectRepository{}
+			uc := usecases.NewSearchUseCase(subjects, &testhelper.MockChunkRepository{}, &testhelper.MockLLMClient{})
+			_, err := uc.Search(context.Background(), tt.in)
+			assert.True(t, errors.Is(err, domain.ErrInvalidInput))
+			subjects.AssertNotCalled(t, "GetByIDAndUserID")
+		})
+	}
+}
+
+func TestSearchUseCase_Search_SubjectNotFound(t *testing.T) {
+	ctx := context.Background()
+	subjects := &testhelper.MockSubjectRepository{}
+	chunks := &testhelper.MockChunkRepository{}
+
+	subjects.On("GetByIDAndUserID", ctx, testhelper.FixtureSubjectID, testhelper.FixtureUserID).
+		Return(nil, domain.ErrNotFound)
+
+	uc := usecases.NewSearchUseCase(subjects, chunks, &testhelper.MockLLMClient{})
+	_, err := uc.Search(ctx, usecases.SearchInput{
+		SubjectID: testhelper.FixtureSubjectID,
+		UserID:    testhelper.FixtureUserID,
+		Query:     "固有値",
+	})
+
+	assert.True(t, errors.Is(err, domain.ErrNotFound))
+	chunks.AssertNotCalled(t, "SearchByText")
+}
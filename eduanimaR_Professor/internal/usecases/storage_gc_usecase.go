@@ -0,0 +1,182 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+// MinOrphanGracePeriod は孤立オブジェクトを削除するまでの猶予の下限。
+// 直接アップロード・再開可能アップロードでは DB 行の作成前にオブジェクトが存在するため、
+// 予約の有効期限と掃除の猶予を合わせた時間より短くすると処理中のアップロードを消してしまう。
+const MinOrphanGracePeriod = resumableUploadTTL + uploadCleanupGrace
+
+// StorageGCUseCase はオブジェクトストレージと files テーブルの整合性チェックを担う。
+// 教材オブジェクトは "{userID}/{subjectID}/{fileID}/..." に配置されている前提で、
+// どの教材からも参照されないオブジェクト（孤立オブジェクト）を猶予期間後に削除し、
+// オブジェクトが存在しない教材を報告する。
+type StorageGCUseCase struct {
+	files   ports.FileRepository
+	storage ports.ObjectStorage
+}
+
+// NewStorageGCUseCase は StorageGCUseCase を生成する。
+func NewStorageGCUseCase(files ports.FileRepository, storage ports.ObjectStorage) *StorageGCUseCase {
+	return &StorageGCUseCase{files: files, storage: storage}
+}
+
+// ReconcileStorageInput は整合性チェックの実行条件
+type ReconcileStorageInput struct {
+	Now         time.Time
+	GracePeriod time.Duration // 最終更新からこの時間が経過した孤立オブジェクトのみ削除する
+	DryRun      bool          // true の場合は削除せず報告のみ行う
+}
+
+// OrphanObject はどの教材からも参照されていないオブジェクト
+type OrphanObject struct {
+	StoragePath  string    `json:"storage_path"`
+	Size         int64     `json:"size_bytes"`
+	LastModified time.Time `json:"last_modified"`
+	Deleted      bool      `json:"deleted"`
+	Error        string    `json:"error,omitempty"` // 削除に失敗した場合の理由
+}
+
+// MissingObject はオブジェクトが存在しない教材
+type MissingObject struct {
+	FileID      uuid.UUID `json:"file_id"`
+	SubjectID   uuid.UUID `json:"subject_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Name        string    `json:"name"`
+	StoragePath string    `json:"storage_path"`
+}
+
+// ReconcileStorageReport は整合性チェックの結果
+type ReconcileStorageReport struct {
+	ScannedObjects int             `json:"scanned_objects"`
+	ScannedFiles   int             `json:"scanned_files"`
+	Orphans        []OrphanObject  `json:"orphans"`         // 猶予期間を過ぎた孤立オブジェクト
+	PendingOrphans int             `json:"pending_orphans"` // 猶予期間内のため残した孤立オブジェクト数
+	DeletedBytes   int64           `json:"deleted_bytes"`
+	Missing        []MissingObject `json:"missing"`
+}
+
+// Reconcile はストレージ上の教材オブジェクトと files テーブルを突き合わせる。
+//
+// フロー:
+//  1. files を全件取得し、参照されているストレージパス（原本・ページプレビュー）を集める
+//  2. ストレージを列挙し、"{userID}/{subjectID}/{fileID}/" 配下で参照されていないものを孤立とする
+//  3. 猶予期間を過ぎた孤立オブジェクトを削除する（DryRun の場合は報告のみ）
+//  4. 原本オブジェクトが見つからなかった教材を報告する
+func (uc *StorageGCUseCase) Reconcile(ctx context.Context, in ReconcileStorageInput) (*ReconcileStorageReport, error) {
+	if in.GracePeriod < MinOrphanGracePeriod {
+		return nil, fmt.Errorf("grace period must be at least %s: %w", MinOrphanGracePeriod, domain.ErrInvalidInput)
+	}
+
+	// 1. 参照されているストレージパスを集める
+	files, err := uc.files.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list files: %w", err)
+	}
+	referenced := make(map[string]struct{})
+	for _, f := range files {
+		referenced[f.StoragePath] = struct{}{}
+		for page := 1; f.HasPagePreview(page); page++ {
+			referenced[f.PagePreviewPath(page)] = struct{}{}
+		}
+	}
+
+	// 2, 3. ストレージを列挙し、孤立オブジェクトを削除する
+	report := &ReconcileStorageReport{ScannedFiles: len(files)}
+	found := make(map[string]struct{})
+	cutoff := in.Now.Add(-in.GracePeriod)
+	err = uc.storage.ListObjects(ctx, "", func(obj ports.ObjectInfo) error {
+		if !isMaterialObjectKey(obj.Key) {
+			return nil
+		}
+		report.ScannedObjects++
+		found[obj.StoragePath] = struct{}{}
+		found[obj.Key] = struct{}{} // スキーム導入前に保存された教材はキーで参照している
+		if isReferenced(referenced, obj) {
+			return nil
+		}
+		if obj.LastModified.After(cutoff) {
+			report.PendingOrphans++
+			return nil
+		}
+
+		orphan := OrphanObject{
+			StoragePath:  obj.StoragePath,
+			Size:         obj.Size,
+			LastModified: obj.LastModified,
+		}
+		if !in.DryRun {
+			if err := uc.storage.Delete(ctx, obj.StoragePath); err != nil {
+				slog.Warn("orphan object delete failed", "path", obj.StoragePath, "error", err)
+				orphan.Error = err.Error()
+			} else {
+				orphan.Deleted = true
+				report.DeletedBytes += obj.Size
+			}
+		}
+		report.Orphans = append(report.Orphans, orphan)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list objects: %w", err)
+	}
+
+	// 4. 原本オブジェクトが存在しない教材を報告する
+	for _, f := range files {
+		if _, ok := found[f.StoragePath]; ok {
+			continue
+		}
+		report.Missing = append(report.Missing, MissingObject{
+			FileID:      f.ID,
+			SubjectID:   f.SubjectID,
+			UserID:      f.UserID,
+			Name:        f.Name,
+			StoragePath: f.StoragePath,
+		})
+	}
+
+	slog.Info("storage reconciliation completed",
+		"scanned_objects", report.ScannedObjects,
+		"scanned_files", report.ScannedFiles,
+		"orphans", len(report.Orphans),
+		"pending_orphans", report.PendingOrphans,
+		"deleted_bytes", report.DeletedBytes,
+		"missing", len(report.Missing),
+		"dry_run", in.DryRun,
+	)
+	return report, nil
+}
+
+// isMaterialObjectKey はキーが教材のレイアウト "{userID}/{subjectID}/{fileID}/..." に従うかを返す。
+// それ以外のオブジェクト（マルチパートの一時オブジェクト等）は整合性チェックの対象外とする。
+func isMaterialObjectKey(key string) bool {
+	parts := strings.SplitN(key, "/", 4)
+	if len(parts) < 4 || parts[3] == "" {
+		return false
+	}
+	for _, p := range parts[:3] {
+		if _, err := uuid.Parse(p); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func isReferenced(referenced map[string]struct{}, obj ports.ObjectInfo) bool {
+	if _, ok := referenced[obj.StoragePath]; ok {
+		return true
+	}
+	_, ok := referenced[obj.Key]
+	return ok
+}
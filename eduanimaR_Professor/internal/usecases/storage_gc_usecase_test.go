@@ -0,0 +1,128 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/testhelper"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/usecases"
+)
+
+// ─── テストヘルパー ────────────────────────────────────────────────
+
+var gcNow = time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// materialObject は教材レイアウトに従うオブジェクトを返す。
+func materialObject(fileID uuid.UUID, name string, age time.Duration) ports.ObjectInfo {
+	key := testhelper.FixtureUserID.String() + "/" + testhelper.FixtureSubjectID.String() + "/" + fileID.String() + "/" + name
+	return ports.ObjectInfo{
+		Key:          key,
+		Size:         100,
+		StoragePath:  "minio://eduanima/" + key,
+		LastModified: gcNow.Add(-age),
+	}
+}
+
+// ─── Reconcile ───────────────────────────────────────────────────
+
+func TestStorageGCUseCase_Reconcile_DeletesExpiredOrphans(t *testing.T) {
+	ctx := context.Background()
+	files := &testhelper.MockFileRepository{}
+	storage := &testhelper.MockObjectStorage{}
+
+	live := materialObject(testhelper.FixtureFileID, "test.pdf", 200*time.Hour)
+	preview := materialObject(testhelper.FixtureFileID, "previews/page-0001.png", 200*time.Hour)
+	oldOrphan := materialObject(uuid.New(), "deleted.pdf", 100*time.Hour)
+	newOrphan := materialObject(uuid.New(), "uploading.pdf", time.Hour)
+	tempPart := ports.ObjectInfo{Key: ".multipart/abc/part-00001", StoragePath: "minio://eduanima/.multipart/abc/part-00001"}
+
+	file := testhelper.NewFile(domain.FileStatusReady)
+	file.StoragePath = live.StoragePath
+	previewPath := "minio://eduanima/" + testhelper.FixtureUserID.String() + "/" + testhelper.FixtureSubjectID.String() + "/" + testhelper.FixtureFileID.String() + "/previews"
+	file.PreviewPath = &previewPath
+	file.PreviewPageCount = 1
+	missing := testhelper.NewFile(domain.FileStatusReady)
+	missing.ID = uuid.New()
+	missing.StoragePath = "minio://eduanima/lost.pdf"
+
+	files.On("ListAll", ctx).Return([]*domain.File{file, missing}, nil)
+	storage.On("ListObjects", ctx, "", mock.Anything).
+		Return([]ports.ObjectInfo{live, preview, oldOrphan, newOrphan, tempPart}, nil)
+	storage.On("Delete", ctx, oldOrphan.StoragePath).Return(nil)
+
+	uc := usecases.NewStorageGCUseCase(files, storage)
+	report, err := uc.Reconcile(ctx, usecases.ReconcileStorageInput{Now: gcNow, GracePeriod: 72 * time.Hour})
+
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.ScannedObjects)
+	require.Len(t, report.Orphans, 1)
+	assert.Equal(t, oldOrphan.StoragePath, report.Orphans[0].StoragePath)
+	assert.True(t, report.Orphans[0].Deleted)
+	assert.Equal(t, 1, report.PendingOrphans)
+	assert.Equal(t, int64(100), report.DeletedBytes)
+	require.Len(t, report.Missing, 1)
+	assert.Equal(t, missing.ID, report.Missing[0].FileID)
+	storage.AssertExpectations(t)
+	storage.AssertNumberOfCalls(t, "Delete", 1)
+}
+
+func TestStorageGCUseCase_Reconcile_LegacyKeyPathIsReferenced(t *testing.T) {
+	ctx := context.Background()
+	files := &testhelper.MockFileRepository{}
+	storage := &testhelper.MockObjectStorage{}
+
+	obj := materialObject(testhelper.FixtureFileID, "test.pdf", 200*time.Hour)
+	file := testhelper.NewFile(domain.FileStatusReady)
+	file.StoragePath = obj.Key // スキーム導入前の行はキーのみを保持している
+
+	files.On("ListAll", ctx).Return([]*domain.File{file}, nil)
+	storage.On("ListObjects", ctx, "", mock.Anything).Return([]ports.ObjectInfo{obj}, nil)
+
+	uc := usecases.NewStorageGCUseCase(files, storage)
+	report, err := uc.Reconcile(ctx, usecases.ReconcileStorageInput{Now: gcNow, GracePeriod: 72 * time.Hour})
+
+	require.NoError(t, err)
+	assert.Empty(t, report.Orphans)
+	assert.Empty(t, report.Missing)
+	storage.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestStorageGCUseCase_Reconcile_DryRunKeepsObjects(t *testing.T) {
+	ctx := context.Background()
+	files := &testhelper.MockFileRepository{}
+	storage := &testhelper.MockObjectStorage{}
+
+	orphan := materialObject(uuid.New(), "deleted.pdf", 100*time.Hour)
+	files.On("ListAll", ctx).Return([]*domain.File{}, nil)
+	storage.On("ListObjects", ctx, "", mock.Anything).Return([]ports.ObjectInfo{orphan}, nil)
+
+	uc := usecases.NewStorageGCUseCase(files, storage)
+	report, err := uc.Reconcile(ctx, usecases.ReconcileStorageInput{Now: gcNow, GracePeriod: 72 * time.Hour, DryRun: true})
+
+	require.NoError(t, err)
+	require.Len(t, report.Orphans, 1)
+	assert.False(t, report.Orphans[0].Deleted)
+	storage.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestStorageGCUseCase_Reconcile_RejectsShortGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	files := &testhelper.MockFileRepository{}
+	storage := &testhelper.MockObjectStorage{}
+
+	uc := usecases.NewStorageGCUseCase(files, storage)
+	report, err := uc.Reconcile(ctx, usecases.ReconcileStorageInput{Now: gcNow, GracePeriod: time.Hour})
+
+	assert.Nil(t, report)
+	assert.True(t, errors.Is(err, domain.ErrInvalidInput))
+	files.AssertNotCalled(t, "ListAll", mock.Anything)
+}
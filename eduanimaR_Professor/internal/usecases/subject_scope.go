@@ -0,0 +1,50 @@
+package usecases
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/domain"
+	"github.com/ttokunaga-ja/eduanimaR/eduanimaR_Professor/internal/ports"
+)
+
+// MaxScopeSubjects は 1 回の検索・質問で横断できる subject の最大数（起点の subject を含む）
+const MaxScopeSubjects = 10
+
+// resolveSubjectScope は検索対象の subject 集合を確定する。
+// 起点の subjectID と追加の extraSubjectIDs のすべてについて userID の所有権を確認し、
+// 重複を除いた集合（先頭が subjectID）と、起点の subject を返す。
+// 1 つでも所有していない subject が含まれる場合は ErrNotFound を返す（存在有無を区別させない）。
+func resolveSubjectScope(
+	ctx context.Context,
+	subjects ports.SubjectRepository,
+	subjectID uuid.UUID,
+	extraSubjectIDs []uuid.UUID,
+	userID uuid.UUID,
+) ([]uuid.UUID, *domain.Subject, error) {
+	scope := []uuid.UUID{subjectID}
+	seen := map[uuid.UUID]struct{}{subjectID: {}}
+	for _, id := range extraSubjectIDs {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		scope = append(scope, id)
+	}
+	if len(scope) > MaxScopeSubjects {
+		return nil, nil, fmt.Errorf("at most %d subjects can be searched at once: %w", MaxScopeSubjects, domain.ErrInvalidInput)
+	}
+	var origin *domain.Subject
+	for _, id := range scope {
+		subject, err := subjects.GetByIDAndUserID(ctx, id, userID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get subject %s: %w", id, err)
+		}
+		if origin == nil {
+			origin = subject
+		}
+	}
+	return scope, origin, nil
+}
@@ -36,6 +36,8 @@ func (uc *SubjectUseCase) GetByIDAndUser(ctx context.Context, id, userID uuid.UU
 type CreateSubjectInput struct {
 	Name        string
 	LMSCourseID *string
+	// ResearchMode は質問で Librarian に調べさせる深さ（空の場合は standard）
+	ResearchMode domain.ResearchMode
 }
 
 // Create は新規科目を作成する。
@@ -43,14 +45,22 @@ func (uc *SubjectUseCase) Create(ctx context.Context, userID uuid.UUID, in Creat
 	if in.Name == "" {
 		return nil, domain.ErrInvalidInput
 	}
+	mode := domain.ResearchModeStandard
+	if in.ResearchMode != "" {
+		var err error
+		if mode, err = domain.ParseResearchMode(string(in.ResearchMode)); err != nil {
+			return nil, err
+		}
+	}
 	now := time.Now().UTC()
 	s := &domain.Subject{
-		ID:          uuid.New(),
-		UserID:      userID,
-		Name:        in.Name,
-		LMSCourseID: in.LMSCourseID,
-		CreatedAt:   now,
-		UpdatedAt:   now,
+		ID:           uuid.New(),
+		UserID:       userID,
+		Name:         in.Name,
+		LMSCourseID:  in.LMSCourseID,
+		ResearchMode: mode,
+		CreatedAt:    now,
+		UpdatedAt:    now,
 	}
 	if err := uc.subjects.Create(ctx, s); err != nil {
 		return nil, err
@@ -66,6 +76,15 @@ func (uc *SubjectUseCase) UpdateName(ctx context.Context, id, userID uuid.UUID,
 	return uc.subjects.UpdateName(ctx, id, userID, name)
 }
 
+// UpdateResearchMode は科目の質問で Librarian に調べさせる深さを更新する。
+func (uc *SubjectUseCase) UpdateResearchMode(ctx context.Context, id, userID uuid.UUID, mode domain.ResearchMode) (*domain.Subject, error) {
+	mode, err := domain.ParseResearchMode(string(mode))
+	if err != nil {
+		return nil, err
+	}
+	return uc.subjects.UpdateResearchMode(ctx, id, userID, mode)
+}
+
 // Delete は科目を削除する。
 func (uc *SubjectUseCase) Delete(ctx context.Context, id, userID uuid.UUID) error {
 	return uc.subjects.Delete(ctx, id, userID)
@@ -0,0 +1,37 @@
+-- ===================================================================
+-- 002_material_uploads.sql
+-- 署名付き URL による直接アップロード（2 ステップ方式）の受付管理
+-- 適用方法: atlas migrate apply --dir "file://schema/migrations" --url $DATABASE_URL
+-- ===================================================================
+
+-- ── material_uploads ──────────────────────────────────────────────────
+-- クライアントが presigned PUT URL でバケットへ直接アップロードする間の予約レコード。
+-- 完了通知（complete）で files / ingest_jobs を作成し completed_at を記録する。
+-- 期限切れかつ未完了のレコードはオブジェクトと共にバックグラウンドで削除する。
+CREATE TABLE material_uploads (
+    upload_id    UUID        NOT NULL DEFAULT uuidv7(),
+    file_id      UUID        NOT NULL,   -- 完了時に作成する files.file_id（ストレージキーに含まれる）
+    subject_id   UUID        NOT NULL,
+    user_id      UUID        NOT NULL,
+    file_name    TEXT        NOT NULL,
+    storage_key  TEXT        NOT NULL,   -- {userID}/{subjectID}/{fileID}/{fileName}
+    mime_type    TEXT        NOT NULL,   -- クライアント申告値（完了時に実オブジェクトと照合）
+    size_bytes   BIGINT      NOT NULL,   -- クライアント申告値（完了時に実オブジェクトと照合）
+    expires_at   TIMESTAMPTZ NOT NULL,   -- presigned URL の有効期限
+    completed_at TIMESTAMPTZ NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT material_uploads_pkey        PRIMARY KEY (upload_id),
+    CONSTRAINT material_uploads_file_unique UNIQUE (file_id),
+    CONSTRAINT material_uploads_subject_fk  FOREIGN KEY (subject_id)
+        REFERENCES subjects (subject_id) ON DELETE CASCADE,
+    CONSTRAINT material_uploads_user_fk     FOREIGN KEY (user_id)
+        REFERENCES users (user_id) ON DELETE CASCADE,
+    CONSTRAINT material_uploads_size_chk    CHECK (size_bytes > 0)
+);
+
+CREATE INDEX idx_material_uploads_user_id ON material_uploads (user_id);
+-- 未完了アップロードの期限切れスキャン用（部分インデックス）
+CREATE INDEX idx_material_uploads_pending_expires_at
+    ON material_uploads (expires_at)
+    WHERE completed_at IS NULL;
@@ -0,0 +1,30 @@
+-- ===================================================================
+-- 003_resumable_uploads.sql
+-- 大容量教材向けの再開可能アップロード（S3 マルチパートアップロード）
+-- 適用方法: atlas migrate apply --dir "file://schema/migrations" --url $DATABASE_URL
+-- ===================================================================
+
+-- ── material_uploads 拡張 ─────────────────────────────────────────────
+-- multipart_upload_id: NULL = presigned PUT 方式 / NOT NULL = 再開可能アップロード
+-- uploaded_bytes: 受信済みバイト数（= 次に受け付けるオフセット）
+ALTER TABLE material_uploads
+    ADD COLUMN multipart_upload_id TEXT   NULL,
+    ADD COLUMN uploaded_bytes      BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE material_uploads
+    ADD CONSTRAINT material_uploads_uploaded_bytes_chk
+        CHECK (uploaded_bytes >= 0 AND uploaded_bytes <= size_bytes);
+
+-- ── material_upload_parts ─────────────────────────────────────────────
+-- 受信済みパート（CompleteMultipartUpload に渡す ETag を保持）
+CREATE TABLE material_upload_parts (
+    upload_id   UUID        NOT NULL,
+    part_number INT         NOT NULL,   -- 1 始まり（S3 の PartNumber）
+    etag        TEXT        NOT NULL,
+    size_bytes  BIGINT      NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT material_upload_parts_pkey      PRIMARY KEY (upload_id, part_number),
+    CONSTRAINT material_upload_parts_upload_fk FOREIGN KEY (upload_id)
+        REFERENCES material_uploads (upload_id) ON DELETE CASCADE
+);
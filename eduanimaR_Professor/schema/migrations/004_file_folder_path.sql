@@ -0,0 +1,10 @@
+-- ===================================================================
+-- 004_file_folder_path.sql
+-- ZIP 一括取り込み時のフォルダ階層を教材メタデータとして保持する
+-- 適用方法: atlas migrate apply --dir "file://schema/migrations" --url $DATABASE_URL
+-- ===================================================================
+
+-- ── files 拡張 ────────────────────────────────────────────────────────
+-- folder_path: ZIP 内のフォルダパス（例: "第1回/スライド"）。単体アップロードは NULL
+ALTER TABLE files
+    ADD COLUMN folder_path TEXT NULL;
@@ -0,0 +1,12 @@
+-- ===================================================================
+-- 005_file_source_url.sql
+-- URL からの教材取り込み（取得元 URL と内容ハッシュによる変更検知）
+-- 適用方法: atlas migrate apply --dir "file://schema/migrations" --url $DATABASE_URL
+-- ===================================================================
+
+-- ── files 拡張 ────────────────────────────────────────────────────────
+-- source_url:     取り込み元 URL（アップロードされた教材は NULL）
+-- content_sha256: 取得した内容の SHA-256（16 進）。再取得時に変更有無の判定に使う
+ALTER TABLE files
+    ADD COLUMN source_url     TEXT NULL,
+    ADD COLUMN content_sha256 TEXT NULL;
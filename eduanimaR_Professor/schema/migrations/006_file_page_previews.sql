@@ -0,0 +1,13 @@
+-- ===================================================================
+-- 006_file_page_previews.sql
+-- Ingest 時に生成するページプレビュー（サムネイル）画像の保存先
+-- 適用方法: atlas migrate apply --dir "file://schema/migrations" --url $DATABASE_URL
+-- ===================================================================
+
+-- ── files 拡張 ────────────────────────────────────────────────────────
+-- preview_path:       サムネイル画像を置くディレクトリのストレージパス
+--                     （例: "minio://bucket/previews/{file_id}"）。未生成は NULL
+-- preview_page_count: 生成済みサムネイルのページ数（未生成は 0）
+ALTER TABLE files
+    ADD COLUMN preview_path       TEXT NULL,
+    ADD COLUMN preview_page_count INT  NOT NULL DEFAULT 0;
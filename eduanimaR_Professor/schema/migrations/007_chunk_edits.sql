@@ -0,0 +1,34 @@
+-- ===================================================================
+-- 007_chunk_edits.sql
+-- チャンクの手動修正（編集・分割・結合・削除）と修正履歴
+-- 適用方法: atlas migrate apply --dir "file://schema/migrations" --url $DATABASE_URL
+-- ===================================================================
+
+-- ── chunks 拡張 ───────────────────────────────────────────────────────
+-- manually_edited: 手動修正されたチャンク。再処理（URL 再取得等）でも明示的な指定が無い限り置き換えない
+-- updated_at:      最後に手動修正した日時（未修正は NULL）
+ALTER TABLE chunks
+    ADD COLUMN manually_edited BOOLEAN     NOT NULL DEFAULT FALSE,
+    ADD COLUMN updated_at      TIMESTAMPTZ NULL;
+
+-- ── chunk_edits ───────────────────────────────────────────────────────
+-- 手動修正の履歴（1 操作 = 1 行）。チャンクは削除・結合で消えるため、
+-- 変更前後の内容をスナップショットとして保持する
+CREATE TABLE chunk_edits (
+    edit_id       UUID        NOT NULL DEFAULT uuidv7(),
+    file_id       UUID        NOT NULL,
+    user_id       UUID        NOT NULL,
+    operation     TEXT        NOT NULL, -- edit / split / merge / delete
+    before_chunks JSONB       NOT NULL, -- [{chunk_id, chunk_index, page_number, content}]
+    after_chunks  JSONB       NOT NULL, -- 同上（delete の場合は空配列）
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chunk_edits_pkey      PRIMARY KEY (edit_id),
+    CONSTRAINT chunk_edits_file_fk   FOREIGN KEY (file_id)
+        REFERENCES files (file_id) ON DELETE CASCADE,
+    CONSTRAINT chunk_edits_user_fk   FOREIGN KEY (user_id)
+        REFERENCES users (user_id) ON DELETE CASCADE,
+    CONSTRAINT chunk_edits_operation CHECK (operation IN ('edit', 'split', 'merge', 'delete'))
+);
+
+CREATE INDEX idx_chunk_edits_file_id ON chunk_edits (file_id, created_at DESC);
@@ -0,0 +1,11 @@
+-- ===================================================================
+-- 008_cross_subject_chat.sql
+-- 複数 subject を横断する質問応答（関連科目をまとめて検索する）
+-- 適用方法: atlas migrate apply --dir "file://schema/migrations" --url $DATABASE_URL
+-- ===================================================================
+
+-- ── qa_sessions 拡張 ──────────────────────────────────────────────────
+-- extra_subject_ids: subject_id に加えて検索対象にした subject（所有権確認済み。空の場合は subject_id のみ）
+-- セッションの一覧・所属は従来どおり subject_id（質問を起点にした subject）で管理する
+ALTER TABLE qa_sessions
+    ADD COLUMN extra_subject_ids UUID[] NOT NULL DEFAULT '{}';
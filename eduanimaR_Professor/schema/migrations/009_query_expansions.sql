@@ -0,0 +1,11 @@
+-- ===================================================================
+-- 009_query_expansions.sql
+-- ベクトル検索クエリの書き換え・拡張（言い換え・仮想回答・日英訳）の監査記録
+-- 適用方法: atlas migrate apply --dir "file://schema/migrations" --url $DATABASE_URL
+-- ===================================================================
+
+-- ── qa_sessions 拡張 ──────────────────────────────────────────────────
+-- query_expansions: Librarian のベクトル検索クエリごとに生成した変形の一覧（JSONB）
+-- 拡張を行わなかったセッションは NULL
+ALTER TABLE qa_sessions
+    ADD COLUMN query_expansions JSONB;
@@ -0,0 +1,34 @@
+-- ===================================================================
+-- 010_answer_cache.sql
+-- 回答キャッシュ（同じ subject での似た質問に、生成済みの回答と出典を返す）
+-- 適用方法: atlas migrate apply --dir "file://schema/migrations" --url $DATABASE_URL
+-- ===================================================================
+
+-- ── answer_cache ──────────────────────────────────────────────────────
+-- キー: subject（extra_subject_ids はソート済み） + 教材のバージョン + 質問の埋め込みの類似度
+-- material_version: 検索対象のチャンク数と最終更新日時から作る指紋。
+--   教材の追加・削除・再処理・チャンク編集で変わり、古いバージョンのエントリは一致しなくなる
+-- 新しいエントリの保存時に、同じ subject の古いバージョンのエントリを削除する
+CREATE TABLE answer_cache (
+    cache_id           UUID        NOT NULL DEFAULT uuidv7(),
+    subject_id         UUID        NOT NULL,
+    extra_subject_ids  UUID[]      NOT NULL DEFAULT '{}',
+    material_version   TEXT        NOT NULL,
+    question           TEXT        NOT NULL,
+    question_embedding vector(768) NOT NULL,
+    answer             TEXT        NOT NULL,
+    sources            JSONB       NOT NULL,
+    session_id         UUID        NULL,     -- 回答を生成した質問セッション
+    hit_count          INT         NOT NULL DEFAULT 0,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_hit_at        TIMESTAMPTZ NULL,
+
+    CONSTRAINT answer_cache_pkey       PRIMARY KEY (cache_id),
+    CONSTRAINT answer_cache_subject_fk FOREIGN KEY (subject_id)
+        REFERENCES subjects (subject_id) ON DELETE CASCADE,
+    CONSTRAINT answer_cache_session_fk FOREIGN KEY (session_id)
+        REFERENCES qa_sessions (session_id) ON DELETE SET NULL
+);
+
+-- 1 subject あたりのエントリは少ないため、類似度は絞り込み後に全件で計算する（HNSW は使わない）
+CREATE INDEX idx_answer_cache_subject_version ON answer_cache (subject_id, material_version);
@@ -0,0 +1,19 @@
+-- ===================================================================
+-- 011_embedding_cache.sql
+-- 検索クエリの埋め込みキャッシュ（同じクエリの埋め込みをユーザー・検索ラウンドをまたいで再利用する）
+-- 適用方法: atlas migrate apply --dir "file://schema/migrations" --url $DATABASE_URL
+-- ===================================================================
+
+-- ── embedding_cache ───────────────────────────────────────────────────
+-- cache_key: 埋め込みモデル名とクエリ文字列の SHA-256（16 進）
+-- model: 埋め込みモデル名（モデル変更後に古いエントリを削除するため）
+CREATE TABLE embedding_cache (
+    cache_key  TEXT        NOT NULL,
+    model      TEXT        NOT NULL,
+    embedding  vector(768) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT embedding_cache_pkey PRIMARY KEY (cache_key)
+);
+
+CREATE INDEX idx_embedding_cache_model ON embedding_cache (model);
@@ -0,0 +1,23 @@
+-- ===================================================================
+-- 012_qa_session_status.sql
+-- 質問セッションの回答生成の状態（中断したセッションは途中までの回答を保存する）
+-- 適用方法: atlas migrate apply --dir "file://schema/migrations" --url $DATABASE_URL
+-- ===================================================================
+
+CREATE TYPE qa_session_status AS ENUM (
+    'running',
+    'completed',
+    'cancelled',
+    'failed'
+);
+
+-- ── qa_sessions 拡張 ──────────────────────────────────────────────────
+-- status: running（回答生成中） / completed（回答済み） / cancelled（クライアントの切断・中断） / failed（エラー）
+-- 既存のセッションは回答の有無で completed / failed とする
+ALTER TABLE qa_sessions
+    ADD COLUMN status qa_session_status NOT NULL DEFAULT 'completed';
+
+UPDATE qa_sessions SET status = 'failed' WHERE answer IS NULL;
+
+ALTER TABLE qa_sessions
+    ALTER COLUMN status SET DEFAULT 'running';
@@ -0,0 +1,39 @@
+-- ===================================================================
+-- 013_qa_answer_versions.sql
+-- 回答の再生成（同じ質問セッションに回答の版を追加し、表示する版を選べるようにする）
+-- 適用方法: atlas migrate apply --dir "file://schema/migrations" --url $DATABASE_URL
+-- ===================================================================
+
+-- ── qa_sessions 拡張 ──────────────────────────────────────────────────
+-- selected_version: クライアントに表示する回答版（answer / sources / feedback / status / answered_at はこの版の値を保持する）
+-- latest_version:   最新の回答版の番号（回答版の数）
+-- 既存のセッションは版 1 のみとし、新しいセッションは版 1 を作るときに latest_version を 1 にする
+ALTER TABLE qa_sessions
+    ADD COLUMN selected_version INT NOT NULL DEFAULT 1,
+    ADD COLUMN latest_version   INT NOT NULL DEFAULT 1;
+
+ALTER TABLE qa_sessions
+    ALTER COLUMN latest_version SET DEFAULT 0;
+
+-- ── qa_answer_versions ───────────────────────────────────────────────
+-- 質問セッションの回答の版（最初の回答が 1、再生成するたびに増える）。フィードバックは版ごとに記録する
+CREATE TABLE qa_answer_versions (
+    session_id  UUID              NOT NULL,
+    version     INT               NOT NULL,
+    answer      TEXT              NULL,
+    sources     JSONB             NULL,
+    feedback    SMALLINT          NULL,    -- -1: bad, 1: good (NULL: 未評価)
+    status      qa_session_status NOT NULL DEFAULT 'running',
+    retrieved   BOOLEAN           NOT NULL DEFAULT TRUE, -- FALSE: 前の版の出典を再利用して生成した
+    created_at  TIMESTAMPTZ       NOT NULL DEFAULT NOW(),
+    answered_at TIMESTAMPTZ       NULL,
+
+    CONSTRAINT qa_answer_versions_pkey         PRIMARY KEY (session_id, version),
+    CONSTRAINT qa_answer_versions_session_fk   FOREIGN KEY (session_id)
+        REFERENCES qa_sessions (session_id) ON DELETE CASCADE,
+    CONSTRAINT qa_answer_versions_feedback_chk CHECK (feedback IS NULL OR feedback IN (-1, 1))
+);
+
+INSERT INTO qa_answer_versions (session_id, version, answer, sources, feedback, status, created_at, answered_at)
+SELECT session_id, 1, answer, sources, feedback, status, created_at, answered_at
+FROM qa_sessions;
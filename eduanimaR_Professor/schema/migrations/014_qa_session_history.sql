@@ -0,0 +1,16 @@
+-- ===================================================================
+-- 014_qa_session_history.sql
+-- 会話履歴のキーセットページングと過去の質問の文字列検索
+-- 適用方法: atlas migrate apply --dir "file://schema/migrations" --url $DATABASE_URL
+-- ===================================================================
+
+-- 日本語の質問は単語に区切れないため、部分一致（ILIKE）を pg_trgm の GIN インデックスで引く
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- ── qa_sessions インデックス ─────────────────────────────────────────
+-- 一覧は subject・ユーザーごとに (created_at, session_id) の新しい順でキーセットページングする
+CREATE INDEX idx_qa_sessions_history
+    ON qa_sessions (subject_id, user_id, created_at DESC, session_id DESC);
+
+CREATE INDEX idx_qa_sessions_question_trgm
+    ON qa_sessions USING GIN (question gin_trgm_ops);
@@ -0,0 +1,11 @@
+-- ===================================================================
+-- 015_qa_reasoning_trace.sql
+-- 回答版ごとの推論の記録（検索ラウンド・選んだエビデンス・Librarian の結果・モデル名・所要時間）
+-- 適用方法: atlas migrate apply --dir "file://schema/migrations" --url $DATABASE_URL
+-- ===================================================================
+
+-- ── qa_answer_versions 拡張 ──────────────────────────────────────────
+-- trace: 回答の品質を後から調べるためのデバッグ用の記録（domain.ReasoningTrace の JSON）
+-- 既存の回答版と、記録する前に終了した回答版は NULL
+ALTER TABLE qa_answer_versions
+    ADD COLUMN trace JSONB NULL;
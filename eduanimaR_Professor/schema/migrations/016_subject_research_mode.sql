@@ -0,0 +1,12 @@
+-- ===================================================================
+-- 016_subject_research_mode.sql
+-- 科目ごとの調べる深さ（Librarian の検索ラウンド数・検索結果数・制限時間の組）
+-- 適用方法: atlas migrate apply --dir "file://schema/migrations" --url $DATABASE_URL
+-- ===================================================================
+
+-- ── subjects 拡張 ────────────────────────────────────────────────────
+-- research_mode: 'quick'（速く答える） / 'standard'（既定） / 'deep'（時間をかけて広く調べる）
+-- 各モードの制約はサーバー設定（LIBRARIAN_* 環境変数）で決め、質問ごとに上書きできる
+ALTER TABLE subjects
+    ADD COLUMN research_mode TEXT NOT NULL DEFAULT 'standard',
+    ADD CONSTRAINT subjects_research_mode_chk CHECK (research_mode IN ('quick', 'standard', 'deep'));
@@ -0,0 +1,54 @@
+-- sql/queries/answer_cache.sql
+
+-- name: GetMaterialVersion :one
+-- 検索対象の subject の教材のバージョン（チャンク数と最終更新日時）
+-- 教材の追加・削除・再処理・チャンク編集のいずれでも変わる
+SELECT
+    COUNT(*)::bigint AS chunk_count,
+    COALESCE(MAX(COALESCE(updated_at, created_at)), 'epoch'::timestamptz)::timestamptz AS last_changed_at
+FROM chunks
+WHERE subject_id = ANY(sqlc.arg(subject_ids)::uuid[]);
+
+-- name: FindSimilarCachedAnswer :one
+-- 同じ subject・教材バージョンで質問の埋め込みが最も近いエントリ
+-- similarity: コサイン類似度（1 - コサイン距離）
+SELECT
+    cache_id,
+    subject_id,
+    extra_subject_ids,
+    material_version,
+    question,
+    answer,
+    sources,
+    session_id,
+    hit_count,
+    created_at,
+    (1 - (question_embedding <=> sqlc.arg(question_embedding)::vector))::float8 AS similarity
+FROM answer_cache
+WHERE subject_id        = sqlc.arg(subject_id)
+  AND extra_subject_ids = sqlc.arg(extra_subject_ids)::uuid[]
+  AND material_version  = sqlc.arg(material_version)
+  AND created_at       >= sqlc.arg(created_after)
+ORDER BY question_embedding <=> sqlc.arg(question_embedding)::vector
+LIMIT 1;
+
+-- name: InsertCachedAnswer :exec
+INSERT INTO answer_cache (
+    cache_id, subject_id, extra_subject_ids, material_version,
+    question, question_embedding, answer, sources, session_id
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+
+-- name: DeleteStaleCachedAnswers :exec
+-- 同じ検索対象で教材のバージョンが変わった、または有効期限を過ぎたエントリを削除する
+DELETE FROM answer_cache
+WHERE subject_id        = sqlc.arg(subject_id)
+  AND extra_subject_ids = sqlc.arg(extra_subject_ids)::uuid[]
+  AND (material_version <> sqlc.arg(material_version) OR created_at < sqlc.arg(created_after));
+
+-- name: RecordCachedAnswerHit :exec
+UPDATE answer_cache
+SET
+    hit_count   = hit_count + 1,
+    last_hit_at = NOW()
+WHERE cache_id = $1;
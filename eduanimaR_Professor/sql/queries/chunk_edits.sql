@@ -0,0 +1,28 @@
+-- sql/queries/chunk_edits.sql
+
+-- name: InsertChunkEdit :exec
+INSERT INTO chunk_edits (
+    edit_id,
+    file_id,
+    user_id,
+    operation,
+    before_chunks,
+    after_chunks,
+    created_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListChunkEditsByFileID :many
+-- 修正履歴を新しい順に取得する
+-- $1: file_id, $2: limit, $3: offset
+SELECT *
+FROM chunk_edits
+WHERE file_id = $1
+ORDER BY created_at DESC
+LIMIT  $2
+OFFSET $3;
+
+-- name: CountChunkEditsByFileID :one
+SELECT COUNT(*)
+FROM chunk_edits
+WHERE file_id = $1;
@@ -6,22 +6,36 @@ FROM chunks
 WHERE file_id = $1
 ORDER BY chunk_index;
 
--- name: InsertChunk :one
-INSERT INTO chunks (
+-- name: ListChunkEmbeddings :many
+-- 検索候補の embedding の取得（MMR による多様性を考慮した並べ替えに使う）
+SELECT
+    chunk_id,
+    embedding
+FROM chunks
+WHERE chunk_id = ANY(sqlc.arg(chunk_ids)::uuid[]);
+
+-- name: ListChunkPageByFileID :many
+-- チャンク閲覧用のページング取得（embedding は返さない）
+-- $1: file_id, $2: limit, $3: offset
+SELECT
     chunk_id,
     file_id,
     subject_id,
     page_number,
     chunk_index,
     content,
-    embedding
-)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING *;
+    created_at,
+    manually_edited,
+    updated_at
+FROM chunks
+WHERE file_id = $1
+ORDER BY chunk_index
+LIMIT  $2
+OFFSET $3;
 
--- name: SearchChunksByVector :many
--- コサイン類似度でのベクトル検索（HNSW インデックス使用）
--- $1: query_embedding (vector), $2: subject_id, $3: limit
+-- name: ListNeighbourChunks :many
+-- エビデンスの前後文脈の取得（embedding は返さない）
+-- chunk_index が index_from〜index_to の範囲、または page_number が一致するチャンク（page_number が NULL の場合は範囲のみ）
 SELECT
     chunk_id,
     file_id,
@@ -29,28 +43,156 @@ SELECT
     page_number,
     chunk_index,
     content,
-    created_at
+    created_at,
+    manually_edited,
+    updated_at
 FROM chunks
-WHERE subject_id = $2
-ORDER BY embedding <=> $1::vector
-LIMIT $3;
+WHERE file_id = sqlc.arg(file_id)
+  AND (
+        chunk_index BETWEEN sqlc.arg(index_from)::int AND sqlc.arg(index_to)::int
+     OR (sqlc.narg(page_number)::int IS NOT NULL AND page_number = sqlc.narg(page_number)::int)
+  )
+ORDER BY chunk_index;
 
--- name: SearchChunksByText :many
--- 全文検索（simple 辞書 / plainto_tsquery）
--- $1: query_text, $2: subject_id, $3: limit
-SELECT
+-- name: CountChunksByFileID :one
+SELECT COUNT(*)
+FROM chunks
+WHERE file_id = $1;
+
+-- name: CountChunksBySubjectIDs :one
+-- 検索対象の subject のチャンク数（max_count + 1 件で数えるのを打ち切る）
+-- ベクトル検索で HNSW インデックスを使わず厳密検索するかの判定に使う
+SELECT COUNT(*)
+FROM (
+    SELECT 1
+    FROM chunks
+    WHERE subject_id = ANY(sqlc.arg(subject_ids)::uuid[])
+    LIMIT sqlc.arg(max_count)::int + 1
+) AS scoped;
+
+-- name: GetChunkByID :one
+SELECT *
+FROM chunks
+WHERE chunk_id = $1;
+
+-- name: InsertChunk :one
+INSERT INTO chunks (
     chunk_id,
     file_id,
     subject_id,
     page_number,
     chunk_index,
     content,
-    created_at
+    embedding,
+    manually_edited,
+    updated_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: UpsertEditedChunk :exec
+-- 手動修正したチャンクを作成または更新する（manually_edited = TRUE）
+INSERT INTO chunks (
+    chunk_id,
+    file_id,
+    subject_id,
+    page_number,
+    chunk_index,
+    content,
+    embedding,
+    manually_edited,
+    updated_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, TRUE, NOW())
+ON CONFLICT (chunk_id) DO UPDATE
+SET page_number     = EXCLUDED.page_number,
+    chunk_index     = EXCLUDED.chunk_index,
+    content         = EXCLUDED.content,
+    embedding       = EXCLUDED.embedding,
+    manually_edited = TRUE,
+    updated_at      = NOW();
+
+-- name: LockChunksByFileID :many
+-- 手動修正のトランザクション内でファイルの全チャンクを行ロックし、修正前の状態を読み直す
+-- 同じ教材への修正はここで直列化される
+SELECT chunk_id, chunk_index, content
 FROM chunks
-WHERE subject_id = $2
-  AND to_tsvector('simple', content) @@ plainto_tsquery('simple', $1)
-ORDER BY ts_rank(to_tsvector('simple', content), plainto_tsquery('simple', $1)) DESC
-LIMIT $3;
+WHERE file_id = $1
+ORDER BY chunk_index
+FOR UPDATE;
+
+-- name: ShiftChunkIndexes :exec
+-- 分割・結合・削除の後にファイル内連番を詰める／空ける
+-- file_id 内で from_index 以上の chunk_index に delta を加算する
+UPDATE chunks
+SET chunk_index = chunk_index + sqlc.arg(delta)::int
+WHERE file_id = sqlc.arg(file_id)
+  AND chunk_index >= sqlc.arg(from_index)::int;
+
+-- name: DeleteChunkByID :exec
+DELETE FROM chunks
+WHERE chunk_id = $1;
+
+-- name: SearchChunksByVector :many
+-- コサイン類似度でのベクトル検索（HNSW インデックス使用。ef_search・iterative_scan・厳密検索への切り替えは SetLocalSearchConfig で行う）
+-- subject_ids: 所有権確認済みの subject（物理制約。複数 subject を横断する場合も必ず指定する）
+-- file_ids が空の場合は全教材、page_from / page_to が NULL の場合はページで絞り込まない
+-- score: コサイン類似度（1 - コサイン距離）
+SELECT
+    c.chunk_id,
+    c.file_id,
+    c.subject_id,
+    c.page_number,
+    c.chunk_index,
+    c.content,
+    c.created_at,
+    f.name AS file_name,
+    f.mime_type,
+    f.preview_page_count,
+    s.name AS subject_name,
+    (1 - (c.embedding <=> sqlc.arg(query_embedding)::vector))::float8 AS score
+FROM chunks c
+JOIN files f ON f.file_id = c.file_id
+JOIN subjects s ON s.subject_id = c.subject_id
+WHERE c.subject_id = ANY(sqlc.arg(subject_ids)::uuid[])
+  AND (cardinality(sqlc.arg(file_ids)::uuid[]) = 0 OR c.file_id = ANY(sqlc.arg(file_ids)::uuid[]))
+  AND (sqlc.narg(page_from)::int IS NULL OR c.page_number >= sqlc.narg(page_from)::int)
+  AND (sqlc.narg(page_to)::int IS NULL OR c.page_number <= sqlc.narg(page_to)::int)
+ORDER BY c.embedding <=> sqlc.arg(query_embedding)::vector
+LIMIT sqlc.arg(max_results);
+
+-- name: SetLocalSearchConfig :exec
+-- 検索用のパラメータ（hnsw.ef_search など）をトランザクション内に限って設定する
+SELECT set_config(sqlc.arg(name)::text, sqlc.arg(value)::text, true);
+
+-- name: SearchChunksByText :many
+-- 全文検索（simple 辞書 / plainto_tsquery）
+-- subject_ids: 所有権確認済みの subject（物理制約。複数 subject を横断する場合も必ず指定する）
+-- file_ids が空の場合は全教材、page_from / page_to が NULL の場合はページで絞り込まない
+-- score: ts_rank
+SELECT
+    c.chunk_id,
+    c.file_id,
+    c.subject_id,
+    c.page_number,
+    c.chunk_index,
+    c.content,
+    c.created_at,
+    f.name AS file_name,
+    f.mime_type,
+    f.preview_page_count,
+    s.name AS subject_name,
+    ts_rank(to_tsvector('simple', c.content), plainto_tsquery('simple', sqlc.arg(query_text)))::float8 AS score
+FROM chunks c
+JOIN files f ON f.file_id = c.file_id
+JOIN subjects s ON s.subject_id = c.subject_id
+WHERE c.subject_id = ANY(sqlc.arg(subject_ids)::uuid[])
+  AND to_tsvector('simple', c.content) @@ plainto_tsquery('simple', sqlc.arg(query_text))
+  AND (cardinality(sqlc.arg(file_ids)::uuid[]) = 0 OR c.file_id = ANY(sqlc.arg(file_ids)::uuid[]))
+  AND (sqlc.narg(page_from)::int IS NULL OR c.page_number >= sqlc.narg(page_from)::int)
+  AND (sqlc.narg(page_to)::int IS NULL OR c.page_number <= sqlc.narg(page_to)::int)
+ORDER BY score DESC, c.chunk_id
+LIMIT sqlc.arg(max_results);
 
 -- name: DeleteChunksByFileID :exec
 DELETE FROM chunks
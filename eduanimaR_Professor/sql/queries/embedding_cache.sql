@@ -0,0 +1,11 @@
+-- sql/queries/embedding_cache.sql
+
+-- name: GetCachedEmbedding :one
+SELECT embedding
+FROM embedding_cache
+WHERE cache_key = sqlc.arg(cache_key);
+
+-- name: UpsertCachedEmbedding :exec
+INSERT INTO embedding_cache (cache_key, model, embedding)
+VALUES (sqlc.arg(cache_key), sqlc.arg(model), sqlc.arg(embedding))
+ON CONFLICT (cache_key) DO NOTHING;
@@ -17,6 +17,12 @@ FROM files
 WHERE subject_id = $1
 ORDER BY uploaded_at DESC;
 
+-- name: ListAllFiles :many
+-- ストレージ整合性チェック（孤立オブジェクト検出）用の全件取得
+SELECT *
+FROM files
+ORDER BY file_id;
+
 -- name: CreateFile :one
 INSERT INTO files (
     file_id,
@@ -26,9 +32,12 @@ INSERT INTO files (
     storage_path,
     mime_type,
     size_bytes,
-    status
+    status,
+    folder_path,
+    source_url,
+    content_sha256
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 RETURNING *;
 
 -- name: UpdateFileStatus :one
@@ -43,6 +52,30 @@ SET
 WHERE file_id = $1
 RETURNING *;
 
+-- name: UpdateFileContent :one
+-- 再取得で内容が変わった教材を再処理待ちに戻す（種別が変わった場合は教材名も更新する）
+UPDATE files
+SET
+    name           = $2,
+    storage_path   = $3,
+    mime_type      = $4,
+    size_bytes     = $5,
+    content_sha256 = $6,
+    status         = 'pending',
+    error_message  = NULL,
+    processed_at   = NULL
+WHERE file_id = $1
+RETURNING *;
+
+-- name: UpdateFilePreviews :one
+-- Ingest 時に生成したページプレビューの保存先とページ数を記録する
+UPDATE files
+SET
+    preview_path       = $2,
+    preview_page_count = $3
+WHERE file_id = $1
+RETURNING *;
+
 -- name: DeleteFile :exec
 DELETE FROM files
 WHERE file_id = $1
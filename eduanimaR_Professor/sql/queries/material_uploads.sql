@@ -0,0 +1,66 @@
+-- sql/queries/material_uploads.sql
+
+-- name: CreateMaterialUpload :one
+INSERT INTO material_uploads (
+    upload_id,
+    file_id,
+    subject_id,
+    user_id,
+    file_name,
+    storage_key,
+    mime_type,
+    size_bytes,
+    expires_at,
+    multipart_upload_id
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: GetMaterialUploadByIDAndUserID :one
+SELECT *
+FROM material_uploads
+WHERE upload_id = $1
+  AND user_id   = $2;
+
+-- name: MarkMaterialUploadCompleted :one
+-- 未完了のレコードのみ完了にする（二重 complete 防止）
+UPDATE material_uploads
+SET completed_at = NOW()
+WHERE upload_id = $1
+  AND completed_at IS NULL
+RETURNING *;
+
+-- name: ListExpiredMaterialUploads :many
+-- 期限切れかつ未完了のアップロード（孤立オブジェクト掃除用）
+SELECT *
+FROM material_uploads
+WHERE completed_at IS NULL
+  AND expires_at < $1
+ORDER BY expires_at
+LIMIT $2;
+
+-- name: DeleteMaterialUpload :exec
+DELETE FROM material_uploads
+WHERE upload_id = $1;
+
+-- name: AddMaterialUploadPart :one
+-- オフセットが一致する場合のみ受信済みバイト数を進め、パートを記録する（楽観ロック）
+WITH advanced AS (
+    UPDATE material_uploads
+    SET uploaded_bytes = uploaded_bytes + sqlc.arg(size_bytes)::bigint,
+        expires_at     = sqlc.arg(expires_at)::timestamptz
+    WHERE material_uploads.upload_id = sqlc.arg(upload_id)
+      AND uploaded_bytes = sqlc.arg(expected_offset)::bigint
+      AND completed_at IS NULL
+    RETURNING upload_id
+)
+INSERT INTO material_upload_parts (upload_id, part_number, etag, size_bytes)
+SELECT upload_id, sqlc.arg(part_number)::int, sqlc.arg(etag)::text, sqlc.arg(size_bytes)::bigint
+FROM advanced
+RETURNING *;
+
+-- name: ListMaterialUploadParts :many
+SELECT *
+FROM material_upload_parts
+WHERE upload_id = $1
+ORDER BY part_number;
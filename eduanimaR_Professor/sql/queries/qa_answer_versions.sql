@@ -0,0 +1,50 @@
+-- sql/queries/qa_answer_versions.sql
+
+-- name: CreateQAAnswerVersion :one
+-- 回答版を追加し、セッションの最新版とする（最初の回答は 1）
+WITH next AS (
+    UPDATE qa_sessions
+    SET latest_version = latest_version + 1
+    WHERE qa_sessions.session_id = $1
+    RETURNING session_id, latest_version
+)
+INSERT INTO qa_answer_versions (session_id, version, retrieved)
+SELECT session_id, latest_version, $2
+FROM next
+RETURNING *;
+
+-- name: FinishLatestQAAnswerVersion :exec
+-- 最新の回答版に回答・出典・推論の記録と終了時の状態を保存する
+UPDATE qa_answer_versions
+SET
+    answer      = $2,
+    sources     = $3,
+    status      = $4,
+    trace       = $5,
+    answered_at = NOW()
+WHERE qa_answer_versions.session_id = $1
+  AND version = (SELECT s.latest_version FROM qa_sessions s WHERE s.session_id = $1);
+
+-- name: GetQAAnswerVersionTrace :one
+-- 回答版の推論の記録（記録が無い場合は NULL）
+SELECT version, trace
+FROM qa_answer_versions
+WHERE session_id = $1
+  AND version    = $2;
+
+-- name: ListQAAnswerVersions :many
+-- 出典の subject を補うため、セッションの subject も返す
+SELECT v.*, s.subject_id
+FROM qa_answer_versions v
+JOIN qa_sessions s ON s.session_id = v.session_id
+WHERE v.session_id = $1
+ORDER BY v.version;
+
+-- name: UpdateQAAnswerVersionFeedback :execrows
+UPDATE qa_answer_versions v
+SET feedback = $3
+FROM qa_sessions s
+WHERE v.session_id = $1
+  AND v.version    = $2
+  AND s.session_id = v.session_id
+  AND s.user_id    = $4;
@@ -1,8 +1,8 @@
 -- sql/queries/qa_sessions.sql
 
 -- name: CreateQASession :one
-INSERT INTO qa_sessions (session_id, user_id, subject_id, question)
-VALUES ($1, $2, $3, $4)
+INSERT INTO qa_sessions (session_id, user_id, subject_id, question, extra_subject_ids)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: GetQASessionByID :one
@@ -17,35 +17,53 @@ WHERE session_id = $1
   AND user_id    = $2;
 
 -- name: ListQASessionsBySubjectID :many
+-- 新しい順のキーセットページング（cursor_created_at / cursor_session_id が NULL の場合は最新から）
+-- question_pattern: 質問の ILIKE パターン（空の場合は絞り込まない）
 SELECT
     session_id, user_id, subject_id,
     question, answer, sources, feedback,
-    created_at, answered_at
+    created_at, answered_at, extra_subject_ids, query_expansions, status,
+    selected_version, latest_version
 FROM qa_sessions
-WHERE subject_id = $1
-  AND user_id    = $2
-ORDER BY created_at DESC
-LIMIT  $3
-OFFSET $4;
+WHERE subject_id = sqlc.arg(subject_id)
+  AND user_id    = sqlc.arg(user_id)
+  AND (sqlc.narg(cursor_created_at)::timestamptz IS NULL
+       OR (created_at, session_id) < (sqlc.narg(cursor_created_at)::timestamptz, sqlc.narg(cursor_session_id)::uuid))
+  AND (sqlc.arg(question_pattern)::text = '' OR question ILIKE sqlc.arg(question_pattern)::text)
+ORDER BY created_at DESC, session_id DESC
+LIMIT sqlc.arg(max_results);
 
 -- name: CountQASessionsBySubjectID :one
 SELECT COUNT(*)
 FROM qa_sessions
-WHERE subject_id = $1
-  AND user_id    = $2;
+WHERE subject_id = sqlc.arg(subject_id)
+  AND user_id    = sqlc.arg(user_id)
+  AND (sqlc.arg(question_pattern)::text = '' OR question ILIKE sqlc.arg(question_pattern)::text);
 
--- name: UpdateQASessionAnswer :one
+-- name: SelectQASessionVersion :execrows
+-- 表示する回答版を選ぶ（存在しない版の場合は 0 行）
 UPDATE qa_sessions
-SET
-    answer      = $2,
-    sources     = $3,
-    answered_at = NOW()
+SET selected_version = $2
 WHERE session_id = $1
-RETURNING *;
+  AND user_id    = $3
+  AND $2 BETWEEN 1 AND latest_version;
+
+-- name: SyncQASessionWithSelectedVersion :one
+-- 選ばれている回答版の回答・出典・フィードバック・状態をセッションに反映する
+UPDATE qa_sessions s
+SET
+    answer      = v.answer,
+    sources     = v.sources,
+    feedback    = v.feedback,
+    status      = v.status,
+    answered_at = v.answered_at
+FROM qa_answer_versions v
+WHERE s.session_id = $1
+  AND v.session_id = s.session_id
+  AND v.version    = s.selected_version
+RETURNING s.*;
 
--- name: UpdateQASessionFeedback :one
+-- name: UpdateQASessionQueryExpansions :exec
 UPDATE qa_sessions
-SET feedback = $2
-WHERE session_id = $1
-  AND user_id    = $3
-RETURNING *;
+SET query_expansions = $2
+WHERE session_id = $1;
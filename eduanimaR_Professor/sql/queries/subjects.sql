@@ -18,8 +18,8 @@ WHERE subject_id = $1
   AND user_id    = $2;
 
 -- name: CreateSubject :one
-INSERT INTO subjects (subject_id, user_id, name, lms_course_id)
-VALUES ($1, $2, $3, $4)
+INSERT INTO subjects (subject_id, user_id, name, lms_course_id, research_mode)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: UpdateSubjectName :one
@@ -30,6 +30,14 @@ WHERE subject_id = $1
   AND user_id    = $3
 RETURNING *;
 
+-- name: UpdateSubjectResearchMode :one
+UPDATE subjects
+SET research_mode = $2,
+    updated_at    = NOW()
+WHERE subject_id = $1
+  AND user_id    = $3
+RETURNING *;
+
 -- name: DeleteSubject :exec
 DELETE FROM subjects
 WHERE subject_id = $1